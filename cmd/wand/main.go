@@ -2,12 +2,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
 	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
 	domainorchestrators "github.com/ochairo/wand/internal/domain-orchestrators"
+	"github.com/ochairo/wand/internal/domain/entities"
 	"github.com/ochairo/wand/internal/domain/services"
 	externaladapters "github.com/ochairo/wand/internal/external-adapters"
 	"github.com/ochairo/wand/internal/external-adapters/cli"
@@ -27,32 +34,104 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Failed to get home directory: %v\n", err)
 		os.Exit(1)
 	}
-	wandDir := filepath.Join(homeDir, ".wand")
+	fs := domainadapters.NewFileSystemAdapter()
+
+	// Load settings. A broken configuration stops every command except
+	// `wand config`, which is how it gets fixed.
+	configCommand := len(os.Args) > 1 && os.Args[1] == "config"
+	invalidSettings := func(what string, err error) {
+		if configCommand {
+			return // reported by the config subcommands themselves
+		}
+		fmt.Fprintf(os.Stderr, "%s: %v\n", what, err)
+		fmt.Fprintf(os.Stderr, "Run 'wand config validate' to check your settings\n")
+		os.Exit(1)
+	}
+	configRepo := domainadapters.NewConfigRepository(fs, domainadapters.ConfigPath(homeDir))
+	config, err := domainadapters.LoadConfig(configRepo, homeDir)
+	if err != nil {
+		invalidSettings("Invalid configuration", err)
+		config = entities.DefaultConfig()
+	}
+
+	wandDir := config.Home
+	if wandDir == "" {
+		wandDir = filepath.Join(homeDir, ".wand")
+	}
 	formulasDir := filepath.Join(wandDir, "formulas")
 
 	// Initialize domain adapters
-	fs := domainadapters.NewFileSystemAdapter()
-	downloader := domainadapters.NewDownloaderAdapter()
+	mirrors, err := domainadapters.ParseMirrors(strings.Join(config.Network.Mirrors, ","))
+	if err != nil {
+		invalidSettings("Invalid network.mirrors", err)
+	}
+	// Credentials stay beside the configuration file when home moves
+	network, err := domainadapters.LoadNetworkConfig(fs, filepath.Dir(configRepo.Path()))
+	if err != nil {
+		invalidSettings("Invalid network configuration", err)
+		network = &entities.NetworkConfig{}
+	}
+	config.ApplyTo(network)
+	transport, err := domainadapters.NewHTTPTransport(network, config.ConnectTimeoutDuration())
+	if err != nil {
+		invalidSettings("Invalid network configuration", err)
+		transport, _ = domainadapters.NewHTTPTransport(nil, config.ConnectTimeoutDuration())
+	}
+	downloader := domainadapters.NewDownloaderAdapterWithOptions(domainadapters.DownloaderOptions{
+		Transport:  transport,
+		Mirrors:    mirrors,
+		PartialDir: filepath.Join(wandDir, "cache", "downloads"),
+	})
+	verifier := domainadapters.NewVerifierAdapter()
 	extractor := domainadapters.NewExtractorAdapter(fs)
 	shellExecutor := domainadapters.NewShellExecutorAdapter()
+	httpChecker := domainadapters.NewHTTPCheckerAdapter(&http.Client{Transport: transport, Timeout: 30 * time.Second})
 
 	// Initialize repositories
 	registryRepo := domainadapters.NewRegistryRepository(fs, wandDir)
-	formulaRepo := domainadapters.NewFormulaRepository(fs, formulasDir)
+	lockRepo := domainadapters.NewChecksumLockRepository(fs, wandDir)
+	approvalRepo := domainadapters.NewHookApprovalRepository(fs, wandDir)
+	shimIndexRepo := domainadapters.NewShimIndexRepository(fs, wandDir)
+	formulaRepo := domainadapters.NewFormulaRepositoryWithOptions(fs, formulasDir, domainadapters.FormulaRepositoryOptions{
+		Taps:      config.Formulas.Taps,
+		RemoteURL: config.Formulas.Repository,
+		Network:   network,
+	})
 	wandrcRepo := domainadapters.NewWandRCRepository(fs)
 	wandfileRepo := domainadapters.NewWandfileRepository(fs)
 	dotfileRepo := domainadapters.NewDotfileRepository(fs, wandDir)
 
 	// Initialize external adapters
-	githubClient := externaladapters.NewGitHubAdapter("")
+	githubClient := externaladapters.NewGitHubAdapterWithOptions(externaladapters.GitHubAdapterOptions{
+		Token:            network.GitHubToken,
+		HTTPClient:       &http.Client{Transport: transport},
+		Timeout:          externaladapters.DefaultAPITimeout,
+		MaxRateLimitWait: externaladapters.DefaultRateLimitWait,
+		CacheDir:         filepath.Join(wandDir, "cache", "github"),
+	})
 
 	// Initialize domain services
 	versionService := services.NewVersionService(githubClient, formulaRepo)
-	shimService := services.NewShimService(registryRepo, wandrcRepo, formulaRepo, fs, wandDir)
+	executable, err := os.Executable()
+	if err != nil {
+		executable = "wand"
+	}
+	versionFiles, err := domainadapters.NewVersionFileReaders(fs, config.VersionFileNames())
+	if err != nil {
+		invalidSettings("Invalid version_files", err)
+	}
+	shimService := services.NewShimServiceWithOptions(registryRepo, wandrcRepo, formulaRepo, fs, wandDir, services.ShimServiceOptions{
+		Executable:   executable,
+		VersionFiles: versionFiles,
+		ShimIndex:    shimIndexRepo,
+	})
 	installerService := services.NewInstallerService(
 		formulaRepo,
 		registryRepo,
+		lockRepo,
+		approvalRepo,
 		downloader,
+		verifier,
 		extractor,
 		fs,
 		shellExecutor,
@@ -71,6 +150,19 @@ func main() {
 		homeDir,
 	)
 
+	formulaLinter := services.NewFormulaLinter(
+		formulaRepo,
+		httpChecker,
+		downloader,
+		verifier,
+		extractor,
+		fs,
+		shellExecutor,
+		versionService,
+		filepath.Join(wandDir, "tmp"),
+	)
+	formulaScaffolder := services.NewFormulaScaffolder(githubClient)
+
 	// Initialize orchestrators
 	installOrchestrator := domainorchestrators.NewInstallOrchestrator(
 		installerService,
@@ -84,6 +176,7 @@ func main() {
 		installOrchestrator,
 		registryRepo,
 		wandrcRepo,
+		config,
 	)
 	listHandler := domainorchestrators.NewListCommandHandler(
 		registryRepo,
@@ -93,10 +186,12 @@ func main() {
 	switchHandler := domainorchestrators.NewSwitchCommandHandler(
 		registryRepo,
 		wandrcRepo,
+		config,
 	)
 	infoHandler := domainorchestrators.NewInfoCommandHandler(
 		registryRepo,
 		wandrcRepo,
+		shimService,
 	)
 	uninstallHandler := domainorchestrators.NewUninstallCommandHandler(
 		installOrchestrator,
@@ -107,6 +202,7 @@ func main() {
 	addHandler := domainorchestrators.NewAddCommandHandler(
 		wandrcRepo,
 		registryRepo,
+		versionFiles,
 	)
 	removeHandler := domainorchestrators.NewRemoveCommandHandler(
 		wandrcRepo,
@@ -131,6 +227,7 @@ func main() {
 		formulaRepo,
 		fs,
 		wandDir,
+		shimService,
 	)
 	updateHandler := domainorchestrators.NewUpdateCommandHandler(
 		installOrchestrator,
@@ -144,7 +241,40 @@ func main() {
 	outdatedHandler := domainorchestrators.NewOutdatedCommandHandler(
 		installOrchestrator,
 		registryRepo,
+		config,
+	)
+	validateHandler := domainorchestrators.NewValidateCommandHandler(
+		fs,
+	)
+	formulaTestHandler := domainorchestrators.NewFormulaTestCommandHandler(
+		formulaLinter,
+	)
+	formulaNewHandler := domainorchestrators.NewFormulaNewCommandHandler(
+		formulaScaffolder,
+		fs,
+	)
+	configGetHandler := domainorchestrators.NewConfigGetCommandHandler(configRepo)
+	configSetHandler := domainorchestrators.NewConfigSetCommandHandler(configRepo)
+	configListHandler := domainorchestrators.NewConfigListCommandHandler(configRepo)
+	configResetHandler := domainorchestrators.NewConfigResetCommandHandler(configRepo)
+	configValidateHandler := domainorchestrators.NewConfigValidateCommandHandler(configRepo, fs, homeDir)
+	initShellHandler := domainorchestrators.NewInitShellCommandHandler(wandDir, executable)
+	envHandler := domainorchestrators.NewEnvCommandHandler(services.NewEnvService(registryRepo, wandrcRepo, formulaRepo, shimService))
+	execHandler := domainorchestrators.NewExecCommandHandler(
+		installOrchestrator,
+		shimService,
+		registryRepo,
+		formulaRepo,
+		config,
 	)
+	whichHandler := domainorchestrators.NewWhichCommandHandler(shimService)
+	resolveHandler := domainorchestrators.NewResolveCommandHandler(shimService)
+	shellHandler := domainorchestrators.NewShellCommandHandler(shimService, config)
+	shimsListHandler := domainorchestrators.NewShimsListCommandHandler(shimService)
+	shimsWhichHandler := domainorchestrators.NewShimsWhichCommandHandler(shimService)
+	shimsRepairHandler := domainorchestrators.NewShimsRepairCommandHandler(shimService)
+	reshimHandler := domainorchestrators.NewReshimCommandHandler(shimService)
+	formulaSyncHandler := domainorchestrators.NewFormulaSyncCommandHandler(formulaRepo, shimService)
 
 	// Initialize CLI adapter with handlers
 	cliAdapter := cli.NewCobraCLIAdapter(
@@ -164,10 +294,40 @@ func main() {
 		updateHandler,
 		versionHandler,
 		outdatedHandler,
+		validateHandler,
+		formulaTestHandler,
+		formulaNewHandler,
+		configGetHandler,
+		configSetHandler,
+		configListHandler,
+		configResetHandler,
+		configValidateHandler,
+		initShellHandler,
+		envHandler,
+		execHandler,
+		whichHandler,
+		resolveHandler,
+		shellHandler,
+		shimsListHandler,
+		shimsWhichHandler,
+		shimsRepairHandler,
+		reshimHandler,
+		formulaSyncHandler,
 	)
 
-	if err := cliAdapter.Execute(); err != nil {
+	// Ctrl-C or SIGTERM cancels the running command, which cleans up after
+	// itself; a second one kills wand as usual
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	err = cliAdapter.ExecuteContext(ctx)
+	interrupted := ctx.Err() != nil
+	stop()
+
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if interrupted {
+			os.Exit(130)
+		}
 		os.Exit(1)
 	}
 }
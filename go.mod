@@ -1,10 +1,13 @@
 module github.com/ochairo/wand
 
-go 1.24
+go 1.24.0
 
 require (
 	github.com/google/go-github/v57 v57.0.0
+	github.com/klauspost/compress v1.18.0
 	github.com/spf13/cobra v1.10.1
+	github.com/ulikunitz/xz v0.5.15
+	golang.org/x/crypto v0.45.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -12,4 +15,5 @@ require (
 	github.com/google/go-querystring v1.1.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
+	golang.org/x/sys v0.38.0 // indirect
 )
@@ -0,0 +1,337 @@
+package domainadapters
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+)
+
+// Magic bytes at the start of each supported format
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	zipMagics  = [][]byte{[]byte("PK\x03\x04"), []byte("PK\x05\x06")}
+	arMagic    = []byte("!<arch>\n")
+	rpmMagic   = []byte{0xed, 0xab, 0xee, 0xdb}
+	elfMagic   = []byte("\x7fELF")
+	cpioMagics = [][]byte{[]byte("070701"), []byte("070702")}
+)
+
+// compressionFormat returns the single-file format for data starting with a
+// compression magic, or ArchiveFormatNone
+func compressionFormat(header []byte) entities.ArchiveFormat {
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return entities.ArchiveFormatGz
+	case bytes.HasPrefix(header, bzip2Magic):
+		return entities.ArchiveFormatBz2
+	case bytes.HasPrefix(header, xzMagic):
+		return entities.ArchiveFormatXz
+	case bytes.HasPrefix(header, zstdMagic):
+		return entities.ArchiveFormatZst
+	}
+	return entities.ArchiveFormatNone
+}
+
+// tarFormats maps a compression to the tarball format compressed with it
+var tarFormats = map[entities.ArchiveFormat]entities.ArchiveFormat{
+	entities.ArchiveFormatNone: entities.ArchiveFormatTar,
+	entities.ArchiveFormatGz:   entities.ArchiveFormatTarGz,
+	entities.ArchiveFormatBz2:  entities.ArchiveFormatTarBz2,
+	entities.ArchiveFormatXz:   entities.ArchiveFormatTarXz,
+	entities.ArchiveFormatZst:  entities.ArchiveFormatTarZst,
+}
+
+// tarCompression returns the compression of a tarball format
+func tarCompression(format entities.ArchiveFormat) (entities.ArchiveFormat, bool) {
+	for compression, tarFormat := range tarFormats {
+		if tarFormat == format {
+			return compression, true
+		}
+	}
+	return entities.ArchiveFormatNone, false
+}
+
+// decompress wraps r in a reader for a single-file compression format
+func decompress(format entities.ArchiveFormat, r io.Reader) (io.ReadCloser, error) {
+	switch format {
+	case entities.ArchiveFormatNone:
+		return io.NopCloser(r), nil
+	case entities.ArchiveFormatGz:
+		return gzip.NewReader(r)
+	case entities.ArchiveFormatBz2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	case entities.ArchiveFormatXz:
+		reader, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(reader), nil
+	case entities.ArchiveFormatZst:
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	}
+	return nil, fmt.Errorf("unsupported compression: %s", format)
+}
+
+// decompressAny wraps r in a reader for whatever compression its first bytes
+// show, if any
+func decompressAny(r io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(len(xzMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return decompress(compressionFormat(header), buffered)
+}
+
+// isTar returns true if r starts with a valid tar header
+func isTar(r io.Reader) bool {
+	_, err := tar.NewReader(r).Next()
+	return err == nil
+}
+
+// detectFormat identifies an archive from its contents
+func detectFormat(file *os.File) (entities.ArchiveFormat, error) {
+	header := make([]byte, 16)
+	n, err := io.ReadFull(file, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return entities.ArchiveFormatNone, err
+	}
+	header = header[:n]
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return entities.ArchiveFormatNone, err
+	}
+
+	if compression := compressionFormat(header); compression != entities.ArchiveFormatNone {
+		reader, err := decompress(compression, file)
+		if err != nil {
+			return entities.ArchiveFormatNone, fmt.Errorf("corrupt %s data: %w", compression, err)
+		}
+		defer func() { _ = reader.Close() }()
+		if isTar(reader) {
+			return tarFormats[compression], nil
+		}
+		return compression, nil
+	}
+
+	switch {
+	case bytes.HasPrefix(header, zipMagics[0]) || bytes.HasPrefix(header, zipMagics[1]):
+		return entities.ArchiveFormatZip, nil
+	case bytes.HasPrefix(header, arMagic):
+		return entities.ArchiveFormatDeb, nil
+	case bytes.HasPrefix(header, rpmMagic):
+		return entities.ArchiveFormatRpm, nil
+	case bytes.HasPrefix(header, elfMagic) && len(header) >= 11 && header[8] == 'A' && header[9] == 'I' && (header[10] == 1 || header[10] == 2):
+		return entities.ArchiveFormatAppImage, nil
+	case isTar(file):
+		return entities.ArchiveFormatTar, nil
+	case isDmg(file):
+		return entities.ArchiveFormatDmg, nil
+	}
+	return entities.ArchiveFormatNone, nil
+}
+
+// isDmg returns true if a file ends with a UDIF trailer, which disk images
+// carry in their last 512 bytes
+func isDmg(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil || info.Size() < 512 {
+		return false
+	}
+	trailer := make([]byte, 4)
+	if _, err := file.ReadAt(trailer, info.Size()-512); err != nil {
+		return false
+	}
+	return string(trailer) == "koly"
+}
+
+// singleFileName returns the name a compressed file unpacks to: its own name
+// without the compression suffix
+func singleFileName(archivePath string, format entities.ArchiveFormat) string {
+	name := baseName(archivePath)
+	if format == entities.ArchiveFormatAppImage {
+		return name
+	}
+	for _, suffix := range []string{".gz", ".bz2", ".xz", ".zst", ".zstd"} {
+		if trimmed := strings.TrimSuffix(name, suffix); trimmed != name && trimmed != "" {
+			return trimmed
+		}
+	}
+	return name
+}
+
+// baseName returns the last element of a slash- or OS-separated path
+func baseName(path string) string {
+	if i := strings.LastIndexAny(path, `/\`); i != -1 {
+		return path[i+1:]
+	}
+	return path
+}
+
+// debData returns the data.tar member of a Debian package, an ar archive of
+// debian-binary, control.tar.* and data.tar.*
+func debData(r io.Reader) (io.Reader, error) {
+	magic := make([]byte, len(arMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || !bytes.Equal(magic, arMagic) {
+		return nil, fmt.Errorf("not an ar archive")
+	}
+
+	header := make([]byte, 60)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("no data.tar member in deb")
+			}
+			return nil, fmt.Errorf("failed to read ar header: %w", err)
+		}
+		if string(header[58:60]) != "`\n" {
+			return nil, fmt.Errorf("corrupt ar header")
+		}
+		name := strings.TrimSuffix(strings.TrimSpace(string(header[0:16])), "/")
+		size, err := strconv.ParseInt(strings.TrimSpace(string(header[48:58])), 10, 64)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("corrupt ar member size for %s", name)
+		}
+
+		if strings.HasPrefix(name, "data.tar") {
+			return io.LimitReader(r, size), nil
+		}
+		// Members are padded to an even length
+		if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+			return nil, fmt.Errorf("failed to skip ar member %s: %w", name, err)
+		}
+	}
+}
+
+// rpmPayload skips an RPM's lead, signature and header, returning the
+// compressed cpio payload that follows
+func rpmPayload(r io.Reader) (io.Reader, error) {
+	lead := make([]byte, 96)
+	if _, err := io.ReadFull(r, lead); err != nil || !bytes.HasPrefix(lead, rpmMagic) {
+		return nil, fmt.Errorf("not an rpm")
+	}
+
+	// The signature header is padded to a multiple of 8 bytes; the main header is not
+	for _, pad := range []bool{true, false} {
+		header := make([]byte, 16)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, fmt.Errorf("failed to read rpm header: %w", err)
+		}
+		if !bytes.Equal(header[0:3], []byte{0x8e, 0xad, 0xe8}) {
+			return nil, fmt.Errorf("corrupt rpm header")
+		}
+		entries := int64(binary.BigEndian.Uint32(header[8:12]))
+		dataSize := int64(binary.BigEndian.Uint32(header[12:16]))
+		size := entries*16 + dataSize
+		if pad {
+			size += (8 - size%8) % 8
+		}
+		if _, err := io.CopyN(io.Discard, r, size); err != nil {
+			return nil, fmt.Errorf("failed to skip rpm header: %w", err)
+		}
+	}
+	return r, nil
+}
+
+// cpioEntry is one file in a cpio archive
+type cpioEntry struct {
+	Name  string
+	Mode  int64
+	Size  int64
+	Mtime int64
+}
+
+// Types in cpioEntry.Mode
+const (
+	cpioTypeMask    = 0o170000
+	cpioTypeDir     = 0o040000
+	cpioTypeReg     = 0o100000
+	cpioTypeSymlink = 0o120000
+)
+
+// cpioReader reads the "newc" cpio archives RPM payloads use
+type cpioReader struct {
+	r         io.Reader
+	remaining int64 // unread data of the current entry
+	padding   int64 // padding after it
+}
+
+// Next advances to the next entry, returning io.EOF at the trailer
+func (c *cpioReader) Next() (*cpioEntry, error) {
+	if _, err := io.CopyN(io.Discard, c.r, c.remaining+c.padding); err != nil {
+		return nil, err
+	}
+	c.remaining, c.padding = 0, 0
+
+	header := make([]byte, 110)
+	if _, err := io.ReadFull(c.r, header); err != nil {
+		return nil, fmt.Errorf("failed to read cpio header: %w", err)
+	}
+	if !bytes.Equal(header[0:6], cpioMagics[0]) && !bytes.Equal(header[0:6], cpioMagics[1]) {
+		return nil, fmt.Errorf("unsupported cpio format")
+	}
+	field := func(i int) (int64, error) {
+		return strconv.ParseInt(string(header[6+i*8:14+i*8]), 16, 64)
+	}
+	mode, err := field(1)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt cpio header: %w", err)
+	}
+	mtime, err := field(5)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt cpio header: %w", err)
+	}
+	size, err := field(6)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt cpio header: %w", err)
+	}
+	nameSize, err := field(11)
+	if err != nil || nameSize < 1 || nameSize > 4096 {
+		return nil, fmt.Errorf("corrupt cpio header")
+	}
+
+	// The header and name, then the data, are each padded to 4 bytes
+	name := make([]byte, nameSize+(4-(110+nameSize)%4)%4)
+	if _, err := io.ReadFull(c.r, name); err != nil {
+		return nil, fmt.Errorf("failed to read cpio name: %w", err)
+	}
+	entry := &cpioEntry{Name: string(name[:nameSize-1]), Mode: mode, Size: size, Mtime: mtime}
+	if entry.Name == "TRAILER!!!" {
+		return nil, io.EOF
+	}
+	c.remaining, c.padding = size, (4-size%4)%4
+	return entry, nil
+}
+
+// Read reads the current entry's data
+func (c *cpioReader) Read(p []byte) (int, error) {
+	if c.remaining <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	return n, err
+}
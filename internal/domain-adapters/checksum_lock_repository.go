@@ -0,0 +1,62 @@
+package domainadapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+)
+
+// ChecksumLockRepository implements checksum lock persistence using a JSON file
+type ChecksumLockRepository struct {
+	fs      interfaces.FileSystem
+	wandDir string
+}
+
+// NewChecksumLockRepository creates a new ChecksumLockRepository
+func NewChecksumLockRepository(fs interfaces.FileSystem, wandDir string) interfaces.ChecksumLockRepository {
+	return &ChecksumLockRepository{
+		fs:      fs,
+		wandDir: wandDir,
+	}
+}
+
+// Load loads the checksum lock from disk
+func (r *ChecksumLockRepository) Load() (*entities.ChecksumLock, error) {
+	lockPath := filepath.Join(r.wandDir, "checksums.lock")
+
+	// If the lock doesn't exist yet, nothing has been recorded
+	if !r.fs.Exists(lockPath) {
+		return entities.NewChecksumLock(), nil
+	}
+
+	data, err := r.fs.ReadFile(lockPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksum lock: %w", err)
+	}
+
+	lock := entities.NewChecksumLock()
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse checksum lock: %w", err)
+	}
+
+	return lock, nil
+}
+
+// Save saves the checksum lock to disk
+func (r *ChecksumLockRepository) Save(lock *entities.ChecksumLock) error {
+	lockPath := filepath.Join(r.wandDir, "checksums.lock")
+
+	data, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize checksum lock: %w", err)
+	}
+
+	if err := r.fs.WriteFile(lockPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checksum lock: %w", err)
+	}
+
+	return nil
+}
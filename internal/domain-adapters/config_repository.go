@@ -0,0 +1,110 @@
+package domainadapters
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+)
+
+// ConfigFile is the configuration file's name in the wand directory
+const ConfigFile = "config.yaml"
+
+// ConfigRepository implements configuration persistence using a YAML file
+type ConfigRepository struct {
+	fs   interfaces.FileSystem
+	path string
+}
+
+// NewConfigRepository creates a ConfigRepository for the file at path
+func NewConfigRepository(fs interfaces.FileSystem, path string) interfaces.ConfigRepository {
+	return &ConfigRepository{
+		fs:   fs,
+		path: path,
+	}
+}
+
+// ConfigPath returns $WAND_CONFIG, or config.yaml in ~/.wand
+func ConfigPath(homeDir string) string {
+	if path := os.Getenv("WAND_CONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(homeDir, ".wand", ConfigFile)
+}
+
+// Load loads the settings in the file; a missing file has none
+func (r *ConfigRepository) Load() (*entities.Config, error) {
+	if !r.fs.Exists(r.path) {
+		return &entities.Config{}, nil
+	}
+
+	data, err := r.fs.ReadFile(r.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	// Unknown keys are errors, so a misspelled setting is not silently ignored
+	var config entities.Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse %s: %w", r.path, err)
+	}
+
+	return &config, nil
+}
+
+// Save writes the settings to the file
+func (r *ConfigRepository) Save(config *entities.Config) error {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to serialize config: %w", err)
+	}
+
+	if err := r.fs.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := r.fs.WriteFile(r.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+
+	return nil
+}
+
+// Path returns the configuration file's path
+func (r *ConfigRepository) Path() string {
+	return r.path
+}
+
+// Environment returns the set WAND_* variables that override settings
+func (r *ConfigRepository) Environment() map[string]string {
+	env := make(map[string]string)
+	for _, key := range entities.ConfigKeys() {
+		if value, ok := os.LookupEnv(key.Env); ok && value != "" {
+			env[key.Env] = value
+		}
+	}
+	return env
+}
+
+// LoadConfig returns the effective settings: the defaults, overridden by the
+// file, overridden by the environment. A leading ~ in paths is homeDir.
+func LoadConfig(repo interfaces.ConfigRepository, homeDir string) (*entities.Config, error) {
+	file, err := repo.Load()
+	if err != nil {
+		return nil, err
+	}
+	config, _, err := entities.ResolveConfig(file, repo.Environment())
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	config.ExpandPaths(homeDir)
+	return config, nil
+}
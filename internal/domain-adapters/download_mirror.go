@@ -0,0 +1,93 @@
+package domainadapters
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ochairo/wand/internal/domain/resilience"
+)
+
+const (
+	// mirrorFailureThreshold is how many failed downloads in a row make a mirror be skipped
+	mirrorFailureThreshold = 3
+	// mirrorCooldown is how long a failing mirror is skipped before it is tried again
+	mirrorCooldown = 5 * time.Minute
+)
+
+// Mirror serves copies of download URLs from another host, such as an
+// Artifactory remote repository.
+//
+// With a Prefix, URLs starting with Prefix are fetched from BaseURL with the
+// prefix replaced. Without one, every URL is fetched from BaseURL followed by
+// its host and path, e.g. https://proxy/dl/github.com/owner/repo/...
+type Mirror struct {
+	Prefix  string
+	BaseURL string
+}
+
+// Rewrite returns the mirror URL for rawURL, or "" if the mirror does not serve it
+func (m Mirror) Rewrite(rawURL string) string {
+	base := strings.TrimSuffix(m.BaseURL, "/")
+
+	if m.Prefix != "" {
+		if !strings.HasPrefix(rawURL, m.Prefix) {
+			return ""
+		}
+		return base + "/" + strings.TrimPrefix(rawURL[len(m.Prefix):], "/")
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return ""
+	}
+	return base + "/" + parsed.Host + parsed.RequestURI()
+}
+
+// ParseMirrors parses a comma-separated mirror list. Each entry is a base URL,
+// or "prefix=base URL" to mirror only URLs starting with prefix.
+func ParseMirrors(spec string) ([]Mirror, error) {
+	var mirrors []Mirror
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		mirror := Mirror{BaseURL: entry}
+		if prefix, base, ok := strings.Cut(entry, "="); ok {
+			mirror = Mirror{Prefix: strings.TrimSpace(prefix), BaseURL: strings.TrimSpace(base)}
+		}
+
+		parsed, err := url.Parse(mirror.BaseURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid mirror %q: expected an http(s) base URL", entry)
+		}
+		mirrors = append(mirrors, mirror)
+	}
+	return mirrors, nil
+}
+
+// downloadSource is one place a file can be downloaded from
+type downloadSource struct {
+	url     string
+	breaker *resilience.CircuitBreaker // nil for the original URL
+}
+
+// mirrorSource pairs a mirror with the breaker that tracks its failures
+type mirrorSource struct {
+	mirror  Mirror
+	breaker *resilience.CircuitBreaker
+}
+
+// sources returns the mirrors serving rawURL, in order, followed by rawURL itself
+func (d *DownloaderAdapter) sources(rawURL string) []downloadSource {
+	sources := make([]downloadSource, 0, len(d.mirrors)+1)
+	for _, m := range d.mirrors {
+		if mirrored := m.mirror.Rewrite(rawURL); mirrored != "" {
+			sources = append(sources, downloadSource{url: mirrored, breaker: m.breaker})
+		}
+	}
+	return append(sources, downloadSource{url: rawURL})
+}
@@ -1,17 +1,22 @@
 package domainadapters
 
 import (
+	"context"
 	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/ochairo/wand/internal/domain/interfaces"
+	"github.com/ochairo/wand/internal/domain/resilience"
 )
 
 const (
@@ -20,49 +25,152 @@ const (
 	retryDelayMax = 10 * time.Second
 )
 
+const (
+	// DefaultConnectTimeout bounds dialing, the TLS handshake and waiting for response headers
+	DefaultConnectTimeout = 30 * time.Second
+	// DefaultStallTimeout aborts a download attempt that receives no data for this long
+	DefaultStallTimeout = 60 * time.Second
+)
+
+// DownloaderOptions configures download timeouts and mirrors. Zero values use the defaults.
+type DownloaderOptions struct {
+	Transport      http.RoundTripper // Replaces the default transport, e.g. one from NewHTTPTransport
+	ConnectTimeout time.Duration     // Used by the default transport
+	StallTimeout   time.Duration
+	RetryDelay     time.Duration // First backoff between attempts, doubled for each retry
+	Mirrors        []Mirror      // Tried in order before the original URL
+	// PartialDir keeps interrupted downloads, keyed by URL, for a later call
+	// to resume; it must be on the destination's filesystem. Empty keeps them
+	// beside the destination only for the duration of a call.
+	PartialDir string
+}
+
 // DownloaderAdapter implements file downloading
-type DownloaderAdapter struct{}
+type DownloaderAdapter struct {
+	client       *http.Client
+	stallTimeout time.Duration
+	retryDelay   time.Duration
+	mirrors      []mirrorSource
+	partialDir   string
+}
 
 // NewDownloaderAdapter creates a new DownloaderAdapter
 func NewDownloaderAdapter() interfaces.Downloader {
-	return &DownloaderAdapter{}
+	return NewDownloaderAdapterWithOptions(DownloaderOptions{})
+}
+
+// NewDownloaderAdapterWithOptions creates a DownloaderAdapter with custom timeouts and mirrors
+func NewDownloaderAdapterWithOptions(opts DownloaderOptions) interfaces.Downloader {
+	if opts.ConnectTimeout <= 0 {
+		opts.ConnectTimeout = DefaultConnectTimeout
+	}
+	if opts.StallTimeout <= 0 {
+		opts.StallTimeout = DefaultStallTimeout
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = retryDelay
+	}
+
+	transport := opts.Transport
+	if transport == nil {
+		// Without a network config this cannot fail
+		transport, _ = NewHTTPTransport(nil, opts.ConnectTimeout)
+	}
+
+	mirrors := make([]mirrorSource, 0, len(opts.Mirrors))
+	for _, mirror := range opts.Mirrors {
+		mirrors = append(mirrors, mirrorSource{
+			mirror:  mirror,
+			breaker: resilience.NewCircuitBreaker(mirrorFailureThreshold, mirrorCooldown),
+		})
+	}
+
+	return &DownloaderAdapter{
+		client:       &http.Client{Transport: transport},
+		stallTimeout: opts.StallTimeout,
+		retryDelay:   opts.RetryDelay,
+		mirrors:      mirrors,
+		partialDir:   opts.PartialDir,
+	}
 }
 
 // Download downloads a file from a URL to a destination path
 func (d *DownloaderAdapter) Download(url, destPath string) error {
-	return d.downloadWithRetry(url, destPath, nil)
+	return d.DownloadContext(context.Background(), url, destPath, nil)
 }
 
 // DownloadWithProgress downloads a file with progress reporting
 func (d *DownloaderAdapter) DownloadWithProgress(url, destPath string, progress io.Writer) error {
-	return d.downloadWithRetry(url, destPath, progress)
+	return d.DownloadContext(context.Background(), url, destPath, progress)
+}
+
+// DownloadContext downloads a file, retrying transient failures, until ctx is
+// done. Mirrors serving the URL are tried in order before the URL itself; a
+// mirror that keeps failing is skipped for a while. A partial download is
+// only resumed from the source that started it.
+func (d *DownloaderAdapter) DownloadContext(ctx context.Context, url, destPath string, progress io.Writer) error {
+	var lastErr error
+	for _, source := range d.sources(url) {
+		if source.breaker != nil && source.breaker.IsOpen() {
+			continue
+		}
+
+		partPath := d.partialPath(source.url, destPath)
+		err := d.downloadWithRetry(ctx, source.url, destPath, partPath, progress)
+		if d.partialDir == "" {
+			removePartial(partPath)
+		}
+		if err == nil {
+			if source.breaker != nil {
+				source.breaker.RecordSuccess()
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		// A mirror without the file is not failing
+		if source.breaker != nil && !isClientError(err) {
+			source.breaker.RecordFailure()
+		}
+		lastErr = err
+	}
+
+	return lastErr
 }
 
-// downloadWithRetry implements download with exponential backoff retry logic
-func (d *DownloaderAdapter) downloadWithRetry(url, destPath string, progress io.Writer) error {
+// downloadWithRetry implements download with exponential backoff retry logic.
+// Each retry resumes from the bytes already received.
+func (d *DownloaderAdapter) downloadWithRetry(ctx context.Context, url, destPath, partPath string, progress io.Writer) error {
 	var lastErr error
 
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		if attempt > 0 {
-			// Exponential backoff: 2s, 4s, 8s (capped at 10s)
+			// Exponential backoff: 2s, 4s, 8s by default (capped at 10s)
 			// Safe cast: attempt is always >= 1 at this point, uint conversion safe
 			shift := attempt - 1
-			delay := retryDelay * time.Duration(1<<uint(shift)) //nolint:gosec // G115: shift >= 0 guaranteed by guard
+			delay := d.retryDelay * time.Duration(1<<uint(shift)) //nolint:gosec // G115: shift >= 0 guaranteed by guard
 			if delay > retryDelayMax {
 				delay = retryDelayMax
 			}
-			time.Sleep(delay)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
 		}
 
-		err := d.doDownload(url, destPath, progress)
+		err := d.doDownload(ctx, url, destPath, partPath, progress)
 		if err == nil {
 			return nil
 		}
 
 		lastErr = err
 
-		// Don't retry on 404 or other client errors
-		if isClientError(err) {
+		// Don't retry once cancelled, on 404 or other client errors, or on an
+		// untrusted certificate
+		if ctx.Err() != nil || isClientError(err) || isCertificateError(err) {
 			return err
 		}
 	}
@@ -70,42 +178,168 @@ func (d *DownloaderAdapter) downloadWithRetry(url, destPath string, progress io.
 	return fmt.Errorf("failed after %d attempts: %w", maxRetries, lastErr)
 }
 
-// doDownload performs a single download attempt
-// Note: Uses http.Get with variable URL as this is by design - tool downloads from user-specified URLs
-// Note: Uses os.Create with variable paths as this is by design - tool creates files at user-specified locations
-func (d *DownloaderAdapter) doDownload(url, destPath string, progress io.Writer) error {
-	resp, err := http.Get(url) //nolint:gosec
+// errStalled is the cancellation cause when a download stops receiving data
+var errStalled = errors.New("download stalled")
+
+// partialPath returns the .part file a download of url is written to until
+// it is complete. Beside it, a .validator file holds the ETag, or else the
+// Last-Modified date, of the response it came from, so a resumed request asks
+// for the rest of the same file.
+func (d *DownloaderAdapter) partialPath(url, destPath string) string {
+	if d.partialDir == "" {
+		return destPath + ".part"
+	}
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(d.partialDir, hex.EncodeToString(sum[:16])+".part")
+}
+
+// removePartial removes a .part file and its validator
+func removePartial(partPath string) {
+	_ = os.Remove(partPath)
+	_ = os.Remove(partPath + ".validator")
+}
+
+// doDownload performs a single download attempt. The body is written to
+// partPath, which is renamed into place only when complete. If an earlier
+// attempt left a partial file, only the remaining bytes are requested.
+// Note: Uses variable URLs as this is by design - tool downloads from user-specified URLs
+// Note: Uses os.OpenFile with variable paths as this is by design - tool creates files at user-specified locations
+func (d *DownloaderAdapter) doDownload(parent context.Context, url, destPath, partPath string, progress io.Writer) error {
+	ctx, cancel := context.WithCancelCause(parent)
+	defer cancel(nil)
+
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if validator, err := os.ReadFile(partPath + ".validator"); err == nil && len(validator) > 0 { //nolint:gosec
+			req.Header.Set("If-Range", string(validator))
+		}
+	}
+
+	resp, err := d.client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to download %s: %w", url, err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
+	var total int64
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0 && contentRangeStart(resp) == offset:
+		flags |= os.O_APPEND
+		total = -1
+		if resp.ContentLength >= 0 {
+			total = offset + resp.ContentLength
+		}
+	case (resp.StatusCode == http.StatusRequestedRangeNotSatisfiable || resp.StatusCode == http.StatusPartialContent) && offset > 0:
+		// The partial file does not fit the remote file; start over
+		_ = resp.Body.Close()
+		removePartial(partPath)
+		return d.doDownload(parent, url, destPath, partPath, progress)
+	case resp.StatusCode == http.StatusOK:
+		flags |= os.O_TRUNC
+		offset, total = 0, resp.ContentLength
+	default:
 		return &httpError{
 			statusCode: resp.StatusCode,
 			status:     resp.Status,
 		}
 	}
 
-	outFile, err := os.Create(destPath) //nolint:gosec
+	if d.partialDir != "" {
+		if err := os.MkdirAll(d.partialDir, 0755); err != nil { //nolint:gosec
+			return fmt.Errorf("failed to create directory %s: %w", d.partialDir, err)
+		}
+	}
+	outFile, err := os.OpenFile(partPath, flags, 0644) //nolint:gosec
 	if err != nil {
 		return fmt.Errorf("failed to create file %s: %w", destPath, err)
 	}
-	defer func() { _ = outFile.Close() }()
+
+	// A full response replaces the partial file, and its validator the old one
+	if flags&os.O_TRUNC != 0 {
+		validator := resp.Header.Get("ETag")
+		if validator == "" {
+			validator = resp.Header.Get("Last-Modified")
+		}
+		if err := os.WriteFile(partPath+".validator", []byte(validator), 0644); err != nil { //nolint:gosec
+			_ = outFile.Close()
+			return fmt.Errorf("failed to create file %s: %w", destPath, err)
+		}
+	}
+
+	if sized, ok := progress.(interfaces.SizedWriter); ok {
+		sized.SetTotal(total)
+		if offset > 0 {
+			sized.SetOffset(offset)
+		}
+	}
 
 	var writer io.Writer = outFile
 	if progress != nil {
 		writer = io.MultiWriter(outFile, progress)
 	}
 
-	_, err = io.Copy(writer, resp.Body)
+	// Cancel the request if no data arrives for stallTimeout
+	stall := time.AfterFunc(d.stallTimeout, func() { cancel(errStalled) })
+	_, err = io.Copy(writer, &stallReader{reader: resp.Body, timer: stall, timeout: d.stallTimeout})
+	stall.Stop()
+
+	if closeErr := outFile.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+		// The .part file is kept so the next attempt can resume it
+		if errors.Is(context.Cause(ctx), errStalled) {
+			return fmt.Errorf("failed to download %s: no data received for %s", url, d.stallTimeout)
+		}
+		if parent.Err() != nil {
+			return fmt.Errorf("failed to download %s: %w", url, parent.Err())
+		}
+		return fmt.Errorf("failed to download %s: %w", url, err)
 	}
 
+	if err := os.Rename(partPath, destPath); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	_ = os.Remove(partPath + ".validator")
 	return nil
 }
 
+// contentRangeStart returns the first byte position of a 206 response's
+// Content-Range header, or -1 if it is missing or malformed
+func contentRangeStart(resp *http.Response) int64 {
+	var start, end int64
+	var size string
+	if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%s", &start, &end, &size); err != nil {
+		return -1
+	}
+	return start
+}
+
+// stallReader resets a timer each time data is read
+type stallReader struct {
+	reader  io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (r *stallReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+	return n, err
+}
+
 // httpError represents an HTTP error response
 type httpError struct {
 	statusCode int
@@ -125,12 +359,21 @@ func isClientError(err error) bool {
 	return false
 }
 
+// isCertificateError checks if the server's certificate was rejected, which
+// retrying cannot fix
+func isCertificateError(err error) bool {
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	return errors.As(err, &verifyErr) || errors.As(err, &authorityErr) || errors.As(err, &hostnameErr)
+}
+
 // VerifyChecksum verifies the SHA256 checksum of a file
 // Note: Uses http.Get with variable URL as this is by design - checksum URLs from package formulas
 // Note: Uses os.Open with variable paths as this is by design - tool opens downloaded files
 func (d *DownloaderAdapter) VerifyChecksum(filePath, checksumURL string) error {
 	// Download checksum file
-	resp, err := http.Get(checksumURL) //nolint:gosec
+	resp, err := d.client.Get(checksumURL) //nolint:gosec
 	if err != nil {
 		return fmt.Errorf("failed to download checksum: %w", err)
 	}
@@ -0,0 +1,243 @@
+package domainadapters
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// extraction writes the entries of one archive into a destination directory.
+// It enforces the size and entry limits across the whole archive, keeps links
+// inside the destination and preserves modes and mtimes. Errors name the
+// entry that broke a rule.
+type extraction struct {
+	destDir  string
+	limits   ExtractorOptions
+	files    int
+	size     int64
+	symlinks []extractedSymlink
+	dirTimes map[string]time.Time
+}
+
+// extractedSymlink is a symlink waiting to be created. Symlinks are created
+// last, so no file in the archive can be written through one.
+type extractedSymlink struct {
+	name     string
+	target   string
+	linkname string
+}
+
+// newExtraction starts extracting into destDir
+func newExtraction(destDir string, limits ExtractorOptions) (*extraction, error) {
+	absDestDir, err := filepath.Abs(destDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination directory: %w", err)
+	}
+	return &extraction{destDir: absDestDir, limits: limits, dirTimes: make(map[string]time.Time)}, nil
+}
+
+// target returns where an entry goes, counting it against the entry limit
+func (x *extraction) target(name string) (string, error) {
+	x.files++
+	if x.files > x.limits.MaxFiles {
+		return "", fmt.Errorf("%s: archive has more than %d entries", name, x.limits.MaxFiles)
+	}
+
+	target, err := ValidateArchivePath(x.destDir, name)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	if err := x.checkParents(name, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// checkParents refuses a target below a symlink, which could lead anywhere
+func (x *extraction) checkParents(name, target string) error {
+	for dir := filepath.Dir(target); dir != x.destDir && strings.HasPrefix(dir, x.destDir); dir = filepath.Dir(dir) {
+		if info, err := os.Lstat(dir); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s: parent directory %s is a symlink", name, dir)
+		}
+	}
+	return nil
+}
+
+// dir creates a directory entry
+func (x *extraction) dir(name string, mode os.FileMode, mtime time.Time) error {
+	target, err := x.target(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(target, 0755); err != nil { //nolint:gosec // G301: modes are applied below
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	// The owner must be able to add entries and remove the tree later
+	if err := os.Chmod(target, mode.Perm()|0700); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if !mtime.IsZero() {
+		x.dirTimes[target] = mtime
+	}
+	return nil
+}
+
+// file writes a regular file entry. size is the size the archive declares,
+// or -1 if unknown; the bytes actually written are what count.
+func (x *extraction) file(name string, r io.Reader, size int64, mode os.FileMode, mtime time.Time) error {
+	if size > x.limits.MaxFileSize {
+		return fmt.Errorf("%s: file is too large: %d bytes (max %d)", name, size, x.limits.MaxFileSize)
+	}
+	target, err := x.target(name)
+	if err != nil {
+		return err
+	}
+	if err := x.prepare(name, target); err != nil {
+		return err
+	}
+
+	// O_EXCL after removing any existing entry never follows a symlink
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600) //nolint:gosec // G304: target is validated
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	limit := min(x.limits.MaxFileSize, x.limits.MaxSize-x.size)
+	written, err := io.Copy(out, io.LimitReader(r, limit+1))
+	x.size += written
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	switch {
+	case err != nil:
+		return fmt.Errorf("%s: %w", name, err)
+	case written > x.limits.MaxFileSize:
+		return fmt.Errorf("%s: file is too large (max %d bytes)", name, x.limits.MaxFileSize)
+	case written > limit:
+		return fmt.Errorf("%s: archive unpacks to more than %d bytes", name, x.limits.MaxSize)
+	}
+
+	// setuid, setgid and sticky bits are dropped
+	if err := os.Chmod(target, mode.Perm()); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if !mtime.IsZero() {
+		if err := os.Chtimes(target, mtime, mtime); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// symlink records a symlink entry, refusing targets outside the destination.
+// Targets must be relative, and may only climb with leading ".." elements so
+// the check does not depend on other links.
+func (x *extraction) symlink(name, linkname string) error {
+	target, err := x.target(name)
+	if err != nil {
+		return err
+	}
+	if linkname == "" || filepath.IsAbs(linkname) || strings.HasPrefix(linkname, "/") {
+		return fmt.Errorf("%s: symlink to %q must be relative", name, linkname)
+	}
+
+	climbing := true
+	for _, element := range strings.Split(filepath.ToSlash(linkname), "/") {
+		switch element {
+		case "..":
+			if !climbing {
+				return fmt.Errorf("%s: symlink to %q has '..' after a directory name", name, linkname)
+			}
+		case ".", "":
+		default:
+			climbing = false
+		}
+	}
+
+	resolved := filepath.Join(filepath.Dir(target), linkname)
+	if resolved != x.destDir && !strings.HasPrefix(resolved, x.destDir+string(filepath.Separator)) {
+		return fmt.Errorf("%s: symlink to %q points outside %s", name, linkname, x.destDir)
+	}
+
+	x.symlinks = append(x.symlinks, extractedSymlink{name: name, target: target, linkname: linkname})
+	return nil
+}
+
+// hardlink links an entry to a regular file extracted earlier
+func (x *extraction) hardlink(name, linkname string) error {
+	target, err := x.target(name)
+	if err != nil {
+		return err
+	}
+	source, err := ValidateArchivePath(x.destDir, linkname)
+	if err != nil {
+		return fmt.Errorf("%s: hard link %w", name, err)
+	}
+	info, err := os.Lstat(source)
+	if err != nil || !info.Mode().IsRegular() {
+		return fmt.Errorf("%s: hard link to %q, which is not a file extracted earlier", name, linkname)
+	}
+	if err := x.prepare(name, target); err != nil {
+		return err
+	}
+
+	if err := os.Link(source, target); err != nil {
+		// Fall back to a copy where hard links are not supported
+		in, openErr := os.Open(source) //nolint:gosec // G304: source is validated
+		if openErr != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		defer func() { _ = in.Close() }()
+		x.files-- // file counts the entry again
+		return x.file(name, in, info.Size(), info.Mode(), info.ModTime())
+	}
+	return nil
+}
+
+// prepare creates an entry's parent directories and removes whatever is in
+// its way, so a file replaces an earlier entry of the same name
+func (x *extraction) prepare(name, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil { //nolint:gosec // G301: installed trees must be readable
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if info, err := os.Lstat(target); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%s: a directory of the same name was extracted earlier", name)
+		}
+		if err := os.Remove(target); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// finish creates the symlinks and restores directory mtimes, which adding
+// entries changed
+func (x *extraction) finish() error {
+	for _, link := range x.symlinks {
+		if err := x.checkParents(link.name, link.target); err != nil {
+			return err
+		}
+		if err := x.prepare(link.name, link.target); err != nil {
+			return err
+		}
+		if err := os.Symlink(link.linkname, link.target); err != nil {
+			return fmt.Errorf("%s: %w", link.name, err)
+		}
+	}
+
+	// Deepest first, so setting a directory's mtime is not undone by its children
+	dirs := make([]string, 0, len(x.dirTimes))
+	for dir := range x.dirTimes {
+		dirs = append(dirs, dir)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, dir := range dirs {
+		_ = os.Chtimes(dir, x.dirTimes[dir], x.dirTimes[dir])
+	}
+	return nil
+}
@@ -3,8 +3,8 @@ package domainadapters
 import (
 	"archive/tar"
 	"archive/zip"
-	"compress/bzip2"
-	"compress/gzip"
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -12,26 +12,53 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/ochairo/wand/internal/domain/entities"
 	"github.com/ochairo/wand/internal/domain/interfaces"
 )
 
 const (
-	// MaxExtractSize limits total extraction size to prevent decompression bombs (1GB)
+	// MaxExtractSize limits what one archive may unpack to, to prevent decompression bombs (1GB)
 	MaxExtractSize = 1 << 30
 	// MaxFileSize limits individual file size (500MB)
 	MaxFileSize = 500 << 20
+	// MaxExtractFiles limits the number of entries one archive may unpack
+	MaxExtractFiles = 100_000
 )
 
+// ExtractorOptions configures extraction limits. Zero values use the defaults.
+type ExtractorOptions struct {
+	MaxSize     int64 // Bytes one archive may unpack to
+	MaxFileSize int64
+	MaxFiles    int
+}
+
 // ExtractorAdapter implements archive extraction
 type ExtractorAdapter struct {
-	fs interfaces.FileSystem
+	fs     interfaces.FileSystem
+	limits ExtractorOptions
 }
 
 // NewExtractorAdapter creates a new ExtractorAdapter
 func NewExtractorAdapter(fs interfaces.FileSystem) interfaces.Extractor {
+	return NewExtractorAdapterWithOptions(fs, ExtractorOptions{})
+}
+
+// NewExtractorAdapterWithOptions creates an ExtractorAdapter with custom limits
+func NewExtractorAdapterWithOptions(fs interfaces.FileSystem, opts ExtractorOptions) interfaces.Extractor {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = MaxExtractSize
+	}
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = MaxFileSize
+	}
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = MaxExtractFiles
+	}
 	return &ExtractorAdapter{
-		fs: fs,
+		fs:     fs,
+		limits: opts,
 	}
 }
 
@@ -57,21 +84,45 @@ func ValidateArchivePath(destDir, targetPath string) (string, error) {
 
 // Extract extracts an archive to a destination directory
 func (e *ExtractorAdapter) Extract(archivePath, destDir string) error {
+	return e.ExtractContext(context.Background(), archivePath, destDir)
+}
+
+// Format detects an archive's format from its first bytes
+func (e *ExtractorAdapter) Format(archivePath string) (entities.ArchiveFormat, error) {
+	file, err := os.Open(archivePath) //nolint:gosec // G304: archivePath is from validated archive
+	if err != nil {
+		return entities.ArchiveFormatNone, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	return detectFormat(file)
+}
+
+// ExtractContext extracts an archive to a destination directory, stopping
+// between entries when ctx is done
+func (e *ExtractorAdapter) ExtractContext(ctx context.Context, archivePath, destDir string) error {
+	format, err := e.Format(archivePath)
+	if err != nil {
+		return err
+	}
 	if err := e.fs.MkdirAll(destDir, 0700); err != nil {
 		return fmt.Errorf("failed to create destination directory: %w", err)
 	}
 
-	switch {
-	case strings.HasSuffix(archivePath, ".tar.gz") || strings.HasSuffix(archivePath, ".tgz"):
-		return e.extractTarGz(archivePath, destDir)
-	case strings.HasSuffix(archivePath, ".tar.bz2"):
-		return e.extractTarBz2(archivePath, destDir)
-	case strings.HasSuffix(archivePath, ".tar"):
-		return e.extractTar(archivePath, destDir)
-	case strings.HasSuffix(archivePath, ".zip"):
-		return e.extractZip(archivePath, destDir)
-	case strings.HasSuffix(archivePath, ".dmg"):
-		return e.extractDmg(archivePath, destDir)
+	if compression, ok := tarCompression(format); ok {
+		return e.extractCompressedTar(ctx, archivePath, compression, destDir)
+	}
+	switch format {
+	case entities.ArchiveFormatZip:
+		return e.extractZip(ctx, archivePath, destDir)
+	case entities.ArchiveFormatGz, entities.ArchiveFormatBz2, entities.ArchiveFormatXz, entities.ArchiveFormatZst, entities.ArchiveFormatAppImage:
+		return e.extractSingleFile(archivePath, format, destDir)
+	case entities.ArchiveFormatDeb:
+		return e.extractDeb(ctx, archivePath, destDir)
+	case entities.ArchiveFormatRpm:
+		return e.extractRpm(ctx, archivePath, destDir)
+	case entities.ArchiveFormatDmg:
+		return e.extractDmg(ctx, archivePath, destDir)
 	default:
 		return fmt.Errorf("unsupported archive format: %s", archivePath)
 	}
@@ -79,31 +130,35 @@ func (e *ExtractorAdapter) Extract(archivePath, destDir string) error {
 
 // ExtractFile extracts a single file from an archive
 func (e *ExtractorAdapter) ExtractFile(archivePath, fileName, destPath string) error {
-	if strings.HasSuffix(archivePath, ".tar.gz") || strings.HasSuffix(archivePath, ".tgz") {
-		return e.extractTarGzFile(archivePath, fileName, destPath)
-	} else if strings.HasSuffix(archivePath, ".zip") {
+	format, err := e.Format(archivePath)
+	if err != nil {
+		return err
+	}
+	if compression, ok := tarCompression(format); ok {
+		return e.extractTarFileSingle(archivePath, compression, fileName, destPath)
+	} else if format == entities.ArchiveFormatZip {
 		return e.extractZipFileSingle(archivePath, fileName, destPath)
 	}
 	return fmt.Errorf("unsupported archive format for file extraction")
 }
 
-// extractTarGzFile extracts a single file from a tar.gz archive
+// extractTarFileSingle extracts a single file from a tarball
 // Note: Extracts files from archive to user-specified destinations (by design)
 // Note: Uses path validation to prevent directory traversal attacks
-func (e *ExtractorAdapter) extractTarGzFile(archivePath, fileName, destPath string) error {
+func (e *ExtractorAdapter) extractTarFileSingle(archivePath string, compression entities.ArchiveFormat, fileName, destPath string) error {
 	file, err := os.Open(archivePath) //nolint:gosec // G304: archivePath is from validated archive
 	if err != nil {
 		return fmt.Errorf("failed to open archive: %w", err)
 	}
 	defer func() { _ = file.Close() }()
 
-	gz, err := gzip.NewReader(file)
+	reader, err := decompress(compression, file)
 	if err != nil {
-		return fmt.Errorf("failed to create gzip reader: %w", err)
+		return fmt.Errorf("failed to create %s reader: %w", compression, err)
 	}
-	defer func() { _ = gz.Close() }()
+	defer func() { _ = reader.Close() }()
 
-	tr := tar.NewReader(gz)
+	tr := tar.NewReader(reader)
 	for {
 		header, err := tr.Next()
 		if errors.Is(err, io.EOF) {
@@ -204,168 +259,233 @@ func (e *ExtractorAdapter) extractZipFileSingle(archivePath, fileName, destPath
 	return fmt.Errorf("file not found in archive: %s", fileName)
 }
 
-// extractTarGz extracts a .tar.gz archive
-func (e *ExtractorAdapter) extractTarGz(archivePath, destDir string) error {
+// extractCompressedTar extracts a tarball, compressed or not
+func (e *ExtractorAdapter) extractCompressedTar(ctx context.Context, archivePath string, compression entities.ArchiveFormat, destDir string) error {
 	file, err := os.Open(archivePath) //nolint:gosec // G304: archivePath is from validated archive
 	if err != nil {
 		return err
 	}
 	defer func() { _ = file.Close() }()
 
-	gzReader, err := gzip.NewReader(file)
+	reader, err := decompress(compression, file)
 	if err != nil {
 		return err
 	}
-	defer func() { _ = gzReader.Close() }()
+	defer func() { _ = reader.Close() }()
 
-	return e.extractTarReader(tar.NewReader(gzReader), destDir)
+	return e.extractTarReader(ctx, tar.NewReader(reader), destDir)
 }
 
-// extractTarBz2 extracts a .tar.bz2 archive
-func (e *ExtractorAdapter) extractTarBz2(archivePath, destDir string) error {
+// extractSingleFile unpacks a compressed binary, or copies an AppImage, into
+// destDir as an executable
+func (e *ExtractorAdapter) extractSingleFile(archivePath string, format entities.ArchiveFormat, destDir string) error {
 	file, err := os.Open(archivePath) //nolint:gosec // G304: archivePath is from validated archive
 	if err != nil {
 		return err
 	}
 	defer func() { _ = file.Close() }()
 
-	bzReader := bzip2.NewReader(file)
-	return e.extractTarReader(tar.NewReader(bzReader), destDir)
+	compression := format
+	if format == entities.ArchiveFormatAppImage {
+		compression = entities.ArchiveFormatNone
+	}
+	reader, err := decompress(compression, file)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = reader.Close() }()
+
+	x, err := newExtraction(destDir, e.limits)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if err := x.file(singleFileName(archivePath, format), reader, -1, 0755, info.ModTime()); err != nil {
+		return err
+	}
+	return x.finish()
 }
 
-// extractTar extracts a .tar archive
-func (e *ExtractorAdapter) extractTar(archivePath, destDir string) error {
+// extractDeb extracts the files a Debian package installs
+func (e *ExtractorAdapter) extractDeb(ctx context.Context, archivePath, destDir string) error {
 	file, err := os.Open(archivePath) //nolint:gosec // G304: archivePath is from validated archive
 	if err != nil {
 		return err
 	}
 	defer func() { _ = file.Close() }()
 
-	return e.extractTarReader(tar.NewReader(file), destDir)
+	data, err := debData(file)
+	if err != nil {
+		return err
+	}
+	reader, err := decompressAny(data)
+	if err != nil {
+		return fmt.Errorf("failed to read deb data: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	return e.extractTarReader(ctx, tar.NewReader(reader), destDir)
 }
 
-// extractTarReader extracts from a tar reader
-func (e *ExtractorAdapter) extractTarReader(tarReader *tar.Reader, destDir string) error {
+// extractRpm extracts the files an RPM package installs
+func (e *ExtractorAdapter) extractRpm(ctx context.Context, archivePath, destDir string) error {
+	file, err := os.Open(archivePath) //nolint:gosec // G304: archivePath is from validated archive
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	payload, err := rpmPayload(bufio.NewReader(file))
+	if err != nil {
+		return err
+	}
+	reader, err := decompressAny(payload)
+	if err != nil {
+		return fmt.Errorf("failed to read rpm payload: %w", err)
+	}
+	defer func() { _ = reader.Close() }()
+
+	x, err := newExtraction(destDir, e.limits)
+	if err != nil {
+		return err
+	}
+	cpio := &cpioReader{r: reader}
 	for {
-		header, err := tarReader.Next()
-		if errors.Is(err, io.EOF) {
-			break
-		}
-		if err != nil {
+		if err := ctx.Err(); err != nil {
 			return err
 		}
 
-		// Validate file size to prevent decompression bombs
-		if header.Size > MaxFileSize {
-			return fmt.Errorf("file %s is too large: %d bytes (max %d)", header.Name, header.Size, MaxFileSize)
+		entry, err := cpio.Next()
+		if errors.Is(err, io.EOF) {
+			return x.finish()
 		}
-
-		// Validate path to prevent directory traversal
-		target, err := ValidateArchivePath(destDir, header.Name)
 		if err != nil {
 			return err
 		}
 
-		switch header.Typeflag {
-		case tar.TypeDir:
-			if err := e.fs.MkdirAll(target, 0700); err != nil {
-				return err
-			}
-		case tar.TypeReg:
-			if err := e.extractTarFile(tarReader, target, header.Mode); err != nil {
-				return err
-			}
-		case tar.TypeSymlink:
-			if err := e.fs.Symlink(header.Linkname, target); err != nil {
-				return err
+		mode := os.FileMode(entry.Mode & 0o777) //nolint:gosec // G115: masked to permission bits
+		mtime := time.Unix(entry.Mtime, 0)
+		switch entry.Mode & cpioTypeMask {
+		case cpioTypeDir:
+			err = x.dir(entry.Name, mode, mtime)
+		case cpioTypeReg:
+			err = x.file(entry.Name, cpio, entry.Size, mode, mtime)
+		case cpioTypeSymlink:
+			var link []byte
+			if link, err = io.ReadAll(io.LimitReader(cpio, 4096)); err == nil {
+				err = x.symlink(entry.Name, string(link))
 			}
 		}
+		if err != nil {
+			return err
+		}
 	}
-
-	return nil
 }
 
-// extractTarFile extracts a single file from tar
-func (e *ExtractorAdapter) extractTarFile(reader io.Reader, target string, mode int64) error {
-	// Create parent directory
-	if err := e.fs.MkdirAll(filepath.Dir(target), 0700); err != nil {
-		return err
-	}
-
-	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(mode)) //nolint:gosec // G304: target is validated
+// extractTarReader extracts from a tar reader. Devices, FIFOs and other
+// special files are skipped.
+func (e *ExtractorAdapter) extractTarReader(ctx context.Context, tarReader *tar.Reader, destDir string) error {
+	x, err := newExtraction(destDir, e.limits)
 	if err != nil {
 		return err
 	}
-	defer func() { _ = file.Close() }()
 
-	// Copy with size limit to prevent decompression bombs
-	limitedReader := io.LimitReader(reader, MaxFileSize)
-	_, err = io.Copy(file, limitedReader)
-	return err
-}
-
-// extractZip extracts a .zip archive
-func (e *ExtractorAdapter) extractZip(archivePath, destDir string) error {
-	reader, err := zip.OpenReader(archivePath)
-	if err != nil {
-		return err
-	}
-	defer func() { _ = reader.Close() }()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
-	for _, file := range reader.File {
-		// Validate path to prevent directory traversal
-		target, err := ValidateArchivePath(destDir, file.Name)
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
 		if err != nil {
 			return err
 		}
 
-		if file.FileInfo().IsDir() {
-			if err := e.fs.MkdirAll(target, 0700); err != nil {
-				return err
-			}
-			continue
+		mode := os.FileMode(header.Mode & 0o777) //nolint:gosec // G115: masked to permission bits
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = x.dir(header.Name, mode, header.ModTime)
+		case tar.TypeReg, tar.TypeRegA: //nolint:staticcheck // TypeRegA is still found in old archives
+			err = x.file(header.Name, tarReader, header.Size, mode, header.ModTime)
+		case tar.TypeSymlink:
+			err = x.symlink(header.Name, header.Linkname)
+		case tar.TypeLink:
+			err = x.hardlink(header.Name, header.Linkname)
 		}
-
-		if err := e.extractZipFile(file, target); err != nil {
+		if err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return x.finish()
 }
 
-// extractZipFile extracts a single file from zip
-func (e *ExtractorAdapter) extractZipFile(file *zip.File, target string) error {
-	// Validate file size to prevent decompression bombs
-	if file.UncompressedSize64 > MaxFileSize {
-		return fmt.Errorf("file %s is too large: %d bytes (max %d)", file.Name, file.UncompressedSize64, MaxFileSize)
-	}
-
-	// Create parent directory
-	if err := e.fs.MkdirAll(filepath.Dir(target), 0700); err != nil {
-		return err
-	}
-
-	reader, err := file.Open()
+// extractZip extracts a .zip archive
+func (e *ExtractorAdapter) extractZip(ctx context.Context, archivePath, destDir string) error {
+	reader, err := zip.OpenReader(archivePath)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = reader.Close() }()
 
-	outFile, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, file.Mode()) //nolint:gosec // G304: target is validated
+	x, err := newExtraction(destDir, e.limits)
 	if err != nil {
 		return err
 	}
-	defer func() { _ = outFile.Close() }()
+	for _, file := range reader.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := extractZipEntry(x, file); err != nil {
+			return err
+		}
+	}
 
-	// Copy with size limit to prevent decompression bombs
-	limitedReader := io.LimitReader(reader, MaxFileSize)
-	_, err = io.Copy(outFile, limitedReader)
-	return err
+	return x.finish()
+}
+
+// extractZipEntry extracts one zip entry. Archives made on Unix carry modes
+// and symlinks; others get the modes their attributes imply.
+func extractZipEntry(x *extraction, file *zip.File) error {
+	mode := file.Mode()
+	switch {
+	case mode.IsDir():
+		return x.dir(file.Name, mode, file.Modified)
+	case mode&os.ModeSymlink != 0:
+		reader, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.Name, err)
+		}
+		defer func() { _ = reader.Close() }()
+		link, err := io.ReadAll(io.LimitReader(reader, 4096))
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.Name, err)
+		}
+		return x.symlink(file.Name, string(link))
+	case mode.IsRegular():
+		size := int64(-1)
+		if file.UncompressedSize64 <= uint64(x.limits.MaxFileSize) {
+			size = int64(file.UncompressedSize64) //nolint:gosec // G115: checked against the limit
+		} else {
+			size = x.limits.MaxFileSize + 1
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("%s: %w", file.Name, err)
+		}
+		defer func() { _ = reader.Close() }()
+		return x.file(file.Name, reader, size, mode, file.Modified)
+	}
+	return nil
 }
 
 // extractDmg extracts a macOS .dmg disk image
-func (e *ExtractorAdapter) extractDmg(dmgPath, destDir string) error {
+func (e *ExtractorAdapter) extractDmg(ctx context.Context, dmgPath, destDir string) error {
 	// On macOS, use hdiutil to mount and copy .app bundle
 	// For now, we'll implement a simple copy approach assuming the .app is directly accessible
 	// Full implementation would use shell commands to mount DMG, copy contents, unmount
@@ -379,7 +499,7 @@ func (e *ExtractorAdapter) extractDmg(dmgPath, destDir string) error {
 
 	// Mount the DMG (macOS only)
 	// Use explicit command with arguments instead of string parsing
-	mountCmd := exec.Command("hdiutil", "attach", "-nobrowse", "-mountpoint", tmpMount, dmgPath) //nolint:gosec // G204: hardcoded command
+	mountCmd := exec.CommandContext(ctx, "hdiutil", "attach", "-nobrowse", "-mountpoint", tmpMount, dmgPath) //nolint:gosec // G204: hardcoded command
 	if err := mountCmd.Run(); err != nil {
 		return fmt.Errorf("failed to mount dmg: %w", err)
 	}
@@ -1,6 +1,8 @@
 package domainadapters
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -79,3 +81,62 @@ func (fs *FileSystemAdapter) Walk(root string, walkFn func(path string, isDir bo
 		return walkFn(path, info.IsDir(), nil)
 	})
 }
+
+// Rename moves a file or directory
+func (fs *FileSystemAdapter) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+// CopyDir copies a directory tree, preserving file modes and symlinks
+func (fs *FileSystemAdapter) CopyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case entry.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			return fmt.Errorf("cannot copy %s: unsupported file type", path)
+		}
+	})
+}
+
+// copyFile copies a regular file with the given permissions
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
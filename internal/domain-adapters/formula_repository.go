@@ -2,6 +2,7 @@ package domainadapters
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
 
@@ -15,21 +16,55 @@ import (
 type FormulaRepository struct {
 	fs          interfaces.FileSystem
 	formulasDir string
+	taps        []string
+	remoteURL   string
+	network     *entities.NetworkConfig
+}
+
+// FormulaRepositoryOptions configures a FormulaRepository
+type FormulaRepositoryOptions struct {
+	Taps      []string                // Extra formula directories, searched after formulasDir
+	RemoteURL string                  // Git repository Sync clones; empty uses the default
+	Network   *entities.NetworkConfig // Proxy, CA file and credentials for Sync
 }
 
 // NewFormulaRepository creates a new FormulaRepository
 func NewFormulaRepository(fs interfaces.FileSystem, formulasDir string) interfaces.FormulaRepository {
+	return NewFormulaRepositoryWithOptions(fs, formulasDir, FormulaRepositoryOptions{})
+}
+
+// NewFormulaRepositoryWithOptions creates a FormulaRepository with the given options
+func NewFormulaRepositoryWithOptions(fs interfaces.FileSystem, formulasDir string, opts FormulaRepositoryOptions) interfaces.FormulaRepository {
+	remoteURL := opts.RemoteURL
+	if remoteURL == "" {
+		remoteURL = entities.DefaultFormulaRepository
+	}
+
 	return &FormulaRepository{
 		fs:          fs,
 		formulasDir: formulasDir,
+		taps:        opts.Taps,
+		remoteURL:   remoteURL,
+		network:     opts.Network,
 	}
 }
 
-// GetFormula loads a formula by name
+// dirs returns the formula directories in search order
+func (r *FormulaRepository) dirs() []string {
+	return append([]string{r.formulasDir}, r.taps...)
+}
+
+// GetFormula loads a formula by name from the first directory that has it
 func (r *FormulaRepository) GetFormula(name string) (*entities.Formula, error) {
-	formulaPath := filepath.Join(r.formulasDir, name+".yaml")
+	var formulaPath string
+	for _, dir := range r.dirs() {
+		if path := filepath.Join(dir, name+".yaml"); r.fs.Exists(path) {
+			formulaPath = path
+			break
+		}
+	}
 
-	if !r.fs.Exists(formulaPath) {
+	if formulaPath == "" {
 		return nil, fmt.Errorf("formula not found: %s", name)
 	}
 
@@ -46,11 +81,36 @@ func (r *FormulaRepository) GetFormula(name string) (*entities.Formula, error) {
 	return &formula, nil
 }
 
-// ListFormulas lists all available formulas
+// ListFormulas lists all available formulas. A formula in a tap is hidden by
+// one of the same name in an earlier directory.
 func (r *FormulaRepository) ListFormulas() ([]*entities.Formula, error) {
 	var formulas []*entities.Formula
+	seen := make(map[string]bool)
+
+	for i, dir := range r.dirs() {
+		if i > 0 && !r.fs.Exists(dir) {
+			continue // a missing tap has no formulas
+		}
+		found, err := r.listDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, formula := range found {
+			if !seen[formula.Name] {
+				seen[formula.Name] = true
+				formulas = append(formulas, formula)
+			}
+		}
+	}
+
+	return formulas, nil
+}
+
+// listDir loads every formula below dir
+func (r *FormulaRepository) listDir(dir string) ([]*entities.Formula, error) {
+	var formulas []*entities.Formula
 
-	err := r.fs.Walk(r.formulasDir, func(path string, isDir bool, walkErr error) error {
+	err := r.fs.Walk(dir, func(path string, isDir bool, walkErr error) error {
 		if walkErr != nil {
 			return walkErr
 		}
@@ -80,10 +140,9 @@ func (r *FormulaRepository) ListFormulas() ([]*entities.Formula, error) {
 	return formulas, nil
 }
 
-// Sync updates the formulas directory from the remote repository
+// Sync updates the formulas directory from the remote repository. Taps are
+// managed by the user.
 func (r *FormulaRepository) Sync() error {
-	const repoURL = "https://github.com/ochairo/potions.git"
-
 	// Check if formulas directory is a git repository
 	gitDir := filepath.Join(r.formulasDir, ".git")
 	isGitRepo := r.fs.Exists(gitDir)
@@ -91,12 +150,14 @@ func (r *FormulaRepository) Sync() error {
 	if isGitRepo {
 		// Pull latest changes if already a git repo
 		cmd := exec.Command("git", "-C", r.formulasDir, "pull", "origin", "main") //nolint:gosec // G204: hardcoded git command
+		cmd.Env = append(os.Environ(), gitNetworkEnv(r.network)...)
 		if err := cmd.Run(); err != nil {
 			return fmt.Errorf("failed to pull formulas: %w", err)
 		}
 	} else {
 		// Clone repository if not yet cloned
-		cmd := exec.Command("git", "clone", repoURL, r.formulasDir) //nolint:gosec // G204: hardcoded git command
+		cmd := exec.Command("git", "clone", r.remoteURL, r.formulasDir) //nolint:gosec // G204: URL from user config
+		cmd.Env = append(os.Environ(), gitNetworkEnv(r.network)...)
 		if err := cmd.Run(); err != nil {
 			return fmt.Errorf("failed to clone formulas repository: %w", err)
 		}
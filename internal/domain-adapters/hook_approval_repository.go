@@ -0,0 +1,62 @@
+package domainadapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+)
+
+// HookApprovalRepository implements hook approval persistence using a JSON file
+type HookApprovalRepository struct {
+	fs      interfaces.FileSystem
+	wandDir string
+}
+
+// NewHookApprovalRepository creates a new HookApprovalRepository
+func NewHookApprovalRepository(fs interfaces.FileSystem, wandDir string) interfaces.HookApprovalRepository {
+	return &HookApprovalRepository{
+		fs:      fs,
+		wandDir: wandDir,
+	}
+}
+
+// Load loads the hook approvals from disk
+func (r *HookApprovalRepository) Load() (*entities.HookApprovals, error) {
+	approvalsPath := filepath.Join(r.wandDir, "hooks.json")
+
+	// Nothing has been approved yet
+	if !r.fs.Exists(approvalsPath) {
+		return entities.NewHookApprovals(), nil
+	}
+
+	data, err := r.fs.ReadFile(approvalsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hook approvals: %w", err)
+	}
+
+	approvals := entities.NewHookApprovals()
+	if err := json.Unmarshal(data, approvals); err != nil {
+		return nil, fmt.Errorf("failed to parse hook approvals: %w", err)
+	}
+
+	return approvals, nil
+}
+
+// Save saves the hook approvals to disk
+func (r *HookApprovalRepository) Save(approvals *entities.HookApprovals) error {
+	approvalsPath := filepath.Join(r.wandDir, "hooks.json")
+
+	data, err := json.MarshalIndent(approvals, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize hook approvals: %w", err)
+	}
+
+	if err := r.fs.WriteFile(approvalsPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write hook approvals: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package domainadapters
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ochairo/wand/internal/domain/interfaces"
+)
+
+const httpCheckTimeout = 30 * time.Second
+
+// HTTPCheckerAdapter probes URLs with HEAD requests
+type HTTPCheckerAdapter struct {
+	client *http.Client
+}
+
+// NewHTTPCheckerAdapter creates a new HTTPCheckerAdapter.
+// A nil client uses a default client with a request timeout.
+func NewHTTPCheckerAdapter(client *http.Client) interfaces.HTTPChecker {
+	if client == nil {
+		client = &http.Client{Timeout: httpCheckTimeout}
+	}
+	return &HTTPCheckerAdapter{
+		client: client,
+	}
+}
+
+// Head returns the status code for a URL after following redirects.
+// Servers that reject HEAD are retried with a single-byte ranged GET.
+func (h *HTTPCheckerAdapter) Head(url string) (int, error) {
+	status, err := h.do(http.MethodHead, url)
+	if err != nil {
+		return 0, err
+	}
+
+	if status == http.StatusMethodNotAllowed || status == http.StatusForbidden {
+		return h.do(http.MethodGet, url)
+	}
+
+	return status, nil
+}
+
+// do sends a single request and discards the body
+func (h *HTTPCheckerAdapter) do(method, url string) (int, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("invalid URL %s: %w", url, err)
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request to %s failed: %w", url, err)
+	}
+	_ = resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
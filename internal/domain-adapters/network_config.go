@@ -0,0 +1,183 @@
+package domainadapters
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+)
+
+// credentialsFile holds secrets, so it must not be readable by other users
+const credentialsFile = "credentials.yaml"
+
+// LoadNetworkConfig reads wandDir/credentials.yaml, if present, and applies
+// the environment: WAND_PROXY, WAND_CA_FILE and the first of
+// WAND_GITHUB_TOKEN, GITHUB_TOKEN and GH_TOKEN that is set.
+func LoadNetworkConfig(fs interfaces.FileSystem, wandDir string) (*entities.NetworkConfig, error) {
+	config := &entities.NetworkConfig{}
+
+	path := filepath.Join(wandDir, credentialsFile)
+	if fs.Exists(path) {
+		// Note: Uses os.Stat as FileSystem does not expose permissions
+		if info, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+			return nil, fmt.Errorf("%s is accessible by other users; run: chmod 600 %s", path, path)
+		}
+
+		data, err := fs.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read credentials: %w", err)
+		}
+		if err := yaml.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	}
+
+	// Host names are matched case-insensitively
+	hosts := make(map[string]*entities.HostCredential, len(config.Hosts))
+	for host, credential := range config.Hosts {
+		hosts[strings.ToLower(host)] = credential
+	}
+	config.Hosts = hosts
+
+	if proxy := os.Getenv("WAND_PROXY"); proxy != "" {
+		config.Proxy = proxy
+	}
+	if caFile := os.Getenv("WAND_CA_FILE"); caFile != "" {
+		config.CAFile = caFile
+	}
+	for _, name := range []string{"WAND_GITHUB_TOKEN", "GITHUB_TOKEN", "GH_TOKEN"} {
+		if token := os.Getenv(name); token != "" {
+			config.GitHubToken = token
+			break
+		}
+	}
+
+	return config, nil
+}
+
+// NewHTTPTransport returns a transport using the network config's proxy, CA
+// bundle and host credentials. connectTimeout bounds dialing, the TLS
+// handshake and waiting for response headers. A nil config uses the defaults.
+func NewHTTPTransport(network *entities.NetworkConfig, connectTimeout time.Duration) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if connectTimeout > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}).DialContext
+		transport.TLSHandshakeTimeout = connectTimeout
+		transport.ResponseHeaderTimeout = connectTimeout
+	}
+	if network == nil {
+		return transport, nil
+	}
+
+	if network.Proxy != "" {
+		proxyURL, err := url.Parse(network.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %q", network.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if network.CAFile != "" {
+		pem, err := os.ReadFile(network.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", network.CAFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+	}
+
+	if len(network.Hosts) == 0 {
+		return transport, nil
+	}
+	return &credentialTransport{base: transport, network: network}, nil
+}
+
+// credentialTransport adds the configured credential to HTTPS requests for a
+// host. It applies to each request separately, so a redirect to another host
+// does not carry the credential along.
+type credentialTransport struct {
+	base    http.RoundTripper
+	network *entities.NetworkConfig
+}
+
+func (t *credentialTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" || req.Header.Get("Authorization") != "" {
+		return t.base.RoundTrip(req)
+	}
+	credential, ok := t.network.CredentialFor(req.URL.Host)
+	if !ok || credential.IsZero() {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", authorization(credential))
+	return t.base.RoundTrip(req)
+}
+
+// authorization returns the Authorization header value for a credential
+func authorization(credential *entities.HostCredential) string {
+	if credential.Token != "" {
+		return "Bearer " + credential.Token
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(credential.Username+":"+credential.Password))
+}
+
+// gitNetworkEnv returns the environment that makes git use the network
+// config. Credentials are passed as git config through the environment so
+// they do not appear in the process list.
+func gitNetworkEnv(network *entities.NetworkConfig) []string {
+	if network == nil {
+		return nil
+	}
+
+	var env []string
+	if network.Proxy != "" {
+		env = append(env, "HTTPS_PROXY="+network.Proxy, "HTTP_PROXY="+network.Proxy)
+	}
+	if network.CAFile != "" {
+		env = append(env, "GIT_SSL_CAINFO="+network.CAFile)
+	}
+
+	headers := map[string]string{}
+	if network.GitHubToken != "" {
+		token := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + network.GitHubToken))
+		headers["github.com"] = "Authorization: Basic " + token
+	}
+	for host, credential := range network.Hosts {
+		if !credential.IsZero() {
+			headers[host] = "Authorization: " + authorization(credential)
+		}
+	}
+
+	count := 0
+	for host, header := range headers {
+		env = append(env,
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=http.https://%s/.extraHeader", count, host),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", count, header),
+		)
+		count++
+	}
+	if count > 0 {
+		env = append(env, fmt.Sprintf("GIT_CONFIG_COUNT=%d", count))
+	}
+	return env
+}
@@ -1,9 +1,15 @@
 package domainadapters
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"sort"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/ochairo/wand/internal/domain/interfaces"
 )
@@ -41,7 +47,9 @@ func (s *ShellExecutorAdapter) ExecuteInDir(dir, command string, args ...string)
 func (s *ShellExecutorAdapter) ExecuteWithEnv(env map[string]string, command string, args ...string) (string, error) {
 	cmd := exec.Command(command, args...)
 
-	// Set environment variables
+	// Set environment variables on top of the current environment so PATH and
+	// HOME are still available
+	cmd.Env = os.Environ()
 	for key, value := range env {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
 	}
@@ -52,3 +60,52 @@ func (s *ShellExecutorAdapter) ExecuteWithEnv(env map[string]string, command str
 	}
 	return strings.TrimSpace(string(output)), nil
 }
+
+// ExecuteScript runs a script with sh -c in dir using exactly the given
+// environment. The script runs in its own process group so a timeout kills
+// everything it started.
+func (s *ShellExecutorAdapter) ExecuteScript(dir, script string, env map[string]string, timeout time.Duration) (string, error) {
+	return s.ExecuteScriptContext(context.Background(), dir, script, env, timeout)
+}
+
+// ExecuteScriptContext is ExecuteScript that also kills the script's process
+// group when parent is done
+func (s *ShellExecutorAdapter) ExecuteScriptContext(parent context.Context, dir, script string, env map[string]string, timeout time.Duration) (string, error) {
+	ctx := parent
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", script) //nolint:gosec
+	cmd.Dir = dir
+
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	cmd.Env = make([]string, 0, len(keys))
+	for _, key := range keys {
+		cmd.Env = append(cmd.Env, key+"="+env[key])
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+
+	output, err := cmd.CombinedOutput()
+	if parent.Err() != nil {
+		return string(output), fmt.Errorf("command cancelled: %w", parent.Err())
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return string(output), fmt.Errorf("command timed out after %s", timeout)
+	}
+	if err != nil {
+		return string(output), fmt.Errorf("command failed: %w", err)
+	}
+	return string(output), nil
+}
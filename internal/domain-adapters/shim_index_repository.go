@@ -0,0 +1,62 @@
+package domainadapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+)
+
+// ShimIndexRepository implements shim ownership persistence using a JSON file
+type ShimIndexRepository struct {
+	fs      interfaces.FileSystem
+	wandDir string
+}
+
+// NewShimIndexRepository creates a new ShimIndexRepository
+func NewShimIndexRepository(fs interfaces.FileSystem, wandDir string) interfaces.ShimIndexRepository {
+	return &ShimIndexRepository{
+		fs:      fs,
+		wandDir: wandDir,
+	}
+}
+
+// Load loads the shim index from disk
+func (r *ShimIndexRepository) Load() (*entities.ShimIndex, error) {
+	indexPath := filepath.Join(r.wandDir, "shims.json")
+
+	// No shim has been recorded yet
+	if !r.fs.Exists(indexPath) {
+		return entities.NewShimIndex(), nil
+	}
+
+	data, err := r.fs.ReadFile(indexPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shim index: %w", err)
+	}
+
+	index := entities.NewShimIndex()
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse shim index: %w", err)
+	}
+
+	return index, nil
+}
+
+// Save saves the shim index to disk
+func (r *ShimIndexRepository) Save(index *entities.ShimIndex) error {
+	indexPath := filepath.Join(r.wandDir, "shims.json")
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize shim index: %w", err)
+	}
+
+	if err := r.fs.WriteFile(indexPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write shim index: %w", err)
+	}
+
+	return nil
+}
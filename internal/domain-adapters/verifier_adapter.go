@@ -0,0 +1,341 @@
+package domainadapters
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+)
+
+// VerifierAdapter implements checksum and signature verification
+type VerifierAdapter struct{}
+
+// NewVerifierAdapter creates a new VerifierAdapter
+func NewVerifierAdapter() interfaces.Verifier {
+	return &VerifierAdapter{}
+}
+
+// checksumEntry is one line of a checksum file
+type checksumEntry struct {
+	algorithm string // set by BSD-style lines, otherwise empty
+	hash      string
+	name      string
+}
+
+var (
+	// bsdChecksumPattern matches "SHA256 (file) = hash"
+	bsdChecksumPattern = regexp.MustCompile(`^(SHA256|SHA512) \((.+)\) = ([0-9a-fA-F]+)$`)
+	hexPattern         = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+)
+
+// parseChecksums parses GNU ("hash  file", "hash *file"), BSD ("SHA256 (file) = hash")
+// and bare ("hash") checksum lines
+func parseChecksums(data []byte) []checksumEntry {
+	var entries []checksumEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if m := bsdChecksumPattern.FindStringSubmatch(line); m != nil {
+			entries = append(entries, checksumEntry{algorithm: strings.ToLower(m[1]), hash: strings.ToLower(m[3]), name: m[2]})
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if !hexPattern.MatchString(fields[0]) {
+			continue
+		}
+		entry := checksumEntry{hash: strings.ToLower(fields[0])}
+		if len(fields) > 1 {
+			entry.name = strings.TrimPrefix(strings.Join(fields[1:], " "), "*")
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// selectChecksum picks the entry for assetName. A file with a single entry is
+// treated as a per-file checksum regardless of the name it lists.
+func selectChecksum(entries []checksumEntry, assetName string) (*checksumEntry, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no checksums found in checksum file")
+	}
+
+	for i := range entries {
+		if entries[i].name != "" && path.Base(filepath.ToSlash(entries[i].name)) == assetName {
+			return &entries[i], nil
+		}
+	}
+
+	if len(entries) == 1 {
+		return &entries[0], nil
+	}
+
+	return nil, fmt.Errorf("no checksum for %s in manifest (%d entries)", assetName, len(entries))
+}
+
+// newChecksumHash returns the hash for an algorithm, inferring it from the
+// expected hash length when not given
+func newChecksumHash(algorithm, expected string) (hash.Hash, string, error) {
+	if algorithm == "" {
+		switch len(expected) {
+		case sha256.Size * 2:
+			algorithm = "sha256"
+		case sha512.Size * 2:
+			algorithm = "sha512"
+		default:
+			return nil, "", fmt.Errorf("cannot infer checksum algorithm from a %d character hash", len(expected))
+		}
+	}
+
+	switch strings.ToLower(algorithm) {
+	case "sha256":
+		return sha256.New(), "sha256", nil
+	case "sha512":
+		return sha512.New(), "sha512", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported checksum algorithm: %s", algorithm)
+	}
+}
+
+// VerifyChecksum verifies a file against a checksum file or manifest
+// Note: Uses os.Open/ReadFile with variable paths as this is by design - tool verifies downloaded files
+func (v *VerifierAdapter) VerifyChecksum(filePath, assetName, checksumPath, algorithm string) error {
+	data, err := os.ReadFile(checksumPath) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("failed to read checksum file: %w", err)
+	}
+
+	entry, err := selectChecksum(parseChecksums(data), assetName)
+	if err != nil {
+		return err
+	}
+
+	if algorithm == "" {
+		algorithm = entry.algorithm
+	}
+	hasher, algorithm, err := newChecksumHash(algorithm, entry.hash)
+	if err != nil {
+		return err
+	}
+
+	actual, err := digestFile(filePath, hasher)
+	if err != nil {
+		return err
+	}
+	if actual != entry.hash {
+		return fmt.Errorf("%s checksum mismatch: expected %s, got %s", algorithm, entry.hash, actual)
+	}
+
+	return nil
+}
+
+// Digest returns the hex digest of a file
+func (v *VerifierAdapter) Digest(filePath, algorithm string) (string, error) {
+	hasher, _, err := newChecksumHash(algorithm, "")
+	if err != nil {
+		return "", err
+	}
+	return digestFile(filePath, hasher)
+}
+
+// digestFile hashes a file with the given hasher
+func digestFile(filePath string, hasher hash.Hash) (string, error) {
+	file, err := os.Open(filePath) //nolint:gosec
+	if err != nil {
+		return "", fmt.Errorf("failed to open file for checksum: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("failed to calculate checksum: %w", err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// VerifySignature verifies a detached signature with the pinned key from the formula
+func (v *VerifierAdapter) VerifySignature(filePath, signaturePath string, config *entities.SignatureConfig) error {
+	if config == nil || strings.TrimSpace(config.PublicKey) == "" {
+		return fmt.Errorf("no public key configured")
+	}
+
+	signature, err := os.ReadFile(signaturePath) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("failed to read signature: %w", err)
+	}
+
+	switch config.Type {
+	case "minisign":
+		data, err := os.ReadFile(filePath) //nolint:gosec
+		if err != nil {
+			return fmt.Errorf("failed to read signed file: %w", err)
+		}
+		return verifyMinisign(data, signature, config.PublicKey)
+	case "cosign":
+		data, err := os.ReadFile(filePath) //nolint:gosec
+		if err != nil {
+			return fmt.Errorf("failed to read signed file: %w", err)
+		}
+		return verifyCosign(data, signature, config.PublicKey)
+	case "gpg":
+		return verifyGPG(filePath, signaturePath, config.PublicKey)
+	default:
+		return fmt.Errorf("unsupported signature type: %s", config.Type)
+	}
+}
+
+// lastBase64Line returns the last line that is not a minisign comment
+func lastBase64Line(text string, comment string) string {
+	var result string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, comment) {
+			result = line
+		}
+	}
+	return result
+}
+
+// verifyMinisign verifies a minisign signature, including the trusted comment.
+// Both legacy ("Ed") and prehashed ("ED", BLAKE2b-512) signatures are supported.
+func verifyMinisign(data, signature []byte, publicKey string) error {
+	key, err := base64.StdEncoding.DecodeString(lastBase64Line(publicKey, "untrusted comment:"))
+	if err != nil || len(key) != 2+8+ed25519.PublicKeySize || string(key[:2]) != "Ed" {
+		return fmt.Errorf("invalid minisign public key")
+	}
+	keyID, pub := key[2:10], ed25519.PublicKey(key[10:])
+
+	lines := strings.Split(strings.ReplaceAll(string(signature), "\r\n", "\n"), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[0], "untrusted comment:") || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return fmt.Errorf("malformed minisign signature")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[1]))
+	if err != nil || len(sig) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("malformed minisign signature")
+	}
+	if !bytes.Equal(sig[2:10], keyID) {
+		return fmt.Errorf("signature was made with key %X, expected %X", reverse(sig[2:10]), reverse(keyID))
+	}
+
+	message := data
+	switch string(sig[:2]) {
+	case "Ed":
+	case "ED":
+		digest := blake2b.Sum512(data)
+		message = digest[:]
+	default:
+		return fmt.Errorf("unsupported minisign algorithm %q", sig[:2])
+	}
+
+	if !ed25519.Verify(pub, message, sig[10:]) {
+		return fmt.Errorf("minisign signature verification failed")
+	}
+
+	trustedComment := strings.TrimPrefix(lines[2], "trusted comment: ")
+	globalSig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[3]))
+	if err != nil || len(globalSig) != ed25519.SignatureSize {
+		return fmt.Errorf("malformed minisign trusted comment signature")
+	}
+	if !ed25519.Verify(pub, append(append([]byte{}, sig[10:]...), trustedComment...), globalSig) {
+		return fmt.Errorf("minisign trusted comment verification failed")
+	}
+
+	return nil
+}
+
+// reverse returns a reversed copy; minisign prints key IDs little-endian
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+// verifyCosign verifies a cosign blob signature made with a key pair
+// (cosign sign-blob --key). Keyless signatures need a transparency log and
+// are not supported.
+func verifyCosign(data, signature []byte, publicKey string) error {
+	block, _ := pem.Decode([]byte(publicKey))
+	if block == nil {
+		return fmt.Errorf("invalid cosign public key: expected PEM")
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid cosign public key: %w", err)
+	}
+
+	// cosign writes base64; accept raw DER too
+	sig := bytes.TrimSpace(signature)
+	if decoded, err := base64.StdEncoding.DecodeString(string(sig)); err == nil {
+		sig = decoded
+	}
+
+	switch key := pub.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return fmt.Errorf("cosign signature verification failed")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, data, sig) {
+			return fmt.Errorf("cosign signature verification failed")
+		}
+	default:
+		return fmt.Errorf("unsupported cosign key type %T", pub)
+	}
+
+	return nil
+}
+
+// verifyGPG verifies a detached GPG signature using a throwaway keyring that
+// contains only the pinned key
+func verifyGPG(filePath, signaturePath, publicKey string) error {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		return fmt.Errorf("gpg is required to verify GPG signatures: %w", err)
+	}
+
+	home, err := os.MkdirTemp("", "wand-gpg-")
+	if err != nil {
+		return fmt.Errorf("failed to create keyring: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(home) }()
+
+	keyPath := filepath.Join(home, "pinned.asc")
+	if err := os.WriteFile(keyPath, []byte(publicKey), 0600); err != nil {
+		return fmt.Errorf("failed to write public key: %w", err)
+	}
+
+	if output, err := exec.Command("gpg", "--batch", "--homedir", home, "--import", keyPath).CombinedOutput(); err != nil { //nolint:gosec
+		return fmt.Errorf("failed to import public key: %w\nOutput: %s", err, output)
+	}
+
+	if output, err := exec.Command("gpg", "--batch", "--homedir", home, "--verify", signaturePath, filePath).CombinedOutput(); err != nil { //nolint:gosec
+		return fmt.Errorf("gpg signature verification failed: %w\nOutput: %s", err, output)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package domainadapters
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+)
+
+// toolVersionsNames maps names other tools use in .tool-versions to wand
+// package names; asdf plugin names mostly match already
+var toolVersionsNames = map[string]string{
+	"node": "nodejs",
+	"go":   "golang",
+}
+
+// VersionFileReader reads one kind of version file
+type VersionFileReader struct {
+	fs       interfaces.FileSystem
+	name     string
+	packages []string
+	parse    func(data string) map[string]string
+}
+
+// NewVersionFileReaders returns readers for the named version files, in order.
+// Names must come from entities.VersionFiles.
+func NewVersionFileReaders(fs interfaces.FileSystem, names []string) ([]interfaces.VersionFileReader, error) {
+	readers := make([]interfaces.VersionFileReader, 0, len(names))
+	for _, name := range names {
+		reader := &VersionFileReader{fs: fs, name: name}
+		switch name {
+		case ".tool-versions":
+			reader.parse = parseToolVersions
+		case ".nvmrc", ".node-version":
+			reader.packages = []string{"nodejs"}
+			reader.parse = func(data string) map[string]string { return firstVersion("nodejs", data, parseNodeVersion) }
+		case ".python-version":
+			reader.packages = []string{"python"}
+			reader.parse = func(data string) map[string]string { return firstVersion("python", data, usableVersion) }
+		case "go.mod":
+			reader.packages = []string{"golang"}
+			reader.parse = parseGoModToolchain
+		default:
+			return nil, fmt.Errorf("unknown version file %q", name)
+		}
+		readers = append(readers, reader)
+	}
+	return readers, nil
+}
+
+// FileName returns the file's name
+func (r *VersionFileReader) FileName() string {
+	return r.name
+}
+
+// Packages returns the packages the file can pin, or nil for any
+func (r *VersionFileReader) Packages() []string {
+	return r.packages
+}
+
+// Read returns the versions the file in dir pins, or nil if there is no file
+func (r *VersionFileReader) Read(dir string) (map[string]string, error) {
+	path := filepath.Join(dir, r.name)
+	if !r.fs.Exists(path) || r.fs.IsDir(path) {
+		return nil, nil
+	}
+
+	data, err := r.fs.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	versions := r.parse(string(data))
+	if versions == nil {
+		versions = make(map[string]string) // present, but pins nothing usable
+	}
+	return versions, nil
+}
+
+// parseToolVersions reads asdf's "<tool> <version> [fallback...]" lines,
+// taking each tool's first version wand can use
+func parseToolVersions(data string) map[string]string {
+	versions := make(map[string]string)
+	for _, line := range strings.Split(data, "\n") {
+		if i := strings.Index(line, "#"); i != -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		name := fields[0]
+		if mapped, ok := toolVersionsNames[name]; ok {
+			name = mapped
+		}
+		if _, exists := versions[name]; exists {
+			continue
+		}
+		for _, field := range fields[1:] {
+			if version, ok := usableVersion(field); ok {
+				versions[name] = version
+				break
+			}
+		}
+	}
+	return versions
+}
+
+// parseGoModToolchain reads go.mod's "toolchain go1.22.1" line
+func parseGoModToolchain(data string) map[string]string {
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "toolchain" {
+			if version, ok := usableVersion(strings.TrimPrefix(fields[1], "go")); ok {
+				return map[string]string{"golang": version}
+			}
+		}
+	}
+	return nil
+}
+
+// firstVersion pins pkg to the first line of data that parse accepts
+func firstVersion(pkg, data string, parse func(string) (string, bool)) map[string]string {
+	for _, line := range strings.Split(data, "\n") {
+		if i := strings.Index(line, "#"); i != -1 {
+			line = line[:i]
+		}
+		if version, ok := parse(strings.TrimSpace(line)); ok {
+			return map[string]string{pkg: version}
+		}
+	}
+	return nil
+}
+
+// parseNodeVersion reads an nvm version; the newest release is "latest" and
+// LTS aliases, which need nvm's release index, are skipped
+func parseNodeVersion(value string) (string, bool) {
+	switch value {
+	case "node", "stable":
+		return "latest", true
+	}
+	return usableVersion(value)
+}
+
+// usableVersion returns value without a leading v if it is a version or
+// constraint wand understands; "system" and asdf's ref: and path: are not
+func usableVersion(value string) (string, bool) {
+	value = strings.TrimPrefix(value, "v")
+	if value == "" || value == "system" || strings.Contains(value, ":") {
+		return "", false
+	}
+	if _, err := entities.ParseVersionConstraint(value); err != nil {
+		return "", false
+	}
+	return value, true
+}
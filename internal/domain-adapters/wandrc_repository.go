@@ -76,7 +76,7 @@ func (r *WandRCRepository) FindInPath(startDir string) (*entities.WandRC, string
 		}
 
 		parent := filepath.Dir(dir)
-		if parent == dir || parent == "/" || parent == "." {
+		if parent == dir {
 			// Reached root
 			break
 		}
@@ -2,12 +2,21 @@
 package domainorchestrators
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/ochairo/wand/internal/domain/entities"
+	errs "github.com/ochairo/wand/internal/domain/errors"
 	"github.com/ochairo/wand/internal/domain/interfaces"
 	"github.com/ochairo/wand/internal/domain/services"
+	"github.com/ochairo/wand/internal/domain/validation"
 )
 
 // InstallCommandHandler handles the install command
@@ -15,18 +24,22 @@ type InstallCommandHandler struct {
 	installOrchestrator *InstallOrchestrator
 	registryRepo        interfaces.RegistryRepository
 	wandrcRepo          interfaces.WandRCRepository
+	config              *entities.Config
 }
 
-// NewInstallCommandHandler creates a new install command handler
+// NewInstallCommandHandler creates an install command handler whose
+// flags default to the configured settings
 func NewInstallCommandHandler(
 	installOrchestrator *InstallOrchestrator,
 	registryRepo interfaces.RegistryRepository,
 	wandrcRepo interfaces.WandRCRepository,
+	config *entities.Config,
 ) *InstallCommandHandler {
 	return &InstallCommandHandler{
 		installOrchestrator: installOrchestrator,
 		registryRepo:        registryRepo,
 		wandrcRepo:          wandrcRepo,
+		config:              config,
 	}
 }
 
@@ -57,14 +70,87 @@ func (h *InstallCommandHandler) Handle(ctx interfaces.CommandContext) error {
 		forceFlag = false // default to false if flag not found
 	}
 
+	trustNewChecksum, err := ctx.GetBoolFlag("trust-new-checksum")
+	if err != nil {
+		trustNewChecksum = false // default to false if flag not found
+	}
+
+	buildFromSource, err := ctx.GetBoolFlag("build-from-source")
+	if err != nil {
+		buildFromSource = false // default to false if flag not found
+	}
+
+	hooksFlag := h.config.Hooks
+	if ctx.FlagChanged("hooks") {
+		hooksFlag, _ = ctx.GetStringFlag("hooks")
+	}
+	hookPolicy, err := services.ParseHookPolicy(hooksFlag)
+	if err != nil {
+		return err
+	}
+
+	shimConflict, _ := ctx.GetStringFlag("shim-conflict")
+	switch shimConflict {
+	case "", "ask", "keep", "replace":
+	default:
+		return fmt.Errorf("invalid --shim-conflict %q: expected ask, keep or replace", shimConflict)
+	}
+
+	timeout, err := durationFlag(ctx, "timeout")
+	if err != nil {
+		return err
+	}
+	downloadTimeout, err := durationFlag(ctx, "download-timeout")
+	if err != nil {
+		return err
+	}
+	hookTimeout, err := durationFlag(ctx, "hook-timeout")
+	if err != nil {
+		return err
+	}
+
+	runCtx := ctx.Context()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(runCtx, timeout)
+		defer cancel()
+	}
+
 	ctx.Printf("Installing %s@%s...\n", packageName, versionStr)
 
 	// Install with flags
+	progress := newInstallProgress(ctx)
 	opts := InstallPackageOptions{
-		Global: globalFlag,
-		Force:  forceFlag,
+		Global:           globalFlag,
+		Force:            forceFlag,
+		TrustNewChecksum: trustNewChecksum,
+		BuildFromSource:  buildFromSource,
+		HookPolicy:       hookPolicy,
+		ConfirmHooks: func(plan *services.HookPlan) bool {
+			progress.clear()
+			ctx.Printf("%s@%s will run these commands in %s:\n", plan.Package, plan.Version, plan.InstallDir)
+			for _, command := range plan.Commands() {
+				ctx.Printf("  %s\n", command)
+			}
+			return ctx.Confirm("Run them?")
+		},
+		TakeShim: func(conflict services.ShimConflict) bool {
+			progress.clear()
+			switch shimConflict {
+			case "keep":
+				ctx.Printf("Keeping the %s shim, which runs %s\n", conflict.Binary, conflict.Owner)
+				return false
+			case "replace":
+				ctx.Printf("Taking over the %s shim from %s\n", conflict.Binary, conflict.Owner)
+				return true
+			}
+			return ctx.Confirm(fmt.Sprintf("%s is already provided by %s. Point its shim at %s?", conflict.Binary, conflict.Owner, conflict.Package))
+		},
+		HookTimeout:     hookTimeout,
+		DownloadTimeout: downloadTimeout,
+		Progress:        progress.Observe,
 	}
-	if err := h.installOrchestrator.InstallPackageWithOptions(packageName, versionStr, opts); err != nil {
+	if err := h.installOrchestrator.InstallPackageContext(runCtx, packageName, versionStr, opts); err != nil {
 		return fmt.Errorf("installation failed: %w", err)
 	}
 
@@ -73,6 +159,19 @@ func (h *InstallCommandHandler) Handle(ctx interfaces.CommandContext) error {
 	return nil
 }
 
+// durationFlag parses a duration flag such as "90s" or "30m"; an unset flag is 0
+func durationFlag(ctx interfaces.CommandContext, name string) (time.Duration, error) {
+	value, err := ctx.GetStringFlag(name)
+	if err != nil || value == "" {
+		return 0, nil // default to no limit if flag not found
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("invalid --%s %q: expected a duration such as 90s or 30m", name, value)
+	}
+	return duration, nil
+}
+
 // ListCommandHandler handles the list command
 type ListCommandHandler struct {
 	registryRepo   interfaces.RegistryRepository
@@ -274,16 +373,20 @@ func (h *UseCommandHandler) Handle(ctx interfaces.CommandContext) error {
 type SwitchCommandHandler struct {
 	registryRepo interfaces.RegistryRepository
 	wandrcRepo   interfaces.WandRCRepository
+	config       *entities.Config
 }
 
-// NewSwitchCommandHandler creates a new switch command handler
+// NewSwitchCommandHandler creates a switch command handler whose
+// scope defaults to the configured one
 func NewSwitchCommandHandler(
 	registryRepo interfaces.RegistryRepository,
 	wandrcRepo interfaces.WandRCRepository,
+	config *entities.Config,
 ) *SwitchCommandHandler {
 	return &SwitchCommandHandler{
 		registryRepo: registryRepo,
 		wandrcRepo:   wandrcRepo,
+		config:       config,
 	}
 }
 
@@ -304,9 +407,9 @@ func (h *SwitchCommandHandler) Handle(ctx interfaces.CommandContext) error {
 	packageName := parts[0]
 	versionStr := parts[1]
 
-	global, err := ctx.GetBoolFlag("global")
-	if err != nil {
-		global = false // default to local/project scope
+	global := h.config.Scope == "global"
+	if ctx.FlagChanged("global") {
+		global, _ = ctx.GetBoolFlag("global")
 	}
 
 	// Parse version
@@ -361,16 +464,21 @@ func (h *SwitchCommandHandler) Handle(ctx interfaces.CommandContext) error {
 type InfoCommandHandler struct {
 	registryRepo interfaces.RegistryRepository
 	wandrcRepo   interfaces.WandRCRepository
+	shimService  *services.ShimService
 }
 
-// NewInfoCommandHandler creates a new info command handler
+// NewInfoCommandHandler creates an info command handler that
+// shows the version the current directory resolves to, from .wandrc or
+// other tools' version files
 func NewInfoCommandHandler(
 	registryRepo interfaces.RegistryRepository,
 	wandrcRepo interfaces.WandRCRepository,
+	shimService *services.ShimService,
 ) *InfoCommandHandler {
 	return &InfoCommandHandler{
 		registryRepo: registryRepo,
 		wandrcRepo:   wandrcRepo,
+		shimService:  shimService,
 	}
 }
 
@@ -406,8 +514,22 @@ func (h *InfoCommandHandler) Handle(ctx interfaces.CommandContext) error {
 		ctx.Printf("Active globally: none (using first installed version)\n")
 	}
 
-	// Project version (from .wandrc)
-	if h.wandrcRepo.Exists(".") {
+	// Project version (from .wandrc or another tool's version file)
+	if h.shimService != nil {
+		dir, err := filepath.Abs(".")
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+		if spec, source, err := h.shimService.ResolveSpec(packageName, dir); err == nil && source != "global" {
+			if version, err := h.shimService.ResolveInstalled(packageName, spec); err == nil && version != spec {
+				ctx.Printf("Active in project: %s (%s from %s)\n", version, spec, source)
+			} else if err == nil {
+				ctx.Printf("Active in project: %s (from %s)\n", version, source)
+			} else {
+				ctx.Printf("Active in project: %s (from %s, not installed)\n", spec, source)
+			}
+		}
+	} else if h.wandrcRepo.Exists(".") {
 		wandrc, err := h.wandrcRepo.Load(".")
 		if err == nil && wandrc.HasVersion(packageName) {
 			projectVersion, _ := wandrc.GetVersion(packageName)
@@ -515,22 +637,29 @@ func (h *InitCommandHandler) Handle(ctx interfaces.CommandContext) error {
 type AddCommandHandler struct {
 	wandrcRepo   interfaces.WandRCRepository
 	registryRepo interfaces.RegistryRepository
+	versionFiles []interfaces.VersionFileReader
 }
 
-// NewAddCommandHandler creates a new add command handler
+// NewAddCommandHandler creates an add command handler whose
+// --import flag copies pins from other tools' version files into .wandrc
 func NewAddCommandHandler(
 	wandrcRepo interfaces.WandRCRepository,
 	registryRepo interfaces.RegistryRepository,
+	versionFiles []interfaces.VersionFileReader,
 ) *AddCommandHandler {
 	return &AddCommandHandler{
 		wandrcRepo:   wandrcRepo,
 		registryRepo: registryRepo,
+		versionFiles: versionFiles,
 	}
 }
 
 // Handle executes the add command
 func (h *AddCommandHandler) Handle(ctx interfaces.CommandContext) error {
 	args := ctx.GetArgs()
+	if importFlag, _ := ctx.GetBoolFlag("import"); importFlag {
+		return h.importVersionFiles(ctx, args)
+	}
 	if len(args) == 0 {
 		return fmt.Errorf("package specification required (e.g., nano@8.7.0)")
 	}
@@ -545,13 +674,17 @@ func (h *AddCommandHandler) Handle(ctx interfaces.CommandContext) error {
 	packageName := parts[0]
 	versionStr := parts[1]
 
-	// Parse and validate version
-	version, err := entities.NewVersion(versionStr)
+	// Parse and validate version, which may be a constraint such as ^20
+	constraint, err := entities.ParseVersionConstraint(versionStr)
 	if err != nil {
 		return fmt.Errorf("invalid version: %w", err)
 	}
+	spec := constraint.String()
+	if exact := constraint.IsExact(); exact != nil {
+		spec = exact.String()
+	}
 
-	// Verify package version is installed
+	// Verify an installed version satisfies it
 	registry, err := h.registryRepo.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load registry: %w", err)
@@ -559,11 +692,15 @@ func (h *AddCommandHandler) Handle(ctx interfaces.CommandContext) error {
 
 	entry, exists := registry.Packages[packageName]
 	if !exists {
-		return fmt.Errorf("package '%s' is not installed. Install it first with: wand install %s@%s", packageName, packageName, version)
+		return fmt.Errorf("package '%s' is not installed. Install it first with: wand install %s@%s", packageName, packageName, spec)
 	}
 
-	if _, exists := entry.Versions[version.String()]; !exists {
-		return fmt.Errorf("version %s of '%s' is not installed. Install it first with: wand install %s@%s", version, packageName, packageName, version)
+	installed := make([]*entities.Version, 0, len(entry.Versions))
+	for _, pkg := range entry.Versions {
+		installed = append(installed, pkg.Version)
+	}
+	if constraint.Best(installed) == nil {
+		return fmt.Errorf("version %s of '%s' is not installed. Install it first with: wand install %s@%s", spec, packageName, packageName, spec)
 	}
 
 	// Load or create .wandrc
@@ -578,15 +715,89 @@ func (h *AddCommandHandler) Handle(ctx interfaces.CommandContext) error {
 	}
 
 	// Add package version
-	wandrc.SetVersion(packageName, version.String())
+	wandrc.SetVersion(packageName, spec)
 
 	// Save .wandrc
 	if err := h.wandrcRepo.Save(".", wandrc); err != nil {
 		return fmt.Errorf("failed to save .wandrc: %w", err)
 	}
 
-	ctx.Printf("✓ Added %s@%s to .wandrc\n", packageName, version)
+	ctx.Printf("✓ Added %s@%s to .wandrc\n", packageName, spec)
+
+	return nil
+}
+
+// importVersionFiles copies the pins of other tools' version files in the
+// current directory into .wandrc. files limits the import to those names.
+// Where files disagree the one read first wins, as when resolving, and pins
+// already in .wandrc are kept.
+func (h *AddCommandHandler) importVersionFiles(ctx interfaces.CommandContext, files []string) error {
+	readers := h.versionFiles
+	if len(files) > 0 {
+		readers = nil
+		for _, file := range files {
+			found := false
+			for _, reader := range h.versionFiles {
+				if reader.FileName() == file {
+					readers = append(readers, reader)
+					found = true
+				}
+			}
+			if !found {
+				return fmt.Errorf("%s is not a configured version file (see 'wand config get version_files')", file)
+			}
+		}
+	}
+
+	var wandrc *entities.WandRC
+	if h.wandrcRepo.Exists(".") {
+		var err error
+		wandrc, err = h.wandrcRepo.Load(".")
+		if err != nil {
+			return fmt.Errorf("failed to load .wandrc: %w", err)
+		}
+	} else {
+		wandrc = entities.NewWandRC()
+	}
+
+	imported := 0
+	seen := make(map[string]bool)
+	for _, reader := range readers {
+		versions, err := reader.Read(".")
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(versions))
+		for name := range versions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			if existing, ok := wandrc.GetVersion(name); ok {
+				ctx.Printf("  Kept %s@%s already in .wandrc (%s has %s)\n", name, existing, reader.FileName(), versions[name])
+				continue
+			}
+			wandrc.SetVersion(name, versions[name])
+			imported++
+			ctx.Printf("✓ Imported %s@%s from %s\n", name, versions[name], reader.FileName())
+		}
+	}
+
+	if len(seen) == 0 {
+		return fmt.Errorf("no versions found in version files in the current directory")
+	}
+	if imported == 0 {
+		return nil
+	}
 
+	if err := h.wandrcRepo.Save(".", wandrc); err != nil {
+		return fmt.Errorf("failed to save .wandrc: %w", err)
+	}
 	return nil
 }
 
@@ -679,7 +890,7 @@ func (h *WandfileInstallCommandHandler) Handle(ctx interfaces.CommandContext) er
 	ctx.Printf("Installing packages from wandfile...\n")
 
 	// Install all packages
-	if err := h.wandfileSvc.Install(wandfile); err != nil {
+	if err := h.wandfileSvc.InstallContext(ctx.Context(), wandfile); err != nil {
 		return fmt.Errorf("installation failed: %w", err)
 	}
 
@@ -853,20 +1064,24 @@ type DoctorCommandHandler struct {
 	formulaRepo  interfaces.FormulaRepository
 	fs           interfaces.FileSystem
 	wandDir      string
+	shimService  *services.ShimService
 }
 
-// NewDoctorCommandHandler creates a new doctor command handler
+// NewDoctorCommandHandler creates a doctor command handler; given a shim
+// service it also checks each shim against the installed packages
 func NewDoctorCommandHandler(
 	registryRepo interfaces.RegistryRepository,
 	formulaRepo interfaces.FormulaRepository,
 	fs interfaces.FileSystem,
 	wandDir string,
+	shimService *services.ShimService,
 ) *DoctorCommandHandler {
 	return &DoctorCommandHandler{
 		registryRepo: registryRepo,
 		formulaRepo:  formulaRepo,
 		fs:           fs,
 		wandDir:      wandDir,
+		shimService:  shimService,
 	}
 }
 
@@ -917,6 +1132,10 @@ func (h *DoctorCommandHandler) Handle(ctx interfaces.CommandContext) error {
 		ctx.Printf("  ⚠ Shims directory not found (will be created on first install)\n")
 	}
 
+	if h.shimService != nil && !h.checkShims(ctx) {
+		allGood = false
+	}
+
 	ctx.Printf("\n")
 	if allGood {
 		ctx.Printf("✓ All checks passed!\n")
@@ -927,6 +1146,55 @@ func (h *DoctorCommandHandler) Handle(ctx interfaces.CommandContext) error {
 	return nil
 }
 
+// checkShims reports shims that are outdated, that no installed package
+// provides, and binaries of installed packages that have no shim
+func (h *DoctorCommandHandler) checkShims(ctx interfaces.CommandContext) bool {
+	ctx.Printf("\nChecking shims:\n")
+	shims, err := h.shimService.ListShims()
+	if err != nil {
+		ctx.Printf("  ✗ Cannot check shims: %v\n", err)
+		return false
+	}
+
+	var outdated, dangling, missing []string
+	current := 0
+	for _, shim := range shims {
+		provided := false
+		for _, name := range shim.Providers {
+			provided = provided || name == shim.Owner
+		}
+		switch {
+		case !shim.Exists:
+			missing = append(missing, shim.Binary)
+		case !provided:
+			dangling = append(dangling, shim.Binary)
+		case shim.Outdated:
+			outdated = append(outdated, shim.Binary)
+		default:
+			current++
+		}
+	}
+
+	ctx.Printf("  ✓ %d shim(s) up to date\n", current)
+	for _, problem := range []struct {
+		binaries []string
+		what     string
+	}{
+		{outdated, "outdated, written by another version of wand or for another wand directory"},
+		{dangling, "dangling, no installed package provides them"},
+		{missing, "missing for installed packages"},
+	} {
+		if len(problem.binaries) > 0 {
+			ctx.Printf("  ✗ %d shim(s) %s: %s\n", len(problem.binaries), problem.what, strings.Join(problem.binaries, ", "))
+		}
+	}
+	if len(outdated)+len(dangling)+len(missing) == 0 {
+		return true
+	}
+	ctx.Printf("    Run 'wand reshim' to recreate them\n")
+	return false
+}
+
 // UpdateCommandHandler handles the update command
 type UpdateCommandHandler struct {
 	installOrchestrator *InstallOrchestrator
@@ -961,7 +1229,7 @@ func (h *UpdateCommandHandler) Handle(ctx interfaces.CommandContext) error {
 		Force:  true,
 	}
 
-	if err := h.installOrchestrator.InstallPackageWithOptions(packageName, "latest", opts); err != nil {
+	if err := h.installOrchestrator.InstallPackageContext(ctx.Context(), packageName, "latest", opts); err != nil {
 		return fmt.Errorf("update failed: %w", err)
 	}
 
@@ -999,17 +1267,38 @@ func (h *VersionCommandHandler) Handle(ctx interfaces.CommandContext) error {
 type OutdatedCommandHandler struct {
 	installOrchestrator *InstallOrchestrator
 	registryRepo        interfaces.RegistryRepository
+	config              *entities.Config
 }
 
-// NewOutdatedCommandHandler creates a new outdated command handler
+// NewOutdatedCommandHandler creates an outdated command handler
+// using the configured parallelism
 func NewOutdatedCommandHandler(
 	installOrchestrator *InstallOrchestrator,
 	registryRepo interfaces.RegistryRepository,
+	config *entities.Config,
 ) *OutdatedCommandHandler {
 	return &OutdatedCommandHandler{
 		installOrchestrator: installOrchestrator,
 		registryRepo:        registryRepo,
+		config:              config,
+	}
+}
+
+// latestVersion returns the newest available version of a package, or nil
+// if it has none
+func (h *OutdatedCommandHandler) latestVersion(ctx context.Context, name string) (*entities.Version, error) {
+	availableVersions, err := h.installOrchestrator.ListAvailableVersionsContext(ctx, name)
+	if err != nil || len(availableVersions) == 0 {
+		return nil, err
+	}
+
+	latestVersion := availableVersions[0]
+	for _, v := range availableVersions {
+		if v.Compare(latestVersion) > 0 {
+			latestVersion = v
+		}
 	}
+	return latestVersion, nil
 }
 
 // Handle executes the outdated command
@@ -1026,8 +1315,46 @@ func (h *OutdatedCommandHandler) Handle(ctx interfaces.CommandContext) error {
 
 	ctx.Printf("Checking for outdated packages...\n\n")
 
+	names := make([]string, 0, len(registry.Packages))
+	for name := range registry.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parallelism := h.config.Parallelism
+	if ctx.FlagChanged("parallelism") {
+		value, _ := ctx.GetStringFlag("parallelism")
+		if parallelism, err = strconv.Atoi(value); err != nil || parallelism < 1 {
+			return fmt.Errorf("invalid --parallelism %q: expected a positive number", value)
+		}
+	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	// Release lists are fetched concurrently, then reported in name order
+	type check struct {
+		latest *entities.Version
+		err    error
+	}
+	checks := make([]check, len(names))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			checks[i].latest, checks[i].err = h.latestVersion(ctx.Context(), name)
+		}(i, name)
+	}
+	wg.Wait()
+
 	hasOutdated := false
-	for name, entry := range registry.Packages {
+	for i, name := range names {
+		entry := registry.Packages[name]
+
 		// Get current version
 		currentVersion, hasGlobal := registry.GetGlobalVersion(name)
 		if !hasGlobal && len(entry.Versions) > 0 {
@@ -1038,25 +1365,19 @@ func (h *OutdatedCommandHandler) Handle(ctx interfaces.CommandContext) error {
 			}
 		}
 
-		// Get available versions
-		availableVersions, err := h.installOrchestrator.ListAvailableVersions(name)
-		if err != nil {
+		if err := checks[i].err; err != nil {
+			var wandErr *errs.WandError
+			if errors.As(err, &wandErr) && wandErr.Code == errs.ErrRateLimited {
+				return err
+			}
 			ctx.Printf("  %s: unable to check for updates\n", name)
 			continue
 		}
-
-		if len(availableVersions) == 0 {
+		latestVersion := checks[i].latest
+		if latestVersion == nil {
 			continue
 		}
 
-		// Get latest version
-		latestVersion := availableVersions[0]
-		for _, v := range availableVersions {
-			if v.Compare(latestVersion) > 0 {
-				latestVersion = v
-			}
-		}
-
 		// Compare versions
 		current, err := entities.NewVersion(currentVersion)
 		if err != nil {
@@ -1077,3 +1398,196 @@ func (h *OutdatedCommandHandler) Handle(ctx interfaces.CommandContext) error {
 
 	return nil
 }
+
+// ValidateCommandHandler handles the validate command
+type ValidateCommandHandler struct {
+	fs        interfaces.FileSystem
+	validator *validation.SchemaValidator
+}
+
+// NewValidateCommandHandler creates a new validate command handler
+func NewValidateCommandHandler(
+	fs interfaces.FileSystem,
+) *ValidateCommandHandler {
+	return &ValidateCommandHandler{
+		fs:        fs,
+		validator: validation.NewSchemaValidator(),
+	}
+}
+
+// Handle executes the validate command
+func (h *ValidateCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	paths := ctx.GetArgs()
+	if len(paths) == 0 {
+		// Default to the project files in the current directory
+		for _, candidate := range []string{"./wandfile", "./.wandrc"} {
+			if h.fs.Exists(candidate) {
+				paths = append(paths, candidate)
+			}
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no wandfile or .wandrc found in current directory")
+		}
+	}
+
+	kindFlag, _ := ctx.GetStringFlag("type")
+	verbose, _ := ctx.GetBoolFlag("verbose")
+
+	failed := 0
+	for _, path := range paths {
+		kind := validation.DetectKind(path)
+		switch kindFlag {
+		case "":
+		case string(validation.KindFormula), string(validation.KindWandfile), string(validation.KindWandRC):
+			kind = validation.DocumentKind(kindFlag)
+		default:
+			return fmt.Errorf("unknown document type: %s (expected formula, wandfile or wandrc)", kindFlag)
+		}
+
+		if !h.fs.Exists(path) {
+			ctx.PrintError("✗ %s not found\n", path)
+			failed++
+			continue
+		}
+
+		data, err := h.fs.ReadFile(path)
+		if err != nil {
+			ctx.PrintError("✗ %s: %v\n", path, err)
+			failed++
+			continue
+		}
+
+		if verbose {
+			ctx.Printf("Validating %s as %s...\n", path, kind)
+		}
+
+		issues := h.validator.Validate(kind, data)
+		if validation.HasErrors(issues) {
+			failed++
+			ctx.Printf("✗ %s has errors:\n", path)
+		} else {
+			ctx.Printf("✓ %s is valid\n", path)
+		}
+
+		for _, issue := range issues {
+			if issue.Severity == validation.SeverityWarning {
+				ctx.Printf("  ⚠ %s\n", issue)
+			} else {
+				ctx.Printf("  - %s\n", issue)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d file(s) failed validation", failed, len(paths))
+	}
+
+	return nil
+}
+
+// FormulaTestCommandHandler handles the formula test command
+type FormulaTestCommandHandler struct {
+	linter *services.FormulaLinter
+}
+
+// NewFormulaTestCommandHandler creates a new formula test command handler
+func NewFormulaTestCommandHandler(
+	linter *services.FormulaLinter,
+) *FormulaTestCommandHandler {
+	return &FormulaTestCommandHandler{
+		linter: linter,
+	}
+}
+
+// Handle executes the formula test command
+func (h *FormulaTestCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	args := ctx.GetArgs()
+	if len(args) == 0 {
+		return fmt.Errorf("formula name required")
+	}
+
+	version, _ := ctx.GetStringFlag("version")
+
+	ctx.Printf("Testing formula %s...\n", args[0])
+	report, err := h.linter.LintContext(ctx.Context(), args[0], version)
+	if err != nil {
+		return err
+	}
+	ctx.Printf("Version: %s\n\n", report.Version)
+
+	for _, result := range report.Results {
+		switch {
+		case result.Skipped:
+			ctx.Printf("  - %-8s %s: skipped (%s)\n", result.Check, result.Target, result.Message)
+		case result.Passed:
+			ctx.Printf("  ✓ %-8s %s: %s\n", result.Check, result.Target, result.Message)
+		default:
+			ctx.Printf("  ✗ %-8s %s: %s\n", result.Check, result.Target, result.Message)
+		}
+	}
+
+	if report.Failed() {
+		return fmt.Errorf("formula %s@%s failed testing", report.Formula, report.Version)
+	}
+
+	ctx.Printf("\n✓ Formula %s@%s passed all checks\n", report.Formula, report.Version)
+	return nil
+}
+
+// FormulaNewCommandHandler handles the formula new command
+type FormulaNewCommandHandler struct {
+	scaffolder *services.FormulaScaffolder
+	fs         interfaces.FileSystem
+}
+
+// NewFormulaNewCommandHandler creates a new formula new command handler
+func NewFormulaNewCommandHandler(
+	scaffolder *services.FormulaScaffolder,
+	fs interfaces.FileSystem,
+) *FormulaNewCommandHandler {
+	return &FormulaNewCommandHandler{
+		scaffolder: scaffolder,
+		fs:         fs,
+	}
+}
+
+// Handle executes the formula new command
+func (h *FormulaNewCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	args := ctx.GetArgs()
+	if len(args) == 0 {
+		return fmt.Errorf("repository required (owner/repo)")
+	}
+
+	output, _ := ctx.GetStringFlag("output")
+	force, _ := ctx.GetBoolFlag("force")
+
+	if output != "" && h.fs.Exists(output) && !force {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", output)
+	}
+
+	result, err := h.scaffolder.Scaffold(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Notes go to stderr so the YAML can be redirected
+	for _, note := range result.Notes {
+		ctx.PrintError("⚠ %s\n", note)
+	}
+	for _, issue := range result.Issues {
+		ctx.PrintError("⚠ %s\n", issue)
+	}
+
+	if output == "" {
+		ctx.Printf("%s", result.YAML)
+		return nil
+	}
+
+	if err := h.fs.WriteFile(output, result.YAML, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+
+	ctx.Printf("✓ Wrote %s from %s release %s\n", output, args[0], result.Version)
+	ctx.Printf("Review the description and binaries, then run 'wand formula test %s'\n", result.Formula.Name)
+	return nil
+}
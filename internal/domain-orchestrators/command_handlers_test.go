@@ -1,18 +1,22 @@
 package domainorchestrators
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/interfaces"
 )
 
 // mockCommandContext for testing
 type mockCommandContext struct {
-	args   []string
-	flags  map[string]interface{}
-	output strings.Builder
+	args     []string
+	flags    map[string]interface{}
+	output   strings.Builder
+	confirm  bool
+	terminal bool
 }
 
 func newMockContext(args []string) *mockCommandContext {
@@ -27,6 +31,9 @@ func (m *mockCommandContext) Printf(format string, args ...interface{}) {
 	fmt.Fprintf(&m.output, format, args...)
 }
 func (m *mockCommandContext) PrintError(format string, args ...interface{}) {}
+func (m *mockCommandContext) Confirm(prompt string) bool                    { return m.confirm }
+func (m *mockCommandContext) IsTerminal() bool                              { return m.terminal }
+func (m *mockCommandContext) Context() context.Context                      { return context.Background() }
 
 func (m *mockCommandContext) GetStringFlag(name string) (string, error) {
 	if val, ok := m.flags[name].(string); ok {
@@ -35,6 +42,11 @@ func (m *mockCommandContext) GetStringFlag(name string) (string, error) {
 	return "", fmt.Errorf("flag not found")
 }
 
+func (m *mockCommandContext) FlagChanged(name string) bool {
+	_, ok := m.flags[name]
+	return ok
+}
+
 func (m *mockCommandContext) GetBoolFlag(name string) (bool, error) {
 	if val, ok := m.flags[name].(bool); ok {
 		return val, nil
@@ -45,6 +57,7 @@ func (m *mockCommandContext) GetBoolFlag(name string) (bool, error) {
 // mockFormulaRepo for testing
 type mockFormulaRepo struct {
 	formulas map[string]*entities.Formula
+	sync     func() // run by Sync, if set
 }
 
 func newMockFormulaRepo() *mockFormulaRepo {
@@ -71,7 +84,12 @@ func (m *mockFormulaRepo) ListFormulas() ([]*entities.Formula, error) {
 	return formulas, nil
 }
 
-func (m *mockFormulaRepo) Sync() error { return nil }
+func (m *mockFormulaRepo) Sync() error {
+	if m.sync != nil {
+		m.sync()
+	}
+	return nil
+}
 
 // mockRegistryRepo for testing
 type mockRegistryRepo struct {
@@ -89,15 +107,16 @@ func (m *mockRegistryRepo) Exists() bool                      { return true }
 // mockFileSystem for testing
 type mockFileSystem struct {
 	exists map[string]bool
+	files  map[string][]byte
 }
 
 func newMockFileSystem() *mockFileSystem {
-	return &mockFileSystem{exists: make(map[string]bool)}
+	return &mockFileSystem{exists: make(map[string]bool), files: make(map[string][]byte)}
 }
 
 func (m *mockFileSystem) Exists(path string) bool                                        { return m.exists[path] }
 func (m *mockFileSystem) IsDir(path string) bool                                         { return false }
-func (m *mockFileSystem) ReadFile(path string) ([]byte, error)                           { return nil, nil }
+func (m *mockFileSystem) ReadFile(path string) ([]byte, error)                           { return m.files[path], nil }
 func (m *mockFileSystem) WriteFile(path string, data []byte, perm uint32) error          { return nil }
 func (m *mockFileSystem) MkdirAll(path string, perm uint32) error                        { return nil }
 func (m *mockFileSystem) Remove(path string) error                                       { return nil }
@@ -106,6 +125,8 @@ func (m *mockFileSystem) Symlink(oldname, newname string) error
 func (m *mockFileSystem) ReadSymlink(name string) (string, error)                        { return "", nil }
 func (m *mockFileSystem) Chmod(path string, mode uint32) error                           { return nil }
 func (m *mockFileSystem) Walk(root string, walkFn func(string, bool, error) error) error { return nil }
+func (m *mockFileSystem) Rename(oldpath, newpath string) error                           { return nil }
+func (m *mockFileSystem) CopyDir(src, dst string) error                                  { return nil }
 
 func TestSearchCommandHandler(t *testing.T) {
 	repo := newMockFormulaRepo()
@@ -162,7 +183,7 @@ func TestDoctorCommandHandler(t *testing.T) {
 	wandDir := "/test/.wand"
 	fs.exists[wandDir] = true
 
-	handler := NewDoctorCommandHandler(repo, formulas, fs, wandDir)
+	handler := NewDoctorCommandHandler(repo, formulas, fs, wandDir, nil)
 	ctx := newMockContext(nil)
 
 	if err := handler.Handle(ctx); err != nil {
@@ -176,7 +197,7 @@ func TestDoctorCommandHandler(t *testing.T) {
 
 func TestOutdatedCommandHandler_Empty(t *testing.T) {
 	repo := newMockRegistryRepo()
-	handler := NewOutdatedCommandHandler(nil, repo)
+	handler := NewOutdatedCommandHandler(nil, repo, entities.DefaultConfig())
 	ctx := newMockContext(nil)
 
 	if err := handler.Handle(ctx); err != nil {
@@ -188,6 +209,32 @@ func TestOutdatedCommandHandler_Empty(t *testing.T) {
 	}
 }
 
+func TestValidateCommandHandler(t *testing.T) {
+	fs := newMockFileSystem()
+	fs.exists["./wandfile"] = true
+	fs.files["./wandfile"] = []byte("cli:\n  - name: nano\n    version: \"8.7\"\n")
+	fs.exists["bad.wandfile"] = true
+	fs.files["bad.wandfile"] = []byte("cli:\n  - name: nano\n    version: nope\n")
+
+	handler := NewValidateCommandHandler(fs)
+
+	ctx := newMockContext(nil)
+	if err := handler.Handle(ctx); err != nil {
+		t.Fatalf("expected default wandfile to validate: %v", err)
+	}
+	if !strings.Contains(ctx.output.String(), "./wandfile is valid") {
+		t.Errorf("unexpected output: %s", ctx.output.String())
+	}
+
+	ctx = newMockContext([]string{"bad.wandfile"})
+	if err := handler.Handle(ctx); err == nil {
+		t.Fatal("expected validation failure")
+	}
+	if !strings.Contains(ctx.output.String(), "line 3, column 14") {
+		t.Errorf("expected line/column in output: %s", ctx.output.String())
+	}
+}
+
 func TestInstallCommandHandler_ParseSpec(t *testing.T) {
 	tests := []struct {
 		spec    string
@@ -213,3 +260,55 @@ func TestInstallCommandHandler_ParseSpec(t *testing.T) {
 		}
 	}
 }
+
+func TestInstallCommandHandler_InvalidHookPolicy(t *testing.T) {
+	handler := NewInstallCommandHandler(nil, newMockRegistryRepo(), nil, entities.DefaultConfig())
+	ctx := newMockContext([]string{"nano"})
+	ctx.flags["hooks"] = "sometimes"
+
+	err := handler.Handle(ctx)
+	if err == nil || !strings.Contains(err.Error(), "sometimes") {
+		t.Fatalf("expected invalid hook policy error, got %v", err)
+	}
+	if strings.Contains(ctx.output.String(), "Installing") {
+		t.Error("should fail before installing")
+	}
+}
+
+// mockVersionFile serves fixed versions as another tool's version file
+type mockVersionFile struct {
+	name     string
+	versions map[string]string
+}
+
+func (m *mockVersionFile) FileName() string                           { return m.name }
+func (m *mockVersionFile) Packages() []string                         { return nil }
+func (m *mockVersionFile) Read(dir string) (map[string]string, error) { return m.versions, nil }
+
+func TestAddCommandHandler_Import(t *testing.T) {
+	wandrc := entities.NewWandRC()
+	wandrc.SetVersion("python", "3.12")
+	wandrcRepo := &mockWandRCRepo{dir: ".", wandrc: wandrc}
+	h := NewAddCommandHandler(wandrcRepo, newMockRegistryRepo(), []interfaces.VersionFileReader{
+		&mockVersionFile{name: ".tool-versions", versions: map[string]string{"nodejs": "18.19.0", "python": "3.11.7"}},
+		&mockVersionFile{name: ".nvmrc", versions: map[string]string{"nodejs": "20.10.0"}},
+	})
+
+	ctx := newMockContext(nil)
+	ctx.flags["import"] = true
+	if err := h.Handle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := wandrc.GetVersion("nodejs"); v != "18.19.0" {
+		t.Errorf("expected the first file read to win, got nodejs@%s", v)
+	}
+	if v, _ := wandrc.GetVersion("python"); v != "3.12" {
+		t.Errorf("expected the existing pin to be kept, got python@%s", v)
+	}
+
+	ctx = newMockContext([]string{"go.mod"})
+	ctx.flags["import"] = true
+	if err := h.Handle(ctx); err == nil {
+		t.Error("expected an error for a file that is not configured")
+	}
+}
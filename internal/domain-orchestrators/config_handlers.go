@@ -0,0 +1,231 @@
+package domainorchestrators
+
+import (
+	"fmt"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+)
+
+// ConfigGetCommandHandler handles the config get command
+type ConfigGetCommandHandler struct {
+	configRepo interfaces.ConfigRepository
+}
+
+// NewConfigGetCommandHandler creates a new config get command handler
+func NewConfigGetCommandHandler(configRepo interfaces.ConfigRepository) *ConfigGetCommandHandler {
+	return &ConfigGetCommandHandler{configRepo: configRepo}
+}
+
+// Handle prints the effective value of a setting
+func (h *ConfigGetCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	args := ctx.GetArgs()
+	if len(args) != 1 {
+		return fmt.Errorf("usage: wand config get <setting>")
+	}
+
+	key, err := entities.LookupConfigKey(args[0])
+	if err != nil {
+		return err
+	}
+	config, sources, err := resolveConfig(h.configRepo)
+	if err != nil {
+		return err
+	}
+
+	ctx.Printf("%s\n", configValue(key, config, sources))
+	return nil
+}
+
+// ConfigSetCommandHandler handles the config set command
+type ConfigSetCommandHandler struct {
+	configRepo interfaces.ConfigRepository
+}
+
+// NewConfigSetCommandHandler creates a new config set command handler
+func NewConfigSetCommandHandler(configRepo interfaces.ConfigRepository) *ConfigSetCommandHandler {
+	return &ConfigSetCommandHandler{configRepo: configRepo}
+}
+
+// Handle validates a value and stores it in the configuration file
+func (h *ConfigSetCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	args := ctx.GetArgs()
+	if len(args) != 2 {
+		return fmt.Errorf("usage: wand config set <setting> <value>")
+	}
+
+	key, err := entities.LookupConfigKey(args[0])
+	if err != nil {
+		return err
+	}
+	config, err := h.configRepo.Load()
+	if err != nil {
+		return err
+	}
+	if err := config.Set(key.Name, args[1]); err != nil {
+		return err
+	}
+	if err := h.configRepo.Save(config); err != nil {
+		return err
+	}
+
+	ctx.Printf("✓ Set %s = %s in %s\n", key.Name, args[1], h.configRepo.Path())
+	if _, overridden := h.configRepo.Environment()[key.Env]; overridden {
+		ctx.Printf("Note: %s is set and overrides this setting\n", key.Env)
+	}
+	return nil
+}
+
+// ConfigListCommandHandler handles the config list command
+type ConfigListCommandHandler struct {
+	configRepo interfaces.ConfigRepository
+}
+
+// NewConfigListCommandHandler creates a new config list command handler
+func NewConfigListCommandHandler(configRepo interfaces.ConfigRepository) *ConfigListCommandHandler {
+	return &ConfigListCommandHandler{configRepo: configRepo}
+}
+
+// Handle prints every setting with its effective value and where it came from
+func (h *ConfigListCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	config, sources, err := resolveConfig(h.configRepo)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range entities.ConfigKeys() {
+		source := string(sources[key.Name])
+		if sources[key.Name] == entities.ConfigSourceEnv {
+			source = key.Env
+		}
+		ctx.Printf("%-24s %-40s (%s)\n", key.Name, configValue(key, config, sources), source)
+	}
+	return nil
+}
+
+// ConfigResetCommandHandler handles the config reset command
+type ConfigResetCommandHandler struct {
+	configRepo interfaces.ConfigRepository
+}
+
+// NewConfigResetCommandHandler creates a new config reset command handler
+func NewConfigResetCommandHandler(configRepo interfaces.ConfigRepository) *ConfigResetCommandHandler {
+	return &ConfigResetCommandHandler{configRepo: configRepo}
+}
+
+// Handle removes one setting, or all of them, from the configuration file
+func (h *ConfigResetCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	args := ctx.GetArgs()
+
+	config := &entities.Config{}
+	if len(args) > 0 {
+		key, err := entities.LookupConfigKey(args[0])
+		if err != nil {
+			return err
+		}
+		if config, err = h.configRepo.Load(); err != nil {
+			return err
+		}
+		if err := config.Set(key.Name, ""); err != nil {
+			return err
+		}
+	}
+
+	if err := h.configRepo.Save(config); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		ctx.Printf("✓ Reset %s to its default\n", args[0])
+	} else {
+		ctx.Printf("✓ Reset all settings to their defaults\n")
+	}
+	return nil
+}
+
+// ConfigValidateCommandHandler handles the config validate command
+type ConfigValidateCommandHandler struct {
+	configRepo interfaces.ConfigRepository
+	fs         interfaces.FileSystem
+	homeDir    string
+}
+
+// NewConfigValidateCommandHandler creates a new config validate command handler
+func NewConfigValidateCommandHandler(configRepo interfaces.ConfigRepository, fs interfaces.FileSystem, homeDir string) *ConfigValidateCommandHandler {
+	return &ConfigValidateCommandHandler{configRepo: configRepo, fs: fs, homeDir: homeDir}
+}
+
+// Handle checks the configuration file and the environment, reporting every
+// problem rather than stopping at the first
+func (h *ConfigValidateCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	file, err := h.configRepo.Load()
+	if err != nil {
+		ctx.Printf("✗ %v\n", err)
+		return fmt.Errorf("configuration is invalid")
+	}
+
+	var problems []string
+	for _, err := range file.Validate() {
+		problems = append(problems, fmt.Sprintf("%s: %v", h.configRepo.Path(), err))
+	}
+	env := h.configRepo.Environment()
+	for _, key := range entities.ConfigKeys() {
+		if value, ok := env[key.Env]; ok {
+			if err := (&entities.Config{}).Set(key.Name, value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", key.Env, err))
+			}
+		}
+	}
+
+	// Paths are checked once the layers are combined
+	if len(problems) == 0 {
+		config, _, err := entities.ResolveConfig(file, env)
+		if err != nil {
+			return err
+		}
+		config.ExpandPaths(h.homeDir)
+		if config.Network.CAFile != "" && !h.fs.Exists(config.Network.CAFile) {
+			problems = append(problems, fmt.Sprintf("network.ca_file: %s does not exist", config.Network.CAFile))
+		}
+		for _, tap := range config.Formulas.Taps {
+			if !h.fs.IsDir(tap) {
+				problems = append(problems, fmt.Sprintf("formulas.taps: %s is not a directory", tap))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			ctx.Printf("✗ %s\n", problem)
+		}
+		return fmt.Errorf("configuration has %d problem(s)", len(problems))
+	}
+
+	ctx.Printf("✓ Configuration is valid\n")
+	return nil
+}
+
+// resolveConfig returns the effective settings and where each came from
+func resolveConfig(configRepo interfaces.ConfigRepository) (*entities.Config, map[string]entities.ConfigSource, error) {
+	file, err := configRepo.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	config, sources, err := entities.ResolveConfig(file, configRepo.Environment())
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration: %w (run 'wand config validate')", err)
+	}
+	return config, sources, nil
+}
+
+// configValue returns a setting's display value, showing the default when unset
+func configValue(key *entities.ConfigKey, config *entities.Config, sources map[string]entities.ConfigSource) string {
+	value, _ := config.Get(key.Name)
+	if sources[key.Name] == entities.ConfigSourceDefault {
+		value = key.Default
+	}
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,267 @@
+package domainorchestrators
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	errs "github.com/ochairo/wand/internal/domain/errors"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// ExecCommandHandler handles the exec command, which shims hand every run to
+type ExecCommandHandler struct {
+	installOrchestrator *InstallOrchestrator
+	shimService         *services.ShimService
+	registryRepo        interfaces.RegistryRepository
+	formulaRepo         interfaces.FormulaRepository
+	config              *entities.Config
+	environ             func() []string
+	getwd               func() (string, error)
+	exec                func(path string, argv, env []string) error
+}
+
+// NewExecCommandHandler creates a new exec command handler
+func NewExecCommandHandler(
+	installOrchestrator *InstallOrchestrator,
+	shimService *services.ShimService,
+	registryRepo interfaces.RegistryRepository,
+	formulaRepo interfaces.FormulaRepository,
+	config *entities.Config,
+) *ExecCommandHandler {
+	return &ExecCommandHandler{
+		installOrchestrator: installOrchestrator,
+		shimService:         shimService,
+		registryRepo:        registryRepo,
+		formulaRepo:         formulaRepo,
+		config:              config,
+		environ:             os.Environ,
+		getwd:               os.Getwd,
+		exec:                syscall.Exec,
+	}
+}
+
+// Handle runs a package's binary at the version the working directory
+// resolves to, installing a version pinned in the project first when
+// auto_install is on
+func (h *ExecCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	args := ctx.GetArgs()
+	if len(args) == 0 {
+		return fmt.Errorf("usage: wand exec [--package <name>] <binary> [args...]")
+	}
+	binary := args[0]
+
+	packageName, _ := ctx.GetStringFlag("package")
+	if packageName == "" {
+		name, err := h.shimService.PackageForBinary(binary)
+		if err != nil {
+			return err
+		}
+		packageName = name
+	}
+
+	dir, err := h.getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	spec, source, err := h.shimService.ResolveSpec(packageName, dir)
+	if err != nil {
+		return err
+	}
+
+	version, err := h.shimService.ResolveInstalled(packageName, spec)
+	var wandErr *errs.WandError
+	if err != nil && errors.As(err, &wandErr) && wandErr.Code == errs.ErrPackageNotInstalled &&
+		h.config.AutoInstall && source != "global" {
+		ctx.PrintError("wand: installing %s@%s (pinned in %s)\n", packageName, spec, source)
+		if err := h.autoInstall(ctx, packageName, spec); err != nil {
+			return err
+		}
+		version, err = h.shimService.ResolveInstalled(packageName, spec)
+	}
+	if err != nil {
+		return err
+	}
+
+	binaryPath, err := h.shimService.GetBinaryPath(packageName, version, binary)
+	if err != nil {
+		return err
+	}
+
+	return h.exec(binaryPath, args, h.runtimeEnv(packageName, version))
+}
+
+// autoInstall installs the newest release matching spec without making it
+// the global version. Formula commands only run under the allow policy, as
+// there is no one to ask.
+func (h *ExecCommandHandler) autoInstall(ctx interfaces.CommandContext, packageName, spec string) error {
+	hookPolicy, err := services.ParseHookPolicy(h.config.Hooks)
+	if err != nil {
+		return err
+	}
+	opts := InstallPackageOptions{
+		HookPolicy:   hookPolicy,
+		ConfirmHooks: func(*services.HookPlan) bool { return false },
+		KeepGlobal:   true,
+	}
+	if err := h.installOrchestrator.InstallPackageContext(ctx.Context(), packageName, spec, opts); err != nil {
+		return fmt.Errorf("installation failed: %w", err)
+	}
+	return nil
+}
+
+// runtimeEnv returns the environment with the formula's runtime variables
+// and PATH directories for the version applied
+func (h *ExecCommandHandler) runtimeEnv(packageName, version string) []string {
+	env := h.environ()
+
+	formula, err := h.formulaRepo.GetFormula(packageName)
+	if err != nil || (len(formula.Env) == 0 && len(formula.Path) == 0) {
+		return env
+	}
+	registry, err := h.registryRepo.Load()
+	if err != nil {
+		return env
+	}
+	pkg, exists := registry.GetPackage(packageName, version)
+	if !exists || pkg.InstallPath == "" {
+		return env
+	}
+
+	vars := formula.RuntimeEnv(pkg.InstallPath)
+	if dirs := formula.RuntimePath(pkg.InstallPath); len(dirs) > 0 {
+		path := strings.Join(dirs, string(os.PathListSeparator))
+		for _, entry := range env {
+			if value, ok := strings.CutPrefix(entry, "PATH="); ok && value != "" {
+				path += string(os.PathListSeparator) + value
+			}
+		}
+		vars["PATH"] = path
+	}
+
+	result := make([]string, 0, len(env)+len(vars))
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		if _, replaced := vars[name]; !replaced {
+			result = append(result, entry)
+		}
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result = append(result, name+"="+vars[name])
+	}
+	return result
+}
+
+// ResolveCommandHandler handles the resolve command
+type ResolveCommandHandler struct {
+	shimService *services.ShimService
+	getwd       func() (string, error)
+}
+
+// NewResolveCommandHandler creates a new resolve command handler
+func NewResolveCommandHandler(shimService *services.ShimService) *ResolveCommandHandler {
+	return &ResolveCommandHandler{
+		shimService: shimService,
+		getwd:       os.Getwd,
+	}
+}
+
+// Handle prints the version of a package the working directory resolves to
+// and the files and rules that chose it
+func (h *ResolveCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	args := ctx.GetArgs()
+	if len(args) != 1 {
+		return fmt.Errorf("usage: wand resolve <package>")
+	}
+
+	dir, err := h.getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	res, err := h.shimService.Resolve(args[0], dir)
+	printResolution(ctx, res)
+	return err
+}
+
+// WhichCommandHandler handles the which command
+type WhichCommandHandler struct {
+	shimService *services.ShimService
+	getwd       func() (string, error)
+}
+
+// NewWhichCommandHandler creates a new which command handler
+func NewWhichCommandHandler(shimService *services.ShimService) *WhichCommandHandler {
+	return &WhichCommandHandler{
+		shimService: shimService,
+		getwd:       os.Getwd,
+	}
+}
+
+// Handle prints the path a shim runs in the working directory, followed by
+// how its version was chosen
+func (h *WhichCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	args := ctx.GetArgs()
+	if len(args) != 1 {
+		return fmt.Errorf("usage: wand which <binary>")
+	}
+	binary := args[0]
+
+	packageName, _ := ctx.GetStringFlag("package")
+	if packageName == "" {
+		name, err := h.shimService.PackageForBinary(binary)
+		if err != nil {
+			return err
+		}
+		packageName = name
+	}
+
+	dir, err := h.getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	res, err := h.shimService.Resolve(packageName, dir)
+	if err != nil {
+		printResolution(ctx, res)
+		return err
+	}
+	binaryPath, err := h.shimService.GetBinaryPath(packageName, res.Version, binary)
+	if err != nil {
+		return err
+	}
+
+	ctx.Printf("%s\n", binaryPath)
+	printResolution(ctx, res)
+	return nil
+}
+
+// printResolution prints the chosen version and, beneath it, each file or
+// rule consulted
+func printResolution(ctx interfaces.CommandContext, res *services.Resolution) {
+	if res.Version != "" {
+		ctx.Printf("%s %s\n", res.Package, res.Version)
+	} else if res.Spec != "" {
+		ctx.Printf("%s %s (not installed)\n", res.Package, res.Spec)
+	} else {
+		ctx.Printf("%s (no version)\n", res.Package)
+	}
+
+	width := 0
+	for _, step := range res.Steps {
+		if len(step.Source) > width {
+			width = len(step.Source)
+		}
+	}
+	for _, step := range res.Steps {
+		ctx.Printf("  %-*s  %s\n", width, step.Source, step.Note)
+	}
+}
@@ -0,0 +1,154 @@
+package domainorchestrators
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+func newTestExecHandler(spec string) (*ExecCommandHandler, *[]string) {
+	registryRepo := newMockRegistryRepo()
+	fs := newMockFileSystem()
+	for _, v := range []string{"18.19.0", "20.9.0", "20.11.1", "21.6.0"} {
+		version, _ := entities.NewVersion(v)
+		pkg := entities.NewPackage("node", entities.PackageTypeCLI, version)
+		pkg.InstallPath = "/wand/packages/node/" + v
+		pkg.BinPath = pkg.InstallPath + "/bin"
+		registryRepo.registry.AddPackage(pkg)
+		fs.exists[pkg.BinPath+"/node"] = true
+	}
+	registryRepo.registry.SetGlobalVersion("node", "21.6.0")
+
+	formulaRepo := newMockFormulaRepo()
+	formulaRepo.formulas["node"] = &entities.Formula{
+		Name:     "node",
+		Binaries: []string{"node", "npm"},
+		Env:      map[string]string{"NODE_HOME": "{install_path}"},
+		Path:     []string{"lib/node_modules/.bin"},
+	}
+
+	wandrc := entities.NewWandRC()
+	wandrc.SetVersion("node", spec)
+	wandrcRepo := &mockWandRCRepo{dir: "/work/proj", wandrc: wandrc}
+	shimService := services.NewShimService(registryRepo, wandrcRepo, formulaRepo, fs, "/wand")
+
+	var ran []string
+	h := NewExecCommandHandler(nil, shimService, registryRepo, formulaRepo, entities.DefaultConfig())
+	h.environ = func() []string { return []string{"PATH=/usr/bin"} }
+	h.getwd = func() (string, error) { return "/work/proj", nil }
+	h.exec = func(path string, argv, env []string) error {
+		ran = append(append([]string{path}, argv...), env...)
+		return nil
+	}
+	return h, &ran
+}
+
+func TestExecCommandHandler_Constraint(t *testing.T) {
+	h, ran := newTestExecHandler("^20")
+	if err := h.Handle(newMockContext([]string{"node", "--version"})); err != nil {
+		t.Fatal(err)
+	}
+
+	got := strings.Join(*ran, " ")
+	want := "/wand/packages/node/20.11.1/bin/node node --version NODE_HOME=/wand/packages/node/20.11.1 PATH=/wand/packages/node/20.11.1/lib/node_modules/.bin:/usr/bin"
+	if got != want {
+		t.Errorf("ran %q, want %q", got, want)
+	}
+}
+
+func TestExecCommandHandler_NotInstalled(t *testing.T) {
+	h, ran := newTestExecHandler("22")
+	err := h.Handle(newMockContext([]string{"npm", "install"}))
+	if err == nil || !strings.Contains(err.Error(), "wand install node@22") {
+		t.Fatalf("expected an install hint, got %v", err)
+	}
+	if len(*ran) != 0 {
+		t.Errorf("expected nothing to run, ran %v", *ran)
+	}
+}
+
+func TestExecCommandHandler_EnvOverride(t *testing.T) {
+	h, ran := newTestExecHandler("^20")
+	t.Setenv("WAND_NODE_VERSION", "18")
+	if err := h.Handle(newMockContext([]string{"node"})); err != nil {
+		t.Fatal(err)
+	}
+	if len(*ran) == 0 || (*ran)[0] != "/wand/packages/node/18.19.0/bin/node" {
+		t.Errorf("expected WAND_NODE_VERSION to override the .wandrc, ran %v", *ran)
+	}
+
+	t.Setenv("WAND_NODE_VERSION", "not a version")
+	if err := h.Handle(newMockContext([]string{"node"})); err == nil || !strings.Contains(err.Error(), "WAND_NODE_VERSION") {
+		t.Errorf("expected an invalid override to be reported, got %v", err)
+	}
+}
+
+// mockWandRCTree serves a .wandrc in each of several directories
+type mockWandRCTree map[string]*entities.WandRC
+
+func (m mockWandRCTree) Load(dir string) (*entities.WandRC, error)      { return m[dir], nil }
+func (m mockWandRCTree) Save(dir string, wandrc *entities.WandRC) error { return nil }
+func (m mockWandRCTree) Exists(dir string) bool                         { return m[dir] != nil }
+func (m mockWandRCTree) FindInPath(startDir string) (*entities.WandRC, string, error) {
+	return nil, "", fmt.Errorf(".wandrc not found in path")
+}
+
+func TestResolveCommandHandler_Inheritance(t *testing.T) {
+	registryRepo := newMockRegistryRepo()
+	for _, id := range []string{"node@18.19.0", "node@20.11.1", "python@3.11.7", "python@3.12.1", "jq@1.7.1"} {
+		name, v, _ := strings.Cut(id, "@")
+		version, _ := entities.NewVersion(v)
+		registryRepo.registry.AddPackage(entities.NewPackage(name, entities.PackageTypeCLI, version))
+	}
+	registryRepo.registry.SetGlobalVersion("python", "3.12.1")
+
+	root := &entities.WandRC{Versions: map[string]string{"jq": "1.7.1"}}
+	work := &entities.WandRC{Versions: map[string]string{"node": "18", "python": "3.11"}}
+	app := &entities.WandRC{Versions: map[string]string{"node": "^20"}}
+	tree := mockWandRCTree{"/": root, "/work": work, "/work/app": app}
+	shimService := services.NewShimService(registryRepo, tree, newMockFormulaRepo(), newMockFileSystem(), "/wand")
+
+	resolve := func(pkg string) (string, error) {
+		h := NewResolveCommandHandler(shimService)
+		h.getwd = func() (string, error) { return "/work/app/src", nil }
+		ctx := newMockContext([]string{pkg})
+		err := h.Handle(ctx)
+		return ctx.output.String(), err
+	}
+
+	// The child overrides the parent; the parent and the root fill in the rest
+	for pkg, want := range map[string]string{
+		"node":   "node 20.11.1\n  /work/app/.wandrc  pins ^20\n",
+		"python": "python 3.11.7\n",
+		"jq":     "jq 1.7.1\n",
+	} {
+		output, err := resolve(pkg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(output, want) {
+			t.Errorf("resolve %s: got\n%s", pkg, output)
+		}
+	}
+	if output, _ := resolve("python"); !strings.Contains(output, "/work/.wandrc      pins 3.11") {
+		t.Errorf("expected the chain to show the inherited pin, got\n%s", output)
+	}
+
+	// extends: false stops at the child
+	noExtends := false
+	app.Extends = &noExtends
+	output, err := resolve("python")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(output, "python 3.12.1\n") || !strings.Contains(output, "extends: false") || !strings.Contains(output, "global version is 3.12.1") {
+		t.Errorf("expected the global version after extends: false, got\n%s", output)
+	}
+
+	if output, err := resolve("ruby"); err == nil || !strings.Contains(output, "no global version") {
+		t.Errorf("expected an unresolved package to fail with its chain, got %v\n%s", err, output)
+	}
+}
@@ -1,7 +1,9 @@
 package domainorchestrators
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/ochairo/wand/internal/domain/entities"
 	"github.com/ochairo/wand/internal/domain/interfaces"
@@ -33,21 +35,62 @@ func NewInstallOrchestrator(
 
 // InstallPackageOptions contains installation options
 type InstallPackageOptions struct {
-	Global bool // Install to global location (/usr/local/bin)
-	Force  bool // Overwrite existing installation
+	Global           bool // Install to global location (/usr/local/bin)
+	Force            bool // Overwrite existing installation
+	TrustNewChecksum bool // Accept an artifact whose checksum changed since first install
+	BuildFromSource  bool // Build from source even when a prebuilt artifact exists
+
+	HookPolicy   services.HookPolicy           // Whether formula commands may run (default allow)
+	ConfirmHooks func(*services.HookPlan) bool // Asked under the confirm policy
+	KeepGlobal   bool                          // Leave an existing global version in place
+
+	// TakeShim is asked for each shim another installed package owns; true
+	// takes it over. Without it, the other package keeps its shims.
+	TakeShim func(services.ShimConflict) bool
+
+	HookTimeout     time.Duration // Per-command limit for formula commands
+	DownloadTimeout time.Duration // Per-download limit
+
+	Progress services.InstallObserver // Receives install events, ending with done once shims exist
 }
 
 // InstallPackageWithOptions installs a package with the specified options and creates shims for all binaries.
-// InstallPackage installs a package and creates shims
 func (o *InstallOrchestrator) InstallPackageWithOptions(packageName, versionStr string, opts InstallPackageOptions) error {
+	return o.InstallPackageContext(context.Background(), packageName, versionStr, opts)
+}
+
+// InstallPackageContext is InstallPackageWithOptions that stops when ctx is done
+func (o *InstallOrchestrator) InstallPackageContext(ctx context.Context, packageName, versionStr string, opts InstallPackageOptions) error {
 	// If force flag is set, uninstall existing version first
 	if opts.Force {
 		// Try to uninstall, but don't fail if it doesn't exist
 		_ = o.UninstallPackage(packageName, "*")
 	}
 
+	// The installer reports done before shims exist; hold it back until they do
+	var installed services.InstallEvent
+	progress := func(event services.InstallEvent) {
+		if event.Package == packageName && event.Stage == services.StageDone {
+			installed = event
+			return
+		}
+		opts.Progress(event)
+	}
+
 	// Install the package
-	if err := o.installerSvc.InstallPackage(packageName, versionStr); err != nil {
+	installOpts := services.InstallOptions{
+		TrustNewChecksum: opts.TrustNewChecksum,
+		BuildFromSource:  opts.BuildFromSource,
+		HookPolicy:       opts.HookPolicy,
+		ConfirmHooks:     opts.ConfirmHooks,
+		KeepGlobal:       opts.KeepGlobal,
+		HookTimeout:      opts.HookTimeout,
+		DownloadTimeout:  opts.DownloadTimeout,
+	}
+	if opts.Progress != nil {
+		installOpts.Progress = progress
+	}
+	if err := o.installerSvc.InstallPackageContext(ctx, packageName, versionStr, installOpts); err != nil {
 		return fmt.Errorf("installation failed: %w", err)
 	}
 
@@ -67,13 +110,52 @@ func (o *InstallOrchestrator) InstallPackageWithOptions(packageName, versionStr
 	}
 
 	// Create shims for CLI packages
+	report := func(stage services.InstallStage, message string, err error) {
+		if opts.Progress != nil {
+			installed.Package, installed.Stage, installed.Message, installed.Err = packageName, stage, message, err
+			opts.Progress(installed)
+		}
+	}
+	report(services.StageLinking, "creating shims", nil)
+	binaries, err = o.claimShims(packageName, binaries, opts.TakeShim)
+	if err != nil {
+		err = fmt.Errorf("failed to check shims: %w", err)
+		report(services.StageFailed, err.Error(), err)
+		return err
+	}
 	if err := o.shimSvc.CreateShims(packageName, binaries); err != nil {
-		return fmt.Errorf("failed to create shims: %w", err)
+		err = fmt.Errorf("failed to create shims: %w", err)
+		report(services.StageFailed, err.Error(), err)
+		return err
 	}
 
+	report(services.StageDone, "", nil)
 	return nil
 }
 
+// claimShims returns the binaries a package gets shims for: all of them,
+// less those another installed package owns and keeps
+func (o *InstallOrchestrator) claimShims(packageName string, binaries []string, takeShim func(services.ShimConflict) bool) ([]string, error) {
+	conflicts, err := o.shimSvc.ShimConflicts(packageName, binaries)
+	if err != nil || len(conflicts) == 0 {
+		return binaries, err
+	}
+
+	kept := make(map[string]bool, len(conflicts))
+	for _, conflict := range conflicts {
+		if takeShim == nil || !takeShim(conflict) {
+			kept[conflict.Binary] = true
+		}
+	}
+	claimed := make([]string, 0, len(binaries))
+	for _, binary := range binaries {
+		if !kept[binary] {
+			claimed = append(claimed, binary)
+		}
+	}
+	return claimed, nil
+}
+
 // InstallPackage installs a package and creates shims (backward compatible)
 func (o *InstallOrchestrator) InstallPackage(packageName, versionStr string) error {
 	return o.InstallPackageWithOptions(packageName, versionStr, InstallPackageOptions{})
@@ -96,17 +178,17 @@ func (o *InstallOrchestrator) UninstallPackage(packageName, version string) erro
 		binaries = []string{packageName}
 	}
 
-	// Remove shims first
-	if err := o.shimSvc.RemoveShims(binaries); err != nil {
-		// Log warning but continue
-		fmt.Printf("Warning: failed to remove shims: %v\n", err)
-	}
-
 	// Uninstall the package
 	if err := o.installerSvc.UninstallPackage(packageName, version); err != nil {
 		return fmt.Errorf("uninstallation failed: %w", err)
 	}
 
+	// Then release its shims, which stay while any version remains
+	if err := o.shimSvc.RemoveShims(packageName, binaries); err != nil {
+		// Log warning but continue
+		fmt.Printf("Warning: failed to remove shims: %v\n", err)
+	}
+
 	return nil
 }
 
@@ -114,3 +196,8 @@ func (o *InstallOrchestrator) UninstallPackage(packageName, version string) erro
 func (o *InstallOrchestrator) ListAvailableVersions(packageName string) ([]*entities.Version, error) {
 	return o.versionSvc.ListAvailableVersions(packageName)
 }
+
+// ListAvailableVersionsContext is ListAvailableVersions that stops when ctx is done
+func (o *InstallOrchestrator) ListAvailableVersionsContext(ctx context.Context, packageName string) ([]*entities.Version, error) {
+	return o.versionSvc.ListAvailableVersionsContext(ctx, packageName)
+}
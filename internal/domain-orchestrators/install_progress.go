@@ -0,0 +1,124 @@
+package domainorchestrators
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ochairo/wand/internal/domain/interfaces"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// progressBarWidth is the number of cells in a download progress bar
+const progressBarWidth = 30
+
+// progressRedrawInterval limits how often a download bar is redrawn
+const progressRedrawInterval = 100 * time.Millisecond
+
+// installProgress renders install events. On a terminal it redraws a single
+// status line with a download bar; otherwise it prints one line per stage.
+type installProgress struct {
+	ctx      interfaces.CommandContext
+	terminal bool
+
+	stage    services.InstallStage
+	pkg      string
+	drawn    bool // a status line is on screen and must be cleared
+	lastDraw time.Time
+}
+
+// newInstallProgress creates a renderer for the command's output
+func newInstallProgress(ctx interfaces.CommandContext) *installProgress {
+	return &installProgress{ctx: ctx, terminal: ctx.IsTerminal()}
+}
+
+// Observe renders one event; it is a services.InstallObserver
+func (p *installProgress) Observe(event services.InstallEvent) {
+	changed := event.Stage != p.stage || event.Package != p.pkg
+	p.stage, p.pkg = event.Stage, event.Package
+
+	if p.terminal {
+		p.drawTerminal(event, changed)
+		return
+	}
+	if changed {
+		p.printLine(event)
+	}
+}
+
+func (p *installProgress) drawTerminal(event services.InstallEvent, changed bool) {
+	switch event.Stage {
+	case services.StageDone, services.StageFailed:
+		p.clear()
+		return
+	case services.StageDownloading:
+		if !changed && time.Since(p.lastDraw) < progressRedrawInterval && event.Bytes != event.Total {
+			return
+		}
+	}
+
+	line := fmt.Sprintf("%s@%s %s", event.Package, event.Version, event.Stage)
+	switch {
+	case event.Stage == services.StageDownloading:
+		line += " " + downloadStatus(event.Bytes, event.Total)
+	case event.Message != "":
+		line += " (" + event.Message + ")"
+	}
+
+	p.ctx.Printf("\r\033[K%s", line)
+	p.drawn = true
+	p.lastDraw = time.Now()
+}
+
+// clear removes the status line so following output starts on a clean line
+func (p *installProgress) clear() {
+	if p.drawn {
+		p.ctx.Printf("\r\033[K")
+		p.drawn = false
+	}
+}
+
+func (p *installProgress) printLine(event services.InstallEvent) {
+	switch event.Stage {
+	case services.StageDone, services.StageFailed:
+		return // the command reports the outcome
+	case services.StageDownloading:
+		p.ctx.Printf("%s@%s: downloading %s\n", event.Package, event.Version, event.Message)
+	default:
+		if event.Message != "" {
+			p.ctx.Printf("%s@%s: %s (%s)\n", event.Package, event.Version, event.Stage, event.Message)
+		} else {
+			p.ctx.Printf("%s@%s: %s\n", event.Package, event.Version, event.Stage)
+		}
+	}
+}
+
+// downloadStatus renders a bar with percentage and sizes, or just the size
+// received when the total is unknown
+func downloadStatus(bytes, total int64) string {
+	if total <= 0 {
+		return formatBytes(bytes)
+	}
+
+	ratio := float64(bytes) / float64(total)
+	if ratio > 1 {
+		ratio = 1
+	}
+	filled := int(ratio * progressBarWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	return fmt.Sprintf("[%s] %3d%% %s / %s", bar, int(ratio*100), formatBytes(bytes), formatBytes(total))
+}
+
+// formatBytes formats a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
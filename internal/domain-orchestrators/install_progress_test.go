@@ -0,0 +1,83 @@
+package domainorchestrators
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+func progressEvents() []services.InstallEvent {
+	return []services.InstallEvent{
+		{Package: "jq", Version: "latest", Stage: services.StageResolving},
+		{Package: "jq", Version: "1.7.1", Stage: services.StageDownloading, Message: "https://example.com/jq.tar.gz", Total: 2048},
+		{Package: "jq", Version: "1.7.1", Stage: services.StageDownloading, Message: "https://example.com/jq.tar.gz", Bytes: 1024, Total: 2048},
+		{Package: "jq", Version: "1.7.1", Stage: services.StageDownloading, Message: "https://example.com/jq.tar.gz", Bytes: 2048, Total: 2048},
+		{Package: "jq", Version: "1.7.1", Stage: services.StageVerifying},
+		{Package: "jq", Version: "1.7.1", Stage: services.StageHooks, Message: "post_install"},
+		{Package: "jq", Version: "1.7.1", Stage: services.StageDone},
+	}
+}
+
+func TestInstallProgress_PlainOutput(t *testing.T) {
+	ctx := newMockContext(nil)
+	progress := newInstallProgress(ctx)
+	for _, event := range progressEvents() {
+		progress.Observe(event)
+	}
+
+	want := "jq@latest: resolving\n" +
+		"jq@1.7.1: downloading https://example.com/jq.tar.gz\n" +
+		"jq@1.7.1: verifying\n" +
+		"jq@1.7.1: hooks (post_install)\n"
+	if got := ctx.output.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestInstallProgress_TerminalOutput(t *testing.T) {
+	ctx := newMockContext(nil)
+	ctx.terminal = true
+	progress := newInstallProgress(ctx)
+	for _, event := range progressEvents() {
+		progress.Observe(event)
+	}
+
+	output := ctx.output.String()
+	if strings.Contains(output, "\n") {
+		t.Errorf("expected a single redrawn line, got %q", output)
+	}
+	if !strings.Contains(output, "100% 2.0 KiB / 2.0 KiB") {
+		t.Errorf("expected a completed download bar, got %q", output)
+	}
+	if !strings.HasSuffix(output, "\r\033[K") {
+		t.Errorf("expected the status line to be cleared when done, got %q", output)
+	}
+}
+
+func TestInstallProgress_FailureClearsLine(t *testing.T) {
+	ctx := newMockContext(nil)
+	ctx.terminal = true
+	progress := newInstallProgress(ctx)
+	progress.Observe(services.InstallEvent{Package: "jq", Version: "latest", Stage: services.StageResolving})
+	progress.Observe(services.InstallEvent{Package: "jq", Version: "latest", Stage: services.StageFailed, Err: errors.New("boom")})
+
+	if output := ctx.output.String(); !strings.HasSuffix(output, "\r\033[K") {
+		t.Errorf("expected the status line to be cleared on failure, got %q", output)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:               "0 B",
+		1023:            "1023 B",
+		1536:            "1.5 KiB",
+		5 * 1024 * 1024: "5.0 MiB",
+	}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
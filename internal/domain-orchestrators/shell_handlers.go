@@ -0,0 +1,342 @@
+package domainorchestrators
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	errs "github.com/ochairo/wand/internal/domain/errors"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// envStateVar holds what the shell hook exported, so it can be undone when
+// leaving the project
+const envStateVar = "WAND_ENV_STATE"
+
+// envState records the project the shell is in, if any, and the value each
+// exported variable had before, nil if it was unset
+type envState struct {
+	Dir   string             `json:"dir"`
+	Saved map[string]*string `json:"saved"`
+}
+
+// shells lists the supported shells
+var shells = []string{"bash", "zsh", "fish"}
+
+// InitShellCommandHandler handles the init-shell command
+type InitShellCommandHandler struct {
+	wandDir    string
+	executable string
+}
+
+// NewInitShellCommandHandler creates a new init-shell command handler.
+// executable is the wand binary the hook runs.
+func NewInitShellCommandHandler(wandDir, executable string) *InitShellCommandHandler {
+	return &InitShellCommandHandler{
+		wandDir:    wandDir,
+		executable: executable,
+	}
+}
+
+// Handle prints the shell code that puts the shims on PATH and installs the
+// directory-change hook
+func (h *InitShellCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	args := ctx.GetArgs()
+	if len(args) != 1 {
+		return fmt.Errorf("usage: wand init-shell <%s>", strings.Join(shells, "|"))
+	}
+	noHook, _ := ctx.GetBoolFlag("no-hook")
+
+	shimsDir := filepath.Join(h.wandDir, "shims")
+	switch args[0] {
+	case "bash":
+		ctx.Printf("%s", posixPathScript(shimsDir))
+		if !noHook {
+			ctx.Printf(`_wand_hook() {
+  local status=$?
+  if [ "$PWD" != "${_WAND_LAST_PWD-}" ]; then
+    _WAND_LAST_PWD=$PWD
+    eval "$(%s env --shell bash)"
+  fi
+  return $status
+}
+case ";${PROMPT_COMMAND-};" in
+  *";_wand_hook;"*) ;;
+  *) PROMPT_COMMAND="_wand_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`, services.ShellQuote(h.executable))
+		}
+	case "zsh":
+		ctx.Printf("%s", posixPathScript(shimsDir))
+		if !noHook {
+			ctx.Printf(`_wand_hook() {
+  eval "$(%s env --shell zsh)"
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd _wand_hook
+_wand_hook
+`, services.ShellQuote(h.executable))
+		}
+	case "fish":
+		ctx.Printf(`if not contains -- %[1]s $PATH
+    set -gx PATH %[1]s $PATH
+end
+`, fishQuote(shimsDir))
+		if !noHook {
+			ctx.Printf(`function _wand_hook --on-variable PWD
+    command %s env --shell fish | source
+end
+_wand_hook
+`, fishQuote(h.executable))
+		}
+	default:
+		return fmt.Errorf("unsupported shell %q (supported: %s)", args[0], strings.Join(shells, ", "))
+	}
+
+	return nil
+}
+
+// posixPathScript prepends dir to PATH unless it is already there
+func posixPathScript(dir string) string {
+	return fmt.Sprintf(`case ":$PATH:" in
+  *:%[1]s:*) ;;
+  *) export PATH=%[1]s:"$PATH" ;;
+esac
+`, services.ShellQuote(dir))
+}
+
+// EnvCommandHandler handles the env command
+type EnvCommandHandler struct {
+	envService *services.EnvService
+	environ    func() []string
+	getwd      func() (string, error)
+}
+
+// NewEnvCommandHandler creates a new env command handler
+func NewEnvCommandHandler(envService *services.EnvService) *EnvCommandHandler {
+	return &EnvCommandHandler{
+		envService: envService,
+		environ:    os.Environ,
+		getwd:      os.Getwd,
+	}
+}
+
+// Handle prints the shell code that moves the environment from the directory
+// the shell was in to the working directory. Variables set for the previous
+// directory are restored to their earlier values first.
+func (h *EnvCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	current := make(map[string]string)
+	for _, entry := range h.environ() {
+		if name, value, ok := strings.Cut(entry, "="); ok {
+			current[name] = value
+		}
+	}
+
+	shell, _ := ctx.GetStringFlag("shell")
+	if shell == "" {
+		shell = filepath.Base(current["SHELL"])
+	}
+	setVar, unsetVar := posixSet, posixUnset
+	switch shell {
+	case "fish":
+		setVar, unsetVar = fishSet, fishUnset
+	case "bash", "zsh", "sh":
+	default:
+		if ctx.FlagChanged("shell") {
+			return fmt.Errorf("unsupported shell %q (supported: %s)", shell, strings.Join(shells, ", "))
+		}
+	}
+
+	dir, err := h.getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get current directory: %w", err)
+	}
+	env, err := h.envService.Environment(dir)
+	if err != nil {
+		return err
+	}
+
+	// Undo the previous directory's variables
+	restored := make(map[string]string, len(current))
+	for name, value := range current {
+		restored[name] = value
+	}
+	names := make(map[string]bool)
+	if previous := decodeEnvState(current[envStateVar]); previous != nil {
+		for name, value := range previous.Saved {
+			names[name] = true
+			if value == nil {
+				delete(restored, name)
+			} else {
+				restored[name] = *value
+			}
+		}
+	}
+
+	// Apply the new ones, remembering what they replace
+	target := make(map[string]string, len(restored))
+	for name, value := range restored {
+		target[name] = value
+	}
+	delete(target, envStateVar)
+	if !env.IsEmpty() {
+		vars := make(map[string]string, len(env.Vars)+1)
+		for name, value := range env.Vars {
+			vars[name] = value
+		}
+		if len(env.Path) > 0 {
+			dirs := append([]string{}, env.Path...)
+			if path := restored["PATH"]; path != "" {
+				dirs = append(dirs, path)
+			}
+			vars["PATH"] = strings.Join(dirs, string(os.PathListSeparator))
+		}
+
+		state := &envState{Dir: env.Project, Saved: make(map[string]*string)}
+		for name, value := range vars {
+			names[name] = true
+			if previous, ok := restored[name]; ok {
+				state.Saved[name] = &previous
+			} else {
+				state.Saved[name] = nil
+			}
+			target[name] = value
+		}
+		target[envStateVar] = encodeEnvState(state)
+	}
+	names[envStateVar] = true
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		want, wantOK := target[name]
+		have, haveOK := current[name]
+		switch {
+		case wantOK && (!haveOK || want != have):
+			ctx.Printf("%s\n", setVar(name, want))
+		case !wantOK && haveOK:
+			ctx.Printf("%s\n", unsetVar(name))
+		}
+	}
+
+	return nil
+}
+
+// ShellCommandHandler handles the shell command
+type ShellCommandHandler struct {
+	shimService *services.ShimService
+	config      *entities.Config
+	environ     func() []string
+	lookPath    func(file string) (string, error)
+	exec        func(path string, argv, env []string) error
+}
+
+// NewShellCommandHandler creates a new shell command handler
+func NewShellCommandHandler(shimService *services.ShimService, config *entities.Config) *ShellCommandHandler {
+	return &ShellCommandHandler{
+		shimService: shimService,
+		config:      config,
+		environ:     os.Environ,
+		lookPath:    exec.LookPath,
+		exec:        syscall.Exec,
+	}
+}
+
+// Handle starts $SHELL with WAND_<PACKAGE>_VERSION set for each package@version
+// given, so shims in it run those versions until it exits. A version that is
+// not installed is refused unless auto_install is on.
+func (h *ShellCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	args := ctx.GetArgs()
+	if len(args) == 0 {
+		return fmt.Errorf("usage: wand shell <package>@<version> [<package>@<version>...]")
+	}
+
+	overrides := make(map[string]string, len(args))
+	for _, arg := range args {
+		packageName, spec, ok := strings.Cut(arg, "@")
+		if !ok || packageName == "" || spec == "" {
+			return fmt.Errorf("version required (use package@version format, e.g., nodejs@18)")
+		}
+		if _, err := h.shimService.ResolveInstalled(packageName, spec); err != nil {
+			var wandErr *errs.WandError
+			if !errors.As(err, &wandErr) || wandErr.Code != errs.ErrPackageNotInstalled || !h.config.AutoInstall {
+				return err
+			}
+		}
+		overrides[entities.VersionEnvVar(packageName)] = spec
+	}
+
+	shell := "/bin/sh"
+	env := make([]string, 0, len(overrides))
+	for _, entry := range h.environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if name == "SHELL" && value != "" {
+			shell = value
+		}
+		if _, replaced := overrides[name]; !replaced {
+			env = append(env, entry)
+		}
+	}
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, name+"="+overrides[name])
+	}
+
+	path, err := h.lookPath(shell)
+	if err != nil {
+		return fmt.Errorf("failed to find shell %s: %w", shell, err)
+	}
+	ctx.PrintError("wand: starting %s with %s; exit to return\n", filepath.Base(shell), strings.Join(args, " "))
+	return h.exec(path, []string{shell}, env)
+}
+
+// encodeEnvState serializes state for the environment
+func encodeEnvState(state *envState) string {
+	data, _ := json.Marshal(state)
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// decodeEnvState parses the state variable, returning nil if it is missing or
+// damaged
+func decodeEnvState(value string) *envState {
+	if value == "" {
+		return nil
+	}
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil
+	}
+	var state envState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil
+	}
+	return &state
+}
+
+func posixSet(name, value string) string { return "export " + name + "=" + services.ShellQuote(value) }
+func posixUnset(name string) string      { return "unset " + name }
+func fishSet(name, value string) string  { return "set -gx " + name + " " + fishQuote(value) }
+func fishUnset(name string) string       { return "set -e " + name }
+
+// fishQuote quotes s for fish
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
@@ -0,0 +1,209 @@
+package domainorchestrators
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// mockWandRCRepo serves one .wandrc in dir
+type mockWandRCRepo struct {
+	dir    string
+	wandrc *entities.WandRC
+}
+
+func (m *mockWandRCRepo) Load(dir string) (*entities.WandRC, error)      { return m.wandrc, nil }
+func (m *mockWandRCRepo) Save(dir string, wandrc *entities.WandRC) error { return nil }
+func (m *mockWandRCRepo) Exists(dir string) bool                         { return dir == m.dir }
+func (m *mockWandRCRepo) FindInPath(startDir string) (*entities.WandRC, string, error) {
+	if startDir == m.dir || strings.HasPrefix(startDir, m.dir+"/") {
+		return m.wandrc, filepath.Join(m.dir, ".wandrc"), nil
+	}
+	return nil, "", fmt.Errorf(".wandrc not found in path")
+}
+
+func newTestEnvHandler(environ []string, dir string) *EnvCommandHandler {
+	wandrc := entities.NewWandRC()
+	wandrc.SetVersion("jq", "1.7.1")
+	wandrcRepo := &mockWandRCRepo{dir: "/work/proj", wandrc: wandrc}
+	shimService := services.NewShimService(newMockRegistryRepo(), wandrcRepo, newMockFormulaRepo(), newMockFileSystem(), "/wand")
+
+	h := NewEnvCommandHandler(services.NewEnvService(newMockRegistryRepo(), wandrcRepo, newMockFormulaRepo(), shimService))
+	h.environ = func() []string { return environ }
+	h.getwd = func() (string, error) { return dir, nil }
+	return h
+}
+
+func TestEnvCommandHandler_EnterAndLeave(t *testing.T) {
+	// Entering the project exports its variables, remembering the old value
+	ctx := newMockContext(nil)
+	ctx.flags["shell"] = "bash"
+	if err := newTestEnvHandler([]string{"WAND_VERSIONS=mine"}, "/work/proj/src").Handle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	output := ctx.output.String()
+	if !strings.Contains(output, "export WAND_PROJECT='/work/proj'\n") || !strings.Contains(output, "export WAND_VERSIONS='jq@1.7.1'\n") {
+		t.Fatalf("expected the project variables, got:\n%s", output)
+	}
+
+	environ := []string{"WAND_PROJECT=/work/proj", "WAND_VERSIONS=jq@1.7.1"}
+	for _, line := range strings.Split(output, "\n") {
+		if value, ok := strings.CutPrefix(line, "export "+envStateVar+"="); ok {
+			environ = append(environ, envStateVar+"="+strings.Trim(value, "'"))
+		}
+	}
+
+	// Staying inside prints nothing
+	ctx = newMockContext(nil)
+	ctx.flags["shell"] = "bash"
+	if err := newTestEnvHandler(environ, "/work/proj").Handle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if ctx.output.Len() != 0 {
+		t.Errorf("expected no changes inside the project, got:\n%s", ctx.output.String())
+	}
+
+	// Leaving restores the previous value and unsets the rest
+	ctx = newMockContext(nil)
+	ctx.flags["shell"] = "fish"
+	if err := newTestEnvHandler(environ, "/work/other").Handle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := "set -e " + envStateVar + "\nset -e WAND_PROJECT\nset -gx WAND_VERSIONS 'mine'\n"
+	if ctx.output.String() != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, ctx.output.String())
+	}
+}
+
+func TestEnvCommandHandler_ResolvesFromDir(t *testing.T) {
+	// A version file below the project's .wandrc pins jq for that directory
+	wandrc := entities.NewWandRC()
+	wandrc.SetVersion("jq", "1.7.1")
+	wandrcRepo := &mockWandRCRepo{dir: "/work/proj", wandrc: wandrc}
+	shimService := services.NewShimServiceWithOptions(newMockRegistryRepo(), wandrcRepo, newMockFormulaRepo(), newMockFileSystem(), "/wand", services.ShimServiceOptions{
+		VersionFiles: []interfaces.VersionFileReader{&mockVersionFile{name: ".tool-versions", versions: map[string]string{"jq": "1.6"}}},
+	})
+	h := NewEnvCommandHandler(services.NewEnvService(newMockRegistryRepo(), wandrcRepo, newMockFormulaRepo(), shimService))
+	h.environ = func() []string { return nil }
+	h.getwd = func() (string, error) { return "/work/proj/src", nil }
+
+	ctx := newMockContext(nil)
+	ctx.flags["shell"] = "bash"
+	if err := h.Handle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if output := ctx.output.String(); !strings.Contains(output, "export WAND_VERSIONS='jq@1.6'\n") {
+		t.Errorf("expected the version pinned for the directory, got:\n%s", output)
+	}
+}
+
+func TestInitShellCommandHandler(t *testing.T) {
+	h := NewInitShellCommandHandler("/home/o'neil/.wand", "/usr/local/bin/wand")
+
+	ctx := newMockContext([]string{"zsh"})
+	if err := h.Handle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	output := ctx.output.String()
+	if !strings.Contains(output, `export PATH='/home/o'\''neil/.wand/shims':"$PATH"`) {
+		t.Errorf("expected the shims directory to be quoted, got:\n%s", output)
+	}
+	if !strings.Contains(output, "add-zsh-hook chpwd _wand_hook") {
+		t.Errorf("expected the chpwd hook, got:\n%s", output)
+	}
+
+	ctx = newMockContext([]string{"fish"})
+	ctx.flags["no-hook"] = true
+	if err := h.Handle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(ctx.output.String(), "_wand_hook") {
+		t.Errorf("expected no hook with --no-hook, got:\n%s", ctx.output.String())
+	}
+
+	if err := h.Handle(newMockContext([]string{"tcsh"})); err == nil {
+		t.Error("expected an unsupported shell to be rejected")
+	}
+}
+
+func TestEnvCommandHandler_FormulaPath(t *testing.T) {
+	registryRepo := newMockRegistryRepo()
+	version, _ := entities.NewVersion("1.22.0")
+	pkg := entities.NewPackage("go", entities.PackageTypeCLI, version)
+	pkg.InstallPath = "/wand/packages/go/1.22.0"
+	registryRepo.registry.AddPackage(pkg)
+	registryRepo.registry.SetGlobalVersion("go", "1.22.0")
+
+	formulaRepo := newMockFormulaRepo()
+	formulaRepo.formulas["go"] = &entities.Formula{
+		Name: "go",
+		Env:  map[string]string{"GOROOT": "{install_path}"},
+		Path: []string{"bin"},
+	}
+
+	wandrcRepo := &mockWandRCRepo{dir: "/work/proj", wandrc: entities.NewWandRC()}
+	shimService := services.NewShimService(registryRepo, wandrcRepo, formulaRepo, newMockFileSystem(), "/wand")
+	h := NewEnvCommandHandler(services.NewEnvService(registryRepo, wandrcRepo, formulaRepo, shimService))
+	h.environ = func() []string { return []string{"PATH=/usr/bin", "SHELL=/bin/zsh"} }
+	h.getwd = func() (string, error) { return "/home/me", nil }
+
+	ctx := newMockContext(nil)
+	if err := h.Handle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	output := ctx.output.String()
+	if !strings.Contains(output, "export GOROOT='/wand/packages/go/1.22.0'\n") {
+		t.Errorf("expected GOROOT for the global version, got:\n%s", output)
+	}
+	if !strings.Contains(output, "export PATH='/wand/packages/go/1.22.0/bin:/usr/bin'\n") {
+		t.Errorf("expected the formula's directory in front of PATH, got:\n%s", output)
+	}
+}
+
+func TestShellCommandHandler(t *testing.T) {
+	registryRepo := newMockRegistryRepo()
+	for _, v := range []string{"18.19.0", "20.11.1"} {
+		version, _ := entities.NewVersion(v)
+		registryRepo.registry.AddPackage(entities.NewPackage("nodejs", entities.PackageTypeCLI, version))
+	}
+	shimService := services.NewShimService(registryRepo, &mockWandRCRepo{}, newMockFormulaRepo(), newMockFileSystem(), "/wand")
+
+	config := entities.DefaultConfig()
+	var ran []string
+	h := NewShellCommandHandler(shimService, config)
+	h.environ = func() []string {
+		return []string{"SHELL=/bin/zsh", "WAND_NODEJS_VERSION=20", "HOME=/home/me"}
+	}
+	h.lookPath = func(file string) (string, error) { return file, nil }
+	h.exec = func(path string, argv, env []string) error {
+		ran = append(append([]string{path}, argv...), env...)
+		return nil
+	}
+
+	if err := h.Handle(newMockContext([]string{"nodejs@18", "jq@1.7.1"})); err == nil || !strings.Contains(err.Error(), "wand install jq@1.7.1") {
+		t.Fatalf("expected a version that is not installed to be refused, got %v", err)
+	}
+	if len(ran) != 0 {
+		t.Fatalf("expected no shell to start, ran %v", ran)
+	}
+
+	// With auto_install, exec installs it on first use
+	config.AutoInstall = true
+	if err := h.Handle(newMockContext([]string{"nodejs@18", "jq@1.7.1"})); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(ran, " ")
+	want := "/bin/zsh /bin/zsh SHELL=/bin/zsh HOME=/home/me WAND_JQ_VERSION=1.7.1 WAND_NODEJS_VERSION=18"
+	if got != want {
+		t.Errorf("ran %q, want %q", got, want)
+	}
+
+	if err := h.Handle(newMockContext([]string{"nodejs"})); err == nil {
+		t.Error("expected a package without a version to be refused")
+	}
+}
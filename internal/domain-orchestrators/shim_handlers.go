@@ -0,0 +1,246 @@
+package domainorchestrators
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	errs "github.com/ochairo/wand/internal/domain/errors"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// ShimsListCommandHandler handles the shims list command
+type ShimsListCommandHandler struct {
+	shimService *services.ShimService
+}
+
+// NewShimsListCommandHandler creates a new shims list command handler
+func NewShimsListCommandHandler(shimService *services.ShimService) *ShimsListCommandHandler {
+	return &ShimsListCommandHandler{shimService: shimService}
+}
+
+// Handle prints each shim, the package it runs and any other installed
+// package that ships the same binary
+func (h *ShimsListCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	shims, err := h.shimService.ListShims()
+	if err != nil {
+		return err
+	}
+	if len(shims) == 0 {
+		ctx.Printf("No shims\n")
+		return nil
+	}
+
+	binaryWidth, ownerWidth := 0, 0
+	for _, shim := range shims {
+		binaryWidth = max(binaryWidth, len(shim.Binary))
+		ownerWidth = max(ownerWidth, len(shimOwnerName(shim)))
+	}
+
+	broken := false
+	for _, shim := range shims {
+		problem, also := shimNotes(shim)
+		if problem != "" {
+			broken = true
+		}
+		line := fmt.Sprintf("%-*s  %-*s  %s", binaryWidth, shim.Binary, ownerWidth, shimOwnerName(shim), strings.Join(nonEmpty(problem, also), "; "))
+		ctx.Printf("%s\n", strings.TrimRight(line, " "))
+	}
+	if broken {
+		ctx.Printf("\nRun 'wand shims repair' to fix the shims marked above\n")
+	}
+	return nil
+}
+
+// ShimsWhichCommandHandler handles the shims which command
+type ShimsWhichCommandHandler struct {
+	shimService *services.ShimService
+}
+
+// NewShimsWhichCommandHandler creates a new shims which command handler
+func NewShimsWhichCommandHandler(shimService *services.ShimService) *ShimsWhichCommandHandler {
+	return &ShimsWhichCommandHandler{shimService: shimService}
+}
+
+// Handle prints the shim for a binary and the package that owns it
+func (h *ShimsWhichCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	args := ctx.GetArgs()
+	if len(args) != 1 {
+		return fmt.Errorf("usage: wand shims which <binary>")
+	}
+
+	shims, err := h.shimService.ListShims()
+	if err != nil {
+		return err
+	}
+	for _, shim := range shims {
+		if shim.Binary != args[0] {
+			continue
+		}
+		ctx.Printf("%s\n", shim.Path)
+		ctx.Printf("  package: %s\n", shimOwnerName(shim))
+		for _, note := range nonEmpty(shimNotes(shim)) {
+			ctx.Printf("  %s\n", note)
+		}
+		return nil
+	}
+
+	return errs.New(errs.ErrPackageNotInstalled, fmt.Sprintf("No shim or installed package provides %s", args[0]))
+}
+
+// ShimsRepairCommandHandler handles the shims repair command
+type ShimsRepairCommandHandler struct {
+	shimService *services.ShimService
+}
+
+// NewShimsRepairCommandHandler creates a new shims repair command handler
+func NewShimsRepairCommandHandler(shimService *services.ShimService) *ShimsRepairCommandHandler {
+	return &ShimsRepairCommandHandler{shimService: shimService}
+}
+
+// Handle fixes shims and their owners to match the installed packages,
+// printing each change
+func (h *ShimsRepairCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	changes, err := h.shimService.RepairShims()
+	for _, change := range changes {
+		if change.Owner != "" {
+			ctx.Printf("  %s: %s (%s)\n", change.Binary, change.Note, change.Owner)
+		} else {
+			ctx.Printf("  %s: %s\n", change.Binary, change.Note)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		ctx.Printf("✓ Shims are in order\n")
+	} else {
+		ctx.Printf("✓ Repaired %d shim(s)\n", len(changes))
+	}
+	return nil
+}
+
+// shimOwnerName returns the package a shim runs, or a placeholder
+func shimOwnerName(shim services.ShimInfo) string {
+	if shim.Owner == "" {
+		return "-"
+	}
+	return shim.Owner
+}
+
+// shimNotes describes what is wrong with a shim, if anything, and the other
+// installed packages that ship its binary
+func shimNotes(shim services.ShimInfo) (problem, also string) {
+	owned := false
+	var others []string
+	for _, name := range shim.Providers {
+		if name == shim.Owner {
+			owned = true
+		} else {
+			others = append(others, name)
+		}
+	}
+	switch {
+	case len(shim.Providers) == 0:
+		problem = "no installed package provides it"
+	case !shim.Exists:
+		problem = "shim missing"
+	case shim.Owner == "":
+		problem = "no owner"
+	case !owned:
+		problem = shim.Owner + " does not provide it"
+	}
+
+	switch {
+	case shim.Owner == "" && len(others) > 0:
+		also = "provided by " + strings.Join(others, ", ")
+	case len(others) > 0:
+		also = "also provided by " + strings.Join(others, ", ")
+	}
+	return problem, also
+}
+
+// nonEmpty returns the values that are not ""
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// ReshimCommandHandler handles the reshim command
+type ReshimCommandHandler struct {
+	shimService *services.ShimService
+}
+
+// NewReshimCommandHandler creates a new reshim command handler
+func NewReshimCommandHandler(shimService *services.ShimService) *ReshimCommandHandler {
+	return &ReshimCommandHandler{shimService: shimService}
+}
+
+// Handle recreates every shim from the current template
+func (h *ReshimCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	if err := h.shimService.RefreshAllShims(); err != nil {
+		return err
+	}
+	shims, err := h.shimService.ListShims()
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, shim := range shims {
+		if shim.Exists {
+			count++
+		}
+	}
+	ctx.Printf("✓ Recreated %d shim(s)\n", count)
+	return nil
+}
+
+// FormulaSyncCommandHandler handles the formula sync command
+type FormulaSyncCommandHandler struct {
+	formulaRepo interfaces.FormulaRepository
+	shimService *services.ShimService
+}
+
+// NewFormulaSyncCommandHandler creates a new formula sync command handler
+func NewFormulaSyncCommandHandler(formulaRepo interfaces.FormulaRepository, shimService *services.ShimService) *FormulaSyncCommandHandler {
+	return &FormulaSyncCommandHandler{
+		formulaRepo: formulaRepo,
+		shimService: shimService,
+	}
+}
+
+// Handle updates the formulas from the formula repository, recreating the
+// shims when the binaries of an installed package changed
+func (h *FormulaSyncCommandHandler) Handle(ctx interfaces.CommandContext) error {
+	before, err := h.shimService.BinaryProviders()
+	if err != nil {
+		return err
+	}
+
+	ctx.Printf("Syncing formulas...\n")
+	if err := h.formulaRepo.Sync(); err != nil {
+		return err
+	}
+	ctx.Printf("✓ Formulas synced\n")
+
+	after, err := h.shimService.BinaryProviders()
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(before, after) {
+		return nil
+	}
+	if err := h.shimService.RefreshAllShims(); err != nil {
+		return fmt.Errorf("binaries of installed packages changed, but recreating shims failed: %w", err)
+	}
+	ctx.Printf("✓ Binaries of installed packages changed; shims recreated\n")
+	return nil
+}
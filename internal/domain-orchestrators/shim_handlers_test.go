@@ -0,0 +1,109 @@
+package domainorchestrators
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// newTestShimService serves findutils and coreutils, which both ship find,
+// with the find shim running findutils
+func newTestShimService() *services.ShimService {
+	registryRepo := newMockRegistryRepo()
+	formulaRepo := newMockFormulaRepo()
+	for name, binaries := range map[string][]string{"findutils": {"find", "xargs"}, "coreutils": {"find", "ls"}} {
+		version, _ := entities.NewVersion("1.0.0")
+		registryRepo.registry.AddPackage(entities.NewPackage(name, entities.PackageTypeCLI, version))
+		formulaRepo.formulas[name] = &entities.Formula{Name: name, Binaries: binaries}
+	}
+	fs := newMockFileSystem()
+	fs.files["/wand/shims/find"] = []byte("exec \"$WAND_BIN\" exec --package 'findutils' 'find' \"$@\"\n")
+	return services.NewShimService(registryRepo, &mockWandRCRepo{}, formulaRepo, fs, "/wand")
+}
+
+func TestInstallOrchestrator_ClaimShims(t *testing.T) {
+	o := NewInstallOrchestrator(nil, newTestShimService(), nil, newMockFormulaRepo())
+
+	var asked []string
+	keep := func(conflict services.ShimConflict) bool {
+		asked = append(asked, conflict.Binary+" from "+conflict.Owner)
+		return false
+	}
+	claimed, err := o.claimShims("coreutils", []string{"find", "ls"}, keep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(claimed, " ") != "ls" || strings.Join(asked, ",") != "find from findutils" {
+		t.Errorf("expected to be asked about find and keep it, claimed %v after %v", claimed, asked)
+	}
+
+	take := func(services.ShimConflict) bool { return true }
+	if claimed, _ := o.claimShims("coreutils", []string{"find", "ls"}, take); len(claimed) != 2 {
+		t.Errorf("expected to take over find, claimed %v", claimed)
+	}
+	if claimed, _ := o.claimShims("coreutils", []string{"find", "ls"}, nil); strings.Join(claimed, " ") != "ls" {
+		t.Errorf("expected other packages to keep their shims by default, claimed %v", claimed)
+	}
+}
+
+func TestShimsListCommandHandler(t *testing.T) {
+	ctx := newMockContext(nil)
+	if err := NewShimsListCommandHandler(newTestShimService()).Handle(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"find   findutils  shim missing; also provided by coreutils\n",
+		"ls     -          shim missing; provided by coreutils\n",
+		"xargs  -          shim missing; provided by findutils\n",
+		"Run 'wand shims repair'",
+	} {
+		if !strings.Contains(ctx.output.String(), want) {
+			t.Errorf("expected %q in\n%s", want, ctx.output.String())
+		}
+	}
+}
+
+func TestFormulaSyncCommandHandler(t *testing.T) {
+	registryRepo := newMockRegistryRepo()
+	version, _ := entities.NewVersion("1.0.0")
+	registryRepo.registry.AddPackage(entities.NewPackage("nano", entities.PackageTypeCLI, version))
+	formulaRepo := newMockFormulaRepo()
+	shimService := services.NewShimService(registryRepo, &mockWandRCRepo{}, formulaRepo, newMockFileSystem(), "/wand")
+	h := NewFormulaSyncCommandHandler(formulaRepo, shimService)
+
+	ctx := newMockContext(nil)
+	if err := h.Handle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(ctx.output.String(), "shims recreated") {
+		t.Errorf("expected no reshim when no binaries changed, got\n%s", ctx.output.String())
+	}
+
+	formulaRepo.sync = func() { formulaRepo.formulas["nano"].Binaries = []string{"nano", "rnano"} }
+	ctx = newMockContext(nil)
+	if err := h.Handle(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(ctx.output.String(), "shims recreated") {
+		t.Errorf("expected a reshim after nano gained rnano, got\n%s", ctx.output.String())
+	}
+}
+
+func TestDoctorCommandHandler_Shims(t *testing.T) {
+	fs := newMockFileSystem()
+	fs.exists["/wand"] = true
+	h := NewDoctorCommandHandler(newMockRegistryRepo(), newMockFormulaRepo(), fs, "/wand", newTestShimService())
+	ctx := newMockContext(nil)
+	if err := h.Handle(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"3 shim(s) missing for installed packages: find, ls, xargs", "Run 'wand reshim'", "Some issues detected"} {
+		if !strings.Contains(ctx.output.String(), want) {
+			t.Errorf("expected %q in\n%s", want, ctx.output.String())
+		}
+	}
+}
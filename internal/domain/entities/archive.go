@@ -0,0 +1,48 @@
+package entities
+
+// ArchiveFormat is the kind of file a download turned out to be, detected
+// from its contents rather than its name
+type ArchiveFormat string
+
+const (
+	// ArchiveFormatNone is a file that is not an archive, such as a bare binary
+	ArchiveFormatNone ArchiveFormat = ""
+	// ArchiveFormatTar is an uncompressed tarball
+	ArchiveFormatTar ArchiveFormat = "tar"
+	// ArchiveFormatTarGz is a gzip-compressed tarball
+	ArchiveFormatTarGz ArchiveFormat = "tar.gz"
+	// ArchiveFormatTarBz2 is a bzip2-compressed tarball
+	ArchiveFormatTarBz2 ArchiveFormat = "tar.bz2"
+	// ArchiveFormatTarXz is an xz-compressed tarball
+	ArchiveFormatTarXz ArchiveFormat = "tar.xz"
+	// ArchiveFormatTarZst is a zstd-compressed tarball
+	ArchiveFormatTarZst ArchiveFormat = "tar.zst"
+	// ArchiveFormatZip is a zip archive
+	ArchiveFormatZip ArchiveFormat = "zip"
+	// ArchiveFormatGz is a single gzip-compressed file
+	ArchiveFormatGz ArchiveFormat = "gz"
+	// ArchiveFormatBz2 is a single bzip2-compressed file
+	ArchiveFormatBz2 ArchiveFormat = "bz2"
+	// ArchiveFormatXz is a single xz-compressed file
+	ArchiveFormatXz ArchiveFormat = "xz"
+	// ArchiveFormatZst is a single zstd-compressed file
+	ArchiveFormatZst ArchiveFormat = "zst"
+	// ArchiveFormatDeb is a Debian package
+	ArchiveFormatDeb ArchiveFormat = "deb"
+	// ArchiveFormatRpm is an RPM package
+	ArchiveFormatRpm ArchiveFormat = "rpm"
+	// ArchiveFormatAppImage is a self-contained Linux application
+	ArchiveFormatAppImage ArchiveFormat = "appimage"
+	// ArchiveFormatDmg is a macOS disk image
+	ArchiveFormatDmg ArchiveFormat = "dmg"
+)
+
+// SingleFile returns true if the format holds one executable, such as a
+// compressed binary or an AppImage, rather than a directory tree
+func (f ArchiveFormat) SingleFile() bool {
+	switch f {
+	case ArchiveFormatGz, ArchiveFormatBz2, ArchiveFormatXz, ArchiveFormatZst, ArchiveFormatAppImage:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package entities
+
+import (
+	"strings"
+	"time"
+)
+
+// ChecksumLock records the digest of every artifact wand has installed, keyed
+// by download URL, so a release that changes after first use is detected
+type ChecksumLock struct {
+	Artifacts map[string]*LockedArtifact `json:"artifacts"` // download URL -> LockedArtifact
+}
+
+// LockedArtifact is the digest recorded for one download URL
+type LockedArtifact struct {
+	Package    string    `json:"package"`
+	Version    string    `json:"version"`
+	SHA256     string    `json:"sha256"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// NewChecksumLock creates a new ChecksumLock
+func NewChecksumLock() *ChecksumLock {
+	return &ChecksumLock{
+		Artifacts: make(map[string]*LockedArtifact),
+	}
+}
+
+// Get returns the recorded artifact for a URL
+func (l *ChecksumLock) Get(url string) (*LockedArtifact, bool) {
+	artifact, ok := l.Artifacts[url]
+	return artifact, ok
+}
+
+// Record stores the digest for a URL, replacing any previous entry
+func (l *ChecksumLock) Record(url, packageName, version, sha256 string) {
+	if l.Artifacts == nil {
+		l.Artifacts = make(map[string]*LockedArtifact)
+	}
+	l.Artifacts[url] = &LockedArtifact{
+		Package:    packageName,
+		Version:    version,
+		SHA256:     strings.ToLower(sha256),
+		RecordedAt: time.Now(),
+	}
+}
+
+// Matches reports whether sha256 agrees with the recorded digest for a URL.
+// A URL with no recorded digest always matches.
+func (l *ChecksumLock) Matches(url, sha256 string) bool {
+	artifact, ok := l.Artifacts[url]
+	return !ok || strings.EqualFold(artifact.SHA256, sha256)
+}
@@ -0,0 +1,379 @@
+package entities
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultFormulaRepository is the git repository formulas are synced from
+const DefaultFormulaRepository = "https://github.com/ochairo/potions.git"
+
+// Config holds the user settings in ~/.wand/config.yaml. An empty field is
+// unset and falls back to the next layer: the file overrides the defaults,
+// WAND_* environment variables override the file and command flags override
+// both.
+type Config struct {
+	Home         string         `yaml:"home,omitempty"` // Data directory for packages, shims and caches
+	Formulas     FormulaConfig  `yaml:"formulas,omitempty"`
+	Network      NetworkOptions `yaml:"network,omitempty"`
+	Parallelism  int            `yaml:"parallelism,omitempty"`   // Release lists fetched at once
+	Scope        string         `yaml:"scope,omitempty"`         // Where `wand switch` records a version: project or global
+	Hooks        string         `yaml:"hooks,omitempty"`         // Formula command policy: allow, confirm or deny
+	AutoInstall  bool           `yaml:"auto_install,omitempty"`  // Install versions pinned in a project on first use
+	VersionFiles []string       `yaml:"version_files,omitempty"` // Other tools' version files read after .wandrc, or "none"
+}
+
+// FormulaConfig configures where formulas come from
+type FormulaConfig struct {
+	Repository string   `yaml:"repository,omitempty"` // Git URL synced into the formulas directory
+	Taps       []string `yaml:"taps,omitempty"`       // Extra formula directories, searched after the main one
+}
+
+// NetworkOptions holds the non-secret network settings; tokens and host
+// credentials live in credentials.yaml
+type NetworkOptions struct {
+	Proxy          string   `yaml:"proxy,omitempty"`
+	CAFile         string   `yaml:"ca_file,omitempty"`
+	Mirrors        []string `yaml:"mirrors,omitempty"` // base or prefix=base, as in WAND_MIRRORS
+	ConnectTimeout string   `yaml:"connect_timeout,omitempty"`
+}
+
+// ConfigSource says which layer a setting's value came from
+type ConfigSource string
+
+const (
+	// ConfigSourceDefault is a built-in default.
+	ConfigSourceDefault ConfigSource = "default"
+	// ConfigSourceFile is the configuration file.
+	ConfigSourceFile ConfigSource = "file"
+	// ConfigSourceEnv is a WAND_* environment variable.
+	ConfigSourceEnv ConfigSource = "env"
+)
+
+// ConfigKey describes one setting
+type ConfigKey struct {
+	Name        string // dotted key, e.g. network.proxy
+	Env         string // environment variable overriding the file
+	Default     string
+	Description string
+
+	get func(c *Config) string
+	set func(c *Config, value string) error
+}
+
+// configKeys lists every setting in display order
+var configKeys = []*ConfigKey{
+	{
+		Name: "home", Env: "WAND_HOME", Default: "~/.wand",
+		Description: "Data directory for packages, shims and caches",
+		get:         func(c *Config) string { return c.Home },
+		set:         func(c *Config, v string) error { c.Home = v; return nil },
+	},
+	{
+		Name: "formulas.repository", Env: "WAND_FORMULA_REPO", Default: DefaultFormulaRepository,
+		Description: "Git repository formulas are synced from",
+		get:         func(c *Config) string { return c.Formulas.Repository },
+		set:         func(c *Config, v string) error { c.Formulas.Repository = v; return nil },
+	},
+	{
+		Name: "formulas.taps", Env: "WAND_TAPS",
+		Description: "Comma-separated extra formula directories",
+		get:         func(c *Config) string { return strings.Join(c.Formulas.Taps, ",") },
+		set:         func(c *Config, v string) error { c.Formulas.Taps = splitList(v); return nil },
+	},
+	{
+		Name: "network.proxy", Env: "WAND_PROXY",
+		Description: "Proxy URL for downloads, the GitHub API and formula sync",
+		get:         func(c *Config) string { return c.Network.Proxy },
+		set: func(c *Config, v string) error {
+			if v != "" {
+				u, err := url.Parse(v)
+				if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+					return fmt.Errorf("expected an http, https or socks5 URL, got %q", v)
+				}
+			}
+			c.Network.Proxy = v
+			return nil
+		},
+	},
+	{
+		Name: "network.ca_file", Env: "WAND_CA_FILE",
+		Description: "PEM bundle trusted in addition to the system roots",
+		get:         func(c *Config) string { return c.Network.CAFile },
+		set:         func(c *Config, v string) error { c.Network.CAFile = v; return nil },
+	},
+	{
+		Name: "network.mirrors", Env: "WAND_MIRRORS",
+		Description: "Comma-separated download mirrors, base or prefix=base",
+		get:         func(c *Config) string { return strings.Join(c.Network.Mirrors, ",") },
+		set: func(c *Config, v string) error {
+			mirrors := splitList(v)
+			for _, mirror := range mirrors {
+				base := mirror
+				if _, after, ok := strings.Cut(mirror, "="); ok {
+					base = strings.TrimSpace(after)
+				}
+				if u, err := url.Parse(base); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+					return fmt.Errorf("invalid mirror %q: expected an http(s) base URL", mirror)
+				}
+			}
+			c.Network.Mirrors = mirrors
+			return nil
+		},
+	},
+	{
+		Name: "network.connect_timeout", Env: "WAND_CONNECT_TIMEOUT", Default: "30s",
+		Description: "Limit for connecting and waiting for response headers",
+		get:         func(c *Config) string { return c.Network.ConnectTimeout },
+		set: func(c *Config, v string) error {
+			if v != "" {
+				if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+					return fmt.Errorf("expected a duration such as 30s, got %q", v)
+				}
+			}
+			c.Network.ConnectTimeout = v
+			return nil
+		},
+	},
+	{
+		Name: "parallelism", Env: "WAND_PARALLELISM", Default: "4",
+		Description: "Release lists fetched at once by wand outdated",
+		get: func(c *Config) string {
+			if c.Parallelism == 0 {
+				return ""
+			}
+			return strconv.Itoa(c.Parallelism)
+		},
+		set: func(c *Config, v string) error {
+			if v == "" {
+				c.Parallelism = 0
+				return nil
+			}
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > 64 {
+				return fmt.Errorf("expected a number from 1 to 64, got %q", v)
+			}
+			c.Parallelism = n
+			return nil
+		},
+	},
+	{
+		Name: "scope", Env: "WAND_SCOPE", Default: "project",
+		Description: "Where wand switch records a version: project or global",
+		get:         func(c *Config) string { return c.Scope },
+		set: func(c *Config, v string) error {
+			if v != "" && v != "project" && v != "global" {
+				return fmt.Errorf("expected project or global, got %q", v)
+			}
+			c.Scope = v
+			return nil
+		},
+	},
+	{
+		Name: "hooks", Env: "WAND_HOOKS", Default: "allow",
+		Description: "Policy for formula build and post-install commands: allow, confirm or deny",
+		get:         func(c *Config) string { return c.Hooks },
+		set: func(c *Config, v string) error {
+			if v != "" && v != "allow" && v != "confirm" && v != "deny" {
+				return fmt.Errorf("expected allow, confirm or deny, got %q", v)
+			}
+			c.Hooks = v
+			return nil
+		},
+	},
+	{
+		Name: "auto_install", Env: "WAND_AUTO_INSTALL", Default: "false",
+		Description: "Install a version pinned in a project the first time a shim needs it",
+		get: func(c *Config) string {
+			if !c.AutoInstall {
+				return ""
+			}
+			return "true"
+		},
+		set: func(c *Config, v string) error {
+			if v == "" {
+				c.AutoInstall = false
+				return nil
+			}
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("expected true or false, got %q", v)
+			}
+			c.AutoInstall = b
+			return nil
+		},
+	},
+	{
+		Name: "version_files", Env: "WAND_VERSION_FILES", Default: strings.Join(VersionFiles, ","),
+		Description: "Comma-separated version files of other tools read after .wandrc, or none",
+		get:         func(c *Config) string { return strings.Join(c.VersionFiles, ",") },
+		set: func(c *Config, v string) error {
+			files := splitList(v)
+			if len(files) == 1 && files[0] == "none" {
+				c.VersionFiles = files
+				return nil
+			}
+			for _, file := range files {
+				known := false
+				for _, name := range VersionFiles {
+					known = known || file == name
+				}
+				if !known {
+					return fmt.Errorf("unknown version file %q (supported: %s, or none)", file, strings.Join(VersionFiles, ", "))
+				}
+			}
+			c.VersionFiles = files
+			return nil
+		},
+	},
+}
+
+// ConfigKeys returns every setting in display order
+func ConfigKeys() []*ConfigKey {
+	return configKeys
+}
+
+// LookupConfigKey returns the setting with the given name
+func LookupConfigKey(name string) (*ConfigKey, error) {
+	for _, key := range configKeys {
+		if key.Name == name {
+			return key, nil
+		}
+	}
+
+	names := make([]string, 0, len(configKeys))
+	for _, key := range configKeys {
+		names = append(names, key.Name)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown setting %q (settings: %s)", name, strings.Join(names, ", "))
+}
+
+// DefaultConfig returns the built-in settings
+func DefaultConfig() *Config {
+	c := &Config{}
+	for _, key := range configKeys {
+		_ = key.set(c, key.Default)
+	}
+	c.Home = "" // resolved against the user's home directory
+	return c
+}
+
+// Get returns a setting's value, or "" if it is unset
+func (c *Config) Get(name string) (string, error) {
+	key, err := LookupConfigKey(name)
+	if err != nil {
+		return "", err
+	}
+	return key.get(c), nil
+}
+
+// Set validates and stores a setting; an empty value unsets it
+func (c *Config) Set(name, value string) error {
+	key, err := LookupConfigKey(name)
+	if err != nil {
+		return err
+	}
+	if err := key.set(c, strings.TrimSpace(value)); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// Validate checks every setting, returning one error per invalid value
+func (c *Config) Validate() []error {
+	var problems []error
+	for _, key := range configKeys {
+		if err := key.set(&Config{}, key.get(c)); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", key.Name, err))
+		}
+	}
+	return problems
+}
+
+// ResolveConfig layers file over the defaults and env over both. env maps
+// environment variable names to values. It returns the effective settings
+// and where each came from.
+func ResolveConfig(file *Config, env map[string]string) (*Config, map[string]ConfigSource, error) {
+	effective := DefaultConfig()
+	sources := make(map[string]ConfigSource, len(configKeys))
+
+	for _, key := range configKeys {
+		sources[key.Name] = ConfigSourceDefault
+		if file != nil {
+			if value := key.get(file); value != "" {
+				if err := key.set(effective, value); err != nil {
+					return nil, nil, fmt.Errorf("%s: %w", key.Name, err)
+				}
+				sources[key.Name] = ConfigSourceFile
+			}
+		}
+		if value := strings.TrimSpace(env[key.Env]); value != "" {
+			if err := key.set(effective, value); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", key.Env, err)
+			}
+			sources[key.Name] = ConfigSourceEnv
+		}
+	}
+
+	return effective, sources, nil
+}
+
+// ExpandPaths replaces a leading ~ in path settings with homeDir
+func (c *Config) ExpandPaths(homeDir string) {
+	expand := func(path string) string {
+		if path == "~" {
+			return homeDir
+		}
+		if strings.HasPrefix(path, "~/") {
+			return homeDir + path[1:]
+		}
+		return path
+	}
+
+	c.Home = expand(c.Home)
+	c.Network.CAFile = expand(c.Network.CAFile)
+	for i, tap := range c.Formulas.Taps {
+		c.Formulas.Taps[i] = expand(tap)
+	}
+}
+
+// ConnectTimeoutDuration returns network.connect_timeout, or 0 if unset
+func (c *Config) ConnectTimeoutDuration() time.Duration {
+	d, _ := time.ParseDuration(c.Network.ConnectTimeout)
+	return d
+}
+
+// VersionFileNames returns the version files to read, with "none" as no files
+func (c *Config) VersionFileNames() []string {
+	if len(c.VersionFiles) == 1 && c.VersionFiles[0] == "none" {
+		return nil
+	}
+	return c.VersionFiles
+}
+
+// ApplyTo overrides the network config's proxy and CA file with these
+// settings, where set
+func (c *Config) ApplyTo(network *NetworkConfig) {
+	if c.Network.Proxy != "" {
+		network.Proxy = c.Network.Proxy
+	}
+	if c.Network.CAFile != "" {
+		network.CAFile = c.Network.CAFile
+	}
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
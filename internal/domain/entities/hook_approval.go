@@ -0,0 +1,61 @@
+package entities
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// HookApprovals records which formula commands the user has approved, so a
+// formula is only asked about again when its commands change
+type HookApprovals struct {
+	Packages map[string]*HookApproval `json:"packages"` // package name -> HookApproval
+}
+
+// HookApproval is the approved command list for one package
+type HookApproval struct {
+	Fingerprint string    `json:"fingerprint"`
+	Commands    []string  `json:"commands"`
+	ApprovedAt  time.Time `json:"approved_at"`
+}
+
+// NewHookApprovals creates a new HookApprovals
+func NewHookApprovals() *HookApprovals {
+	return &HookApprovals{
+		Packages: make(map[string]*HookApproval),
+	}
+}
+
+// HookFingerprint returns a stable digest of a command list
+func HookFingerprint(commands []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(commands, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsApproved reports whether exactly these commands were approved for a package
+func (a *HookApprovals) IsApproved(packageName string, commands []string) bool {
+	approval, ok := a.Packages[packageName]
+	return ok && approval.Fingerprint == HookFingerprint(commands)
+}
+
+// Approve records the commands approved for a package, replacing any previous approval
+func (a *HookApprovals) Approve(packageName string, commands []string) {
+	if a.Packages == nil {
+		a.Packages = make(map[string]*HookApproval)
+	}
+	a.Packages[packageName] = &HookApproval{
+		Fingerprint: HookFingerprint(commands),
+		Commands:    append([]string(nil), commands...),
+		ApprovedAt:  time.Now(),
+	}
+}
+
+// Revoke removes the approval for a package
+func (a *HookApprovals) Revoke(packageName string) bool {
+	if _, ok := a.Packages[packageName]; ok {
+		delete(a.Packages, packageName)
+		return true
+	}
+	return false
+}
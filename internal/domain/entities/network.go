@@ -0,0 +1,47 @@
+package entities
+
+import (
+	"net"
+	"strings"
+)
+
+// NetworkConfig configures outgoing connections: downloads, GitHub API
+// requests and formula sync
+type NetworkConfig struct {
+	Proxy       string                     `yaml:"proxy,omitempty"`        // Proxy URL; empty uses HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+	CAFile      string                     `yaml:"ca_file,omitempty"`      // PEM bundle trusted in addition to the system roots
+	GitHubToken string                     `yaml:"github_token,omitempty"` // Token for the GitHub API and formula sync
+	Hosts       map[string]*HostCredential `yaml:"hosts,omitempty"`        // host or host:port -> credential
+}
+
+// HostCredential authenticates requests to one host, with a bearer token or
+// a username and password
+type HostCredential struct {
+	Token    string `yaml:"token,omitempty"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+}
+
+// CredentialFor returns the credential for a request host, matching
+// host:port first and then the bare host name
+func (c *NetworkConfig) CredentialFor(host string) (*HostCredential, bool) {
+	if c == nil || len(c.Hosts) == 0 {
+		return nil, false
+	}
+
+	host = strings.ToLower(host)
+	if credential, ok := c.Hosts[host]; ok && credential != nil {
+		return credential, true
+	}
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		if credential, ok := c.Hosts[name]; ok && credential != nil {
+			return credential, true
+		}
+	}
+	return nil, false
+}
+
+// IsZero returns true if the credential has nothing to send
+func (h *HostCredential) IsZero() bool {
+	return h == nil || (h.Token == "" && h.Username == "")
+}
@@ -16,13 +16,16 @@ const (
 
 // Package represents an installed package
 type Package struct {
-	Name        string      // Package name (e.g., "node", "go", "nvim")
-	Type        PackageType // CLI, GUI, or Dotfile
-	Version     *Version    // Installed version
-	InstalledAt time.Time   // Installation timestamp
-	BinPath     string      // Path to binary/executable
-	InstallPath string      // Full installation directory path
-	IsGlobal    bool        // Whether this is the global version
+	Name        string            // Package name (e.g., "node", "go", "nvim")
+	Type        PackageType       // CLI, GUI, or Dotfile
+	Version     *Version          // Installed version
+	InstalledAt time.Time         // Installation timestamp
+	BinPath     string            // Path to binary/executable
+	Binaries    map[string]string // Binary name -> path, found after extraction
+	InstallPath string            // Full installation directory path
+	IsGlobal    bool              // Whether this is the global version
+
+	IntegrationFiles []string // Files installed outside InstallPath, such as desktop entries and icons
 }
 
 // NewPackage creates a new Package
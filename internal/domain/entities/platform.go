@@ -5,6 +5,12 @@ import (
 	"strings"
 )
 
+// SupportedOSes lists the operating systems formulas may target
+var SupportedOSes = []string{"darwin", "linux"}
+
+// SupportedArches lists the CPU architectures formulas may target
+var SupportedArches = []string{"amd64", "arm64"}
+
 // Platform represents the current operating system and architecture
 type Platform struct {
 	OS   string // darwin, linux
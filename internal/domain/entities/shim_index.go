@@ -0,0 +1,51 @@
+package entities
+
+import "sort"
+
+// ShimIndex records which package owns each shim, so a binary that several
+// packages ship is not taken over silently, nor removed with the wrong one
+type ShimIndex struct {
+	Owners map[string]string `json:"owners"` // binary name -> package name
+}
+
+// NewShimIndex creates a new ShimIndex
+func NewShimIndex() *ShimIndex {
+	return &ShimIndex{
+		Owners: make(map[string]string),
+	}
+}
+
+// Owner returns the package that owns the shim for a binary
+func (i *ShimIndex) Owner(binary string) (string, bool) {
+	owner, ok := i.Owners[binary]
+	return owner, ok
+}
+
+// SetOwner records a package as the owner of the shim for a binary
+func (i *ShimIndex) SetOwner(binary, packageName string) {
+	if i.Owners == nil {
+		i.Owners = make(map[string]string)
+	}
+	i.Owners[binary] = packageName
+}
+
+// Release forgets the owner of the shim for a binary
+func (i *ShimIndex) Release(binary string) bool {
+	if _, ok := i.Owners[binary]; ok {
+		delete(i.Owners, binary)
+		return true
+	}
+	return false
+}
+
+// Binaries returns the binaries whose shims a package owns, sorted
+func (i *ShimIndex) Binaries(packageName string) []string {
+	var binaries []string
+	for binary, owner := range i.Owners {
+		if owner == packageName {
+			binaries = append(binaries, binary)
+		}
+	}
+	sort.Strings(binaries)
+	return binaries
+}
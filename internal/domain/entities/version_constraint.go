@@ -0,0 +1,171 @@
+package entities
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VersionConstraint is a version requirement from a .wandrc or the command
+// line. It is one of:
+//
+//	1.7.1           exactly this version
+//	1.7, 1.7.x, 1   any version with this prefix
+//	^1.7.1          same major version, at least 1.7.1
+//	~1.7.1          same major and minor version, at least 1.7.1
+//	>=1.6 <2        every comparison must hold
+//	latest, *       any version
+//
+// Pre-releases only match an exact constraint.
+type VersionConstraint struct {
+	raw   string
+	exact *Version
+	terms []constraintTerm
+}
+
+// constraintTerm is one comparison, or a prefix when op is ""
+type constraintTerm struct {
+	op      string
+	version *Version
+	parts   int // components given, for prefixes
+}
+
+// ParseVersionConstraint parses a version constraint
+func ParseVersionConstraint(s string) (*VersionConstraint, error) {
+	s = strings.TrimSpace(s)
+	c := &VersionConstraint{raw: s}
+	if s == "" || s == "latest" || s == "*" {
+		return c, nil
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) == 1 && isFullVersion(fields[0]) {
+		version, err := NewVersion(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q: %w", s, err)
+		}
+		c.exact = version
+		return c, nil
+	}
+
+	for _, field := range fields {
+		term, err := parseConstraintTerm(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q: %w", s, err)
+		}
+		c.terms = append(c.terms, term)
+	}
+	return c, nil
+}
+
+// parseConstraintTerm parses one space-separated part of a constraint
+func parseConstraintTerm(field string) (constraintTerm, error) {
+	var term constraintTerm
+	for _, op := range []string{">=", "<=", ">", "<", "^", "~", "="} {
+		if strings.HasPrefix(field, op) {
+			term.op = op
+			field = field[len(op):]
+			break
+		}
+	}
+	if term.op == "=" {
+		term.op = ""
+	}
+
+	// Trailing x or * components make a prefix: 1.7.x is 1.7
+	parts := strings.Split(strings.TrimPrefix(field, "v"), ".")
+	for len(parts) > 0 && (parts[len(parts)-1] == "x" || parts[len(parts)-1] == "*") {
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) == 0 {
+		return term, fmt.Errorf("missing version")
+	}
+	version, err := NewVersion(strings.Join(parts, "."))
+	if err != nil {
+		return term, err
+	}
+	term.version = version
+	term.parts = len(parts)
+	if term.op == "" && term.parts == 3 && version.Pre == "" {
+		term.op = "="
+	}
+	return term, nil
+}
+
+// isFullVersion reports whether s is a major.minor.patch version, optionally
+// with a pre-release or build suffix
+func isFullVersion(s string) bool {
+	s = strings.TrimPrefix(s, "v")
+	if s == "" || s[0] < '0' || s[0] > '9' {
+		return false
+	}
+	core := s
+	if i := strings.IndexAny(core, "-+"); i != -1 {
+		core = core[:i]
+	}
+	return strings.Count(core, ".") == 2 && !strings.ContainsAny(core, "x*")
+}
+
+// String returns the constraint as written
+func (c *VersionConstraint) String() string {
+	return c.raw
+}
+
+// IsExact returns the version an exact constraint names, or nil
+func (c *VersionConstraint) IsExact() *Version {
+	return c.exact
+}
+
+// Matches reports whether v satisfies the constraint
+func (c *VersionConstraint) Matches(v *Version) bool {
+	if c.exact != nil {
+		return v.Equal(c.exact)
+	}
+	if v.Pre != "" {
+		return false
+	}
+	for _, term := range c.terms {
+		if !term.matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t constraintTerm) matches(v *Version) bool {
+	switch t.op {
+	case "=":
+		return v.Equal(t.version)
+	case ">=":
+		return !v.LessThan(t.version)
+	case "<=":
+		return !v.GreaterThan(t.version)
+	case ">":
+		return v.GreaterThan(t.version)
+	case "<":
+		return v.LessThan(t.version)
+	case "^":
+		return v.Major == t.version.Major && !v.LessThan(t.version)
+	case "~":
+		return v.Major == t.version.Major && v.Minor == t.version.Minor && !v.LessThan(t.version)
+	}
+
+	// Prefix: the given components must be equal
+	if v.Major != t.version.Major {
+		return false
+	}
+	if t.parts >= 2 && v.Minor != t.version.Minor {
+		return false
+	}
+	return t.parts < 3 || v.Patch == t.version.Patch
+}
+
+// Best returns the newest of versions that satisfies the constraint, or nil
+func (c *VersionConstraint) Best(versions []*Version) *Version {
+	var best *Version
+	for _, v := range versions {
+		if c.Matches(v) && (best == nil || v.GreaterThan(best)) {
+			best = v
+		}
+	}
+	return best
+}
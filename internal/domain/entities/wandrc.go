@@ -1,8 +1,33 @@
 package entities
 
-// WandRC represents a .wandrc file for per-project version overrides
+import "strings"
+
+// VersionFiles lists the version files of other tools that wand reads, in
+// the order they are consulted after a directory's .wandrc
+var VersionFiles = []string{".tool-versions", ".nvmrc", ".node-version", ".python-version", "go.mod"}
+
+// VersionEnvVar returns the environment variable that overrides the version
+// of a package everywhere, e.g. WAND_NODEJS_VERSION for nodejs. Letters are
+// upper-cased and anything but a letter or digit becomes an underscore.
+func VersionEnvVar(packageName string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, packageName)
+	return "WAND_" + name + "_VERSION"
+}
+
+// WandRC represents a .wandrc file for per-project version overrides. A
+// .wandrc inherits the pins of .wandrc files in parent directories, which
+// its own pins override, unless extends is false.
 type WandRC struct {
-	Versions map[string]string `yaml:"versions"` // package name -> version
+	Versions map[string]string `yaml:"versions"`          // package name -> version
+	Extends  *bool             `yaml:"extends,omitempty"` // false stops inheriting from parent directories
 }
 
 // NewWandRC creates a new WandRC
@@ -42,3 +67,8 @@ func (w *WandRC) HasVersion(name string) bool {
 func (w *WandRC) IsEmpty() bool {
 	return len(w.Versions) == 0
 }
+
+// Inherits returns true unless the file sets extends: false
+func (w *WandRC) Inherits() bool {
+	return w.Extends == nil || *w.Extends
+}
@@ -29,10 +29,14 @@ const (
 	ErrDownloadFailed ErrorCode = "DOWNLOAD_FAILED"
 	// ErrChecksumMismatch indicates a checksum validation failed.
 	ErrChecksumMismatch ErrorCode = "CHECKSUM_MISMATCH"
+	// ErrSignatureInvalid indicates a release signature could not be verified.
+	ErrSignatureInvalid ErrorCode = "SIGNATURE_INVALID"
 	// ErrExtractionFailed indicates archive extraction failed.
 	ErrExtractionFailed ErrorCode = "EXTRACTION_FAILED"
 	// ErrInstallationFailed indicates the installation process failed.
 	ErrInstallationFailed ErrorCode = "INSTALLATION_FAILED"
+	// ErrHookDenied indicates the hook policy refused a formula's commands.
+	ErrHookDenied ErrorCode = "HOOK_DENIED"
 	// ErrBinaryNotFound indicates a required binary was not found.
 	ErrBinaryNotFound ErrorCode = "BINARY_NOT_FOUND"
 	// ErrShimCreationFailed indicates shim creation failed.
@@ -53,6 +57,10 @@ const (
 	ErrHTTPError ErrorCode = "HTTP_ERROR"
 	// ErrTimeout indicates an operation timed out.
 	ErrTimeout ErrorCode = "TIMEOUT"
+	// ErrRateLimited indicates the GitHub API rate limit was exhausted.
+	ErrRateLimited ErrorCode = "RATE_LIMITED"
+	// ErrCancelled indicates an operation was interrupted before it finished.
+	ErrCancelled ErrorCode = "CANCELLED"
 	// ErrConfigMissing indicates a required configuration is missing.
 	ErrConfigMissing ErrorCode = "CONFIG_MISSING"
 	// ErrConfigInvalid indicates a configuration is invalid.
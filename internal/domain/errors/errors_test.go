@@ -129,8 +129,10 @@ func TestErrorCodes(t *testing.T) {
 		ErrVersionInstalled,
 		ErrDownloadFailed,
 		ErrChecksumMismatch,
+		ErrSignatureInvalid,
 		ErrExtractionFailed,
 		ErrInstallationFailed,
+		ErrHookDenied,
 		ErrBinaryNotFound,
 		ErrShimCreationFailed,
 		ErrShimExecutionFailed,
@@ -141,6 +143,8 @@ func TestErrorCodes(t *testing.T) {
 		ErrNetworkUnreachable,
 		ErrHTTPError,
 		ErrTimeout,
+		ErrRateLimited,
+		ErrCancelled,
 		ErrConfigMissing,
 		ErrConfigInvalid,
 		ErrRegistryCorrupted,
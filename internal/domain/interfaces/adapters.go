@@ -1,18 +1,63 @@
 // Package interfaces defines the domain interfaces.
 package interfaces
 
-import "io"
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+)
+
+// SizedWriter is a progress writer that also wants the expected download size.
+// SetTotal is called before each attempt with the full file length, or -1 if
+// unknown; bytes written before a retry should be discarded. When an attempt
+// resumes a partial download, SetOffset follows with the bytes already on disk.
+type SizedWriter interface {
+	io.Writer
+	SetTotal(total int64)
+	SetOffset(offset int64)
+}
 
 // Downloader defines the interface for downloading files
 type Downloader interface {
 	Download(url, destPath string) error
 	DownloadWithProgress(url, destPath string, progress io.Writer) error
+	// DownloadContext downloads url to destPath, writing to progress if it is
+	// not nil. It stops when ctx is done and never leaves a partial destPath.
+	DownloadContext(ctx context.Context, url, destPath string, progress io.Writer) error
+	// Deprecated: VerifyChecksum only handles SHA256 per-file checksums.
+	// Download the checksum file and use Verifier.VerifyChecksum instead.
 	VerifyChecksum(filePath, checksumURL string) error
 }
 
+// Verifier defines the interface for verifying downloaded artifacts against
+// local checksum and signature files
+type Verifier interface {
+	// VerifyChecksum checks filePath against a per-file checksum or a manifest,
+	// matching manifest entries by assetName. An empty algorithm is inferred.
+	VerifyChecksum(filePath, assetName, checksumPath, algorithm string) error
+	// VerifySignature checks a detached signature of filePath with a pinned key
+	VerifySignature(filePath, signaturePath string, config *entities.SignatureConfig) error
+	// Digest returns the hex digest of filePath using sha256 or sha512
+	Digest(filePath, algorithm string) (string, error)
+}
+
+// HTTPChecker defines the interface for probing remote URLs without downloading them
+type HTTPChecker interface {
+	Head(url string) (int, error)
+}
+
 // Extractor defines the interface for extracting archives
 type Extractor interface {
+	// Format detects an archive's format from its first bytes. Files that are
+	// not archives, such as bare binaries, are ArchiveFormatNone.
+	Format(archivePath string) (entities.ArchiveFormat, error)
+	// Extract unpacks an archive into destDir. Single-file formats leave one
+	// executable there, named after the archive without its compression suffix.
 	Extract(archivePath, destDir string) error
+	// ExtractContext is Extract that stops when ctx is done
+	ExtractContext(ctx context.Context, archivePath, destDir string) error
 	ExtractFile(archivePath, fileName, destPath string) error
 }
 
@@ -29,6 +74,9 @@ type FileSystem interface {
 	ReadSymlink(name string) (string, error)
 	Chmod(name string, mode uint32) error
 	Walk(root string, walkFn func(path string, isDir bool, err error) error) error
+	Rename(oldpath, newpath string) error
+	// CopyDir copies a directory tree, preserving file modes and symlinks
+	CopyDir(src, dst string) error
 }
 
 // ShellExecutor defines the interface for executing shell commands
@@ -36,6 +84,11 @@ type ShellExecutor interface {
 	Execute(command string, args ...string) (string, error)
 	ExecuteInDir(dir, command string, args ...string) (string, error)
 	ExecuteWithEnv(env map[string]string, command string, args ...string) (string, error)
+	// ExecuteScript runs script with sh -c in dir using exactly env, killing it
+	// after timeout (0 means no limit). Output is returned even on failure.
+	ExecuteScript(dir, script string, env map[string]string, timeout time.Duration) (string, error)
+	// ExecuteScriptContext is ExecuteScript that also kills the script when ctx is done
+	ExecuteScriptContext(ctx context.Context, dir, script string, env map[string]string, timeout time.Duration) (string, error)
 }
 
 // GitClient defines the interface for Git operations
@@ -1,10 +1,15 @@
 package interfaces
 
+import "context"
+
 // CLIAdapter provides an abstraction over CLI frameworks (Cobra, etc.)
 // This allows the domain to be independent of specific CLI implementations
 type CLIAdapter interface {
 	// Execute runs the CLI application
 	Execute() error
+
+	// ExecuteContext runs the CLI application; commands stop when ctx is done
+	ExecuteContext(ctx context.Context) error
 }
 
 // CommandContext provides context for command execution
@@ -15,6 +20,10 @@ type CommandContext interface {
 	// GetBoolFlag returns a boolean flag value
 	GetBoolFlag(name string) (bool, error)
 
+	// FlagChanged returns true if the flag was given on the command line,
+	// so its value overrides the configured default
+	FlagChanged(name string) bool
+
 	// GetArgs returns positional arguments
 	GetArgs() []string
 
@@ -23,6 +32,15 @@ type CommandContext interface {
 
 	// PrintError prints formatted error output
 	PrintError(format string, args ...interface{})
+
+	// Confirm asks a yes/no question and returns true only for an explicit yes
+	Confirm(prompt string) bool
+
+	// IsTerminal returns true if output goes to an interactive terminal
+	IsTerminal() bool
+
+	// Context returns the command's context, which is cancelled on interrupt
+	Context() context.Context
 }
 
 // CommandHandler handles command execution using domain logic
@@ -2,7 +2,13 @@
 // The package name "interfaces" is a standard Go pattern for abstract contracts in domain-driven design.
 package interfaces
 
-import "github.com/ochairo/wand/internal/domain/entities"
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+)
 
 // GitHubRelease represents a GitHub release
 type GitHubRelease struct {
@@ -22,6 +28,23 @@ type GitHubClient interface {
 	GetRelease(owner, repo, tag string) (*GitHubRelease, error)
 	ListReleases(owner, repo string) ([]*GitHubRelease, error)
 	DownloadAsset(asset *GitHubAsset, destPath string) error
+
+	// Context-aware variants that stop when ctx is done
+	GetLatestReleaseContext(ctx context.Context, owner, repo string) (*GitHubRelease, error)
+	GetReleaseContext(ctx context.Context, owner, repo, tag string) (*GitHubRelease, error)
+	ListReleasesContext(ctx context.Context, owner, repo string) ([]*GitHubRelease, error)
+	DownloadAssetContext(ctx context.Context, asset *GitHubAsset, destPath string) error
+}
+
+// RateLimitError is returned by a GitHubClient when the API rate limit is
+// exhausted until Reset
+type RateLimitError struct {
+	Reset         time.Time
+	Authenticated bool // whether the requests carried a token
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("GitHub API rate limit exceeded until %s", e.Reset.Format(time.RFC3339))
 }
 
 // PackageResolver defines the interface for resolving package versions
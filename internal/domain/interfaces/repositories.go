@@ -27,6 +27,18 @@ type WandRCRepository interface {
 	FindInPath(startDir string) (*entities.WandRC, string, error)
 }
 
+// VersionFileReader reads the versions pinned in another tool's version
+// file, such as .nvmrc or asdf's .tool-versions
+type VersionFileReader interface {
+	// FileName returns the file's name, e.g. ".nvmrc"
+	FileName() string
+	// Packages returns the packages the file can pin, or nil for any
+	Packages() []string
+	// Read returns the versions the file in dir pins by package name, or nil
+	// if dir has no such file
+	Read(dir string) (map[string]string, error)
+}
+
 // WandfileRepository defines the interface for wandfile operations
 type WandfileRepository interface {
 	Load(path string) (*entities.Wandfile, error)
@@ -40,3 +52,30 @@ type DotfileRepository interface {
 	Save(config *entities.DotfileConfig) error
 	Exists() bool
 }
+
+// ChecksumLockRepository defines the interface for the trust-on-first-use checksum store
+type ChecksumLockRepository interface {
+	Load() (*entities.ChecksumLock, error)
+	Save(lock *entities.ChecksumLock) error
+}
+
+// HookApprovalRepository defines the interface for persisting approved formula commands
+type HookApprovalRepository interface {
+	Load() (*entities.HookApprovals, error)
+	Save(approvals *entities.HookApprovals) error
+}
+
+// ShimIndexRepository defines the interface for persisting which package owns each shim
+type ShimIndexRepository interface {
+	Load() (*entities.ShimIndex, error)
+	Save(index *entities.ShimIndex) error
+}
+
+// ConfigRepository defines the interface for the user configuration file and
+// the environment variables layered over it
+type ConfigRepository interface {
+	Load() (*entities.Config, error)
+	Save(config *entities.Config) error
+	Path() string
+	Environment() map[string]string
+}
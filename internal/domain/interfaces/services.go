@@ -2,7 +2,11 @@
 // The package name "interfaces" is a standard Go pattern for abstract contracts in domain-driven design.
 package interfaces
 
-import "github.com/ochairo/wand/internal/domain/entities"
+import (
+	"context"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+)
 
 // PackageInstaller defines the interface for installing packages
 type PackageInstaller interface {
@@ -33,6 +37,8 @@ type DotfileManager interface {
 // WandfileManager defines the interface for managing wandfiles
 type WandfileManager interface {
 	Install(wandfile *entities.Wandfile) error
+	// InstallContext is Install that stops when ctx is done
+	InstallContext(ctx context.Context, wandfile *entities.Wandfile) error
 	Update() error
 	Check(wandfile *entities.Wandfile) ([]string, error)
 	Dump() (*entities.Wandfile, error)
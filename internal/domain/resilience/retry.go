@@ -4,6 +4,7 @@ package resilience
 import (
 	"fmt"
 	"math"
+	"sync"
 	"time"
 )
 
@@ -62,7 +63,9 @@ func (r *Retrier) calculateBackoffDelay(attempt int) time.Duration {
 }
 
 // CircuitBreaker implements the circuit breaker pattern to prevent cascading failures.
+// It is safe for concurrent use.
 type CircuitBreaker struct {
+	mu           sync.Mutex
 	state        string
 	failureCount int
 	threshold    int
@@ -76,10 +79,13 @@ func NewCircuitBreaker(threshold int, timeout time.Duration) *CircuitBreaker {
 }
 
 // IsOpen returns true if the circuit is currently open (rejecting requests).
+// Once the timeout has passed the circuit is half-open and lets a trial request through.
 func (cb *CircuitBreaker) IsOpen() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
 	if cb.state == "open" && time.Since(cb.lastFailTime) > cb.timeout {
 		cb.state = "half-open"
-		cb.failureCount = 0
 		return false
 	}
 	return cb.state == "open"
@@ -87,20 +93,30 @@ func (cb *CircuitBreaker) IsOpen() bool {
 
 // RecordSuccess resets the circuit to closed state after a successful operation.
 func (cb *CircuitBreaker) RecordSuccess() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
 	cb.failureCount = 0
 	cb.state = "closed"
 }
 
 // RecordFailure increments the failure count and opens the circuit if threshold is reached.
+// A failed trial request in the half-open state opens the circuit again immediately.
 func (cb *CircuitBreaker) RecordFailure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
 	cb.failureCount++
 	cb.lastFailTime = time.Now()
-	if cb.failureCount >= cb.threshold {
+	if cb.failureCount >= cb.threshold || cb.state == "half-open" {
 		cb.state = "open"
 	}
 }
 
 // State returns the current state of the circuit breaker ("open", "closed", or "half-open").
 func (cb *CircuitBreaker) State() string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
 	return cb.state
 }
@@ -76,3 +76,23 @@ func TestCircuitBreakerReset(t *testing.T) {
 		t.Error("Circuit should be closed after success")
 	}
 }
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	cb := NewCircuitBreaker(2, 20*time.Millisecond)
+
+	cb.RecordFailure()
+	cb.RecordFailure()
+	time.Sleep(30 * time.Millisecond)
+
+	if cb.IsOpen() {
+		t.Fatal("Circuit should let a trial request through after the timeout")
+	}
+	if cb.State() != "half-open" {
+		t.Errorf("Expected half-open, got %s", cb.State())
+	}
+
+	cb.RecordFailure()
+	if !cb.IsOpen() {
+		t.Error("Circuit should open again when the trial request fails")
+	}
+}
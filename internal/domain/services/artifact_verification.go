@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	errs "github.com/ochairo/wand/internal/domain/errors"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+)
+
+// artifactVerifier fetches the checksum and signature files for a downloaded
+// artifact and checks them against the formula's configuration
+type artifactVerifier struct {
+	downloader interfaces.Downloader
+	verifier   interfaces.Verifier
+}
+
+// artifact describes a downloaded release artifact
+type artifact struct {
+	formula  *entities.Formula
+	config   *entities.PlatformConfig
+	version  *entities.Version
+	platform *entities.Platform
+	url      string // expanded download URL
+	path     string // local download path
+	dir      string // scratch directory for checksum and signature files
+}
+
+// assetName returns the file name the artifact was published under
+func (a *artifact) assetName() string {
+	if parsed, err := url.Parse(a.url); err == nil {
+		return path.Base(parsed.Path)
+	}
+	return path.Base(a.url)
+}
+
+// checksumPath returns where the checksum file is stored
+func (a *artifact) checksumPath() string {
+	return filepath.Join(a.dir, "checksums")
+}
+
+// verifyPinned checks the artifact against the sha256 pinned in the formula for
+// this version. It returns false if the formula pins no digest for the version.
+func (v *artifactVerifier) verifyPinned(a *artifact) (bool, error) {
+	pinned := a.config.PinnedSHA256(a.version)
+	if pinned == "" {
+		return false, nil
+	}
+
+	actual, err := v.verifier.Digest(a.path, "sha256")
+	if err != nil {
+		return true, errs.Wrap(errs.ErrChecksumMismatch, fmt.Sprintf("Failed to hash %s", a.assetName()), err)
+	}
+	if !strings.EqualFold(actual, pinned) {
+		return true, errs.NewWithDetails(errs.ErrChecksumMismatch,
+			fmt.Sprintf("Artifact does not match the sha256 pinned in the %q formula", a.formula.Name),
+			fmt.Sprintf("version: %s, platform: %s, expected: %s, got: %s", a.version, a.platform, pinned, actual))
+	}
+
+	return true, nil
+}
+
+// verifyChecksum downloads the checksum file and checks the artifact against it.
+// It returns false if the formula declares no checksum for the platform.
+func (v *artifactVerifier) verifyChecksum(ctx context.Context, a *artifact) (bool, error) {
+	if a.config.ChecksumURL == "" {
+		return false, nil
+	}
+
+	checksumURL := buildDownloadURL(a.config.ChecksumURL, a.version, a.platform)
+	if err := v.downloader.DownloadContext(ctx, checksumURL, a.checksumPath(), nil); err != nil {
+		return true, errs.Wrap(errs.ErrDownloadFailed, fmt.Sprintf("Failed to download checksum file %s", checksumURL), err)
+	}
+
+	if err := v.verifier.VerifyChecksum(a.path, a.assetName(), a.checksumPath(), a.config.ChecksumAlgorithm); err != nil {
+		return true, errs.Wrap(errs.ErrChecksumMismatch, fmt.Sprintf("Checksum verification failed for %q", a.formula.Name), err)
+	}
+
+	return true, nil
+}
+
+// verifySignature downloads the detached signature and checks it with the
+// pinned key. It returns false if the formula declares no signature.
+// verifyChecksum must run first when the signature covers the checksum file.
+func (v *artifactVerifier) verifySignature(ctx context.Context, a *artifact) (bool, error) {
+	signature := a.formula.Signature
+	if signature == nil {
+		if a.config.SignatureURL != "" {
+			return true, errs.NewWithDetails(errs.ErrConfigInvalid, "signature_url is set but the formula pins no signature key", fmt.Sprintf("package: %q", a.formula.Name))
+		}
+		return false, nil
+	}
+
+	if a.config.SignatureURL == "" {
+		return true, errs.NewWithDetails(errs.ErrSignatureInvalid, "Formula requires a signature but none is published for this platform", fmt.Sprintf("package: %q, platform: %s", a.formula.Name, a.platform))
+	}
+
+	signed := a.path
+	if signature.SignsChecksums() {
+		if a.config.ChecksumURL == "" {
+			return true, errs.NewWithDetails(errs.ErrConfigInvalid, "Signature covers checksums but no checksum_url is set", fmt.Sprintf("package: %q", a.formula.Name))
+		}
+		signed = a.checksumPath()
+	}
+
+	signatureURL := buildDownloadURL(a.config.SignatureURL, a.version, a.platform)
+	signaturePath := filepath.Join(a.dir, "signature")
+	if err := v.downloader.DownloadContext(ctx, signatureURL, signaturePath, nil); err != nil {
+		return true, errs.Wrap(errs.ErrDownloadFailed, fmt.Sprintf("Failed to download signature %s", signatureURL), err)
+	}
+
+	if err := v.verifier.VerifySignature(signed, signaturePath, signature); err != nil {
+		return true, errs.Wrap(errs.ErrSignatureInvalid, fmt.Sprintf("%s signature verification failed for %q", signature.Type, a.formula.Name), err)
+	}
+
+	return true, nil
+}
+
+// verifyTrusted compares the artifact with the digest recorded in the lock
+// the first time its URL was installed, then records the current digest.
+// A changed digest is rejected unless trustNew is set.
+func (v *artifactVerifier) verifyTrusted(a *artifact, lock *entities.ChecksumLock, trustNew bool) error {
+	actual, err := v.verifier.Digest(a.path, "sha256")
+	if err != nil {
+		return errs.Wrap(errs.ErrChecksumMismatch, fmt.Sprintf("Failed to hash %s", a.assetName()), err)
+	}
+
+	if !lock.Matches(a.url, actual) && !trustNew {
+		recorded, _ := lock.Get(a.url)
+		return errs.NewWithDetails(errs.ErrChecksumMismatch,
+			fmt.Sprintf("Artifact for %s@%s changed since it was first installed", a.formula.Name, a.version),
+			fmt.Sprintf("url: %s, recorded: %s (%s), got: %s; reinstall with --trust-new-checksum if the change is expected",
+				a.url, recorded.SHA256, recorded.RecordedAt.Format("2006-01-02"), actual))
+	}
+
+	lock.Record(a.url, a.formula.Name, a.version.String(), actual)
+	return nil
+}
+
+// verify runs the pinned digest, checksum and signature checks
+func (v *artifactVerifier) verify(ctx context.Context, a *artifact) error {
+	if _, err := v.verifyPinned(a); err != nil {
+		return err
+	}
+	if _, err := v.verifyChecksum(ctx, a); err != nil {
+		return err
+	}
+	_, err := v.verifySignature(ctx, a)
+	return err
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+)
+
+// formulaBinDir returns the directory under dir that holds a formula's
+// binaries: its bin_path, or bin/
+func formulaBinDir(formula *entities.Formula, dir string) string {
+	if formula.BinPath != "" {
+		return filepath.Join(dir, filepath.FromSlash(formula.BinPath))
+	}
+	return filepath.Join(dir, "bin")
+}
+
+// topLevelEntries returns the files and directories directly inside dir
+func topLevelEntries(fs interfaces.FileSystem, dir string) []string {
+	var entries []string
+	_ = fs.Walk(dir, func(path string, isDir bool, err error) error {
+		if err != nil || path == dir {
+			return nil
+		}
+		entries = append(entries, path)
+		if isDir {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return entries
+}
+
+// stripComponents removes n leading directories from an extracted tree, like
+// tar --strip-components. Each level must be a single top-level directory.
+func stripComponents(fs interfaces.FileSystem, dir string, n int) error {
+	for i := 0; i < n; i++ {
+		entries := topLevelEntries(fs, dir)
+		if len(entries) != 1 || !fs.IsDir(entries[0]) {
+			return fmt.Errorf("cannot strip %d leading component(s): %s does not contain a single directory", n, dir)
+		}
+
+		// Move the directory aside first, as it may contain an entry of the same name
+		staging := filepath.Join(dir, ".wand-strip")
+		if err := fs.Rename(entries[0], staging); err != nil {
+			return err
+		}
+		for _, entry := range topLevelEntries(fs, staging) {
+			if err := fs.Rename(entry, filepath.Join(dir, filepath.Base(entry))); err != nil {
+				return err
+			}
+		}
+		if err := fs.Remove(staging); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// locateBinaries finds each binary in an installed or extracted tree. With a
+// bin_path only that directory is searched; otherwise the binary is looked for
+// at its declared path, under bin/, and then anywhere in the tree, shallowest
+// first. Names may be globs. It returns the paths found, keyed by name, and
+// the names that were not found.
+func locateBinaries(fs interfaces.FileSystem, formula *entities.Formula, dir string, names []string) (map[string]string, []string) {
+	var files []string
+	_ = fs.Walk(dir, func(p string, isDir bool, err error) error {
+		if err != nil || isDir {
+			return nil
+		}
+		if rel, relErr := filepath.Rel(dir, p); relErr == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.SliceStable(files, func(i, j int) bool {
+		return strings.Count(files[i], "/") < strings.Count(files[j], "/")
+	})
+
+	found := make(map[string]string)
+	var missing []string
+	for _, name := range names {
+		if match := matchBinary(formula, files, name); match != "" {
+			found[name] = filepath.Join(dir, filepath.FromSlash(match))
+		} else {
+			missing = append(missing, name)
+		}
+	}
+	return found, missing
+}
+
+// matchBinary returns the first of files, relative and sorted shallowest
+// first, that name refers to
+func matchBinary(formula *entities.Formula, files []string, name string) string {
+	if formula.BinPath != "" {
+		pattern := path.Join(path.Clean(filepath.ToSlash(formula.BinPath)), name)
+		for _, file := range files {
+			if ok, _ := path.Match(pattern, file); ok {
+				return file
+			}
+		}
+		return ""
+	}
+
+	for _, pattern := range []string{name, "bin/" + name} {
+		for _, file := range files {
+			if ok, _ := path.Match(pattern, file); ok {
+				return file
+			}
+		}
+	}
+
+	// Archives often wrap everything in a directory such as jq-1.7.1-linux-amd64/
+	depth := strings.Count(name, "/") + 1
+	for _, file := range files {
+		segments := strings.Split(file, "/")
+		if len(segments) <= depth {
+			continue
+		}
+		if ok, _ := path.Match(name, strings.Join(segments[len(segments)-depth:], "/")); ok {
+			return file
+		}
+	}
+	return ""
+}
@@ -0,0 +1,390 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"path/filepath"
+	"strings"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	errs "github.com/ochairo/wand/internal/domain/errors"
+)
+
+// desktopDataDir returns the per-user data directory desktop environments
+// read application entries and icons from
+func (s *InstallerService) desktopDataDir() string {
+	return filepath.Join(s.homeDir, ".local", "share")
+}
+
+// appLauncher returns the name of the shim that launches an application: its
+// first binary, or the package name
+func appLauncher(formula *entities.Formula) string {
+	if len(formula.Binaries) > 0 {
+		return formula.Binaries[0]
+	}
+	return formula.Name
+}
+
+// integrateLinuxApp integrates an installed Linux application with the
+// desktop: its desktop entry, rewritten to run the launcher shim or generated
+// if it ships none, goes to ~/.local/share/applications and its icon into the
+// hicolor theme. The shim runs the version wand resolves, so the entry
+// follows wand switch. appImage is the installed AppImage, if that is
+// what the download was. It returns the executables, keyed by launcher name,
+// and the files installed outside appsDir.
+func (s *InstallerService) integrateLinuxApp(formula *entities.Formula, config *entities.PlatformConfig, appsDir, appImage string) (map[string]string, []string, error) {
+	// Read the shipped entry first: without declared binaries, its Exec names the executable
+	var entry []byte
+	if config.DesktopFile != "" {
+		data, err := s.fs.ReadFile(filepath.Join(appsDir, filepath.FromSlash(config.DesktopFile)))
+		if err != nil {
+			return nil, nil, errs.Wrap(errs.ErrFileNotFound, "Failed to read desktop file", err)
+		}
+		entry = data
+	}
+
+	launcher := appLauncher(formula)
+	executables := map[string]string{launcher: appImage}
+	if appImage == "" {
+		var err error
+		if executables, err = s.appExecutables(formula, entry, appsDir); err != nil {
+			return nil, nil, err
+		}
+	}
+	for name, path := range executables {
+		if err := s.fs.Chmod(path, 0755); err != nil {
+			return nil, nil, errs.Wrap(errs.ErrPermissionDenied, fmt.Sprintf("Failed to make %s executable", name), err)
+		}
+	}
+
+	var files []string
+	icon := ""
+	if config.IconFile != "" {
+		iconPath, err := s.installIcon(filepath.Join(appsDir, filepath.FromSlash(config.IconFile)), formula.Name)
+		if err != nil {
+			return nil, nil, errs.NewWithDetails(errs.ErrInstallationFailed, fmt.Sprintf("Failed to install icon of %s", formula.Name), err.Error())
+		}
+		files = append(files, iconPath)
+		icon = formula.Name
+	}
+
+	shim := filepath.Join(s.wandDir, "shims", launcher)
+	data := generateDesktopEntry(formula, shim, icon)
+	if entry != nil {
+		data = rewriteDesktopEntry(entry, shim, icon)
+	}
+	if err := validateDesktopEntry(data); err != nil {
+		return nil, nil, errs.NewWithDetails(errs.ErrInstallationFailed, fmt.Sprintf("Invalid desktop file %s", config.DesktopFile), err.Error())
+	}
+
+	desktopDir := filepath.Join(s.desktopDataDir(), "applications")
+	if err := s.fs.MkdirAll(desktopDir, 0755); err != nil {
+		return nil, nil, errs.Wrap(errs.ErrPermissionDenied, "Failed to create desktop directory", err)
+	}
+	desktopPath := filepath.Join(desktopDir, formula.Name+".desktop")
+	if err := s.fs.WriteFile(desktopPath, data, 0644); err != nil {
+		return nil, nil, errs.Wrap(errs.ErrPermissionDenied, "Failed to install desktop file", err)
+	}
+
+	return executables, append(files, desktopPath), nil
+}
+
+// appExecutables finds the executables of an extracted application, keyed
+// by launcher name: the formula's binaries or, if it declares none, the
+// command its desktop entry runs, launched under the package name
+func (s *InstallerService) appExecutables(formula *entities.Formula, entry []byte, appsDir string) (map[string]string, error) {
+	if len(formula.Binaries) > 0 {
+		executables, missing := locateBinaries(s.fs, formula, appsDir, formula.Binaries)
+		if len(missing) > 0 {
+			return nil, errs.NewWithDetails(errs.ErrBinaryNotFound,
+				fmt.Sprintf("Executables of %s not found: %s", formula.Name, strings.Join(missing, ", ")),
+				fmt.Sprintf("looked in %s; check the formula's binaries and bin_path", appsDir))
+		}
+		return executables, nil
+	}
+
+	command := filepath.Base(desktopExecCommand(entry))
+	if command == "" || command == "." || command == "/" {
+		command = formula.Name
+	}
+	located, missing := locateBinaries(s.fs, formula, appsDir, []string{command})
+	if len(missing) > 0 {
+		return nil, errs.NewWithDetails(errs.ErrBinaryNotFound,
+			fmt.Sprintf("Executable %s of %s not found", command, formula.Name),
+			fmt.Sprintf("looked in %s; declare the executable in the formula's binaries", appsDir))
+	}
+	return map[string]string{formula.Name: located[command]}, nil
+}
+
+// installIcon copies an application icon into the user's hicolor theme,
+// under the size read from a PNG's header or as scalable for SVG, and
+// returns where it went
+func (s *InstallerService) installIcon(src, name string) (string, error) {
+	data, err := s.fs.ReadFile(src)
+	if err != nil {
+		return "", err
+	}
+
+	ext := strings.ToLower(filepath.Ext(src))
+	var size string
+	switch ext {
+	case ".png":
+		config, err := png.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return "", fmt.Errorf("invalid PNG icon %s: %w", src, err)
+		}
+		size = fmt.Sprintf("%dx%d", config.Width, config.Height)
+	case ".svg", ".svgz":
+		size = "scalable"
+	default:
+		return "", fmt.Errorf("unsupported icon format %q (expected one of: %s)", ext, strings.Join(entities.IconExtensions, ", "))
+	}
+
+	dir := filepath.Join(s.desktopDataDir(), "icons", "hicolor", size, "apps")
+	if err := s.fs.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	dest := filepath.Join(dir, name+ext)
+	return dest, s.fs.WriteFile(dest, data, 0644)
+}
+
+// removeIntegrationFiles removes the desktop entries, icons and links a
+// package installed outside its install directory
+func (s *InstallerService) removeIntegrationFiles(pkg *entities.Package) error {
+	for _, path := range pkg.IntegrationFiles {
+		if err := s.fs.Remove(path); err != nil && s.fs.Exists(path) {
+			return errs.Wrap(errs.ErrPermissionDenied, fmt.Sprintf("Failed to remove %s", path), err)
+		}
+	}
+	return nil
+}
+
+// releaseIntegrationFiles handles the files a removed version installed
+// outside its install directory, which all versions of a package share: they
+// are removed with the last version, and links into the removed version are
+// pointed at the version that remains global, or the newest
+func (s *InstallerService) releaseIntegrationFiles(registry *entities.Registry, pkg *entities.Package) error {
+	entry, remains := registry.Packages[pkg.Name]
+	if !remains {
+		return s.removeIntegrationFiles(pkg)
+	}
+
+	var remaining *entities.Package
+	if global, ok := registry.GetGlobalVersion(pkg.Name); ok {
+		remaining = entry.Versions[global]
+	}
+	if remaining == nil {
+		for _, other := range entry.Versions {
+			if remaining == nil || other.Version.GreaterThan(remaining.Version) {
+				remaining = other
+			}
+		}
+	}
+
+	for _, path := range pkg.IntegrationFiles {
+		target, err := s.fs.ReadSymlink(path)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(pkg.InstallPath, target)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		_ = s.fs.Remove(path)
+		if err := s.fs.Symlink(filepath.Join(remaining.InstallPath, rel), path); err != nil {
+			return errs.Wrap(errs.ErrPermissionDenied, fmt.Sprintf("Failed to relink %s", path), err)
+		}
+	}
+	return nil
+}
+
+// desktopEntryGroup returns true for the groups of a desktop entry whose
+// Exec and Icon keys launch the application
+func desktopEntryGroup(group string) bool {
+	return group == "Desktop Entry" || strings.HasPrefix(group, "Desktop Action ")
+}
+
+// rewriteDesktopEntry points a desktop entry's Exec and TryExec keys, and
+// those of its actions, at the installed executable, keeping arguments such
+// as %U. A non-empty icon replaces the Icon key.
+func rewriteDesktopEntry(data []byte, executable, icon string) []byte {
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	out := make([]string, 0, len(lines)+1)
+	group := ""
+	iconSet := false
+	for _, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			group = trimmed[1 : len(trimmed)-1]
+			out = append(out, line)
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || strings.HasPrefix(trimmed, "#") || !desktopEntryGroup(group) {
+			out = append(out, line)
+			continue
+		}
+		switch {
+		case key == "Exec":
+			_, args := splitExecCommand(strings.TrimSpace(value))
+			line = "Exec=" + quoteExecArg(executable) + args
+		case key == "TryExec":
+			line = "TryExec=" + escapeDesktopString(executable)
+		case key == "Icon" && icon != "":
+			line = "Icon=" + icon
+			iconSet = iconSet || group == "Desktop Entry"
+		}
+		out = append(out, line)
+	}
+
+	if icon != "" && !iconSet {
+		for i, line := range out {
+			if strings.TrimSpace(line) == "[Desktop Entry]" {
+				out = append(out[:i+1], append([]string{"Icon=" + icon}, out[i+1:]...)...)
+				break
+			}
+		}
+	}
+	return []byte(strings.Join(out, "\n") + "\n")
+}
+
+// generateDesktopEntry writes a desktop entry for an application that ships
+// none
+func generateDesktopEntry(formula *entities.Formula, executable, icon string) []byte {
+	name := strings.TrimSuffix(formula.AppName, ".app")
+	if name == "" {
+		name = formula.Name
+	}
+
+	var b strings.Builder
+	b.WriteString("[Desktop Entry]\nType=Application\n")
+	b.WriteString("Name=" + escapeDesktopString(name) + "\n")
+	if formula.Description != "" {
+		b.WriteString("Comment=" + escapeDesktopString(formula.Description) + "\n")
+	}
+	b.WriteString("Exec=" + quoteExecArg(executable) + " %U\n")
+	b.WriteString("TryExec=" + escapeDesktopString(executable) + "\n")
+	if icon != "" {
+		b.WriteString("Icon=" + icon + "\n")
+	}
+	b.WriteString("Terminal=false\n")
+	return []byte(b.String())
+}
+
+// validateDesktopEntry checks that data is a desktop entry desktop
+// environments will show: a [Desktop Entry] group first, no duplicate keys,
+// and the Type, Name and, for applications, Exec keys
+func validateDesktopEntry(data []byte) error {
+	groups := make(map[string]map[string]string)
+	var group string
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			group = line[1 : len(line)-1]
+			if len(groups) == 0 && group != "Desktop Entry" {
+				return fmt.Errorf("line %d: the first group must be [Desktop Entry], not [%s]", i+1, group)
+			}
+			if groups[group] != nil {
+				return fmt.Errorf("line %d: duplicate group [%s]", i+1, group)
+			}
+			groups[group] = make(map[string]string)
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		switch {
+		case !ok || key == "":
+			return fmt.Errorf("line %d: expected a group header or key=value, got %q", i+1, line)
+		case group == "":
+			return fmt.Errorf("line %d: key %s before the [Desktop Entry] group", i+1, key)
+		}
+		if _, exists := groups[group][key]; exists {
+			return fmt.Errorf("line %d: duplicate key %s in [%s]", i+1, key, group)
+		}
+		groups[group][key] = strings.TrimSpace(value)
+	}
+
+	entry := groups["Desktop Entry"]
+	if entry == nil {
+		return fmt.Errorf("no [Desktop Entry] group")
+	}
+	for _, key := range []string{"Type", "Name"} {
+		if entry[key] == "" {
+			return fmt.Errorf("[Desktop Entry] has no %s key", key)
+		}
+	}
+	if entry["Type"] == "Application" && entry["Exec"] == "" && entry["DBusActivatable"] != "true" {
+		return fmt.Errorf("[Desktop Entry] has no Exec key")
+	}
+	return nil
+}
+
+// desktopExecCommand returns the command the Exec key of a desktop entry's
+// [Desktop Entry] group runs, or "" if it has none
+func desktopExecCommand(data []byte) string {
+	group := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			group = line[1 : len(line)-1]
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok && group == "Desktop Entry" && strings.TrimSpace(key) == "Exec" {
+			command, _ := splitExecCommand(strings.TrimSpace(value))
+			return command
+		}
+	}
+	return ""
+}
+
+// splitExecCommand splits an Exec value into its command, unquoted, and the
+// arguments that follow, with their leading space
+func splitExecCommand(value string) (command, args string) {
+	if strings.HasPrefix(value, `"`) {
+		for i := 1; i < len(value); i++ {
+			switch value[i] {
+			case '\\':
+				i++
+			case '"':
+				return execUnquoter.Replace(value[1:i]), value[i+1:]
+			}
+		}
+		return value, ""
+	}
+	if i := strings.IndexAny(value, " \t"); i != -1 {
+		return value[:i], value[i:]
+	}
+	return value, ""
+}
+
+// execReserved lists the characters that make an Exec argument need quoting
+const execReserved = " \t\n\"'\\><~|&;$*?#()`"
+
+var (
+	execQuoter   = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`", `$`, `\$`)
+	execUnquoter = strings.NewReplacer(`\\\\`, `\`, `\\`, `\`, `\"`, `"`, "\\`", "`", `\$`, `$`)
+)
+
+// quoteExecArg quotes an argument for an Exec key, following the desktop
+// entry specification: reserved characters need double quotes, a literal %
+// is doubled, and the result is escaped as a string value
+func quoteExecArg(arg string) string {
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	if !strings.ContainsAny(arg, execReserved) {
+		return arg
+	}
+	return strings.ReplaceAll(`"`+execQuoter.Replace(arg)+`"`, `\`, `\\`)
+}
+
+// escapeDesktopString escapes a string value for a desktop entry
+func escapeDesktopString(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`).Replace(value)
+}
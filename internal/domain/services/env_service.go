@@ -0,0 +1,116 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ochairo/wand/internal/domain/interfaces"
+)
+
+// Environment is the environment wand sets for a working directory
+type Environment struct {
+	Project string            // directory containing the .wandrc, "" outside a project
+	Vars    map[string]string // variable name -> value
+	Path    []string          // directories to put in front of PATH
+}
+
+// IsEmpty returns true if the environment sets nothing
+func (e *Environment) IsEmpty() bool {
+	return len(e.Vars) == 0 && len(e.Path) == 0
+}
+
+// EnvService computes the environment for a working directory
+type EnvService struct {
+	registryRepo interfaces.RegistryRepository
+	wandrcRepo   interfaces.WandRCRepository
+	formulaRepo  interfaces.FormulaRepository
+	shimService  *ShimService
+}
+
+// NewEnvService creates a new env service
+func NewEnvService(
+	registryRepo interfaces.RegistryRepository,
+	wandrcRepo interfaces.WandRCRepository,
+	formulaRepo interfaces.FormulaRepository,
+	shimService *ShimService,
+) *EnvService {
+	return &EnvService{
+		registryRepo: registryRepo,
+		wandrcRepo:   wandrcRepo,
+		formulaRepo:  formulaRepo,
+		shimService:  shimService,
+	}
+}
+
+// Environment returns the environment for dir. Inside a project it sets:
+//
+//	WAND_PROJECT   the directory containing the .wandrc
+//	WAND_VERSIONS  the resolved versions, e.g. "jq@1.7.1 node@20.10.0", or
+//	               the pinned spec for versions not installed yet
+//
+// Everywhere, it includes the runtime env and PATH directories that formulas
+// declare, for the version of each installed package that dir resolves to.
+func (s *EnvService) Environment(dir string) (*Environment, error) {
+	env := &Environment{Vars: make(map[string]string)}
+
+	if wandrc, wandrcPath, err := s.wandrcRepo.FindInPath(dir); err == nil && wandrc != nil {
+		env.Project = filepath.Dir(wandrcPath)
+
+		// Pins inherited from parent .wandrc files count too
+		names, err := s.shimService.ProjectPackages(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		versions := make([]string, 0, len(names))
+		for _, name := range names {
+			res, err := s.shimService.Resolve(name, dir)
+			switch {
+			case err == nil:
+				versions = append(versions, name+"@"+res.Version)
+			case res.Spec != "":
+				// Not installed yet: show what the project asks for
+				versions = append(versions, name+"@"+res.Spec)
+			default:
+				return nil, fmt.Errorf("failed to resolve %s: %w", name, err)
+			}
+		}
+
+		env.Vars["WAND_PROJECT"] = env.Project
+		env.Vars["WAND_VERSIONS"] = strings.Join(versions, " ")
+	}
+
+	registry, err := s.registryRepo.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load registry: %w", err)
+	}
+
+	entries := registry.ListAllPackages()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	for _, entry := range entries {
+		formula, err := s.formulaRepo.GetFormula(entry.Name)
+		if err != nil || (len(formula.Env) == 0 && len(formula.Path) == 0) {
+			continue
+		}
+
+		// Packages without a version here, or whose version is not
+		// installed, contribute nothing
+		version, err := s.shimService.ResolveVersion(entry.Name, dir)
+		if err != nil {
+			continue
+		}
+		pkg, exists := registry.GetPackage(entry.Name, version)
+		if !exists || pkg.InstallPath == "" {
+			continue
+		}
+
+		for name, value := range formula.RuntimeEnv(pkg.InstallPath) {
+			env.Vars[name] = value
+		}
+		env.Path = append(env.Path, formula.RuntimePath(pkg.InstallPath)...)
+	}
+
+	return env, nil
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	errs "github.com/ochairo/wand/internal/domain/errors"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+)
+
+// LintResult is the outcome of a single formula check
+type LintResult struct {
+	Check   string // url, download, checksum, signature, extract, binary or test
+	Target  string // what was checked, e.g. "linux/amd64 download_url"
+	Passed  bool
+	Skipped bool
+	Message string
+}
+
+// LintReport collects the results of linting one formula version
+type LintReport struct {
+	Formula string
+	Version string
+	Results []LintResult
+}
+
+// Failed returns true if any check failed
+func (r *LintReport) Failed() bool {
+	for _, result := range r.Results {
+		if !result.Passed && !result.Skipped {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *LintReport) pass(check, target, format string, args ...interface{}) {
+	r.Results = append(r.Results, LintResult{Check: check, Target: target, Passed: true, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *LintReport) fail(check, target, format string, args ...interface{}) {
+	r.Results = append(r.Results, LintResult{Check: check, Target: target, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *LintReport) skip(check, target, format string, args ...interface{}) {
+	r.Results = append(r.Results, LintResult{Check: check, Target: target, Skipped: true, Message: fmt.Sprintf(format, args...)})
+}
+
+// FormulaLinter checks a formula against the artifacts it points at
+type FormulaLinter struct {
+	formulaRepo   interfaces.FormulaRepository
+	httpChecker   interfaces.HTTPChecker
+	downloader    interfaces.Downloader
+	verifier      interfaces.Verifier
+	extractor     interfaces.Extractor
+	fs            interfaces.FileSystem
+	shellExecutor interfaces.ShellExecutor
+	versionSvc    *VersionService
+	workDir       string
+}
+
+// NewFormulaLinter creates a new formula linter
+func NewFormulaLinter(
+	formulaRepo interfaces.FormulaRepository,
+	httpChecker interfaces.HTTPChecker,
+	downloader interfaces.Downloader,
+	verifier interfaces.Verifier,
+	extractor interfaces.Extractor,
+	fs interfaces.FileSystem,
+	shellExecutor interfaces.ShellExecutor,
+	versionSvc *VersionService,
+	workDir string,
+) *FormulaLinter {
+	return &FormulaLinter{
+		formulaRepo:   formulaRepo,
+		httpChecker:   httpChecker,
+		downloader:    downloader,
+		verifier:      verifier,
+		extractor:     extractor,
+		fs:            fs,
+		shellExecutor: shellExecutor,
+		versionSvc:    versionSvc,
+		workDir:       workDir,
+	}
+}
+
+// Lint expands the formula's URLs for every platform and HEAD-checks them,
+// then downloads, verifies and extracts the current platform's artifact,
+// confirms the declared binaries exist and runs the formula's test command.
+func (l *FormulaLinter) Lint(packageName, versionStr string) (*LintReport, error) {
+	return l.LintContext(context.Background(), packageName, versionStr)
+}
+
+// LintContext is Lint that stops when ctx is done, failing with ErrCancelled,
+// or ErrTimeout if ctx's deadline passed
+func (l *FormulaLinter) LintContext(ctx context.Context, packageName, versionStr string) (*LintReport, error) {
+	formula, err := l.formulaRepo.GetFormula(packageName)
+	if err != nil {
+		return nil, errs.New(errs.ErrPackageNotFound, fmt.Sprintf("Formula not found for package %q", packageName))
+	}
+
+	version, err := l.resolveVersion(ctx, packageName, versionStr)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &LintReport{Formula: formula.Name, Version: version.String()}
+
+	l.checkURLs(report, formula, version)
+
+	platform := entities.CurrentPlatform()
+	config := formula.GetPlatformConfigFor(platform)
+	if config == nil {
+		report.skip("download", platform.String(), "no configuration for the current platform")
+		return report, nil
+	}
+
+	tmpDir := filepath.Join(l.workDir, "lint-"+formula.Name+"-"+version.String())
+	if err := l.fs.MkdirAll(tmpDir, 0755); err != nil {
+		return nil, errs.Wrap(errs.ErrPermissionDenied, "Failed to create temp directory", err)
+	}
+	defer func() { _ = l.fs.RemoveAll(tmpDir) }()
+
+	downloadURL := buildDownloadURL(config.DownloadURL, version, platform)
+	downloadPath := artifactPath(tmpDir, downloadURL)
+	if err := l.downloader.DownloadContext(ctx, downloadURL, downloadPath, nil); err != nil {
+		if ctx.Err() != nil {
+			return nil, interrupted(ctx, fmt.Sprintf("Lint of %s", formula.Name), err)
+		}
+		report.fail("download", platform.String(), "%v", err)
+		return report, nil
+	}
+	report.pass("download", platform.String(), "%s", downloadURL)
+
+	verifier := &artifactVerifier{downloader: l.downloader, verifier: l.verifier}
+	target := &artifact{
+		formula:  formula,
+		config:   config,
+		version:  version,
+		platform: platform,
+		url:      downloadURL,
+		path:     downloadPath,
+		dir:      tmpDir,
+	}
+
+	checked, err := verifier.verifyPinned(target)
+	switch {
+	case err != nil:
+		report.fail("checksum", platform.String(), "%v", err)
+		return report, nil
+	case checked:
+		report.pass("checksum", platform.String(), "matches pinned sha256 for %s", version)
+	}
+
+	checked, err = verifier.verifyChecksum(ctx, target)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return nil, interrupted(ctx, fmt.Sprintf("Lint of %s", formula.Name), err)
+	case err != nil:
+		report.fail("checksum", platform.String(), "%v", err)
+		return report, nil
+	case checked:
+		report.pass("checksum", platform.String(), "matches %s", buildDownloadURL(config.ChecksumURL, version, platform))
+	default:
+		report.skip("checksum", platform.String(), "no checksum_url")
+	}
+
+	checked, err = verifier.verifySignature(ctx, target)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return nil, interrupted(ctx, fmt.Sprintf("Lint of %s", formula.Name), err)
+	case err != nil:
+		report.fail("signature", platform.String(), "%v", err)
+		return report, nil
+	case checked:
+		report.pass("signature", platform.String(), "%s signature verified with pinned key", formula.Signature.Type)
+	}
+
+	if !formula.IsCLI() {
+		report.skip("binary", platform.String(), "%s formulas have no binaries to check", formula.Type)
+		return report, nil
+	}
+
+	extractDir := filepath.Join(tmpDir, "extract")
+	if err := l.unpack(ctx, formula, downloadPath, extractDir); err != nil {
+		if ctx.Err() != nil {
+			return nil, interrupted(ctx, fmt.Sprintf("Lint of %s", formula.Name), err)
+		}
+		report.fail("extract", platform.String(), "%v", err)
+		return report, nil
+	}
+
+	binaries := l.checkBinaries(report, formula, extractDir)
+	l.runTest(report, formula, extractDir, binaries)
+
+	return report, nil
+}
+
+// resolveVersion uses an explicit version as-is so formulas can be linted
+// before the release is visible, falling back to the latest release
+func (l *FormulaLinter) resolveVersion(ctx context.Context, packageName, versionStr string) (*entities.Version, error) {
+	if versionStr == "" || versionStr == "latest" {
+		return l.versionSvc.GetLatestVersionContext(ctx, packageName)
+	}
+
+	version, err := entities.NewVersion(versionStr)
+	if err != nil {
+		return nil, errs.New(errs.ErrInvalidVersion, fmt.Sprintf("Invalid version: %q", versionStr))
+	}
+	return version, nil
+}
+
+// checkURLs HEAD-checks the download and checksum URLs of every os/arch entry
+// and of the source archive
+func (l *FormulaLinter) checkURLs(report *LintReport, formula *entities.Formula, version *entities.Version) {
+	osNames := make([]string, 0, len(formula.Platforms))
+	for osName := range formula.Platforms {
+		osNames = append(osNames, osName)
+	}
+	sort.Strings(osNames)
+
+	for _, osName := range osNames {
+		archConfig := formula.Platforms[osName]
+		arches := make([]string, 0, len(archConfig))
+		for arch := range archConfig {
+			arches = append(arches, arch)
+		}
+		sort.Strings(arches)
+
+		for _, arch := range arches {
+			config := archConfig[arch]
+			if config == nil {
+				continue
+			}
+			platform := &entities.Platform{OS: osName, Arch: arch}
+			l.checkURL(report, platform.String()+" download_url", buildDownloadURL(config.DownloadURL, version, platform))
+			if config.ChecksumURL != "" {
+				l.checkURL(report, platform.String()+" checksum_url", buildDownloadURL(config.ChecksumURL, version, platform))
+			}
+			if config.SignatureURL != "" {
+				l.checkURL(report, platform.String()+" signature_url", buildDownloadURL(config.SignatureURL, version, platform))
+			}
+		}
+	}
+
+	if formula.Source != nil {
+		platform := entities.CurrentPlatform()
+		l.checkURL(report, "source url", buildDownloadURL(formula.Source.URL, version, platform))
+		if formula.Source.ChecksumURL != "" {
+			l.checkURL(report, "source checksum_url", buildDownloadURL(formula.Source.ChecksumURL, version, platform))
+		}
+	}
+}
+
+func (l *FormulaLinter) checkURL(report *LintReport, target, url string) {
+	status, err := l.httpChecker.Head(url)
+	switch {
+	case err != nil:
+		report.fail("url", target, "%v", err)
+	case status < http.StatusOK || status >= http.StatusMultipleChoices:
+		report.fail("url", target, "%s returned %d", url, status)
+	default:
+		report.pass("url", target, "%s", url)
+	}
+}
+
+// unpack extracts an archive, or places a single-file download in the bin
+// directory, the same way the installer does
+func (l *FormulaLinter) unpack(ctx context.Context, formula *entities.Formula, downloadPath, destDir string) error {
+	return unpackArtifact(ctx, l.fs, l.extractor, formula, downloadPath, destDir)
+}
+
+// checkBinaries confirms every declared binary is found in the extracted
+// tree the way the installer finds it. Binary entries may be globs.
+// It returns the matched file paths keyed by binary entry.
+func (l *FormulaLinter) checkBinaries(report *LintReport, formula *entities.Formula, dir string) map[string]string {
+	found, _ := locateBinaries(l.fs, formula, dir, formula.Binaries)
+	for _, binary := range formula.Binaries {
+		match, ok := found[binary]
+		if !ok {
+			report.fail("binary", binary, "not found in extracted artifact")
+			continue
+		}
+		rel, _ := filepath.Rel(dir, match)
+		report.pass("binary", binary, "%s", filepath.ToSlash(rel))
+	}
+
+	return found
+}
+
+// runTest runs the formula's test command, resolving its program name to an
+// extracted binary when it matches one
+func (l *FormulaLinter) runTest(report *LintReport, formula *entities.Formula, dir string, binaries map[string]string) {
+	fields := strings.Fields(formula.Test)
+	if len(fields) == 0 {
+		report.skip("test", formula.Name, "formula declares no test command")
+		return
+	}
+
+	program := fields[0]
+	for binary, file := range binaries {
+		if path.Base(binary) == program || filepath.Base(file) == program {
+			program = file
+			break
+		}
+	}
+
+	output, err := l.shellExecutor.ExecuteInDir(dir, program, fields[1:]...)
+	if err != nil {
+		report.fail("test", formula.Test, "%v", err)
+		return
+	}
+
+	firstLine, _, _ := strings.Cut(output, "\n")
+	report.pass("test", formula.Test, "%s", firstLine)
+}
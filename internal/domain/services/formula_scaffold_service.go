@@ -0,0 +1,313 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	errs "github.com/ochairo/wand/internal/domain/errors"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+	"github.com/ochairo/wand/internal/domain/validation"
+)
+
+// ScaffoldResult is a generated formula with notes for the reviewer
+type ScaffoldResult struct {
+	Formula *entities.Formula
+	YAML    []byte
+	Version string             // release version the formula was generated from
+	Notes   []string           // assets that were skipped and other things to review
+	Issues  []validation.Issue // schema validation of the generated YAML
+}
+
+// FormulaScaffolder generates formulas from GitHub release assets
+type FormulaScaffolder struct {
+	githubClient interfaces.GitHubClient
+	validator    *validation.SchemaValidator
+}
+
+// NewFormulaScaffolder creates a new formula scaffolder
+func NewFormulaScaffolder(githubClient interfaces.GitHubClient) *FormulaScaffolder {
+	return &FormulaScaffolder{
+		githubClient: githubClient,
+		validator:    validation.NewSchemaValidator(),
+	}
+}
+
+// releaseAsset is a release asset matched to a platform
+type releaseAsset struct {
+	asset    interfaces.GitHubAsset
+	platform entities.Platform
+	score    int
+}
+
+// Scaffold builds a formula for owner/repo from the assets of its latest release
+func (s *FormulaScaffolder) Scaffold(repository string) (*ScaffoldResult, error) {
+	owner, repo, err := parseRepository(repository)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := s.githubClient.GetLatestRelease(owner, repo)
+	if err != nil {
+		return nil, releasesError(repository, err)
+	}
+
+	versionStr := tagVersion(release.TagName)
+	version, err := entities.NewVersion(versionStr)
+	if err != nil {
+		return nil, errs.NewWithDetails(errs.ErrInvalidVersion, "Cannot determine version from release tag", fmt.Sprintf("tag: %q", release.TagName))
+	}
+
+	name := strings.ToLower(repo)
+	formula := entities.NewFormula(name, entities.PackageTypeCLI)
+	formula.Description = "TODO: describe " + repo
+	formula.Homepage = "https://github.com/" + owner + "/" + repo
+	formula.Repository = owner + "/" + repo
+	formula.Tags = []string{"cli"}
+	formula.Binaries = []string{name}
+	formula.Test = name + " --version"
+
+	result := &ScaffoldResult{Formula: formula, Version: versionStr}
+
+	if version.ShortString() != versionStr {
+		result.Notes = append(result.Notes, fmt.Sprintf(
+			"{version} expands to %q for this release but the tag uses %q; check the URL templates", version.ShortString(), versionStr))
+	}
+
+	assetNames := make(map[string]bool, len(release.Assets))
+	for _, asset := range release.Assets {
+		assetNames[asset.Name] = true
+	}
+
+	chosen := make(map[string]releaseAsset)
+	manifestURL := ""
+	for _, asset := range release.Assets {
+		if isChecksumAsset(asset.Name) {
+			if isChecksumManifest(asset.Name) && manifestURL == "" {
+				manifestURL = asset.DownloadURL
+			}
+			continue
+		}
+		if isIgnoredAsset(asset.Name) {
+			continue
+		}
+
+		platforms, score := inferPlatforms(asset.Name)
+		if len(platforms) == 0 {
+			result.Notes = append(result.Notes, fmt.Sprintf("skipped %s: no supported os/arch in name", asset.Name))
+			continue
+		}
+
+		for _, platform := range platforms {
+			candidate := releaseAsset{asset: asset, platform: platform, score: score}
+			key := platform.String()
+			if current, ok := chosen[key]; !ok || candidate.score < current.score ||
+				(candidate.score == current.score && len(asset.Name) < len(current.asset.Name)) {
+				chosen[key] = candidate
+			}
+		}
+	}
+
+	if len(chosen) == 0 {
+		return nil, errs.NewWithDetails(errs.ErrArchNotSupported, "No release assets match a supported platform", fmt.Sprintf("repository: %s, tag: %s", repository, release.TagName))
+	}
+
+	keys := make([]string, 0, len(chosen))
+	for key := range chosen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		match := chosen[key]
+		config := &entities.PlatformConfig{
+			DownloadURL: templatizeVersion(match.asset.DownloadURL, versionStr),
+		}
+		for _, suffix := range []string{".sha256", ".sha256sum"} {
+			if assetNames[match.asset.Name+suffix] {
+				config.ChecksumURL = templatizeVersion(match.asset.DownloadURL+suffix, versionStr)
+				break
+			}
+		}
+		if config.ChecksumURL == "" && manifestURL != "" {
+			config.ChecksumURL = templatizeVersion(manifestURL, versionStr)
+		}
+		if config.ChecksumURL == "" {
+			result.Notes = append(result.Notes, fmt.Sprintf("%s: no checksum asset found for %s", key, match.asset.Name))
+		}
+
+		if formula.Platforms[match.platform.OS] == nil {
+			formula.Platforms[match.platform.OS] = make(entities.ArchConfig)
+		}
+		formula.Platforms[match.platform.OS][match.platform.Arch] = config
+	}
+
+	data, err := yaml.Marshal(formula)
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrConfigInvalid, "Failed to encode formula", err)
+	}
+	result.YAML = data
+	result.Issues = s.validator.ValidateFormula(data)
+
+	return result, nil
+}
+
+// tagVersion strips prefixes such as "v" or "name-" from a release tag
+func tagVersion(tag string) string {
+	if idx := strings.IndexAny(tag, "0123456789"); idx > 0 {
+		return tag[idx:]
+	}
+	return tag
+}
+
+// templatizeVersion replaces the release version with {version} in the last
+// two segments of a URL, the tag and the file name. Owner and repository
+// names are kept, as are longer versions containing it, e.g. 1.2 in 1.2.10.
+func templatizeVersion(url, version string) string {
+	if version == "" {
+		return url
+	}
+	segments := strings.Split(url, "/")
+	for i := max(0, len(segments)-2); i < len(segments); i++ {
+		segments[i] = replaceVersion(segments[i], version)
+	}
+	return strings.Join(segments, "/")
+}
+
+// replaceVersion replaces each occurrence of version in s that is not part of
+// a longer version with {version}
+func replaceVersion(s, version string) string {
+	var b strings.Builder
+	start := 0
+	for i := 0; i+len(version) <= len(s); i++ {
+		end := i + len(version)
+		if s[i:end] != version || extendsVersion(s[:i], true) || extendsVersion(s[end:], false) {
+			continue
+		}
+		b.WriteString(s[start:i])
+		b.WriteString("{version}")
+		start = end
+		i = end - 1
+	}
+	b.WriteString(s[start:])
+	return b.String()
+}
+
+// extendsVersion reports whether text directly before (or after) a version
+// continues it with more digits, such as "1." before "2.3" or ".10" after "1.2"
+func extendsVersion(text string, before bool) bool {
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	if before {
+		n := len(text)
+		return n > 0 && (isDigit(text[n-1]) || (text[n-1] == '.' && n > 1 && isDigit(text[n-2])))
+	}
+	return len(text) > 0 && (isDigit(text[0]) || (text[0] == '.' && len(text) > 1 && isDigit(text[1])))
+}
+
+// assetTokenPattern splits asset names into words
+var assetTokenPattern = regexp.MustCompile(`[-_.\s]+`)
+
+// inferPlatforms returns the platforms an asset targets and a preference score
+// (lower is better) used when several assets match the same platform
+func inferPlatforms(assetName string) ([]entities.Platform, int) {
+	name := strings.ToLower(assetName)
+	// Multi-part arch names would otherwise be split by the tokenizer
+	for _, alias := range []string{"x86_64", "x86-64"} {
+		name = strings.ReplaceAll(name, alias, "amd64")
+	}
+
+	var osName string
+	var arches []string
+	universal := false
+	for _, token := range assetTokenPattern.Split(name, -1) {
+		switch {
+		case token == "apple":
+			osName = "darwin"
+		case contains(entities.SupportedOSes, entities.NormalizeOS(token)):
+			osName = entities.NormalizeOS(token)
+		case contains(entities.SupportedArches, entities.NormalizeArch(token)):
+			arches = append(arches, entities.NormalizeArch(token))
+		case token == "universal" || token == "universal2":
+			universal = true
+		}
+	}
+
+	if osName == "" {
+		return nil, 0
+	}
+	if len(arches) == 0 && universal && osName == "darwin" {
+		arches = entities.SupportedArches
+	}
+
+	platforms := make([]entities.Platform, 0, len(arches))
+	for _, arch := range arches {
+		platforms = append(platforms, entities.Platform{OS: osName, Arch: arch})
+	}
+
+	score := archiveScore(name)
+	if osName == "linux" && !strings.Contains(name, "musl") {
+		// Prefer statically linked musl builds on Linux
+		score++
+	}
+	if universal {
+		// Prefer arch-specific builds over universal ones
+		score++
+	}
+
+	return platforms, score
+}
+
+// archiveScore ranks archive formats by how well the installer handles them
+func archiveScore(name string) int {
+	switch {
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return 0
+	case strings.HasSuffix(name, ".zip"):
+		return 2
+	case strings.HasSuffix(name, ".tar.xz"), strings.HasSuffix(name, ".tar.bz2"):
+		return 4
+	default:
+		return 6
+	}
+}
+
+// isChecksumAsset reports whether an asset holds checksums
+func isChecksumAsset(name string) bool {
+	lower := strings.ToLower(name)
+	for _, suffix := range []string{".sha256", ".sha256sum", ".sha512", ".sha512sum", ".md5"} {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return isChecksumManifest(name)
+}
+
+// isChecksumManifest reports whether an asset lists checksums for several files
+func isChecksumManifest(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.Contains(lower, "checksums") || strings.HasPrefix(lower, "sha256sums") || strings.HasPrefix(lower, "sha512sums")
+}
+
+// isIgnoredAsset reports whether an asset cannot be installed by wand
+func isIgnoredAsset(name string) bool {
+	lower := strings.ToLower(name)
+	for _, suffix := range []string{".sig", ".asc", ".pem", ".minisig", ".sbom", ".json", ".txt", ".deb", ".rpm", ".apk", ".msi", ".exe", ".pkg"} {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	errs "github.com/ochairo/wand/internal/domain/errors"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+)
+
+// HookPolicy controls whether formula-provided commands may run
+type HookPolicy string
+
+const (
+	// HookPolicyAllow runs formula commands without asking
+	HookPolicyAllow HookPolicy = "allow"
+	// HookPolicyConfirm asks before running commands that have not been approved before
+	HookPolicyConfirm HookPolicy = "confirm"
+	// HookPolicyDeny refuses to install formulas that run commands
+	HookPolicyDeny HookPolicy = "deny"
+)
+
+// HookPolicies lists the valid hook policies
+var HookPolicies = []HookPolicy{HookPolicyAllow, HookPolicyConfirm, HookPolicyDeny}
+
+// ParseHookPolicy parses a policy name; an empty name is HookPolicyAllow
+func ParseHookPolicy(name string) (HookPolicy, error) {
+	if name == "" {
+		return HookPolicyAllow, nil
+	}
+	for _, policy := range HookPolicies {
+		if string(policy) == name {
+			return policy, nil
+		}
+	}
+	return "", errs.NewWithDetails(errs.ErrConfigInvalid, fmt.Sprintf("Unknown hook policy %q", name), "expected allow, confirm or deny")
+}
+
+// DefaultHookTimeout bounds each formula command
+const DefaultHookTimeout = 10 * time.Minute
+
+// hookPath is the PATH formula commands run with, after the package's bin directory
+const hookPath = "/usr/local/bin:/opt/homebrew/bin:/usr/bin:/bin:/usr/sbin:/sbin"
+
+// Hook phases
+const (
+	HookPhaseBuild       = "build"
+	HookPhasePostInstall = "post_install"
+)
+
+// HookStep is one command a formula runs during installation
+type HookStep struct {
+	Phase    string // HookPhaseBuild or HookPhasePostInstall
+	Command  string // with {prefix}, {bin_path} and {version} expanded
+	Template string // as written in the formula
+	Env      map[string]string
+}
+
+// HookPlan lists the commands a formula will run while installing one version
+type HookPlan struct {
+	Package    string
+	Version    string
+	InstallDir string
+	BinDir     string   // The formula's bin_path or bin/ under InstallDir
+	BuildDir   string   // Unpacked source that build steps run in; InstallDir if empty
+	ExtraPath  []string // Build dependency bin directories, searched after the package's own
+	Steps      []HookStep
+}
+
+// Commands returns the commands in the order they run, prefixed by phase
+func (p *HookPlan) Commands() []string {
+	commands := make([]string, 0, len(p.Steps))
+	for _, step := range p.Steps {
+		commands = append(commands, step.Phase+": "+step.Command)
+	}
+	return commands
+}
+
+// templates returns the unexpanded commands, prefixed by phase. Approvals are
+// keyed on these so they survive version changes that only move {prefix}.
+func (p *HookPlan) templates() []string {
+	templates := make([]string, 0, len(p.Steps))
+	for _, step := range p.Steps {
+		templates = append(templates, step.Phase+": "+step.Template)
+	}
+	return templates
+}
+
+// Empty returns true if the formula runs no commands
+func (p *HookPlan) Empty() bool {
+	return len(p.Steps) == 0
+}
+
+// hasPhase returns true if any step belongs to phase
+func (p *HookPlan) hasPhase(phase string) bool {
+	for _, step := range p.Steps {
+		if step.Phase == phase {
+			return true
+		}
+	}
+	return false
+}
+
+// planHooks collects the build and post-install commands for a CLI install
+func planHooks(formula *entities.Formula, config *entities.PlatformConfig, version *entities.Version, installDir string) *HookPlan {
+	plan := &HookPlan{Package: formula.Name, Version: version.String(), InstallDir: installDir, BinDir: formulaBinDir(formula, installDir)}
+	replacer := strings.NewReplacer(
+		"{prefix}", installDir,
+		"{bin_path}", plan.BinDir,
+		"{version}", version.ShortString(), // same form as in URLs
+	)
+
+	if config.RequiresBuild {
+		for _, cmd := range config.BuildCommands {
+			plan.Steps = append(plan.Steps, HookStep{Phase: HookPhaseBuild, Command: replacer.Replace(cmd), Template: cmd})
+		}
+	}
+
+	if formula.PostInstall != nil {
+		for _, cmd := range formula.PostInstall.Commands {
+			plan.Steps = append(plan.Steps, HookStep{Phase: HookPhasePostInstall, Command: replacer.Replace(cmd), Template: cmd, Env: formula.PostInstall.Env})
+		}
+	}
+
+	return plan
+}
+
+// hookRunner authorizes and runs formula commands in a controlled environment.
+// Every phase run by one runner is written to the same log file.
+type hookRunner struct {
+	shellExecutor interfaces.ShellExecutor
+	fs            interfaces.FileSystem
+	approvalRepo  interfaces.HookApprovalRepository
+	wandDir       string
+	homeDir       string
+	progress      *installReporter
+
+	log     strings.Builder
+	logPath string
+}
+
+// authorize applies the hook policy to a plan. With HookPolicyConfirm, commands
+// approved earlier run without asking; new or changed commands go to confirm,
+// and an approval is remembered.
+func (r *hookRunner) authorize(plan *HookPlan, policy HookPolicy, confirm func(*HookPlan) bool) error {
+	if plan.Empty() {
+		return nil
+	}
+
+	details := fmt.Sprintf("package: %q, commands: %s", plan.Package, strings.Join(plan.Commands(), "; "))
+
+	switch policy {
+	case HookPolicyAllow, "":
+		return nil
+	case HookPolicyDeny:
+		return errs.NewWithDetails(errs.ErrHookDenied, fmt.Sprintf("%s runs install commands and the hook policy is deny", plan.Package), details)
+	case HookPolicyConfirm:
+	default:
+		return errs.NewWithDetails(errs.ErrConfigInvalid, fmt.Sprintf("Unknown hook policy %q", policy), "expected allow, confirm or deny")
+	}
+
+	approvals := entities.NewHookApprovals()
+	if r.approvalRepo != nil {
+		loaded, err := r.approvalRepo.Load()
+		if err != nil {
+			return errs.Wrap(errs.ErrConfigInvalid, "Failed to load hook approvals", err)
+		}
+		approvals = loaded
+	}
+
+	commands := plan.templates()
+	if approvals.IsApproved(plan.Package, commands) {
+		return nil
+	}
+
+	if confirm == nil {
+		return errs.NewWithDetails(errs.ErrHookDenied, fmt.Sprintf("%s runs install commands that need approval", plan.Package), details)
+	}
+	if !confirm(plan) {
+		return errs.NewWithDetails(errs.ErrHookDenied, fmt.Sprintf("Install commands for %s were declined", plan.Package), details)
+	}
+
+	approvals.Approve(plan.Package, commands)
+	if r.approvalRepo != nil {
+		if err := r.approvalRepo.Save(approvals); err != nil {
+			return errs.Wrap(errs.ErrPermissionDenied, "Failed to save hook approvals", err)
+		}
+	}
+	return nil
+}
+
+// env returns the environment a step runs with. Formula variables are applied
+// first so they cannot override PATH, HOME or the WAND_ variables.
+func (r *hookRunner) env(plan *HookPlan, step HookStep) map[string]string {
+	env := make(map[string]string, len(step.Env)+6)
+	for key, value := range step.Env {
+		env[key] = value
+	}
+
+	binPath := plan.BinDir
+	if binPath == "" {
+		binPath = filepath.Join(plan.InstallDir, "bin")
+	}
+	env["PATH"] = strings.Join(append(append([]string{binPath}, plan.ExtraPath...), hookPath), ":")
+	env["HOME"] = r.homeDir
+	env["TMPDIR"] = filepath.Join(r.wandDir, "tmp")
+	env["WAND_PACKAGE"] = plan.Package
+	env["WAND_VERSION"] = plan.Version
+	env["WAND_PREFIX"] = plan.InstallDir
+	env["WAND_BIN"] = binPath
+	return env
+}
+
+// run executes the plan's steps for one phase, or every step if phase is
+// empty, and writes a log of each command, its environment and output to
+// wandDir/logs. Build steps run in BuildDir, all others in InstallDir.
+func (r *hookRunner) run(ctx context.Context, plan *HookPlan, phase string, timeout time.Duration) error {
+	if plan.Empty() || (phase != "" && !plan.hasPhase(phase)) {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = DefaultHookTimeout
+	}
+	r.progress.stage(StageHooks, phase)
+
+	logDir := filepath.Join(r.wandDir, "logs")
+	if r.logPath == "" {
+		started := time.Now()
+		r.logPath = filepath.Join(logDir, fmt.Sprintf("%s-%s-%s.log", plan.Package, plan.Version, started.Format("20060102-150405")))
+		fmt.Fprintf(&r.log, "# wand install %s@%s\n# started %s\n# prefix %s\n", plan.Package, plan.Version, started.Format(time.RFC3339), plan.InstallDir)
+	}
+
+	var runErr error
+	for _, step := range plan.Steps {
+		if phase != "" && step.Phase != phase {
+			continue
+		}
+
+		dir := plan.InstallDir
+		if step.Phase == HookPhaseBuild && plan.BuildDir != "" {
+			dir = plan.BuildDir
+		}
+
+		env := r.env(plan, step)
+		fmt.Fprintf(&r.log, "\n## %s: %s\n# dir %s\n", step.Phase, step.Command, dir)
+		keys := make([]string, 0, len(env))
+		for key := range env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&r.log, "# %s=%s\n", key, env[key])
+		}
+
+		output, err := r.shellExecutor.ExecuteScriptContext(ctx, dir, step.Command, env, timeout)
+		r.log.WriteString(output)
+		if err != nil {
+			fmt.Fprintf(&r.log, "\n# error: %v\n", err)
+			runErr = errs.NewWithDetails(errs.ErrInstallationFailed,
+				fmt.Sprintf("%s command failed: %s", step.Phase, step.Command),
+				fmt.Sprintf("%v; see %s", err, r.logPath))
+			break
+		}
+	}
+
+	if err := r.fs.MkdirAll(logDir, 0755); err == nil {
+		_ = r.fs.WriteFile(r.logPath, []byte(r.log.String()), 0644)
+	}
+
+	return runErr
+}
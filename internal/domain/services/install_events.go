@@ -0,0 +1,104 @@
+package services
+
+// InstallStage identifies a step of an installation
+type InstallStage string
+
+const (
+	// StageResolving resolves the requested version
+	StageResolving InstallStage = "resolving"
+	// StageDownloading downloads the artifact; events carry Bytes and Total
+	StageDownloading InstallStage = "downloading"
+	// StageVerifying checks digests, signatures and the checksum lock
+	StageVerifying InstallStage = "verifying"
+	// StageExtracting unpacks the artifact
+	StageExtracting InstallStage = "extracting"
+	// StageHooks runs formula build or post-install commands; Message is the phase
+	StageHooks InstallStage = "hooks"
+	// StageLinking registers the package and creates shims
+	StageLinking InstallStage = "linking"
+	// StageDone ends a successful installation
+	StageDone InstallStage = "done"
+	// StageFailed ends a failed installation; Err is set
+	StageFailed InstallStage = "failed"
+)
+
+// InstallEvent reports the progress of one package installation
+type InstallEvent struct {
+	Package string
+	Version string // Requested version until resolved, then the exact version
+	Stage   InstallStage
+	Message string
+	Bytes   int64 // Bytes downloaded so far (StageDownloading)
+	Total   int64 // Expected download size, -1 if unknown (StageDownloading)
+	Err     error // StageFailed
+}
+
+// InstallObserver receives install events. It is called synchronously from
+// the installing goroutine, so it should return quickly.
+type InstallObserver func(InstallEvent)
+
+// installReporter sends events for one package to an observer, if any
+type installReporter struct {
+	observer InstallObserver
+	pkg      string
+	version  string
+}
+
+func (r *installReporter) emit(event InstallEvent) {
+	if r == nil || r.observer == nil {
+		return
+	}
+	event.Package = r.pkg
+	event.Version = r.version
+	r.observer(event)
+}
+
+func (r *installReporter) stage(stage InstallStage, message string) {
+	r.emit(InstallEvent{Stage: stage, Message: message})
+}
+
+func (r *installReporter) failed(err error) {
+	r.emit(InstallEvent{Stage: StageFailed, Message: err.Error(), Err: err})
+}
+
+// downloadWriter returns a writer that reports bytes written as
+// StageDownloading events, or nil without an observer
+func (r *installReporter) downloadWriter(url string) *downloadProgress {
+	if r == nil || r.observer == nil {
+		return nil
+	}
+	return &downloadProgress{reporter: r, url: url, total: -1}
+}
+
+// downloadProgress counts downloaded bytes. It implements interfaces.SizedWriter
+// so the downloader can report the expected size.
+type downloadProgress struct {
+	reporter *installReporter
+	url      string
+	written  int64
+	total    int64
+}
+
+// SetTotal records the expected size and restarts the count, since the
+// downloader calls it again when it retries
+func (p *downloadProgress) SetTotal(total int64) {
+	p.total = total
+	p.written = 0
+	p.report()
+}
+
+// SetOffset counts the bytes a resumed download already has
+func (p *downloadProgress) SetOffset(offset int64) {
+	p.written = offset
+	p.report()
+}
+
+func (p *downloadProgress) Write(data []byte) (int, error) {
+	p.written += int64(len(data))
+	p.report()
+	return len(data), nil
+}
+
+func (p *downloadProgress) report() {
+	p.reporter.emit(InstallEvent{Stage: StageDownloading, Message: p.url, Bytes: p.written, Total: p.total})
+}
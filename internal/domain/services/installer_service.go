@@ -2,9 +2,13 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/ochairo/wand/internal/domain/entities"
 	errs "github.com/ochairo/wand/internal/domain/errors"
@@ -15,7 +19,10 @@ import (
 type InstallerService struct {
 	formulaRepo   interfaces.FormulaRepository
 	registryRepo  interfaces.RegistryRepository
+	lockRepo      interfaces.ChecksumLockRepository
+	approvalRepo  interfaces.HookApprovalRepository
 	downloader    interfaces.Downloader
+	verifier      interfaces.Verifier
 	extractor     interfaces.Extractor
 	fs            interfaces.FileSystem
 	shellExecutor interfaces.ShellExecutor
@@ -28,7 +35,10 @@ type InstallerService struct {
 func NewInstallerService(
 	formulaRepo interfaces.FormulaRepository,
 	registryRepo interfaces.RegistryRepository,
+	lockRepo interfaces.ChecksumLockRepository,
+	approvalRepo interfaces.HookApprovalRepository,
 	downloader interfaces.Downloader,
+	verifier interfaces.Verifier,
 	extractor interfaces.Extractor,
 	fs interfaces.FileSystem,
 	shellExecutor interfaces.ShellExecutor,
@@ -39,7 +49,10 @@ func NewInstallerService(
 	return &InstallerService{
 		formulaRepo:   formulaRepo,
 		registryRepo:  registryRepo,
+		lockRepo:      lockRepo,
+		approvalRepo:  approvalRepo,
 		downloader:    downloader,
+		verifier:      verifier,
 		extractor:     extractor,
 		fs:            fs,
 		shellExecutor: shellExecutor,
@@ -49,8 +62,68 @@ func NewInstallerService(
 	}
 }
 
+// InstallOptions controls optional installer behaviour
+type InstallOptions struct {
+	TrustNewChecksum bool                 // Accept an artifact whose digest differs from the one recorded on first install
+	HookPolicy       HookPolicy           // Whether formula build and post-install commands may run (default allow)
+	ConfirmHooks     func(*HookPlan) bool // Asked under HookPolicyConfirm; nil refuses unapproved commands
+	HookTimeout      time.Duration        // Per-command limit (default DefaultHookTimeout)
+	DownloadTimeout  time.Duration        // Per-download limit; 0 relies on the downloader's stall timeout
+	BuildFromSource  bool                 // Build from the formula's source even when a prebuilt artifact exists
+	KeepGlobal       bool                 // Leave an existing global version in place instead of switching to the new one
+	Progress         InstallObserver      // Receives install events; nil reports nothing
+
+	buildChain []string // Packages whose build dependencies are being installed, to detect cycles
+}
+
 // InstallPackage installs a package with a specific version
 func (s *InstallerService) InstallPackage(packageName, versionStr string) error {
+	return s.InstallPackageWithOptions(packageName, versionStr, InstallOptions{})
+}
+
+// InstallPackageWithOptions installs a package with a specific version and
+// options, reporting progress to opts.Progress. Every installation ends with a
+// StageDone or StageFailed event.
+func (s *InstallerService) InstallPackageWithOptions(packageName, versionStr string, opts InstallOptions) error {
+	return s.InstallPackageContext(context.Background(), packageName, versionStr, opts)
+}
+
+// InstallPackageContext is InstallPackageWithOptions that stops when ctx is
+// done. An interrupted installation removes its downloads and the partially
+// installed version and fails with ErrCancelled, or ErrTimeout if ctx's
+// deadline passed.
+func (s *InstallerService) InstallPackageContext(ctx context.Context, packageName, versionStr string, opts InstallOptions) error {
+	progress := &installReporter{observer: opts.Progress, pkg: packageName, version: versionStr}
+	if err := s.install(ctx, packageName, versionStr, opts, progress); err != nil {
+		err = interrupted(ctx, fmt.Sprintf("Installation of %s", packageName), err)
+		progress.failed(err)
+		return err
+	}
+	progress.stage(StageDone, "")
+	return nil
+}
+
+// interrupted replaces err with an ErrCancelled or ErrTimeout error when ctx
+// ended the operation
+func interrupted(ctx context.Context, operation string, err error) error {
+	var wandErr *errs.WandError
+	if errors.As(err, &wandErr) && (wandErr.Code == errs.ErrCancelled || wandErr.Code == errs.ErrTimeout) {
+		return err
+	}
+
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		return errs.Wrap(errs.ErrCancelled, operation+" was cancelled", err)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return errs.Wrap(errs.ErrTimeout, operation+" timed out", err)
+	}
+	return err
+}
+
+// install performs an installation for InstallPackageContext
+func (s *InstallerService) install(ctx context.Context, packageName, versionStr string, opts InstallOptions, progress *installReporter) (err error) {
+	progress.stage(StageResolving, "")
+
 	// Get formula
 	formula, err := s.formulaRepo.GetFormula(packageName)
 	if err != nil {
@@ -58,10 +131,11 @@ func (s *InstallerService) InstallPackage(packageName, versionStr string) error
 	}
 
 	// Resolve version
-	version, err := s.versionSvc.ResolveVersion(packageName, versionStr)
+	version, err := s.versionSvc.ResolveVersionContext(ctx, packageName, versionStr)
 	if err != nil {
 		return err // propagate from VersionService
 	}
+	progress.version = version.String()
 
 	// Check if already installed
 	registry, err := s.registryRepo.Load()
@@ -74,12 +148,56 @@ func (s *InstallerService) InstallPackage(packageName, versionStr string) error
 	if _, exists := registry.GetPackage(packageName, version.String()); exists {
 		return errs.NewWithDetails(errs.ErrPackageInstalled, "Package already installed", fmt.Sprintf("package: %q, version: %q", packageName, version.String()))
 	}
+	previousGlobal, hadGlobal := registry.GetGlobalVersion(packageName)
+
+	// A failed or interrupted installation leaves no partial version behind;
+	// once the version is registered its files stay, whatever fails after
+	registered := false
+	defer func() {
+		if err == nil || registered {
+			return
+		}
+		if formula.IsCLI() {
+			_ = s.fs.RemoveAll(s.installDir(formula.Name, version))
+			return
+		}
+		_ = s.fs.RemoveAll(filepath.Join(s.appsRoot(formula.Name), version.String()))
+		if _, others := registry.Packages[formula.Name]; !others {
+			_ = s.fs.RemoveAll(s.appsRoot(formula.Name))
+		}
+	}()
 
 	// Get platform config
 	platform := entities.CurrentPlatform()
-	platformConfig := formula.GetPlatformConfigFor(platform)
-	if platformConfig == nil {
-		return errs.NewWithDetails(errs.ErrArchNotSupported, "Package not available for platform", fmt.Sprintf("package: %q, os: %s, arch: %s", packageName, platform.OS, platform.Arch))
+	platformConfig, err := selectPlatformConfig(formula, platform, opts.BuildFromSource)
+	if err != nil {
+		return err
+	}
+	if platformConfig.RequiresBuild && !formula.IsCLI() {
+		return errs.New(errs.ErrConfigInvalid, fmt.Sprintf("%s is a GUI application and cannot be built from source", packageName))
+	}
+
+	// Check the hook policy before downloading anything
+	hooks := s.hookRunner(progress)
+	var plan *HookPlan
+	if formula.IsCLI() {
+		plan = planHooks(formula, platformConfig, version, s.installDir(formula.Name, version))
+		if err := hooks.authorize(plan, opts.HookPolicy, opts.ConfirmHooks); err != nil {
+			return err
+		}
+	}
+
+	// A version built before with the same recipe is reused as is
+	var cacheDir string
+	if platformConfig.RequiresBuild {
+		cacheDir = s.buildCacheDir(formula, platformConfig, version, platform)
+		if s.fs.IsDir(cacheDir) {
+			if err := s.installFromBuildCache(ctx, formula, version, cacheDir, plan, opts, progress); err != nil {
+				return err
+			}
+			registered = true
+			return s.keepGlobal(opts, hadGlobal, packageName, previousGlobal, version)
+		}
 	}
 
 	// Build download URL
@@ -92,111 +210,191 @@ func (s *InstallerService) InstallPackage(packageName, versionStr string) error
 	}
 	defer func() { _ = s.fs.RemoveAll(tmpDir) }()
 
-	// Preserve file extension from URL for proper extraction detection
-	ext := filepath.Ext(downloadURL)
-	if ext == ".gz" && strings.HasSuffix(downloadURL, ".tar.gz") {
-		ext = ".tar.gz"
+	downloadPath := artifactPath(tmpDir, downloadURL)
+	if err := s.download(ctx, downloadURL, downloadPath, opts.DownloadTimeout, progress); err != nil {
+		return err
+	}
+
+	// Verify pinned digest, checksum and signature if the formula declares them
+	verifier := &artifactVerifier{downloader: s.downloader, verifier: s.verifier}
+	target := &artifact{
+		formula:  formula,
+		config:   platformConfig,
+		version:  version,
+		platform: platform,
+		url:      downloadURL,
+		path:     downloadPath,
+		dir:      tmpDir,
 	}
-	downloadPath := filepath.Join(tmpDir, "package"+ext)
-	if err := s.downloader.Download(downloadURL, downloadPath); err != nil {
-		return errs.Wrap(errs.ErrDownloadFailed, fmt.Sprintf("Failed to download %s@%s", packageName, version.String()), err)
+	progress.stage(StageVerifying, "")
+	if err := verifier.verify(ctx, target); err != nil {
+		return err
 	}
 
-	// Verify checksum if checksum URL is provided
-	if platformConfig.ChecksumURL != "" {
-		checksumURL := buildDownloadURL(platformConfig.ChecksumURL, version, platform)
-		if err := s.downloader.VerifyChecksum(downloadPath, checksumURL); err != nil {
-			return errs.Wrap(errs.ErrChecksumMismatch, fmt.Sprintf("Checksum verification failed for %q", packageName), err)
+	// Compare with the digest recorded the first time this URL was installed
+	var lock *entities.ChecksumLock
+	if s.lockRepo != nil {
+		lock, err = s.lockRepo.Load()
+		if err != nil {
+			return errs.Wrap(errs.ErrConfigInvalid, "Failed to load checksum lock", err)
+		}
+		if err := verifier.verifyTrusted(target, lock, opts.TrustNewChecksum); err != nil {
+			return err
 		}
 	}
 
 	// Install based on package type
 	switch formula.Type {
 	case entities.PackageTypeCLI:
-		return s.installCLI(formula, version, downloadPath, platformConfig)
+		if platformConfig.RequiresBuild {
+			err = s.buildCLI(ctx, formula, version, downloadPath, cacheDir, plan, opts, progress)
+		} else {
+			err = s.installCLI(ctx, formula, version, downloadPath, plan, opts.HookTimeout, progress)
+		}
 	case entities.PackageTypeGUI:
-		return s.installGUI(formula, version, downloadPath, platformConfig, platform)
+		err = s.installGUI(ctx, formula, version, downloadPath, platformConfig, platform, progress)
+	default:
+		err = errs.New(errs.ErrInstallationFailed, fmt.Sprintf("Unsupported package type: %s", formula.Type))
+	}
+	if err != nil {
+		return err
+	}
+	registered = true
+
+	if err := s.keepGlobal(opts, hadGlobal, packageName, previousGlobal, version); err != nil {
+		return err
+	}
+
+	if lock != nil {
+		if err := s.lockRepo.Save(lock); err != nil {
+			return errs.Wrap(errs.ErrPermissionDenied, "Failed to update checksum lock", err)
+		}
+	}
+
+	return nil
+}
+
+// keepGlobal points the global version back at previousGlobal, which the
+// installed version replaced, when opts.KeepGlobal asks for it
+func (s *InstallerService) keepGlobal(opts InstallOptions, hadGlobal bool, packageName, previousGlobal string, version *entities.Version) error {
+	if !opts.KeepGlobal || !hadGlobal {
+		return nil
+	}
+	return s.restoreGlobalVersion(packageName, previousGlobal, version.String())
+}
+
+// download fetches an artifact within timeout (0 means no limit), reporting
+// progress if anyone is listening
+func (s *InstallerService) download(ctx context.Context, url, destPath string, timeout time.Duration, progress *installReporter) error {
+	downloadCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		downloadCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var writer io.Writer
+	if counter := progress.downloadWriter(url); counter != nil {
+		writer = counter
+	}
+
+	err := s.downloader.DownloadContext(downloadCtx, url, destPath, writer)
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() == nil && errors.Is(downloadCtx.Err(), context.DeadlineExceeded):
+		return errs.NewWithDetails(errs.ErrTimeout, fmt.Sprintf("Download of %s@%s timed out", progress.pkg, progress.version), fmt.Sprintf("url: %s, timeout: %s", url, timeout))
 	default:
-		return errs.New(errs.ErrInstallationFailed, fmt.Sprintf("Unsupported package type: %s", formula.Type))
+		return errs.Wrap(errs.ErrDownloadFailed, fmt.Sprintf("Failed to download %s@%s", progress.pkg, progress.version), err)
+	}
+}
+
+// hookRunner returns a runner for formula commands
+func (s *InstallerService) hookRunner(progress *installReporter) *hookRunner {
+	return &hookRunner{
+		progress:      progress,
+		shellExecutor: s.shellExecutor,
+		fs:            s.fs,
+		approvalRepo:  s.approvalRepo,
+		wandDir:       s.wandDir,
+		homeDir:       s.homeDir,
 	}
 }
 
+// appsRoot returns the directory holding the installed versions of a GUI
+// package
+func (s *InstallerService) appsRoot(packageName string) string {
+	return filepath.Join(s.wandDir, "apps", packageName)
+}
+
+// installDir returns where a CLI package version is installed
+func (s *InstallerService) installDir(packageName string, version *entities.Version) string {
+	return filepath.Join(s.wandDir, "packages", packageName, version.String())
+}
+
 // installCLI installs a CLI package
 func (s *InstallerService) installCLI(
+	ctx context.Context,
 	formula *entities.Formula,
 	version *entities.Version,
 	downloadPath string,
-	config *entities.PlatformConfig,
+	plan *HookPlan,
+	hookTimeout time.Duration,
+	progress *installReporter,
 ) error {
 	// Create version directory
-	installDir := filepath.Join(s.wandDir, "packages", formula.Name, version.String())
+	installDir := s.installDir(formula.Name, version)
 	if err := s.fs.MkdirAll(installDir, 0755); err != nil {
 		return errs.Wrap(errs.ErrPermissionDenied, fmt.Sprintf("Failed to create install directory for %s@%s", formula.Name, version.String()), err)
 	}
 
-	// Extract if archive
-	if isArchive(downloadPath) {
-		if err := s.extractor.Extract(downloadPath, installDir); err != nil {
-			return errs.Wrap(errs.ErrExtractionFailed, fmt.Sprintf("Failed to extract package %s@%s", formula.Name, version.String()), err)
-		}
-	} else {
-		// Single binary
-		binDir := filepath.Join(installDir, "bin")
-		if err := s.fs.MkdirAll(binDir, 0755); err != nil {
-			return errs.Wrap(errs.ErrPermissionDenied, "Failed to create bin directory", err)
-		}
-
-		binPath := filepath.Join(binDir, formula.Binaries[0])
-		data, err := s.fs.ReadFile(downloadPath)
-		if err != nil {
-			return errs.Wrap(errs.ErrFileNotFound, "Failed to read binary", err)
-		}
-
-		if err := s.fs.WriteFile(binPath, data, 0755); err != nil {
-			return errs.Wrap(errs.ErrPermissionDenied, "Failed to write binary", err)
-		}
-	}
-
-	// Build from source if needed
-	if config.RequiresBuild {
-		if err := s.buildFromSource(installDir, config.BuildCommands); err != nil {
-			return errs.Wrap(errs.ErrInstallationFailed, "Failed to build from source", err)
-		}
-	}
-
-	// Run post-install hooks
-	if formula.PostInstall != nil {
-		if err := s.runPostInstall(installDir, formula.PostInstall); err != nil {
-			return errs.Wrap(errs.ErrInstallationFailed, "Post-install hook failed", err)
-		}
+	// Extract if archive, otherwise place the binary
+	progress.stage(StageExtracting, "")
+	if err := unpackArtifact(ctx, s.fs, s.extractor, formula, downloadPath, installDir); err != nil {
+		return errs.NewWithDetails(errs.ErrExtractionFailed, fmt.Sprintf("Failed to extract package %s@%s", formula.Name, version.String()), err.Error())
 	}
 
-	// Update registry
-	if err := s.addToRegistry(formula.Name, version.String(), entities.PackageTypeCLI, installDir); err != nil {
-		return errs.Wrap(errs.ErrRegistryCorrupted, "Failed to update registry", err)
+	// Run post-install commands
+	if err := s.hookRunner(progress).run(ctx, plan, HookPhasePostInstall, hookTimeout); err != nil {
+		return err
 	}
 
-	return nil
+	return s.registerCLI(formula, version, installDir)
 }
 
 // installGUI installs a GUI application
 func (s *InstallerService) installGUI(
+	ctx context.Context,
 	formula *entities.Formula,
 	version *entities.Version,
 	downloadPath string,
 	config *entities.PlatformConfig,
 	platform *entities.Platform,
+	progress *installReporter,
 ) error {
-	appsDir := filepath.Join(s.wandDir, "apps", formula.Name)
+	appsDir := filepath.Join(s.appsRoot(formula.Name), version.String())
 	if err := s.fs.MkdirAll(appsDir, 0755); err != nil {
 		return errs.Wrap(errs.ErrPermissionDenied, "Failed to create apps directory", err)
 	}
 
-	// Extract application
-	if err := s.extractor.Extract(downloadPath, appsDir); err != nil {
+	// Extract application; an AppImage is the application itself
+	progress.stage(StageExtracting, "")
+	format, err := s.extractor.Format(downloadPath)
+	if err != nil {
+		return errs.Wrap(errs.ErrExtractionFailed, fmt.Sprintf("Failed to extract application %s@%s", formula.Name, version.String()), err)
+	}
+	appImage := ""
+	if format == entities.ArchiveFormatAppImage {
+		appImage = filepath.Join(appsDir, formula.Name+".AppImage")
+		if err := s.fs.Rename(downloadPath, appImage); err != nil {
+			return errs.Wrap(errs.ErrPermissionDenied, fmt.Sprintf("Failed to install application %s@%s", formula.Name, version.String()), err)
+		}
+	} else if err := s.extractor.ExtractContext(ctx, downloadPath, appsDir); err != nil {
 		return errs.Wrap(errs.ErrExtractionFailed, fmt.Sprintf("Failed to extract application %s@%s", formula.Name, version.String()), err)
 	}
 
+	var binaries map[string]string
+	var files []string
 	if platform.IsDarwin() {
 		// macOS: Symlink .app bundle to ~/Applications
 		homeApps := filepath.Join(s.homeDir, "Applications")
@@ -205,64 +403,77 @@ func (s *InstallerService) installGUI(
 		appPath := filepath.Join(appsDir, formula.AppName)
 		symlinkPath := filepath.Join(homeApps, formula.AppName)
 
+		// The link follows the version installed last
+		if _, err := s.fs.ReadSymlink(symlinkPath); err == nil {
+			_ = s.fs.Remove(symlinkPath)
+		}
 		if err := s.fs.Symlink(appPath, symlinkPath); err != nil {
 			return errs.Wrap(errs.ErrPermissionDenied, fmt.Sprintf("Failed to create symlink for %s", formula.AppName), err)
 		}
-	} else if config.DesktopFile != "" {
-		// Linux: Install .desktop file if provided
-		desktopSrc := filepath.Join(appsDir, config.DesktopFile)
-		desktopDir := filepath.Join(s.homeDir, ".local/share/applications")
-		desktopDest := filepath.Join(desktopDir, formula.Name+".desktop")
-
-		if err := s.fs.MkdirAll(desktopDir, 0755); err != nil {
-			return errs.Wrap(errs.ErrPermissionDenied, "Failed to create desktop directory", err)
-		}
-
-		data, err := s.fs.ReadFile(desktopSrc)
-		if err != nil {
-			return errs.Wrap(errs.ErrFileNotFound, "Failed to read desktop file", err)
+		files = []string{symlinkPath}
+	} else if platform.IsLinux() {
+		// Linux: Install a desktop entry and icon; the executables get launcher shims
+		if binaries, files, err = s.integrateLinuxApp(formula, config, appsDir, appImage); err != nil {
+			return err
 		}
+	}
 
-		if err := s.fs.WriteFile(desktopDest, data, 0644); err != nil {
-			return errs.Wrap(errs.ErrPermissionDenied, "Failed to install desktop file", err)
-		}
+	binPath := ""
+	if executable := binaries[appLauncher(formula)]; executable != "" {
+		binPath = filepath.Dir(executable)
 	}
 
 	// Update registry
-	if err := s.addToRegistry(formula.Name, version.String(), entities.PackageTypeGUI, appsDir); err != nil {
+	if err := s.addToRegistry(formula.Name, version.String(), entities.PackageTypeGUI, appsDir, binPath, binaries, files); err != nil {
 		return errs.Wrap(errs.ErrRegistryCorrupted, "Failed to update registry", err)
 	}
 
 	return nil
 }
 
-// buildFromSource runs build commands in the install directory
-func (s *InstallerService) buildFromSource(dir string, commands []string) error {
-	for _, cmd := range commands {
-		if _, err := s.shellExecutor.ExecuteInDir(dir, cmd); err != nil {
-			return errs.Wrap(errs.ErrInstallationFailed, fmt.Sprintf("Build command failed: %s", cmd), err)
+// registerCLI finds the binaries of an installed CLI version and adds it to
+// the registry with their paths. A formula that declares no binaries gets a
+// shim named after the package, if the package ships such a binary.
+func (s *InstallerService) registerCLI(formula *entities.Formula, version *entities.Version, installDir string) error {
+	names := formula.Binaries
+	if len(names) == 0 {
+		names = []string{formula.Name}
+	}
+	binaries, missing := locateBinaries(s.fs, formula, installDir, names)
+	if len(missing) > 0 && len(formula.Binaries) > 0 {
+		where := "anywhere in " + installDir
+		if formula.BinPath != "" {
+			where = "in " + formulaBinDir(formula, installDir)
 		}
+		return errs.NewWithDetails(errs.ErrBinaryNotFound,
+			fmt.Sprintf("Binaries of %s@%s not found: %s", formula.Name, version.String(), strings.Join(missing, ", ")),
+			fmt.Sprintf("looked %s; check the formula's binaries, bin_path and strip_components", where))
 	}
-	return nil
-}
 
-// runPostInstall runs post-install hooks
-func (s *InstallerService) runPostInstall(installDir string, hook *entities.PostInstallHook) error {
-	binPath := filepath.Join(installDir, "bin")
+	// Archives made on some systems carry no executable bits
+	for name, path := range binaries {
+		if err := s.fs.Chmod(path, 0755); err != nil {
+			return errs.Wrap(errs.ErrPermissionDenied, fmt.Sprintf("Failed to make %s executable", name), err)
+		}
+	}
 
-	for _, cmd := range hook.Commands {
-		// Replace {bin_path} placeholder
-		cmdWithPath := strings.ReplaceAll(cmd, "{bin_path}", binPath)
+	binPath := ""
+	switch {
+	case formula.BinPath != "":
+		binPath = formulaBinDir(formula, installDir)
+	case len(formula.Binaries) > 0:
+		binPath = filepath.Dir(binaries[formula.Binaries[0]])
+	}
 
-		if _, err := s.shellExecutor.ExecuteWithEnv(hook.Env, cmdWithPath); err != nil {
-			return errs.Wrap(errs.ErrInstallationFailed, fmt.Sprintf("Post-install command failed: %s", cmd), err)
-		}
+	if err := s.addToRegistry(formula.Name, version.String(), entities.PackageTypeCLI, installDir, binPath, binaries, nil); err != nil {
+		return errs.Wrap(errs.ErrRegistryCorrupted, "Failed to update registry", err)
 	}
 	return nil
 }
 
-// addToRegistry adds a package to the registry
-func (s *InstallerService) addToRegistry(packageName, versionStr string, pkgType entities.PackageType, installDir string) error {
+// addToRegistry adds a package to the registry. An empty binPath is guessed
+// from the install directory; files are those installed outside it.
+func (s *InstallerService) addToRegistry(packageName, versionStr string, pkgType entities.PackageType, installDir, binPath string, binaries map[string]string, files []string) error {
 	registry, err := s.registryRepo.Load()
 	if err != nil && !s.registryRepo.Exists() {
 		registry = entities.NewRegistry()
@@ -279,14 +490,16 @@ func (s *InstallerService) addToRegistry(packageName, versionStr string, pkgType
 	// Create package
 	pkg := entities.NewPackage(packageName, pkgType, version)
 	pkg.InstallPath = installDir
-
-	// Find binaries - they may be in bin/ subdirectory or at root level
-	binDir := filepath.Join(installDir, "bin")
-	if !s.fs.Exists(binDir) {
-		// Binaries are at root level, use install dir
-		pkg.BinPath = installDir
-	} else {
-		pkg.BinPath = binDir
+	pkg.Binaries = binaries
+	pkg.IntegrationFiles = files
+
+	// Without a known location, binaries may be in bin/ subdirectory or at root level
+	pkg.BinPath = binPath
+	if pkg.BinPath == "" {
+		pkg.BinPath = filepath.Join(installDir, "bin")
+		if !s.fs.Exists(pkg.BinPath) {
+			pkg.BinPath = installDir
+		}
 	}
 
 	pkg.IsGlobal = true
@@ -298,6 +511,20 @@ func (s *InstallerService) addToRegistry(packageName, versionStr string, pkgType
 	return s.registryRepo.Save(registry)
 }
 
+// restoreGlobalVersion sets the global version back to previous after
+// installing installed made it global
+func (s *InstallerService) restoreGlobalVersion(packageName, previous, installed string) error {
+	registry, err := s.registryRepo.Load()
+	if err != nil {
+		return errs.Wrap(errs.ErrRegistryCorrupted, "Failed to load registry", err)
+	}
+	registry.SetGlobalVersion(packageName, previous)
+	if pkg, exists := registry.GetPackage(packageName, installed); exists {
+		pkg.IsGlobal = false
+	}
+	return s.registryRepo.Save(registry)
+}
+
 // UninstallPackage removes a specific version of a package
 func (s *InstallerService) UninstallPackage(packageName, version string) error {
 	registry, err := s.registryRepo.Load()
@@ -322,6 +549,14 @@ func (s *InstallerService) UninstallPackage(packageName, version string) error {
 
 	// Update registry
 	registry.RemovePackage(packageName, version)
+	if err := s.releaseIntegrationFiles(registry, pkg); err != nil {
+		return err
+	}
+	if _, remains := registry.Packages[packageName]; !remains && pkg.Type == entities.PackageTypeGUI {
+		if err := s.fs.RemoveAll(s.appsRoot(packageName)); err != nil {
+			return errs.Wrap(errs.ErrPermissionDenied, "Failed to remove installation", err)
+		}
+	}
 
 	return s.registryRepo.Save(registry)
 }
@@ -342,6 +577,14 @@ func (s *InstallerService) uninstallAllVersions(registry *entities.Registry, pac
 		if err := s.fs.RemoveAll(pkg.InstallPath); err != nil {
 			return errs.Wrap(errs.ErrPermissionDenied, fmt.Sprintf("Failed to remove %s@%s", packageName, version), err)
 		}
+		if err := s.removeIntegrationFiles(pkg); err != nil {
+			return err
+		}
+	}
+	if entry.Type == entities.PackageTypeGUI {
+		if err := s.fs.RemoveAll(s.appsRoot(packageName)); err != nil {
+			return errs.Wrap(errs.ErrPermissionDenied, fmt.Sprintf("Failed to remove %s", packageName), err)
+		}
 	}
 
 	// Remove entire package entry from registry
@@ -371,15 +614,56 @@ func buildDownloadURL(template string, version *entities.Version, platform *enti
 	return url
 }
 
-// isArchive checks if a file is an archive based on extension
-func isArchive(path string) bool {
-	archiveExts := []string{".tar", ".gz", ".tgz", ".zip", ".bz2", ".xz", ".tar.gz", ".tar.bz2", ".tar.xz"}
+// artifactPath returns the download path for a URL inside dir, preserving the
+// file extension so the extractor can detect the archive format
+func artifactPath(dir, downloadURL string) string {
+	ext := filepath.Ext(downloadURL)
+	if ext == ".gz" && strings.HasSuffix(downloadURL, ".tar.gz") {
+		ext = ".tar.gz"
+	}
+	return filepath.Join(dir, "package"+ext)
+}
 
-	for _, ext := range archiveExts {
-		if strings.HasSuffix(path, ext) {
-			return true
+// unpackArtifact extracts a downloaded archive into dir, dropping the
+// formula's strip_components, or places a bare or compressed binary in the
+// formula's bin directory under its first declared name. The format is
+// detected from the file's contents, not its URL.
+func unpackArtifact(ctx context.Context, fs interfaces.FileSystem, extractor interfaces.Extractor, formula *entities.Formula, downloadPath, dir string) error {
+	format, err := extractor.Format(downloadPath)
+	if err != nil {
+		return err
+	}
+	if format != entities.ArchiveFormatNone && !format.SingleFile() {
+		if err := extractor.ExtractContext(ctx, downloadPath, dir); err != nil {
+			return err
 		}
+		return stripComponents(fs, dir, formula.StripComponents)
 	}
 
-	return false
+	if len(formula.Binaries) == 0 {
+		return fmt.Errorf("download is not an archive and the formula declares no binaries")
+	}
+	binary := downloadPath
+	if format.SingleFile() {
+		staging := downloadPath + ".unpacked"
+		defer func() { _ = fs.RemoveAll(staging) }()
+		if err := extractor.ExtractContext(ctx, downloadPath, staging); err != nil {
+			return err
+		}
+		entries := topLevelEntries(fs, staging)
+		if len(entries) != 1 {
+			return fmt.Errorf("expected a single file in %s archive", format)
+		}
+		binary = entries[0]
+	}
+
+	binDir := formulaBinDir(formula, dir)
+	if err := fs.MkdirAll(binDir, 0755); err != nil {
+		return fmt.Errorf("failed to create bin directory: %w", err)
+	}
+	data, err := fs.ReadFile(binary)
+	if err != nil {
+		return fmt.Errorf("failed to read binary: %w", err)
+	}
+	return fs.WriteFile(filepath.Join(binDir, formula.Binaries[0]), data, 0755)
 }
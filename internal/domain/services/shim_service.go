@@ -2,7 +2,11 @@ package services
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/ochairo/wand/internal/domain/entities"
@@ -16,10 +20,22 @@ type ShimService struct {
 	wandrcRepo   interfaces.WandRCRepository
 	formulaRepo  interfaces.FormulaRepository
 	fs           interfaces.FileSystem
+	shimIndex    interfaces.ShimIndexRepository
 	wandDir      string
+	executable   string
+	versionFiles []interfaces.VersionFileReader
+	lookupEnv    func(string) (string, bool)
 	shimTemplate string
 }
 
+// ShimServiceOptions configures a shim service
+type ShimServiceOptions struct {
+	Executable   string                         // wand binary shims hand unresolved versions to (default "wand")
+	VersionFiles []interfaces.VersionFileReader // Other tools' version files read after .wandrc, in order
+	LookupEnv    func(string) (string, bool)    // Reads WAND_<PACKAGE>_VERSION overrides (default os.LookupEnv)
+	ShimIndex    interfaces.ShimIndexRepository // Records which package owns each shim (default: read from the shims)
+}
+
 // NewShimService creates a new shim service
 func NewShimService(
 	registryRepo interfaces.RegistryRepository,
@@ -28,87 +44,658 @@ func NewShimService(
 	fs interfaces.FileSystem,
 	wandDir string,
 ) *ShimService {
+	return NewShimServiceWithOptions(registryRepo, wandrcRepo, formulaRepo, fs, wandDir, ShimServiceOptions{})
+}
+
+// NewShimServiceWithOptions creates a shim service with the given options
+func NewShimServiceWithOptions(
+	registryRepo interfaces.RegistryRepository,
+	wandrcRepo interfaces.WandRCRepository,
+	formulaRepo interfaces.FormulaRepository,
+	fs interfaces.FileSystem,
+	wandDir string,
+	opts ShimServiceOptions,
+) *ShimService {
+	if opts.Executable == "" {
+		opts.Executable = "wand"
+	}
+	if opts.LookupEnv == nil {
+		opts.LookupEnv = os.LookupEnv
+	}
 	return &ShimService{
 		registryRepo: registryRepo,
 		wandrcRepo:   wandrcRepo,
 		formulaRepo:  formulaRepo,
 		fs:           fs,
+		shimIndex:    opts.ShimIndex,
 		wandDir:      wandDir,
+		executable:   opts.Executable,
+		versionFiles: opts.VersionFiles,
+		lookupEnv:    opts.LookupEnv,
 		shimTemplate: getShimTemplate(),
 	}
 }
 
-// CreateShims creates shims for all binaries in a package
+// CreateShims creates shims for all binaries in a package and records the
+// package as their owner, taking over any that another package owns; see
+// ShimConflicts
 func (s *ShimService) CreateShims(packageName string, binaries []string) error {
 	shimsDir := filepath.Join(s.wandDir, "shims")
 	if err := s.fs.MkdirAll(shimsDir, 0755); err != nil {
 		return errs.Wrap(errs.ErrPermissionDenied, "Failed to create shims directory", err)
 	}
 
+	index, err := s.loadShimIndex()
+	if err != nil {
+		return err
+	}
 	for _, binary := range binaries {
-		shimPath := filepath.Join(shimsDir, binary)
-
-		// Generate shim script
-		shimContent := s.generateShimScript(binary, packageName)
-
-		// Write shim file
-		if err := s.fs.WriteFile(shimPath, []byte(shimContent), 0755); err != nil {
-			return errs.Wrap(errs.ErrShimCreationFailed, fmt.Sprintf("Failed to create shim for %s", binary), err)
+		if err := s.writeShim(binary, packageName); err != nil {
+			return err
 		}
+		index.SetOwner(binary, packageName)
 	}
 
-	return nil
+	return s.saveShimIndex(index)
 }
 
-// RemoveShims removes shims for a package's binaries
-func (s *ShimService) RemoveShims(binaries []string) error {
-	shimsDir := filepath.Join(s.wandDir, "shims")
+// RemoveShims removes the shims a package owns for its binaries once no
+// version of it is installed. A shim another installed package also provides
+// is handed over to that package instead, and shims other packages own are
+// left alone.
+func (s *ShimService) RemoveShims(packageName string, binaries []string) error {
+	providers, err := s.shimProviders()
+	if err != nil {
+		return err
+	}
+	index, err := s.loadShimIndex()
+	if err != nil {
+		return err
+	}
 
 	for _, binary := range binaries {
-		shimPath := filepath.Join(shimsDir, binary)
+		owner := s.shimOwner(index, binary)
+		if (owner != "" && owner != packageName) || contains(providers[binary], packageName) {
+			continue
+		}
 
+		if others := providers[binary]; len(others) > 0 {
+			if err := s.writeShim(binary, others[0]); err != nil {
+				return err
+			}
+			index.SetOwner(binary, others[0])
+			continue
+		}
+
+		shimPath := filepath.Join(s.wandDir, "shims", binary)
 		if s.fs.Exists(shimPath) {
 			if err := s.fs.Remove(shimPath); err != nil {
 				return errs.Wrap(errs.ErrShimExecutionFailed, fmt.Sprintf("Failed to remove shim for %s", binary), err)
 			}
 		}
+		index.Release(binary)
+	}
+
+	return s.saveShimIndex(index)
+}
+
+// ShimConflict is a shim that installing a package would take over from
+// another installed package that ships the same binary
+type ShimConflict struct {
+	Binary  string
+	Package string // package being installed
+	Owner   string // package that owns the shim now
+}
+
+// ShimConflicts returns the shims for binaries that another installed
+// package owns
+func (s *ShimService) ShimConflicts(packageName string, binaries []string) ([]ShimConflict, error) {
+	providers, err := s.shimProviders()
+	if err != nil {
+		return nil, err
+	}
+	index, err := s.loadShimIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []ShimConflict
+	for _, binary := range binaries {
+		owner := s.shimOwner(index, binary)
+		if owner != "" && owner != packageName && contains(providers[binary], owner) {
+			conflicts = append(conflicts, ShimConflict{Binary: binary, Package: packageName, Owner: owner})
+		}
 	}
+	return conflicts, nil
+}
+
+// ShimInfo describes the shim for one binary
+type ShimInfo struct {
+	Binary    string
+	Path      string   // shim file
+	Owner     string   // package the shim runs, "" if none
+	Providers []string // installed packages that ship the binary, sorted
+	Exists    bool     // whether the shim file exists
+	Outdated  bool     // the shim exists but was written from an older template or for another wand directory
+}
 
+// ListShims describes every shim file, recorded owner and binary of an
+// installed package, sorted by binary
+func (s *ShimService) ListShims() ([]ShimInfo, error) {
+	providers, err := s.shimProviders()
+	if err != nil {
+		return nil, err
+	}
+	index, err := s.loadShimIndex()
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.shimFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for binary := range providers {
+		seen[binary] = true
+	}
+	for binary := range index.Owners {
+		seen[binary] = true
+	}
+	for binary := range files {
+		seen[binary] = true
+	}
+
+	shims := make([]ShimInfo, 0, len(seen))
+	for binary := range seen {
+		shim := ShimInfo{
+			Binary:    binary,
+			Path:      filepath.Join(s.wandDir, "shims", binary),
+			Owner:     s.shimOwner(index, binary),
+			Providers: providers[binary],
+			Exists:    files[binary],
+		}
+		if shim.Exists {
+			data, err := s.fs.ReadFile(shim.Path)
+			shim.Outdated = err != nil || s.shimOutdated(data)
+		}
+		shims = append(shims, shim)
+	}
+	sort.Slice(shims, func(i, j int) bool { return shims[i].Binary < shims[j].Binary })
+	return shims, nil
+}
+
+// ShimChange is one fix RepairShims made
+type ShimChange struct {
+	Binary string
+	Owner  string // package the shim runs now, "" if it was removed
+	Note   string
+}
+
+// RepairShims brings the shims and the ownership index in line with the
+// installed packages. Missing shims are created, shims no installed package
+// provides are removed, shims whose owner is gone pass to another provider,
+// and shims that do not match their owner are rewritten. Owners that are
+// still installed keep their shims.
+func (s *ShimService) RepairShims() ([]ShimChange, error) {
+	shims, err := s.ListShims()
+	if err != nil {
+		return nil, err
+	}
+	index, err := s.loadShimIndex()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fs.MkdirAll(filepath.Join(s.wandDir, "shims"), 0755); err != nil {
+		return nil, errs.Wrap(errs.ErrPermissionDenied, "Failed to create shims directory", err)
+	}
+
+	var changes []ShimChange
+	for _, shim := range shims {
+		owner := shim.Owner
+		if !contains(shim.Providers, owner) {
+			owner = ""
+			if len(shim.Providers) > 0 {
+				owner = shim.Providers[0]
+			}
+		}
+		recorded, _ := index.Owner(shim.Binary)
+		shimPath := shim.Path
+
+		var note string
+		write := true
+		switch {
+		case owner == "":
+			if shim.Exists {
+				if err := s.fs.Remove(shimPath); err != nil {
+					return changes, errs.Wrap(errs.ErrShimExecutionFailed, fmt.Sprintf("Failed to remove shim for %s", shim.Binary), err)
+				}
+			}
+			index.Release(shim.Binary)
+			changes = append(changes, ShimChange{Binary: shim.Binary, Note: "removed, no installed package provides it"})
+			continue
+		case !shim.Exists:
+			note = "created"
+		case shim.Owner != owner:
+			note = fmt.Sprintf("passed from %s, which does not provide it", shim.Owner)
+		default:
+			data, err := s.fs.ReadFile(shimPath)
+			switch {
+			case err != nil || string(data) != s.generateShimScript(shim.Binary, owner):
+				note = "rewritten"
+			case recorded != owner:
+				note, write = "owner recorded", false
+			default:
+				continue
+			}
+		}
+
+		if write {
+			if err := s.writeShim(shim.Binary, owner); err != nil {
+				return changes, err
+			}
+		}
+		index.SetOwner(shim.Binary, owner)
+		changes = append(changes, ShimChange{Binary: shim.Binary, Owner: owner, Note: note})
+	}
+
+	return changes, s.saveShimIndex(index)
+}
+
+// writeShim writes the shim for a binary that runs a package
+func (s *ShimService) writeShim(binary, packageName string) error {
+	shimPath := filepath.Join(s.wandDir, "shims", binary)
+	if err := s.fs.WriteFile(shimPath, []byte(s.generateShimScript(binary, packageName)), 0755); err != nil {
+		return errs.Wrap(errs.ErrShimCreationFailed, fmt.Sprintf("Failed to create shim for %s", binary), err)
+	}
 	return nil
 }
 
-// ResolveVersion resolves which version of a package to use
-// Resolution order: .wandrc (project) -> global version from registry
-func (s *ShimService) ResolveVersion(packageName, currentDir string) (string, error) {
-	// Check for .wandrc in current directory or parent directories
-	wandrc, wandrcPath, err := s.wandrcRepo.FindInPath(currentDir)
-	if err == nil && wandrc != nil {
-		// Check if package version is specified in .wandrc
-		if version, exists := wandrc.Versions[packageName]; exists {
-			return version, nil
+// shimPackagePattern finds the package a shim runs
+var shimPackagePattern = regexp.MustCompile(`exec --package (\S+) `)
+
+// shimStampPattern and shimHomePattern find the template version a shim was
+// written from and the wand directory it was written for
+var (
+	shimStampPattern = regexp.MustCompile(`(?m)^# wand-shim-version: (\d+)$`)
+	shimHomePattern  = regexp.MustCompile(`(?m)^WAND_HOME=(.*)$`)
+)
+
+// shimOutdated reports whether a shim was written from another template
+// version or for another wand directory, such as before ~/.wand moved
+func (s *ShimService) shimOutdated(data []byte) bool {
+	stamp := shimStampPattern.FindSubmatch(data)
+	if stamp == nil || string(stamp[1]) != strconv.Itoa(shimTemplateVersion) {
+		return true
+	}
+	home := shimHomePattern.FindSubmatch(data)
+	return home == nil || string(home[1]) != ShellQuote(s.wandDir)
+}
+
+// BinaryProviders returns the packages that provide each shim; see shimProviders
+func (s *ShimService) BinaryProviders() (map[string][]string, error) {
+	return s.shimProviders()
+}
+
+// shimOwner returns the package that owns the shim for a binary: the one
+// recorded in the index or, for shims written before there was one, the
+// package the shim runs
+func (s *ShimService) shimOwner(index *entities.ShimIndex, binary string) string {
+	if owner, ok := index.Owner(binary); ok {
+		return owner
+	}
+	data, err := s.fs.ReadFile(filepath.Join(s.wandDir, "shims", binary))
+	if err != nil {
+		return ""
+	}
+	if match := shimPackagePattern.FindSubmatch(data); match != nil {
+		return strings.Trim(string(match[1]), "'")
+	}
+	return ""
+}
+
+// shimProviders maps each binary of the installed CLI packages, and each
+// launcher of the installed GUI applications, to the packages that ship it,
+// sorted
+func (s *ShimService) shimProviders() (map[string][]string, error) {
+	registry, err := s.registryRepo.Load()
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrRegistryCorrupted, "Failed to load registry", err)
+	}
+
+	providers := make(map[string][]string)
+	for _, entry := range registry.ListAllPackages() {
+		if entry.Type != entities.PackageTypeCLI && !recordsBinaries(entry) {
+			continue
+		}
+		for _, binary := range s.packageBinaries(entry.Name) {
+			providers[binary] = append(providers[binary], entry.Name)
+		}
+	}
+	for _, names := range providers {
+		sort.Strings(names)
+	}
+	return providers, nil
+}
+
+// recordsBinaries returns true if a version of a package recorded its
+// binaries, as GUI applications do for their launchers
+func recordsBinaries(entry *entities.PackageEntry) bool {
+	for _, pkg := range entry.Versions {
+		if len(pkg.Binaries) > 0 {
+			return true
 		}
+	}
+	return false
+}
 
-		// Continue searching in parent directory
-		if wandrcPath != "" {
-			parentDir := filepath.Dir(filepath.Dir(wandrcPath))
-			if parentDir != "/" && parentDir != "." {
-				return s.ResolveVersion(packageName, parentDir)
+// packageBinaries returns the binaries a package's formula declares, or the
+// package name if it declares none
+func (s *ShimService) packageBinaries(packageName string) []string {
+	formula, err := s.formulaRepo.GetFormula(packageName)
+	if err != nil || len(formula.Binaries) == 0 {
+		return []string{packageName}
+	}
+	return formula.Binaries
+}
+
+// shimFiles returns the names of the files in the shims directory
+func (s *ShimService) shimFiles() (map[string]bool, error) {
+	shimsDir := filepath.Join(s.wandDir, "shims")
+	files := make(map[string]bool)
+	if !s.fs.Exists(shimsDir) {
+		return files, nil
+	}
+	err := s.fs.Walk(shimsDir, func(path string, isDir bool, err error) error {
+		if err != nil {
+			return err
+		}
+		if !isDir && filepath.Dir(path) == shimsDir {
+			files[filepath.Base(path)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shims directory: %w", err)
+	}
+	return files, nil
+}
+
+// loadShimIndex loads the ownership index, empty if there is none
+func (s *ShimService) loadShimIndex() (*entities.ShimIndex, error) {
+	if s.shimIndex == nil {
+		return entities.NewShimIndex(), nil
+	}
+	index, err := s.shimIndex.Load()
+	if err != nil {
+		return nil, errs.Wrap(errs.ErrShimExecutionFailed, "Failed to load shim index", err)
+	}
+	return index, nil
+}
+
+// saveShimIndex saves the ownership index, if there is one
+func (s *ShimService) saveShimIndex(index *entities.ShimIndex) error {
+	if s.shimIndex == nil {
+		return nil
+	}
+	if err := s.shimIndex.Save(index); err != nil {
+		return errs.Wrap(errs.ErrShimCreationFailed, "Failed to save shim index", err)
+	}
+	return nil
+}
+
+// Resolution explains which version of a package applies in a directory
+type Resolution struct {
+	Package string
+	Spec    string           // version or constraint that applies
+	Source  string           // file or variable that pins it, or "global"
+	Version string           // installed version chosen, "" if none matches
+	Steps   []ResolutionStep // what was consulted, in order
+}
+
+// ResolutionStep is one file or rule consulted while resolving
+type ResolutionStep struct {
+	Source string // file path, variable name, "global" or "installed"
+	Note   string
+}
+
+func (r *Resolution) step(source, format string, args ...interface{}) {
+	r.Steps = append(r.Steps, ResolutionStep{Source: source, Note: fmt.Sprintf(format, args...)})
+}
+
+// Resolve finds the version of a package that applies in dir and explains
+// how. It is the single resolver behind shims, wand exec, which, resolve,
+// info and env.
+//
+// A WAND_<PACKAGE>_VERSION environment variable (see
+// entities.VersionEnvVar) overrides everything else. Otherwise, starting in
+// dir and moving up to the root, the first directory that pins
+// the package wins: within a directory .wandrc comes first, then the
+// configured version files of other tools, in order. A .wandrc with
+// extends: false stops the search at its directory. Without a pin, the
+// global version from the registry applies. The spec found resolves to the
+// newest installed version it allows.
+//
+// On error the resolution so far is returned with it.
+func (s *ShimService) Resolve(packageName, dir string) (*Resolution, error) {
+	res := &Resolution{Package: packageName}
+	if err := s.resolveSpec(res, dir); err != nil {
+		return res, err
+	}
+
+	version, err := s.ResolveInstalled(packageName, res.Spec)
+	if err != nil {
+		res.step("installed", "no installed version matches %s", res.Spec)
+		return res, err
+	}
+	res.Version = version
+	if version == res.Spec {
+		res.step("installed", "%s is installed", version)
+	} else {
+		res.step("installed", "%s is the newest installed version matching %s", version, res.Spec)
+	}
+	return res, nil
+}
+
+// resolveSpec fills in the spec that applies and its source
+func (s *ShimService) resolveSpec(res *Resolution, currentDir string) error {
+	packageName := res.Package
+
+	name := entities.VersionEnvVar(packageName)
+	if version, ok := s.lookupEnv(name); ok && version != "" {
+		if _, err := entities.ParseVersionConstraint(version); err != nil {
+			return errs.NewWithDetails(errs.ErrInvalidVersion,
+				fmt.Sprintf("Invalid version %q in %s", version, name),
+				fmt.Sprintf("Unset %s or set it to a version such as 1.2.3 or ^1", name))
+		}
+		res.Spec, res.Source = version, name
+		res.step(name, "overrides with %s", version)
+		return nil
+	}
+
+	for dir := currentDir; ; {
+		inherits := true
+		if s.wandrcRepo.Exists(dir) {
+			path := filepath.Join(dir, ".wandrc")
+			wandrc, err := s.wandrcRepo.Load(dir)
+			if err != nil {
+				return err
+			}
+			if version, exists := wandrc.Versions[packageName]; exists {
+				res.Spec, res.Source = version, path
+				res.step(path, "pins %s", version)
+				return nil
 			}
+			res.step(path, "no entry for %s", packageName)
+			inherits = wandrc.Inherits()
+		}
+
+		for _, reader := range s.versionFileReaders(packageName) {
+			versions, err := reader.Read(dir)
+			if err != nil {
+				return err
+			}
+			if versions == nil {
+				continue
+			}
+			path := filepath.Join(dir, reader.FileName())
+			if version, exists := versions[packageName]; exists {
+				res.Spec, res.Source = version, path
+				res.step(path, "pins %s", version)
+				return nil
+			}
+			res.step(path, "no entry for %s", packageName)
+		}
+
+		if !inherits {
+			res.step(filepath.Join(dir, ".wandrc"), "extends: false, parent directories are not consulted")
+			break
 		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
 	}
 
 	// Fall back to global version from registry
 	registry, err := s.registryRepo.Load()
 	if err != nil {
-		return "", fmt.Errorf("failed to load registry: %w", err)
+		return fmt.Errorf("failed to load registry: %w", err)
 	}
 
 	globalVersion, exists := registry.GetGlobalVersion(packageName)
 	if !exists {
-		return "", fmt.Errorf("no version found for package %s", packageName)
+		res.step("global", "no global version")
+		return errs.NewWithDetails(errs.ErrPackageNotInstalled,
+			fmt.Sprintf("No version found for package %s", packageName),
+			fmt.Sprintf("Run 'wand install %s'", packageName))
+	}
+
+	res.Spec, res.Source = globalVersion, "global"
+	res.step("global", "global version is %s", globalVersion)
+	return nil
+}
+
+// ResolveSpec returns the version spec that applies to a package in
+// currentDir and where it came from: the path of the file that pins it, the
+// name of the variable that overrides it, or "global" for the registry's
+// global version. The spec may be a constraint.
+func (s *ShimService) ResolveSpec(packageName, currentDir string) (string, string, error) {
+	res := &Resolution{Package: packageName}
+	err := s.resolveSpec(res, currentDir)
+	return res.Spec, res.Source, err
+}
+
+// ProjectPackages returns the packages pinned by the .wandrc files from dir
+// upward, following inheritance
+func (s *ShimService) ProjectPackages(dir string) ([]string, error) {
+	seen := make(map[string]bool)
+	for {
+		if s.wandrcRepo.Exists(dir) {
+			wandrc, err := s.wandrcRepo.Load(dir)
+			if err != nil {
+				return nil, err
+			}
+			for name := range wandrc.Versions {
+				seen[name] = true
+			}
+			if !wandrc.Inherits() {
+				break
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
 	}
 
-	return globalVersion, nil
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// versionFileReaders returns the version files that can pin a package
+func (s *ShimService) versionFileReaders(packageName string) []interfaces.VersionFileReader {
+	var readers []interfaces.VersionFileReader
+	for _, reader := range s.versionFiles {
+		packages := reader.Packages()
+		if packages == nil {
+			readers = append(readers, reader)
+			continue
+		}
+		for _, name := range packages {
+			if name == packageName {
+				readers = append(readers, reader)
+				break
+			}
+		}
+	}
+	return readers
+}
+
+// ResolveVersion resolves which installed version of a package to use in
+// currentDir; see Resolve
+func (s *ShimService) ResolveVersion(packageName, currentDir string) (string, error) {
+	res, err := s.Resolve(packageName, currentDir)
+	if err != nil {
+		return "", err
+	}
+	return res.Version, nil
+}
+
+// ResolveInstalled returns the newest installed version of a package that
+// satisfies spec
+func (s *ShimService) ResolveInstalled(packageName, spec string) (string, error) {
+	constraint, err := entities.ParseVersionConstraint(spec)
+	if err != nil {
+		return "", errs.New(errs.ErrInvalidVersion, fmt.Sprintf("Invalid version %q for %s", spec, packageName))
+	}
+
+	registry, err := s.registryRepo.Load()
+	if err != nil {
+		return "", fmt.Errorf("failed to load registry: %w", err)
+	}
+
+	installed, _ := registry.GetAllVersions(packageName)
+	versions := make([]*entities.Version, 0, len(installed))
+	for _, pkg := range installed {
+		versions = append(versions, pkg.Version)
+	}
+	best := constraint.Best(versions)
+	if best == nil {
+		return "", errs.NewWithDetails(errs.ErrPackageNotInstalled,
+			fmt.Sprintf("No installed version of %s matches %q", packageName, spec),
+			fmt.Sprintf("Run 'wand install %s@%s'", packageName, spec))
+	}
+	return best.String(), nil
+}
+
+// PackageForBinary returns the installed package that provides a binary:
+// the owner of its shim, or else the first package, by name, that ships it
+func (s *ShimService) PackageForBinary(binaryName string) (string, error) {
+	providers, err := s.shimProviders()
+	if err != nil {
+		return "", err
+	}
+	index, err := s.loadShimIndex()
+	if err != nil {
+		return "", err
+	}
+
+	names := providers[binaryName]
+	if owner := s.shimOwner(index, binaryName); contains(names, owner) {
+		return owner, nil
+	}
+	if len(names) > 0 {
+		return names[0], nil
+	}
+
+	return "", errs.New(errs.ErrPackageNotInstalled, fmt.Sprintf("No installed package provides %s", binaryName))
 }
 
 // GetBinaryPath returns the full path to a package's binary
@@ -123,8 +710,11 @@ func (s *ShimService) GetBinaryPath(packageName, version, binaryName string) (st
 		return "", fmt.Errorf("package %s@%s not found in registry", packageName, version)
 	}
 
-	// Construct binary path
-	binaryPath := filepath.Join(pkg.BinPath, binaryName)
+	// Prefer the path found at install time; older installs only recorded BinPath
+	binaryPath, recorded := pkg.Binaries[binaryName]
+	if !recorded {
+		binaryPath = filepath.Join(pkg.BinPath, binaryName)
+	}
 
 	if !s.fs.Exists(binaryPath) {
 		return "", fmt.Errorf("binary %s not found at %s", binaryName, binaryPath)
@@ -136,85 +726,62 @@ func (s *ShimService) GetBinaryPath(packageName, version, binaryName string) (st
 // generateShimScript generates the shim script content
 func (s *ShimService) generateShimScript(binaryName, packageName string) string {
 	script := s.shimTemplate
-	script = strings.ReplaceAll(script, "{{WAND_DIR}}", s.wandDir)
-	script = strings.ReplaceAll(script, "{{BINARY_NAME}}", binaryName)
-	script = strings.ReplaceAll(script, "{{PACKAGE_NAME}}", packageName)
+	script = strings.ReplaceAll(script, "{{TEMPLATE_VERSION}}", strconv.Itoa(shimTemplateVersion))
+	script = strings.ReplaceAll(script, "{{WAND_DIR}}", ShellQuote(s.wandDir))
+	script = strings.ReplaceAll(script, "{{WAND_BIN}}", ShellQuote(s.executable))
+	script = strings.ReplaceAll(script, "{{BINARY_NAME}}", ShellQuote(binaryName))
+	script = strings.ReplaceAll(script, "{{PACKAGE_NAME}}", ShellQuote(packageName))
 	return script
 }
 
-// getShimTemplate returns the shim script template
+// ShellQuote quotes a value for sh, bash and zsh
+func ShellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// shimTemplateVersion is stamped into every shim. Bump it whenever the
+// template changes, so outdated shims can be found and rewritten.
+const shimTemplateVersion = 1
+
+// getShimTemplate returns the shim script template. Shims leave resolution
+// to wand exec, so they agree with wand which and wand resolve.
 func getShimTemplate() string {
 	return `#!/bin/sh
 # Wand shim for {{BINARY_NAME}}
-# This script automatically resolves the correct version based on .wandrc or global config
-
-set -e
-
-# Package info
-PACKAGE_NAME="{{PACKAGE_NAME}}"
-BINARY_NAME="{{BINARY_NAME}}"
-WAND_DIR="{{WAND_DIR}}"
-
-# Get current directory
-CURRENT_DIR="$(pwd)"
-
-# Function to find .wandrc and resolve version
-resolve_version() {
-    local dir="$1"
-    while [ "$dir" != "/" ] && [ "$dir" != "." ]; do
-        if [ -f "$dir/.wandrc" ]; then
-            # Parse YAML to get version for this package
-            # Looking for: versions:\n  package: version
-            version=$(grep -A 100 "^versions:" "$dir/.wandrc" | grep "^  $PACKAGE_NAME:" | awk '{print $2}' | tr -d '"' | head -1)
-            if [ -n "$version" ]; then
-                echo "$version"
-                return 0
-            fi
-        fi
-        dir="$(dirname "$dir")"
-    done
-
-    # Fall back to global version from registry
-    if [ -f "$WAND_DIR/registry.json" ]; then
-        # Extract global version from JSON (simple jq alternative)
-        version=$(grep -A 1 "\"$PACKAGE_NAME\":" "$WAND_DIR/registry.json" | tail -1 | tr -d ' ",')
-        if [ -n "$version" ]; then
-            echo "$version"
-            return 0
-        fi
-    fi
-
-    return 1
-}
-
-# Resolve version
-VERSION=$(resolve_version "$CURRENT_DIR")
-
-if [ -z "$VERSION" ]; then
-    echo "Error: No version found for $PACKAGE_NAME" >&2
-    echo "Run: wand install $PACKAGE_NAME" >&2
-    exit 1
+# wand-shim-version: {{TEMPLATE_VERSION}}
+# wand exec picks the version from WAND_<PACKAGE>_VERSION, .wandrc, other
+# tools' version files or the global version, sets the formula's runtime
+# environment and runs the binary
+
+WAND_BIN={{WAND_BIN}}
+if [ ! -x "$WAND_BIN" ]; then
+    WAND_BIN=wand
 fi
 
-# Construct binary path
-BINARY_PATH="$WAND_DIR/packages/$PACKAGE_NAME/$VERSION/bin/$BINARY_NAME"
-
-if [ ! -f "$BINARY_PATH" ]; then
-    echo "Error: Binary not found at $BINARY_PATH" >&2
-    echo "Try reinstalling: wand install $PACKAGE_NAME@$VERSION" >&2
-    exit 1
-fi
-
-# Execute the actual binary with all arguments
-exec "$BINARY_PATH" "$@"
+WAND_HOME={{WAND_DIR}}
+export WAND_HOME
+exec "$WAND_BIN" exec --package {{PACKAGE_NAME}} {{BINARY_NAME}} "$@"
 `
 }
 
-// RefreshAllShims recreates all shims for all installed packages
+// RefreshAllShims recreates all shims for all installed packages. A binary
+// several packages ship keeps its current owner while that is installed.
 func (s *ShimService) RefreshAllShims() error {
-	registry, err := s.registryRepo.Load()
+	providers, err := s.shimProviders()
 	if err != nil {
-		return errs.Wrap(errs.ErrRegistryCorrupted, "Failed to load registry", err)
+		return err
+	}
+	previous, err := s.loadShimIndex()
+	if err != nil {
+		return err
+	}
+	owners := make(map[string]string, len(providers))
+	for binary, names := range providers {
+		owner := s.shimOwner(previous, binary)
+		if !contains(names, owner) {
+			owner = names[0]
+		}
+		owners[binary] = owner
 	}
 
 	// Remove all existing shims
@@ -230,31 +797,13 @@ func (s *ShimService) RefreshAllShims() error {
 		return errs.Wrap(errs.ErrPermissionDenied, "Failed to create shims directory", err)
 	}
 
-	// Create shims for all CLI packages
-	for _, entry := range registry.ListAllPackages() {
-		if entry.Type != entities.PackageTypeCLI {
-			continue
-		}
-
-		// Load formula to get actual binary names
-		formula, err := s.formulaRepo.GetFormula(entry.Name)
-		if err != nil {
-			// Fallback: assume binary name matches package name
-			formula = nil
-		}
-
-		// Get binaries from formula or use package name as fallback
-		var binaries []string
-		if formula != nil && len(formula.Binaries) > 0 {
-			binaries = formula.Binaries
-		} else {
-			binaries = []string{entry.Name}
-		}
-
-		if err := s.CreateShims(entry.Name, binaries); err != nil {
-			return errs.Wrap(errs.ErrShimCreationFailed, fmt.Sprintf("Failed to create shims for %s", entry.Name), err)
+	index := entities.NewShimIndex()
+	for binary, owner := range owners {
+		if err := s.writeShim(binary, owner); err != nil {
+			return errs.Wrap(errs.ErrShimCreationFailed, fmt.Sprintf("Failed to create shims for %s", owner), err)
 		}
+		index.SetOwner(binary, owner)
 	}
 
-	return nil
+	return s.saveShimIndex(index)
 }
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	errs "github.com/ochairo/wand/internal/domain/errors"
+)
+
+// selectPlatformConfig picks the artifact to install: the platform's prebuilt
+// artifact, or the formula's source build when forced or when no prebuilt
+// artifact exists for this platform
+func selectPlatformConfig(formula *entities.Formula, platform *entities.Platform, buildFromSource bool) (*entities.PlatformConfig, error) {
+	config := formula.GetPlatformConfigFor(platform)
+
+	if buildFromSource {
+		if formula.Source != nil {
+			return formula.Source.PlatformConfig(), nil
+		}
+		if config != nil && config.RequiresBuild {
+			return config, nil
+		}
+		return nil, errs.NewWithDetails(errs.ErrConfigInvalid, fmt.Sprintf("%s cannot be built from source", formula.Name), "the formula has no source section")
+	}
+
+	if config == nil && formula.Source != nil {
+		return formula.Source.PlatformConfig(), nil
+	}
+	if config == nil {
+		return nil, errs.NewWithDetails(errs.ErrArchNotSupported, "Package not available for platform", fmt.Sprintf("package: %q, os: %s, arch: %s", formula.Name, platform.OS, platform.Arch))
+	}
+	return config, nil
+}
+
+// buildCacheDir returns where the built install tree for a version is cached.
+// The key includes the source URL and build commands, so changing the recipe
+// triggers a rebuild.
+func (s *InstallerService) buildCacheDir(formula *entities.Formula, config *entities.PlatformConfig, version *entities.Version, platform *entities.Platform) string {
+	recipe := append([]string{config.DownloadURL}, config.BuildCommands...)
+	key := fmt.Sprintf("%s-%s-%s-%s", version.String(), platform.OS, platform.Arch, entities.HookFingerprint(recipe)[:12])
+	return filepath.Join(s.wandDir, "cache", "builds", formula.Name, key)
+}
+
+// installFromBuildCache installs a previously built version without
+// downloading or building, then runs the post-install commands
+func (s *InstallerService) installFromBuildCache(ctx context.Context, formula *entities.Formula, version *entities.Version, cacheDir string, plan *HookPlan, opts InstallOptions, progress *installReporter) error {
+	progress.stage(StageExtracting, "restoring cached build")
+
+	installDir := s.installDir(formula.Name, version)
+	if err := s.replaceDir(cacheDir, installDir); err != nil {
+		return errs.Wrap(errs.ErrInstallationFailed, fmt.Sprintf("Failed to restore cached build of %s@%s", formula.Name, version.String()), err)
+	}
+
+	if err := s.hookRunner(progress).run(ctx, plan, HookPhasePostInstall, opts.HookTimeout); err != nil {
+		return err
+	}
+
+	return s.registerCLI(formula, version, installDir)
+}
+
+// buildCLI unpacks a source archive into a build directory, runs the build
+// commands there with {prefix} pointing at the install directory, caches the
+// result and runs the post-install commands
+func (s *InstallerService) buildCLI(
+	ctx context.Context,
+	formula *entities.Formula,
+	version *entities.Version,
+	downloadPath string,
+	cacheDir string,
+	plan *HookPlan,
+	opts InstallOptions,
+	progress *installReporter,
+) error {
+	if format, err := s.extractor.Format(downloadPath); err != nil || format == entities.ArchiveFormatNone || format.SingleFile() {
+		return errs.NewWithDetails(errs.ErrExtractionFailed, fmt.Sprintf("Source for %s@%s is not an archive", formula.Name, version.String()), downloadPath)
+	}
+
+	extraPath, err := s.buildDependencyPaths(ctx, formula, opts)
+	if err != nil {
+		return err
+	}
+
+	buildDir := filepath.Join(filepath.Dir(downloadPath), "src")
+	if err := s.fs.MkdirAll(buildDir, 0755); err != nil {
+		return errs.Wrap(errs.ErrPermissionDenied, "Failed to create build directory", err)
+	}
+	progress.stage(StageExtracting, "")
+	if err := s.extractor.ExtractContext(ctx, downloadPath, buildDir); err != nil {
+		return errs.Wrap(errs.ErrExtractionFailed, fmt.Sprintf("Failed to extract source for %s@%s", formula.Name, version.String()), err)
+	}
+
+	installDir := s.installDir(formula.Name, version)
+	_ = s.fs.RemoveAll(installDir)
+	if err := s.fs.MkdirAll(installDir, 0755); err != nil {
+		return errs.Wrap(errs.ErrPermissionDenied, fmt.Sprintf("Failed to create install directory for %s@%s", formula.Name, version.String()), err)
+	}
+
+	plan.BuildDir = s.sourceRoot(buildDir)
+	plan.ExtraPath = extraPath
+
+	hooks := s.hookRunner(progress)
+	if err := hooks.run(ctx, plan, HookPhaseBuild, opts.HookTimeout); err != nil {
+		return err
+	}
+
+	// A failed cache write only costs a rebuild next time
+	_ = s.replaceDir(installDir, cacheDir)
+
+	if err := hooks.run(ctx, plan, HookPhasePostInstall, opts.HookTimeout); err != nil {
+		return err
+	}
+
+	return s.registerCLI(formula, version, installDir)
+}
+
+// buildDependencyPaths installs missing build dependencies and returns their
+// bin directories. Dependencies are installed from prebuilt artifacts where
+// available, like any other package.
+func (s *InstallerService) buildDependencyPaths(ctx context.Context, formula *entities.Formula, opts InstallOptions) ([]string, error) {
+	if len(formula.BuildDependencies) == 0 {
+		return nil, nil
+	}
+
+	chain := append(append([]string{}, opts.buildChain...), formula.Name)
+	paths := make([]string, 0, len(formula.BuildDependencies))
+
+	for _, dep := range formula.BuildDependencies {
+		if contains(chain, dep) {
+			return nil, errs.NewWithDetails(errs.ErrConfigInvalid, fmt.Sprintf("Circular build dependency for %s", formula.Name), strings.Join(append(chain, dep), " -> "))
+		}
+
+		registry, err := s.registryRepo.Load()
+		if err != nil && !s.registryRepo.Exists() {
+			registry = entities.NewRegistry()
+		} else if err != nil {
+			return nil, errs.Wrap(errs.ErrRegistryCorrupted, "Failed to load registry", err)
+		}
+
+		if !registry.HasPackage(dep) {
+			depOpts := opts
+			depOpts.BuildFromSource = false
+			depOpts.buildChain = chain
+			if err := s.InstallPackageContext(ctx, dep, "latest", depOpts); err != nil {
+				return nil, errs.Wrap(errs.ErrInstallationFailed, fmt.Sprintf("Failed to install build dependency %s of %s", dep, formula.Name), err)
+			}
+			if registry, err = s.registryRepo.Load(); err != nil {
+				return nil, errs.Wrap(errs.ErrRegistryCorrupted, "Failed to load registry", err)
+			}
+		}
+
+		pkg := dependencyPackage(registry, dep)
+		if pkg == nil {
+			return nil, errs.New(errs.ErrPackageNotInstalled, fmt.Sprintf("Build dependency %s of %s is not installed", dep, formula.Name))
+		}
+		paths = append(paths, pkg.BinPath)
+	}
+
+	return paths, nil
+}
+
+// dependencyPackage returns the global version of an installed package, or
+// its newest version if none is global
+func dependencyPackage(registry *entities.Registry, name string) *entities.Package {
+	if version, ok := registry.GetGlobalVersion(name); ok {
+		if pkg, exists := registry.GetPackage(name, version); exists {
+			return pkg
+		}
+	}
+
+	entry, ok := registry.Packages[name]
+	if !ok {
+		return nil
+	}
+	var newest *entities.Package
+	for _, pkg := range entry.Versions {
+		if newest == nil || pkg.Version.GreaterThan(newest.Version) {
+			newest = pkg
+		}
+	}
+	return newest
+}
+
+// sourceRoot returns the directory build commands run in: the single
+// top-level directory most source archives unpack to, or dir itself
+func (s *InstallerService) sourceRoot(dir string) string {
+	entries := topLevelEntries(s.fs, dir)
+	if len(entries) == 1 && s.fs.IsDir(entries[0]) {
+		return entries[0]
+	}
+	return dir
+}
+
+// replaceDir replaces dst with a copy of src, staging the copy next to dst so
+// an interrupted copy never leaves a partial tree in place
+func (s *InstallerService) replaceDir(src, dst string) error {
+	staging := dst + ".tmp"
+	_ = s.fs.RemoveAll(staging)
+	if err := s.fs.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := s.fs.CopyDir(src, staging); err != nil {
+		_ = s.fs.RemoveAll(staging)
+		return err
+	}
+	if err := s.fs.RemoveAll(dst); err != nil {
+		return err
+	}
+	return s.fs.Rename(staging, dst)
+}
@@ -1,9 +1,10 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sort"
-	"strconv"
 	"strings"
 
 	"github.com/ochairo/wand/internal/domain/entities"
@@ -37,20 +38,63 @@ func parseRepository(repository string) (string, string, error) {
 	return parts[0], parts[1], nil
 }
 
-// ResolveVersion resolves "latest" or validates specific version
+// releasesError reports a failure to list a package's releases, telling a
+// rate limit apart from the network being unreachable
+func releasesError(packageName string, err error) error {
+	var rateLimit *interfaces.RateLimitError
+	if !errors.As(err, &rateLimit) {
+		return errs.Wrap(errs.ErrNetworkUnreachable, fmt.Sprintf("Failed to fetch releases for %s", packageName), err)
+	}
+
+	details := fmt.Sprintf("resets at %s", rateLimit.Reset.Local().Format("15:04:05"))
+	if !rateLimit.Authenticated {
+		details += "; set WAND_GITHUB_TOKEN to raise the limit"
+	}
+	return errs.NewWithDetails(errs.ErrRateLimited, fmt.Sprintf("GitHub API rate limit exceeded while fetching releases for %s", packageName), details)
+}
+
+// ResolveVersion resolves "latest" or a constraint such as "^1.7" to the newest
+// matching release, or validates an exact version
 func (s *VersionService) ResolveVersion(packageName, versionStr string) (*entities.Version, error) {
+	return s.ResolveVersionContext(context.Background(), packageName, versionStr)
+}
+
+// ResolveVersionContext is ResolveVersion that stops querying GitHub when ctx is done
+func (s *VersionService) ResolveVersionContext(ctx context.Context, packageName, versionStr string) (*entities.Version, error) {
 	if versionStr == "" || versionStr == "latest" {
-		return s.GetLatestVersion(packageName)
+		return s.GetLatestVersionContext(ctx, packageName)
 	}
 
 	// Parse and validate the version
-	version, err := entities.NewVersion(versionStr)
+	constraint, err := entities.ParseVersionConstraint(versionStr)
 	if err != nil {
 		return nil, errs.New(errs.ErrInvalidVersion, fmt.Sprintf("Invalid version: %q", versionStr))
 	}
+	version := constraint.IsExact()
+	if version == nil {
+		tagged, err := s.releaseVersions(ctx, packageName)
+		if err != nil {
+			return nil, err
+		}
+		// A release tagged exactly as written wins over the prefix it also
+		// reads as, so nano@8.7 is the 8.7 release rather than the newest 8.7.x
+		if exact, ok := tagged[strings.TrimPrefix(versionStr, "v")]; ok {
+			return exact, nil
+		}
+
+		// A range resolves to the newest release it allows
+		versions := make([]*entities.Version, 0, len(tagged))
+		for _, v := range tagged {
+			versions = append(versions, v)
+		}
+		if best := constraint.Best(versions); best != nil {
+			return best, nil
+		}
+		return nil, errs.NewWithDetails(errs.ErrVersionNotFound, "No version matching constraint", fmt.Sprintf("package: %q, constraint: %q", packageName, versionStr))
+	}
 
 	// Verify version exists for the package
-	exists, err := s.VersionExists(packageName, version)
+	exists, err := s.VersionExistsContext(ctx, packageName, version)
 	if err != nil {
 		return nil, err
 	}
@@ -63,6 +107,11 @@ func (s *VersionService) ResolveVersion(packageName, versionStr string) (*entiti
 
 // GetLatestVersion fetches the latest available version for a package
 func (s *VersionService) GetLatestVersion(packageName string) (*entities.Version, error) {
+	return s.GetLatestVersionContext(context.Background(), packageName)
+}
+
+// GetLatestVersionContext is GetLatestVersion that stops when ctx is done
+func (s *VersionService) GetLatestVersionContext(ctx context.Context, packageName string) (*entities.Version, error) {
 	formula, err := s.formulaRepo.GetFormula(packageName)
 	if err != nil {
 		return nil, errs.NewWithDetails(errs.ErrPackageNotFound, "Formula not found", fmt.Sprintf("package: %q", packageName))
@@ -74,9 +123,9 @@ func (s *VersionService) GetLatestVersion(packageName string) (*entities.Version
 		return nil, err
 	}
 
-	releases, err := s.githubClient.ListReleases(owner, repo)
+	releases, err := s.githubClient.ListReleasesContext(ctx, owner, repo)
 	if err != nil {
-		return nil, errs.Wrap(errs.ErrNetworkUnreachable, fmt.Sprintf("Failed to fetch releases for %s", packageName), err)
+		return nil, releasesError(packageName, err)
 	}
 
 	if len(releases) == 0 {
@@ -111,6 +160,11 @@ func (s *VersionService) GetLatestVersion(packageName string) (*entities.Version
 
 // VersionExists checks if a specific version exists for a package
 func (s *VersionService) VersionExists(packageName string, version *entities.Version) (bool, error) {
+	return s.VersionExistsContext(context.Background(), packageName, version)
+}
+
+// VersionExistsContext is VersionExists that stops when ctx is done
+func (s *VersionService) VersionExistsContext(ctx context.Context, packageName string, version *entities.Version) (bool, error) {
 	formula, err := s.formulaRepo.GetFormula(packageName)
 	if err != nil {
 		return false, errs.NewWithDetails(errs.ErrPackageNotFound, "Formula not found", fmt.Sprintf("package: %q", packageName))
@@ -121,9 +175,9 @@ func (s *VersionService) VersionExists(packageName string, version *entities.Ver
 		return false, err
 	}
 
-	releases, err := s.githubClient.ListReleases(owner, repo)
+	releases, err := s.githubClient.ListReleasesContext(ctx, owner, repo)
 	if err != nil {
-		return false, errs.Wrap(errs.ErrNetworkUnreachable, fmt.Sprintf("Failed to fetch releases for %s", packageName), err)
+		return false, releasesError(packageName, err)
 	}
 
 	// Check if version exists in releases
@@ -146,30 +200,18 @@ func (s *VersionService) VersionExists(packageName string, version *entities.Ver
 
 // ListAvailableVersions returns all available versions for a package
 func (s *VersionService) ListAvailableVersions(packageName string) ([]*entities.Version, error) {
-	formula, err := s.formulaRepo.GetFormula(packageName)
-	if err != nil {
-		return nil, errs.NewWithDetails(errs.ErrPackageNotFound, "Formula not found", fmt.Sprintf("package: %q", packageName))
-	}
+	return s.ListAvailableVersionsContext(context.Background(), packageName)
+}
 
-	owner, repo, err := parseRepository(formula.Repository)
+// ListAvailableVersionsContext is ListAvailableVersions that stops when ctx is done
+func (s *VersionService) ListAvailableVersionsContext(ctx context.Context, packageName string) ([]*entities.Version, error) {
+	tagged, err := s.releaseVersions(ctx, packageName)
 	if err != nil {
 		return nil, err
 	}
 
-	releases, err := s.githubClient.ListReleases(owner, repo)
-	if err != nil {
-		return nil, errs.Wrap(errs.ErrNetworkUnreachable, fmt.Sprintf("Failed to fetch releases for %s", packageName), err)
-	}
-
-	versions := make([]*entities.Version, 0)
-	for _, release := range releases {
-		// Strip package name prefix from tag (e.g., "nano-8.7" -> "8.7")
-		tagName := strings.TrimPrefix(release.TagName, packageName+"-")
-
-		version, err := entities.NewVersion(tagName)
-		if err != nil {
-			continue
-		}
+	versions := make([]*entities.Version, 0, len(tagged))
+	for _, version := range tagged {
 		versions = append(versions, version)
 	}
 
@@ -181,213 +223,59 @@ func (s *VersionService) ListAvailableVersions(packageName string) ([]*entities.
 	return versions, nil
 }
 
-// FindBestMatch finds the best version matching a constraint
-func (s *VersionService) FindBestMatch(packageName, constraint string) (*entities.Version, error) {
-	versions, err := s.ListAvailableVersions(packageName)
+// releaseVersions returns the versions of a package's releases, keyed by tag
+// without the package name and "v" prefixes
+func (s *VersionService) releaseVersions(ctx context.Context, packageName string) (map[string]*entities.Version, error) {
+	formula, err := s.formulaRepo.GetFormula(packageName)
 	if err != nil {
-		return nil, err
-	}
-
-	if len(versions) == 0 {
-		return nil, errs.NewWithDetails(errs.ErrVersionNotFound, "No versions found", fmt.Sprintf("package: %q", packageName))
-	}
-
-	// Handle constraint matching
-	switch constraint {
-	case "", "latest", "*":
-		return versions[0], nil // Already sorted, first is latest
-	default:
-		// Try semver constraint matching
-		if matched := s.matchConstraint(versions, constraint); matched != nil {
-			return matched, nil
-		}
-
-		// Fallback: try exact match
-		targetVersion, err := entities.NewVersion(constraint)
-		if err != nil {
-			return nil, errs.New(errs.ErrInvalidVersion, fmt.Sprintf("Invalid version constraint: %q", constraint))
-		}
-
-		for _, v := range versions {
-			if v.Equal(targetVersion) {
-				return v, nil
-			}
-		}
-
-		return nil, errs.NewWithDetails(errs.ErrVersionNotFound, "No version matching constraint", fmt.Sprintf("package: %q, constraint: %q", packageName, constraint))
-	}
-}
-
-// matchConstraint matches versions against a constraint pattern
-// Supports: ^1.2.3 (compatible), ~1.2.3 (approximately), >=1.0.0, <=2.0.0, 1.x, etc.
-func (s *VersionService) matchConstraint(versions []*entities.Version, constraint string) *entities.Version {
-	if len(constraint) == 0 {
-		return nil
-	}
-
-	// Handle caret (^) - compatible version
-	if constraint[0] == '^' {
-		return s.matchCaret(versions, constraint[1:])
-	}
-
-	// Handle tilde (~) - approximately version
-	if constraint[0] == '~' {
-		return s.matchTilde(versions, constraint[1:])
-	}
-
-	// Handle comparison operators
-	if len(constraint) > 1 {
-		switch constraint[:2] {
-		case ">=":
-			return s.matchGreaterOrEqual(versions, constraint[2:])
-		case "<=":
-			return s.matchLessOrEqual(versions, constraint[2:])
-		}
-	}
-
-	if len(constraint) > 0 {
-		if constraint[0] == '>' {
-			return s.matchGreater(versions, constraint[1:])
-		}
-		if constraint[0] == '<' {
-			return s.matchLess(versions, constraint[1:])
-		}
-	}
-
-	// Handle x-ranges (1.x, 1.2.x, etc.)
-	if strings.Contains(constraint, "x") {
-		return s.matchXRange(versions, constraint)
+		return nil, errs.NewWithDetails(errs.ErrPackageNotFound, "Formula not found", fmt.Sprintf("package: %q", packageName))
 	}
 
-	return nil
-}
-
-// matchCaret handles ^ (caret) constraint: ^1.2.3 allows >=1.2.3 <2.0.0
-func (s *VersionService) matchCaret(versions []*entities.Version, constraint string) *entities.Version {
-	baseVersion, err := entities.NewVersion(constraint)
+	owner, repo, err := parseRepository(formula.Repository)
 	if err != nil {
-		return nil
-	}
-
-	for _, v := range versions {
-		// Same major version, greater or equal to base
-		if v.Major == baseVersion.Major && (v.Minor > baseVersion.Minor ||
-			(v.Minor == baseVersion.Minor && v.Patch >= baseVersion.Patch)) {
-			return v
-		}
+		return nil, err
 	}
-	return nil
-}
 
-// matchTilde handles ~ (tilde) constraint: ~1.2.3 allows >=1.2.3 <1.3.0
-func (s *VersionService) matchTilde(versions []*entities.Version, constraint string) *entities.Version {
-	baseVersion, err := entities.NewVersion(constraint)
+	releases, err := s.githubClient.ListReleasesContext(ctx, owner, repo)
 	if err != nil {
-		return nil
-	}
-
-	for _, v := range versions {
-		// Same major.minor, patch >= base patch
-		if v.Major == baseVersion.Major && v.Minor == baseVersion.Minor && v.Patch >= baseVersion.Patch {
-			return v
-		}
+		return nil, releasesError(packageName, err)
 	}
-	return nil
-}
 
-// matchGreaterOrEqual handles >= constraint
-func (s *VersionService) matchGreaterOrEqual(versions []*entities.Version, constraint string) *entities.Version {
-	baseVersion, err := entities.NewVersion(constraint)
-	if err != nil {
-		return nil
-	}
+	tagged := make(map[string]*entities.Version, len(releases))
+	for _, release := range releases {
+		// Strip package name prefix from tag (e.g., "nano-8.7" -> "8.7")
+		tagName := strings.TrimPrefix(strings.TrimPrefix(release.TagName, packageName+"-"), "v")
 
-	for _, v := range versions {
-		if !v.LessThan(baseVersion) {
-			return v
+		version, err := entities.NewVersion(tagName)
+		if err != nil {
+			continue
 		}
-	}
-	return nil
-}
-
-// matchLessOrEqual handles <= constraint
-func (s *VersionService) matchLessOrEqual(versions []*entities.Version, constraint string) *entities.Version {
-	baseVersion, err := entities.NewVersion(constraint)
-	if err != nil {
-		return nil
+		tagged[tagName] = version
 	}
 
-	// Search backwards since versions are sorted descending
-	for i := len(versions) - 1; i >= 0; i-- {
-		if !versions[i].GreaterThan(baseVersion) {
-			return versions[i]
-		}
-	}
-	return nil
+	return tagged, nil
 }
 
-// matchGreater handles > constraint
-func (s *VersionService) matchGreater(versions []*entities.Version, constraint string) *entities.Version {
-	baseVersion, err := entities.NewVersion(constraint)
+// FindBestMatch finds the best version matching a constraint
+func (s *VersionService) FindBestMatch(packageName, constraint string) (*entities.Version, error) {
+	parsed, err := entities.ParseVersionConstraint(constraint)
 	if err != nil {
-		return nil
+		return nil, errs.New(errs.ErrInvalidVersion, fmt.Sprintf("Invalid version constraint: %q", constraint))
 	}
 
-	for _, v := range versions {
-		if v.GreaterThan(baseVersion) {
-			return v
-		}
-	}
-	return nil
-}
-
-// matchLess handles < constraint
-func (s *VersionService) matchLess(versions []*entities.Version, constraint string) *entities.Version {
-	baseVersion, err := entities.NewVersion(constraint)
+	versions, err := s.ListAvailableVersions(packageName)
 	if err != nil {
-		return nil
-	}
-
-	// Search backwards since versions are sorted descending
-	for i := len(versions) - 1; i >= 0; i-- {
-		if versions[i].LessThan(baseVersion) {
-			return versions[i]
-		}
-	}
-	return nil
-}
-
-// matchXRange handles x-ranges: 1.x, 1.2.x, etc.
-func (s *VersionService) matchXRange(versions []*entities.Version, constraint string) *entities.Version {
-	parts := strings.Split(constraint, ".")
-	if len(parts) == 0 {
-		return nil
-	}
-
-	var major, minor int
-	if major, _ = strconv.Atoi(parts[0]); len(parts) == 0 || parts[0] == "x" {
-		return versions[0] // Any version
+		return nil, err
 	}
 
-	if len(parts) > 1 && parts[1] != "x" {
-		minor, _ = strconv.Atoi(parts[1])
-	} else {
-		// Match major.x - return latest with matching major
-		for _, v := range versions {
-			if v.Major == major {
-				return v
-			}
-		}
-		return nil
+	if len(versions) == 0 {
+		return nil, errs.NewWithDetails(errs.ErrVersionNotFound, "No versions found", fmt.Sprintf("package: %q", packageName))
 	}
 
-	// Match major.minor.x - return latest with matching major.minor
-	for _, v := range versions {
-		if v.Major == major && v.Minor == minor {
-			return v
-		}
+	if best := parsed.Best(versions); best != nil {
+		return best, nil
 	}
-	return nil
+	return nil, errs.NewWithDetails(errs.ErrVersionNotFound, "No version matching constraint", fmt.Sprintf("package: %q, constraint: %q", packageName, constraint))
 }
 
 // CompareVersions compares two version strings
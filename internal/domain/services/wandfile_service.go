@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/ochairo/wand/internal/domain/entities"
@@ -45,16 +46,28 @@ func NewWandfileService(
 
 // Install installs all packages and configures dotfiles from a wandfile
 func (s *WandfileService) Install(wandfile *entities.Wandfile) error {
+	return s.InstallContext(context.Background(), wandfile)
+}
+
+// InstallContext installs all packages and configures dotfiles from a
+// wandfile, stopping when ctx is done
+func (s *WandfileService) InstallContext(ctx context.Context, wandfile *entities.Wandfile) error {
 	// Install CLI packages
 	for _, cliPkg := range wandfile.CLI {
-		if err := s.installerSvc.InstallPackage(cliPkg.Name, cliPkg.Version); err != nil {
+		if err := s.installerSvc.InstallPackageContext(ctx, cliPkg.Name, cliPkg.Version, InstallOptions{}); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
 			return errs.Wrap(errs.ErrInstallationFailed, fmt.Sprintf("Failed to install %s@%s", cliPkg.Name, cliPkg.Version), err)
 		}
 	}
 
 	// Install GUI packages
 	for _, guiPkg := range wandfile.GUI {
-		if err := s.installerSvc.InstallPackage(guiPkg, "latest"); err != nil {
+		if err := s.installerSvc.InstallPackageContext(ctx, guiPkg, "latest", InstallOptions{}); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
 			return errs.Wrap(errs.ErrInstallationFailed, fmt.Sprintf("Failed to install GUI app %s", guiPkg), err)
 		}
 	}
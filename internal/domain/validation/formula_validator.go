@@ -10,6 +10,9 @@ import (
 )
 
 // FormulaValidator validates formula YAML files
+//
+// Deprecated: FormulaSchema predates entities.Formula and no longer matches the
+// formula format. Use SchemaValidator.ValidateFormula instead.
 type FormulaValidator struct {
 	nameValidator    *PackageNameValidator
 	versionValidator *VersionValidator
@@ -18,6 +21,8 @@ type FormulaValidator struct {
 }
 
 // FormulaSchema represents the expected structure of a formula file
+//
+// Deprecated: use entities.Formula with SchemaValidator.
 type FormulaSchema struct {
 	Name        string            `yaml:"name"`
 	Version     string            `yaml:"version"`
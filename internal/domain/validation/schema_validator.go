@@ -0,0 +1,751 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+)
+
+// Severity classifies how serious a validation issue is
+type Severity string
+
+const (
+	// SeverityError marks an issue that makes the document unusable.
+	SeverityError Severity = "error"
+	// SeverityWarning marks an issue that is suspicious but not fatal.
+	SeverityWarning Severity = "warning"
+)
+
+// DocumentKind identifies which schema a YAML document is validated against
+type DocumentKind string
+
+const (
+	// KindFormula is a formula definition (formulas/*.yaml).
+	KindFormula DocumentKind = "formula"
+	// KindWandfile is a declarative system configuration file.
+	KindWandfile DocumentKind = "wandfile"
+	// KindWandRC is a per-project .wandrc file.
+	KindWandRC DocumentKind = "wandrc"
+)
+
+// Issue is a single validation finding with its position in the source document
+type Issue struct {
+	Severity Severity
+	Line     int
+	Column   int
+	Field    string // dotted path of the offending field, e.g. platforms.linux.amd64.download_url
+	Message  string
+}
+
+// String formats the issue as "line L, column C: field: message"
+func (i Issue) String() string {
+	var b strings.Builder
+	if i.Line > 0 {
+		fmt.Fprintf(&b, "line %d, column %d: ", i.Line, i.Column)
+	}
+	if i.Field != "" {
+		b.WriteString(i.Field + ": ")
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+// HasErrors reports whether any issue has error severity
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectKind guesses the document kind from the file name, falling back to a formula
+func DetectKind(path string) DocumentKind {
+	base := strings.ToLower(filepath.Base(path))
+	switch {
+	case base == ".wandrc" || strings.HasPrefix(base, ".wandrc."):
+		return KindWandRC
+	case base == "wandfile" || strings.HasSuffix(base, ".wandfile"):
+		return KindWandfile
+	default:
+		return KindFormula
+	}
+}
+
+// SchemaValidator validates formulas, wandfiles and .wandrc files against the
+// entity types wand actually loads, reporting issues with line and column.
+type SchemaValidator struct {
+	nameValidator *PackageNameValidator
+	urlValidator  *URLValidator
+	pathValidator *PathValidator
+}
+
+// NewSchemaValidator creates a new schema validator
+func NewSchemaValidator() *SchemaValidator {
+	return &SchemaValidator{
+		nameValidator: NewPackageNameValidator(),
+		// Formula URLs may point at any HTTPS host (upstream releases, mirrors)
+		urlValidator:  &URLValidator{allowedHosts: []string{}},
+		pathValidator: NewPathValidator(),
+	}
+}
+
+// Validate validates a document of the given kind
+func (v *SchemaValidator) Validate(kind DocumentKind, data []byte) []Issue {
+	switch kind {
+	case KindWandfile:
+		return v.ValidateWandfile(data)
+	case KindWandRC:
+		return v.ValidateWandRC(data)
+	default:
+		return v.ValidateFormula(data)
+	}
+}
+
+// ValidateFormula validates a formula YAML document
+func (v *SchemaValidator) ValidateFormula(data []byte) []Issue {
+	var formula entities.Formula
+	c, root := parseDocument(data, &formula)
+	if root == nil {
+		return c.sorted()
+	}
+
+	// Required fields
+	for _, field := range []string{"name", "type", "description", "homepage", "repository", "platforms"} {
+		if field == "platforms" && formula.Source != nil {
+			continue // source-only formulas build on every platform
+		}
+		if _, value := find(root, field); value == nil || isEmpty(value) {
+			c.errorf(root, field, "required field is missing")
+		}
+	}
+
+	if formula.Name != "" {
+		if err := v.nameValidator.Validate(formula.Name); err != nil {
+			c.errorf(valueAt(root, "name"), "name", "%v", err)
+		}
+	}
+
+	switch formula.Type {
+	case "", entities.PackageTypeCLI, entities.PackageTypeGUI, entities.PackageTypeDotfile:
+	default:
+		c.errorf(valueAt(root, "type"), "type", "unknown package type %q (expected cli, gui or dotfile)", formula.Type)
+	}
+
+	if formula.Homepage != "" {
+		if err := v.urlValidator.Validate(formula.Homepage); err != nil {
+			c.errorf(valueAt(root, "homepage"), "homepage", "%v", err)
+		}
+	}
+
+	if formula.Repository != "" {
+		if parts := strings.Split(formula.Repository, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			c.errorf(valueAt(root, "repository"), "repository", "expected owner/repo, got %q", formula.Repository)
+		}
+	}
+
+	v.validateBinaries(c, root, &formula)
+	v.validatePlatforms(c, root, &formula)
+	v.validateSignature(c, root, &formula)
+	v.validateSource(c, root, &formula)
+	v.validateRuntimeEnv(c, root, &formula)
+
+	for _, field := range []string{"min_version", "max_version"} {
+		node := valueAt(root, field)
+		if node == nil || node.Value == "" {
+			continue
+		}
+		if _, err := entities.NewVersion(node.Value); err != nil {
+			c.errorf(node, field, "%v", err)
+		}
+	}
+
+	for i, dep := range formula.Dependencies {
+		if err := v.nameValidator.Validate(dep); err != nil {
+			c.errorf(itemAt(root, i, "dependencies"), fmt.Sprintf("dependencies[%d]", i), "%v", err)
+		}
+	}
+
+	return c.sorted()
+}
+
+// validateBinaries checks the binary list against the package type
+func (v *SchemaValidator) validateBinaries(c *issueCollector, root *yaml.Node, formula *entities.Formula) {
+	switch formula.Type {
+	case entities.PackageTypeCLI:
+		if len(formula.Binaries) == 0 {
+			c.warnf(root, "binaries", "cli formula declares no binaries; a shim named after the package will be used")
+		}
+	case entities.PackageTypeGUI:
+		// binaries[0] names the launcher shim on linux
+		if _, ok := formula.Platforms["linux"]; !ok && len(formula.Binaries) > 0 {
+			c.warnf(valueAt(root, "binaries"), "binaries", "binaries are ignored for gui formulas without a linux platform")
+		}
+		if _, ok := formula.Platforms["darwin"]; ok && formula.AppName == "" {
+			c.errorf(root, "app_name", "gui formulas targeting darwin must set app_name")
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, binary := range formula.Binaries {
+		field := fmt.Sprintf("binaries[%d]", i)
+		node := itemAt(root, i, "binaries")
+		switch {
+		case binary == "":
+			c.errorf(node, field, "binary name cannot be empty")
+		case filepath.IsAbs(binary) || strings.HasPrefix(binary, "/"):
+			c.errorf(node, field, "binary path %q must be relative to the package directory", binary)
+		case containsDotDot(binary):
+			c.errorf(node, field, "binary path %q must not contain '..'", binary)
+		case seen[binary]:
+			c.errorf(node, field, "duplicate binary %q", binary)
+		}
+		seen[binary] = true
+	}
+
+	if formula.BinPath != "" && (filepath.IsAbs(formula.BinPath) || containsDotDot(formula.BinPath)) {
+		c.errorf(valueAt(root, "bin_path"), "bin_path", "must be a relative path inside the install directory")
+	}
+	if formula.StripComponents < 0 {
+		c.errorf(valueAt(root, "strip_components"), "strip_components", "must not be negative")
+	}
+}
+
+// validatePlatforms checks os/arch keys and per-platform download configuration
+func (v *SchemaValidator) validatePlatforms(c *issueCollector, root *yaml.Node, formula *entities.Formula) {
+	for osName, archConfig := range formula.Platforms {
+		osKey, _ := find(root, "platforms", osName)
+		if !contains(entities.SupportedOSes, osName) {
+			c.errorf(osKey, "platforms."+osName, "unsupported os %q (expected one of: %s)", osName, strings.Join(entities.SupportedOSes, ", "))
+		}
+
+		for arch, config := range archConfig {
+			field := "platforms." + osName + "." + arch
+			archKey, archValue := find(root, "platforms", osName, arch)
+			if !contains(entities.SupportedArches, arch) {
+				c.errorf(archKey, field, "unsupported arch %q (expected one of: %s)", arch, strings.Join(entities.SupportedArches, ", "))
+			}
+
+			if config == nil {
+				c.errorf(archKey, field, "platform configuration is empty")
+				continue
+			}
+
+			if config.DownloadURL == "" {
+				c.errorf(archValue, field+".download_url", "required field is missing")
+			} else {
+				node := valueAt(root, "platforms", osName, arch, "download_url")
+				v.validateURLTemplate(c, node, field+".download_url", config.DownloadURL)
+				if !strings.Contains(config.DownloadURL, "{version}") {
+					c.warnf(node, field+".download_url", "URL does not reference {version}; every version resolves to the same artifact")
+				}
+			}
+
+			if config.ChecksumURL != "" {
+				node := valueAt(root, "platforms", osName, arch, "checksum_url")
+				v.validateURLTemplate(c, node, field+".checksum_url", config.ChecksumURL)
+			}
+
+			if config.ChecksumAlgorithm != "" {
+				node := valueAt(root, "platforms", osName, arch, "checksum_algorithm")
+				if !contains(entities.ChecksumAlgorithms, config.ChecksumAlgorithm) {
+					c.errorf(node, field+".checksum_algorithm", "unsupported algorithm %q (expected one of: %s)", config.ChecksumAlgorithm, strings.Join(entities.ChecksumAlgorithms, ", "))
+				}
+				if config.ChecksumURL == "" {
+					c.warnf(node, field+".checksum_algorithm", "checksum_algorithm has no effect without checksum_url")
+				}
+			}
+
+			if config.SignatureURL != "" {
+				node := valueAt(root, "platforms", osName, arch, "signature_url")
+				v.validateURLTemplate(c, node, field+".signature_url", config.SignatureURL)
+				if formula.Signature == nil {
+					c.errorf(node, field+".signature_url", "signature_url requires a top-level signature key")
+				}
+			} else if formula.Signature != nil {
+				c.errorf(archValue, field+".signature_url", "formula pins a signature key but this platform has no signature_url")
+			}
+
+			validatePinnedDigests(c, root, field, config.SHA256, "platforms", osName, arch)
+
+			for key, path := range map[string]string{"desktop_file": config.DesktopFile, "icon_file": config.IconFile} {
+				if path != "" && (filepath.IsAbs(path) || containsDotDot(path)) {
+					c.errorf(valueAt(root, "platforms", osName, arch, key), field+"."+key, "must be a relative path inside the archive")
+				}
+			}
+			if ext := strings.ToLower(filepath.Ext(config.IconFile)); config.IconFile != "" && !contains(entities.IconExtensions, ext) {
+				c.errorf(valueAt(root, "platforms", osName, arch, "icon_file"), field+".icon_file", "unsupported icon format %q (expected one of: %s)", ext, strings.Join(entities.IconExtensions, ", "))
+			}
+
+			if config.RequiresBuild && len(config.BuildCommands) == 0 {
+				c.errorf(archValue, field+".build_commands", "requires_build is set but no build_commands are given")
+			}
+			if !config.RequiresBuild && len(config.BuildCommands) > 0 {
+				c.warnf(valueAt(root, "platforms", osName, arch, "build_commands"), field+".build_commands", "build_commands are ignored unless requires_build is true")
+			}
+		}
+	}
+}
+
+// validateSignature checks the pinned signature key configuration
+func (v *SchemaValidator) validateSignature(c *issueCollector, root *yaml.Node, formula *entities.Formula) {
+	signature := formula.Signature
+	if signature == nil {
+		return
+	}
+
+	node := valueAt(root, "signature")
+	if !contains(entities.SignatureTypes, signature.Type) {
+		c.errorf(valueIn(node, "type"), "signature.type", "unsupported signature type %q (expected one of: %s)", signature.Type, strings.Join(entities.SignatureTypes, ", "))
+	}
+	if strings.TrimSpace(signature.PublicKey) == "" {
+		c.errorf(node, "signature.public_key", "required field is missing")
+	}
+	switch signature.Target {
+	case "", "artifact", "checksums":
+	default:
+		c.errorf(valueIn(node, "target"), "signature.target", "unknown target %q (expected artifact or checksums)", signature.Target)
+	}
+}
+
+// validateSource checks the source build configuration and build dependencies
+func (v *SchemaValidator) validateSource(c *issueCollector, root *yaml.Node, formula *entities.Formula) {
+	if source := formula.Source; source != nil {
+		node := valueAt(root, "source")
+		if formula.Type == entities.PackageTypeGUI {
+			c.errorf(node, "source", "gui formulas cannot be built from source")
+		}
+
+		if source.URL == "" {
+			c.errorf(node, "source.url", "required field is missing")
+		} else {
+			urlNode := valueIn(node, "url")
+			v.validateURLTemplate(c, urlNode, "source.url", source.URL)
+			if !strings.Contains(source.URL, "{version}") {
+				c.warnf(urlNode, "source.url", "URL does not reference {version}; every version resolves to the same archive")
+			}
+		}
+
+		if source.ChecksumURL != "" {
+			v.validateURLTemplate(c, valueIn(node, "checksum_url"), "source.checksum_url", source.ChecksumURL)
+		}
+
+		validatePinnedDigests(c, root, "source", source.SHA256, "source")
+
+		if len(source.Commands) == 0 {
+			c.errorf(node, "source.commands", "required field is missing")
+		}
+	}
+
+	seen := make(map[string]bool)
+	for i, dep := range formula.BuildDependencies {
+		field := fmt.Sprintf("build_dependencies[%d]", i)
+		node := itemAt(root, i, "build_dependencies")
+		if err := v.nameValidator.Validate(dep); err != nil {
+			c.errorf(node, field, "%v", err)
+		}
+		if dep == formula.Name {
+			c.errorf(node, field, "a formula cannot be its own build dependency")
+		}
+		if seen[dep] {
+			c.errorf(node, field, "duplicate build dependency %q", dep)
+		}
+		seen[dep] = true
+	}
+	if len(formula.BuildDependencies) > 0 && formula.Source == nil {
+		c.warnf(valueAt(root, "build_dependencies"), "build_dependencies", "build_dependencies are only used when building from source")
+	}
+}
+
+// envNamePattern matches portable environment variable names
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateRuntimeEnv checks the env variables and PATH directories
+func (v *SchemaValidator) validateRuntimeEnv(c *issueCollector, root *yaml.Node, formula *entities.Formula) {
+	for name, value := range formula.Env {
+		key, valueNode := find(root, "env", name)
+		field := "env." + name
+		switch {
+		case !envNamePattern.MatchString(name):
+			c.errorf(key, field, "invalid environment variable name")
+		case name == "PATH":
+			c.errorf(key, field, "use path to add directories to PATH")
+		case strings.HasPrefix(name, "WAND_"):
+			c.errorf(key, field, "WAND_ variables are reserved")
+		}
+		if rest := strings.ReplaceAll(value, entities.InstallPathPlaceholder, ""); strings.Contains(rest, "{") {
+			c.warnf(valueNode, field, "only %s is expanded in env values", entities.InstallPathPlaceholder)
+		}
+	}
+
+	for i, dir := range formula.Path {
+		field := fmt.Sprintf("path[%d]", i)
+		if filepath.IsAbs(dir) || containsDotDot(dir) {
+			c.errorf(itemAt(root, i, "path"), field, "must be a relative path inside the install directory")
+		}
+	}
+}
+
+// validatePinnedDigests checks a version -> sha256 map found at path
+func validatePinnedDigests(c *issueCollector, root *yaml.Node, field string, digests map[string]string, path ...string) {
+	for versionKey, sum := range digests {
+		key, value := find(root, append(append([]string{}, path...), "sha256", versionKey)...)
+		if _, err := entities.NewVersion(versionKey); err != nil {
+			c.errorf(key, field+".sha256."+versionKey, "invalid version %q", versionKey)
+		}
+		if !sha256Pattern.MatchString(sum) {
+			c.errorf(value, field+".sha256."+versionKey, "expected a 64 character hex sha256 digest")
+		}
+	}
+}
+
+// sha256Pattern matches a hex-encoded sha256 digest
+var sha256Pattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// placeholderPattern matches {name} placeholders in URL templates
+var placeholderPattern = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// validateURLTemplate checks placeholders and the URL shape of a download template
+func (v *SchemaValidator) validateURLTemplate(c *issueCollector, node *yaml.Node, field, template string) {
+	for _, match := range placeholderPattern.FindAllStringSubmatch(template, -1) {
+		if !contains(entities.URLPlaceholders, match[1]) {
+			c.errorf(node, field, "unknown placeholder {%s} (supported: {%s})", match[1], strings.Join(entities.URLPlaceholders, "}, {"))
+		}
+	}
+
+	// Substitute sample values so the template can be parsed as a URL
+	sample := placeholderPattern.ReplaceAllString(template, "x")
+	if err := v.urlValidator.Validate(sample); err != nil {
+		c.errorf(node, field, "%v", err)
+	}
+}
+
+// ValidateWandfile validates a wandfile YAML document
+func (v *SchemaValidator) ValidateWandfile(data []byte) []Issue {
+	var wandfile entities.Wandfile
+	c, root := parseDocument(data, &wandfile)
+	if root == nil {
+		return c.sorted()
+	}
+
+	seen := make(map[string]bool)
+	for i, cli := range wandfile.CLI {
+		field := fmt.Sprintf("cli[%d]", i)
+		item := itemAt(root, i, "cli")
+		if cli.Name == "" {
+			c.errorf(item, field+".name", "required field is missing")
+		} else {
+			if err := v.nameValidator.Validate(cli.Name); err != nil {
+				c.errorf(valueIn(item, "name"), field+".name", "%v", err)
+			}
+			if seen[cli.Name] {
+				c.errorf(valueIn(item, "name"), field+".name", "duplicate package %q", cli.Name)
+			}
+			seen[cli.Name] = true
+		}
+		if cli.Version == "" {
+			c.errorf(item, field+".version", "required field is missing")
+		} else if err := validateVersionSpec(cli.Version); err != nil {
+			c.errorf(valueIn(item, "version"), field+".version", "%v", err)
+		}
+	}
+
+	for i, gui := range wandfile.GUI {
+		field := fmt.Sprintf("gui[%d]", i)
+		item := itemAt(root, i, "gui")
+		if err := v.nameValidator.Validate(gui); err != nil {
+			c.errorf(item, field, "%v", err)
+		}
+		if seen[gui] {
+			c.errorf(item, field, "duplicate package %q", gui)
+		}
+		seen[gui] = true
+	}
+
+	if wandfile.Dotfiles != nil {
+		if wandfile.Dotfiles.Repo == "" {
+			c.errorf(valueAt(root, "dotfiles"), "dotfiles.repo", "required field is missing")
+		}
+		for target, source := range wandfile.Dotfiles.Symlinks {
+			key, value := find(root, "dotfiles", "symlinks", target)
+			if err := v.pathValidator.Validate(target); err != nil {
+				c.errorf(key, "dotfiles.symlinks", "target %q: %v", target, err)
+			}
+			if err := v.pathValidator.Validate(source); err != nil {
+				c.errorf(value, "dotfiles.symlinks."+target, "source %q: %v", source, err)
+			}
+		}
+	}
+
+	return c.sorted()
+}
+
+// ValidateWandRC validates a .wandrc YAML document
+func (v *SchemaValidator) ValidateWandRC(data []byte) []Issue {
+	var wandrc entities.WandRC
+	c, root := parseDocument(data, &wandrc)
+	if root == nil {
+		return c.sorted()
+	}
+
+	for name, version := range wandrc.Versions {
+		key, value := find(root, "versions", name)
+		if err := v.nameValidator.Validate(name); err != nil {
+			c.errorf(key, "versions."+name, "%v", err)
+		}
+		if version == "" {
+			c.errorf(key, "versions."+name, "version cannot be empty")
+		} else if err := validateVersionSpec(version); err != nil {
+			c.errorf(value, "versions."+name, "%v", err)
+		}
+	}
+
+	return c.sorted()
+}
+
+// validateVersionSpec accepts "latest", a version or a constraint such as ^1.7
+func validateVersionSpec(spec string) error {
+	if _, err := entities.ParseVersionConstraint(spec); err != nil {
+		return fmt.Errorf("invalid version %q", spec)
+	}
+	return nil
+}
+
+// issueCollector accumulates issues while walking a document
+type issueCollector struct {
+	issues []Issue
+}
+
+func (c *issueCollector) add(severity Severity, node *yaml.Node, field, format string, args ...interface{}) {
+	issue := Issue{
+		Severity: severity,
+		Field:    field,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if node != nil {
+		issue.Line = node.Line
+		issue.Column = node.Column
+	}
+	c.issues = append(c.issues, issue)
+}
+
+func (c *issueCollector) errorf(node *yaml.Node, field, format string, args ...interface{}) {
+	c.add(SeverityError, node, field, format, args...)
+}
+
+func (c *issueCollector) warnf(node *yaml.Node, field, format string, args ...interface{}) {
+	c.add(SeverityWarning, node, field, format, args...)
+}
+
+// sorted returns the issues ordered by position so output is stable
+func (c *issueCollector) sorted() []Issue {
+	sort.SliceStable(c.issues, func(i, j int) bool {
+		if c.issues[i].Line != c.issues[j].Line {
+			return c.issues[i].Line < c.issues[j].Line
+		}
+		return c.issues[i].Column < c.issues[j].Column
+	})
+	return c.issues
+}
+
+// typeErrorPattern extracts the line number from yaml.v3 type error messages
+var typeErrorPattern = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// parseDocument parses YAML into a node tree, decodes it into out and reports
+// syntax errors, type errors and unknown keys. It returns a nil root when the
+// document cannot be inspected any further.
+func parseDocument(data []byte, out interface{}) (*issueCollector, *yaml.Node) {
+	c := &issueCollector{}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		c.errorf(nil, "", "invalid YAML: %v", err)
+		return c, nil
+	}
+	if len(doc.Content) == 0 {
+		c.errorf(nil, "", "document is empty")
+		return c, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		c.errorf(root, "", "expected a mapping at the top level")
+		return c, nil
+	}
+
+	if err := root.Decode(out); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			c.errorf(root, "", "%v", err)
+			return c, nil
+		}
+		for _, msg := range typeErr.Errors {
+			issue := Issue{Severity: SeverityError, Message: msg}
+			if m := typeErrorPattern.FindStringSubmatch(msg); m != nil {
+				issue.Line, _ = strconv.Atoi(m[1])
+				issue.Message = m[2]
+			}
+			c.issues = append(c.issues, issue)
+		}
+	}
+
+	checkKeys(c, root, reflect.TypeOf(out), "")
+	return c, root
+}
+
+// checkKeys reports mapping keys that have no matching yaml tag in the target type
+func checkKeys(c *issueCollector, node *yaml.Node, t reflect.Type, field string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fieldType, ok := fields[key.Value]
+			if !ok {
+				c.errorf(key, joinField(field, key.Value), "unknown key %q", key.Value)
+				continue
+			}
+			checkKeys(c, value, fieldType, joinField(field, key.Value))
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			checkKeys(c, node.Content[i+1], t.Elem(), joinField(field, node.Content[i].Value))
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range node.Content {
+			checkKeys(c, item, t.Elem(), fmt.Sprintf("%s[%d]", field, i))
+		}
+	}
+}
+
+// yamlFields maps yaml keys to field types, following yaml.v3 naming rules
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		tag := f.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if strings.Contains(opts, "inline") {
+			for k, v := range yamlFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// find returns the key and value nodes at a mapping path
+func find(node *yaml.Node, path ...string) (*yaml.Node, *yaml.Node) {
+	var key *yaml.Node
+	for _, segment := range path {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return nil, nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == segment {
+				key, next = node.Content[i], node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil, nil
+		}
+		node = next
+	}
+	return key, node
+}
+
+// valueAt returns the value node at a mapping path
+func valueAt(node *yaml.Node, path ...string) *yaml.Node {
+	_, value := find(node, path...)
+	return value
+}
+
+// valueIn returns the value of a key within a mapping node, falling back to the mapping itself
+func valueIn(node *yaml.Node, key string) *yaml.Node {
+	if value := valueAt(node, key); value != nil {
+		return value
+	}
+	return node
+}
+
+// itemAt returns the i-th element of the sequence at a mapping path
+func itemAt(node *yaml.Node, i int, path ...string) *yaml.Node {
+	seq := valueAt(node, path...)
+	if seq == nil || seq.Kind != yaml.SequenceNode || i >= len(seq.Content) {
+		return seq
+	}
+	return seq.Content[i]
+}
+
+// isEmpty reports whether a node carries no value
+func isEmpty(node *yaml.Node) bool {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Value == "" || node.Tag == "!!null"
+	case yaml.MappingNode, yaml.SequenceNode:
+		return len(node.Content) == 0
+	}
+	return false
+}
+
+// containsDotDot reports whether a slash-separated path has a ".." element
+func containsDotDot(path string) bool {
+	for _, part := range strings.Split(path, "/") {
+		if part == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+func joinField(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+	return parent + "." + child
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,393 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+const validFormula = `name: ripgrep
+description: Fast line-oriented search tool
+type: cli
+homepage: https://github.com/BurntSushi/ripgrep
+repository: BurntSushi/ripgrep
+license: MIT
+tags: [search]
+binaries:
+  - rg
+platforms:
+  linux:
+    amd64:
+      download_url: https://github.com/BurntSushi/ripgrep/releases/download/{version}/ripgrep-{version}-x86_64-unknown-linux-musl.tar.gz
+      checksum_url: https://github.com/BurntSushi/ripgrep/releases/download/{version}/ripgrep-{version}-x86_64-unknown-linux-musl.tar.gz.sha256
+`
+
+func findIssue(issues []Issue, field, substr string) *Issue {
+	for i := range issues {
+		if issues[i].Field == field && strings.Contains(issues[i].Message, substr) {
+			return &issues[i]
+		}
+	}
+	return nil
+}
+
+func TestSchemaValidator_ValidateFormula(t *testing.T) {
+	validator := NewSchemaValidator()
+
+	tests := []struct {
+		name     string
+		yaml     string
+		field    string
+		contains string
+		severity Severity
+		line     int
+	}{
+		{
+			name:     "unknown top-level key",
+			yaml:     validFormula + "homepag: https://example.com\n",
+			field:    "homepag",
+			contains: "unknown key",
+			severity: SeverityError,
+			line:     15,
+		},
+		{
+			name:     "unknown platform key",
+			yaml:     strings.Replace(validFormula, "checksum_url:", "checksum_ur:", 1),
+			field:    "platforms.linux.amd64.checksum_ur",
+			contains: "unknown key",
+			severity: SeverityError,
+			line:     14,
+		},
+		{
+			name:     "unsupported os",
+			yaml:     strings.Replace(validFormula, "  linux:", "  windows:", 1),
+			field:    "platforms.windows",
+			contains: "unsupported os",
+			severity: SeverityError,
+			line:     11,
+		},
+		{
+			name:     "unsupported arch",
+			yaml:     strings.Replace(validFormula, "    amd64:", "    x86_64:", 1),
+			field:    "platforms.linux.x86_64",
+			contains: "unsupported arch",
+			severity: SeverityError,
+			line:     12,
+		},
+		{
+			name:     "unknown placeholder",
+			yaml:     strings.Replace(validFormula, "download/{version}/", "download/{tag}/", 1),
+			field:    "platforms.linux.amd64.download_url",
+			contains: "unknown placeholder {tag}",
+			severity: SeverityError,
+			line:     13,
+		},
+		{
+			name:     "non-https url",
+			yaml:     strings.Replace(validFormula, "download_url: https://", "download_url: http://", 1),
+			field:    "platforms.linux.amd64.download_url",
+			contains: "HTTPS",
+			severity: SeverityError,
+			line:     13,
+		},
+		{
+			name:     "missing required field",
+			yaml:     strings.Replace(validFormula, "repository: BurntSushi/ripgrep\n", "", 1),
+			field:    "repository",
+			contains: "required",
+			severity: SeverityError,
+			line:     1,
+		},
+		{
+			name:     "wrong type",
+			yaml:     strings.Replace(validFormula, "tags: [search]", "tags: {a: b}", 1),
+			field:    "",
+			contains: "cannot unmarshal",
+			severity: SeverityError,
+			line:     7,
+		},
+		{
+			name:     "invalid package type",
+			yaml:     strings.Replace(validFormula, "type: cli", "type: tool", 1),
+			field:    "type",
+			contains: "unknown package type",
+			severity: SeverityError,
+			line:     3,
+		},
+		{
+			name:     "cli without binaries",
+			yaml:     strings.Replace(validFormula, "binaries:\n  - rg\n", "", 1),
+			field:    "binaries",
+			contains: "no binaries",
+			severity: SeverityWarning,
+			line:     1,
+		},
+		{
+			name:     "binary path escapes package",
+			yaml:     strings.Replace(validFormula, "  - rg", "  - ../rg", 1),
+			field:    "binaries[0]",
+			contains: "..",
+			severity: SeverityError,
+			line:     9,
+		},
+		{
+			name:     "bin_path escapes package",
+			yaml:     strings.Replace(validFormula, "binaries:\n", "bin_path: ../bin\nbinaries:\n", 1),
+			field:    "bin_path",
+			contains: "relative path",
+			severity: SeverityError,
+			line:     8,
+		},
+		{
+			name:     "negative strip_components",
+			yaml:     strings.Replace(validFormula, "binaries:\n", "strip_components: -1\nbinaries:\n", 1),
+			field:    "strip_components",
+			contains: "negative",
+			severity: SeverityError,
+			line:     8,
+		},
+		{
+			name:     "requires_build without commands",
+			yaml:     validFormula + "      requires_build: true\n",
+			field:    "platforms.linux.amd64.build_commands",
+			contains: "requires_build",
+			severity: SeverityError,
+			line:     13,
+		},
+		{
+			name:     "desktop_file escapes archive",
+			yaml:     validFormula + "      desktop_file: ../rg.desktop\n",
+			field:    "platforms.linux.amd64.desktop_file",
+			contains: "relative path",
+			severity: SeverityError,
+			line:     15,
+		},
+		{
+			name:     "unsupported icon format",
+			yaml:     validFormula + "      icon_file: share/rg.xpm\n",
+			field:    "platforms.linux.amd64.icon_file",
+			contains: "unsupported icon format",
+			severity: SeverityError,
+			line:     15,
+		},
+		{
+			name:     "unsupported checksum algorithm",
+			yaml:     validFormula + "      checksum_algorithm: md5\n",
+			field:    "platforms.linux.amd64.checksum_algorithm",
+			contains: "unsupported algorithm",
+			severity: SeverityError,
+			line:     15,
+		},
+		{
+			name:     "signature_url without key",
+			yaml:     validFormula + "      signature_url: https://example.com/{version}/rg.minisig\n",
+			field:    "platforms.linux.amd64.signature_url",
+			contains: "requires a top-level signature",
+			severity: SeverityError,
+			line:     15,
+		},
+		{
+			name:     "signature key without signature_url",
+			yaml:     validFormula + "signature:\n  type: minisign\n  public_key: RWQ\n",
+			field:    "platforms.linux.amd64.signature_url",
+			contains: "no signature_url",
+			severity: SeverityError,
+			line:     13,
+		},
+		{
+			name:     "unsupported signature type",
+			yaml:     validFormula + "      signature_url: https://example.com/{version}/rg.sig\nsignature:\n  type: pgp\n  public_key: key\n",
+			field:    "signature.type",
+			contains: "unsupported signature type",
+			severity: SeverityError,
+			line:     17,
+		},
+		{
+			name:     "pinned sha256 with invalid version",
+			yaml:     validFormula + "      sha256:\n        banana: " + strings.Repeat("a", 64) + "\n",
+			field:    "platforms.linux.amd64.sha256.banana",
+			contains: "invalid version",
+			severity: SeverityError,
+			line:     16,
+		},
+		{
+			name:     "pinned sha256 with malformed digest",
+			yaml:     validFormula + "      sha256:\n        \"14.1.1\": abc123\n",
+			field:    "platforms.linux.amd64.sha256.14.1.1",
+			contains: "64 character hex",
+			severity: SeverityError,
+			line:     16,
+		},
+		{
+			name:     "source without commands",
+			yaml:     validFormula + "source:\n  url: https://example.com/rg-{version}.tar.gz\n",
+			field:    "source.commands",
+			contains: "required",
+			severity: SeverityError,
+			line:     16,
+		},
+		{
+			name:     "formula is its own build dependency",
+			yaml:     validFormula + "source:\n  url: https://example.com/rg-{version}.tar.gz\n  commands: [make]\nbuild_dependencies:\n  - ripgrep\n",
+			field:    "build_dependencies[0]",
+			contains: "own build dependency",
+			severity: SeverityError,
+			line:     19,
+		},
+		{
+			name:     "env sets PATH",
+			yaml:     validFormula + "env:\n  PATH: \"{install_path}/bin\"\n",
+			field:    "env.PATH",
+			contains: "use path",
+			severity: SeverityError,
+			line:     16,
+		},
+		{
+			name:     "env with unknown placeholder",
+			yaml:     validFormula + "env:\n  RG_HOME: \"{prefix}\"\n",
+			field:    "env.RG_HOME",
+			contains: "only {install_path}",
+			severity: SeverityWarning,
+			line:     16,
+		},
+		{
+			name:     "path outside the install directory",
+			yaml:     validFormula + "path:\n  - ../bin\n",
+			field:    "path[0]",
+			contains: "relative path",
+			severity: SeverityError,
+			line:     16,
+		},
+		{
+			name:     "gui on darwin without app_name",
+			yaml:     strings.Replace(strings.Replace(validFormula, "type: cli", "type: gui", 1), "  linux:", "  darwin:", 1),
+			field:    "app_name",
+			contains: "app_name",
+			severity: SeverityError,
+			line:     1,
+		},
+		{
+			name:     "gui without linux declares binaries",
+			yaml:     strings.Replace(strings.Replace(validFormula, "type: cli", "type: gui\napp_name: ripgrep", 1), "  linux:", "  darwin:", 1),
+			field:    "binaries",
+			contains: "without a linux platform",
+			severity: SeverityWarning,
+			line:     10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := validator.ValidateFormula([]byte(tt.yaml))
+			issue := findIssue(issues, tt.field, tt.contains)
+			if issue == nil {
+				t.Fatalf("expected issue on %q containing %q, got %v", tt.field, tt.contains, issues)
+			}
+			if issue.Severity != tt.severity {
+				t.Errorf("severity = %s, want %s", issue.Severity, tt.severity)
+			}
+			if issue.Line != tt.line {
+				t.Errorf("line = %d, want %d (%s)", issue.Line, tt.line, issue)
+			}
+		})
+	}
+}
+
+func TestSchemaValidator_ValidFormula(t *testing.T) {
+	issues := NewSchemaValidator().ValidateFormula([]byte(validFormula))
+	if len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+}
+
+func TestSchemaValidator_LinuxGUIBinaries(t *testing.T) {
+	// binaries[0] names the launcher shim of a linux gui formula
+	formula := strings.Replace(validFormula, "type: cli", "type: gui", 1)
+	if issues := NewSchemaValidator().ValidateFormula([]byte(formula)); len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+}
+
+func TestSchemaValidator_InvalidYAML(t *testing.T) {
+	issues := NewSchemaValidator().ValidateFormula([]byte("name: [unterminated"))
+	if !HasErrors(issues) {
+		t.Fatal("expected a syntax error")
+	}
+}
+
+func TestSchemaValidator_ValidateWandfile(t *testing.T) {
+	validator := NewSchemaValidator()
+
+	valid := `cli:
+  - name: nano
+    version: "8.2"
+  - name: make
+    version: latest
+gui:
+  - firefox
+dotfiles:
+  repo: https://github.com/user/dotfiles
+  symlinks:
+    ~/.zshrc: zsh/.zshrc
+`
+	if issues := validator.ValidateWandfile([]byte(valid)); len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+
+	tests := []struct {
+		name     string
+		yaml     string
+		field    string
+		contains string
+	}{
+		{"missing version", "cli:\n  - name: nano\n", "cli[0].version", "required"},
+		{"bad version", "cli:\n  - name: nano\n    version: banana\n", "cli[0].version", "invalid version"},
+		{"duplicate", "cli:\n  - name: nano\n    version: latest\n  - name: nano\n    version: \"8.2\"\n", "cli[1].name", "duplicate"},
+		{"legacy pin key", "cli:\n  - name: nano\n    version: latest\n    pin: true\n", "cli[0].pin", "unknown key"},
+		{"dotfiles without repo", "dotfiles:\n  symlinks:\n    ~/.zshrc: zsh/.zshrc\n", "dotfiles.repo", "required"},
+		{"symlink traversal", "dotfiles:\n  repo: https://example.com/d\n  symlinks:\n    ~/.zshrc: ../../etc/passwd\n", "dotfiles.symlinks.~/.zshrc", "source"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := validator.ValidateWandfile([]byte(tt.yaml))
+			if findIssue(issues, tt.field, tt.contains) == nil {
+				t.Errorf("expected issue on %q containing %q, got %v", tt.field, tt.contains, issues)
+			}
+		})
+	}
+}
+
+func TestSchemaValidator_ValidateWandRC(t *testing.T) {
+	validator := NewSchemaValidator()
+
+	if issues := validator.ValidateWandRC([]byte("versions:\n  nano: \"8.2\"\n")); len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+	if issues := validator.ValidateWandRC([]byte("versions:\n  node: \"^20\"\n  go: \">=1.21 <1.23\"\n  jq: 1.7.x\n")); len(issues) != 0 {
+		t.Errorf("expected constraints to be accepted, got %v", issues)
+	}
+
+	issues := validator.ValidateWandRC([]byte("versions:\n  Nano: \"8.2\"\n  make: x.y\n"))
+	if findIssue(issues, "versions.Nano", "") == nil {
+		t.Errorf("expected invalid name issue, got %v", issues)
+	}
+	if issue := findIssue(issues, "versions.make", "invalid version"); issue == nil || issue.Line != 3 {
+		t.Errorf("expected invalid version issue on line 3, got %v", issues)
+	}
+}
+
+func TestDetectKind(t *testing.T) {
+	tests := map[string]DocumentKind{
+		"wandfile":              KindWandfile,
+		"/home/u/Wandfile":      KindWandfile,
+		"team.wandfile":         KindWandfile,
+		".wandrc":               KindWandRC,
+		"project/.wandrc":       KindWandRC,
+		"formulas/ripgrep.yaml": KindFormula,
+	}
+	for path, want := range tests {
+		if got := DetectKind(path); got != want {
+			t.Errorf("DetectKind(%q) = %s, want %s", path, got, want)
+		}
+	}
+}
@@ -2,7 +2,11 @@
 package cli
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/ochairo/wand/internal/domain/interfaces"
 	"github.com/spf13/cobra"
@@ -27,6 +31,25 @@ type CobraCLIAdapter struct {
 	updateHandler          interfaces.CommandHandler
 	versionHandler         interfaces.CommandHandler
 	outdatedHandler        interfaces.CommandHandler
+	validateHandler        interfaces.CommandHandler
+	formulaTestHandler     interfaces.CommandHandler
+	formulaNewHandler      interfaces.CommandHandler
+	configGetHandler       interfaces.CommandHandler
+	configSetHandler       interfaces.CommandHandler
+	configListHandler      interfaces.CommandHandler
+	configResetHandler     interfaces.CommandHandler
+	configValidateHandler  interfaces.CommandHandler
+	initShellHandler       interfaces.CommandHandler
+	envHandler             interfaces.CommandHandler
+	execHandler            interfaces.CommandHandler
+	whichHandler           interfaces.CommandHandler
+	resolveHandler         interfaces.CommandHandler
+	shellHandler           interfaces.CommandHandler
+	shimsListHandler       interfaces.CommandHandler
+	shimsWhichHandler      interfaces.CommandHandler
+	shimsRepairHandler     interfaces.CommandHandler
+	reshimHandler          interfaces.CommandHandler
+	formulaSyncHandler     interfaces.CommandHandler
 }
 
 // NewCobraCLIAdapter creates a new Cobra CLI adapter
@@ -47,6 +70,25 @@ func NewCobraCLIAdapter(
 	updateHandler interfaces.CommandHandler,
 	versionHandler interfaces.CommandHandler,
 	outdatedHandler interfaces.CommandHandler,
+	validateHandler interfaces.CommandHandler,
+	formulaTestHandler interfaces.CommandHandler,
+	formulaNewHandler interfaces.CommandHandler,
+	configGetHandler interfaces.CommandHandler,
+	configSetHandler interfaces.CommandHandler,
+	configListHandler interfaces.CommandHandler,
+	configResetHandler interfaces.CommandHandler,
+	configValidateHandler interfaces.CommandHandler,
+	initShellHandler interfaces.CommandHandler,
+	envHandler interfaces.CommandHandler,
+	execHandler interfaces.CommandHandler,
+	whichHandler interfaces.CommandHandler,
+	resolveHandler interfaces.CommandHandler,
+	shellHandler interfaces.CommandHandler,
+	shimsListHandler interfaces.CommandHandler,
+	shimsWhichHandler interfaces.CommandHandler,
+	shimsRepairHandler interfaces.CommandHandler,
+	reshimHandler interfaces.CommandHandler,
+	formulaSyncHandler interfaces.CommandHandler,
 ) *CobraCLIAdapter {
 	adapter := &CobraCLIAdapter{
 		installHandler:         installHandler,
@@ -65,6 +107,25 @@ func NewCobraCLIAdapter(
 		updateHandler:          updateHandler,
 		versionHandler:         versionHandler,
 		outdatedHandler:        outdatedHandler,
+		validateHandler:        validateHandler,
+		formulaTestHandler:     formulaTestHandler,
+		formulaNewHandler:      formulaNewHandler,
+		configGetHandler:       configGetHandler,
+		configSetHandler:       configSetHandler,
+		configListHandler:      configListHandler,
+		configResetHandler:     configResetHandler,
+		configValidateHandler:  configValidateHandler,
+		initShellHandler:       initShellHandler,
+		envHandler:             envHandler,
+		execHandler:            execHandler,
+		whichHandler:           whichHandler,
+		resolveHandler:         resolveHandler,
+		shellHandler:           shellHandler,
+		shimsListHandler:       shimsListHandler,
+		shimsWhichHandler:      shimsWhichHandler,
+		shimsRepairHandler:     shimsRepairHandler,
+		reshimHandler:          reshimHandler,
+		formulaSyncHandler:     formulaSyncHandler,
 	}
 	adapter.rootCmd = &cobra.Command{
 		Use:   "wand",
@@ -83,6 +144,11 @@ func (c *CobraCLIAdapter) Execute() error {
 	return c.rootCmd.Execute()
 }
 
+// ExecuteContext runs the CLI application with a context commands can observe
+func (c *CobraCLIAdapter) ExecuteContext(ctx context.Context) error {
+	return c.rootCmd.ExecuteContext(ctx)
+}
+
 // cobraCommandContext wraps a Cobra command to implement CommandContext
 type cobraCommandContext struct {
 	cmd  *cobra.Command
@@ -97,6 +163,10 @@ func (c *cobraCommandContext) GetBoolFlag(name string) (bool, error) {
 	return c.cmd.Flags().GetBool(name)
 }
 
+func (c *cobraCommandContext) FlagChanged(name string) bool {
+	return c.cmd.Flags().Changed(name)
+}
+
 func (c *cobraCommandContext) GetArgs() []string {
 	return c.args
 }
@@ -109,6 +179,32 @@ func (c *cobraCommandContext) PrintError(format string, args ...interface{}) {
 	_, _ = fmt.Fprintf(c.cmd.ErrOrStderr(), format, args...)
 }
 
+func (c *cobraCommandContext) Confirm(prompt string) bool {
+	_, _ = fmt.Fprintf(c.cmd.ErrOrStderr(), "%s [y/N] ", prompt)
+	answer, err := bufio.NewReader(c.cmd.InOrStdin()).ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func (c *cobraCommandContext) IsTerminal() bool {
+	file, ok := c.cmd.OutOrStdout().(*os.File)
+	if !ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (c *cobraCommandContext) Context() context.Context {
+	if ctx := c.cmd.Context(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
 // setupCommands configures all CLI commands
 func (c *CobraCLIAdapter) setupCommands() {
 	c.rootCmd.AddCommand(c.createInstallCommand())
@@ -125,6 +221,17 @@ func (c *CobraCLIAdapter) setupCommands() {
 	c.rootCmd.AddCommand(c.createUpdateCommand())
 	c.rootCmd.AddCommand(c.createVersionCommand())
 	c.rootCmd.AddCommand(c.createOutdatedCommand())
+	c.rootCmd.AddCommand(c.createValidateCommand())
+	c.rootCmd.AddCommand(c.createFormulaCommand())
+	c.rootCmd.AddCommand(c.createConfigCommand())
+	c.rootCmd.AddCommand(c.createInitShellCommand())
+	c.rootCmd.AddCommand(c.createEnvCommand())
+	c.rootCmd.AddCommand(c.createExecCommand())
+	c.rootCmd.AddCommand(c.createWhichCommand())
+	c.rootCmd.AddCommand(c.createResolveCommand())
+	c.rootCmd.AddCommand(c.createShellCommand())
+	c.rootCmd.AddCommand(c.createShimsCommand())
+	c.rootCmd.AddCommand(c.createReshimCommand())
 }
 
 // createInstallCommand creates the install command
@@ -148,6 +255,13 @@ Examples:
 
 	cmd.Flags().BoolP("global", "g", false, "Install globally (system-wide)")
 	cmd.Flags().Bool("force", false, "Force reinstall if already installed")
+	cmd.Flags().Bool("trust-new-checksum", false, "Accept an artifact whose checksum changed since it was first installed")
+	cmd.Flags().Bool("build-from-source", false, "Build from the formula's source even when a prebuilt artifact exists")
+	cmd.Flags().String("hooks", "", "Policy for formula build and post-install commands: allow, confirm or deny (default from the hooks setting, allow)")
+	cmd.Flags().String("timeout", "", "Give up on the whole install after this long, e.g. 30m (default no limit)")
+	cmd.Flags().String("download-timeout", "", "Give up on each download after this long (default no limit; stalled downloads abort after 60s)")
+	cmd.Flags().String("hook-timeout", "", "Kill each build or post-install command after this long (default 10m)")
+	cmd.Flags().String("shim-conflict", "ask", "When another installed package owns a shim for the same binary: ask, keep (theirs) or replace")
 
 	return cmd
 }
@@ -200,7 +314,7 @@ Examples:
 		},
 	}
 
-	cmd.Flags().BoolP("global", "g", false, "Switch version globally (system-wide)")
+	cmd.Flags().BoolP("global", "g", false, "Switch version globally (system-wide); --global=false records it in the project even when the scope setting is global")
 
 	return cmd
 }
@@ -271,23 +385,37 @@ Example:
 // createAddCommand creates the add command
 func (c *CobraCLIAdapter) createAddCommand() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "add <package>@<version>",
+		Use:   "add <package>@<version> | --import [file...]",
 		Short: "Add a package version to .wandrc",
 		Long: `Add a package version to the project's .wandrc file.
-The package and version must already be installed.
+The version may be a constraint such as ^20; an installed version must
+satisfy it.
+
+With --import, copy the versions pinned in other tools' version files in
+the current directory, such as .tool-versions, .nvmrc or go.mod, into
+.wandrc. Name files to import only those.
 
 If .wandrc doesn't exist, it will be created automatically.
 
 Examples:
   wand add nano@8.7.0
-  wand add zsh@5.9.0`,
-		Args: cobra.ExactArgs(1),
+  wand add nodejs@^20
+  wand add --import
+  wand add --import .nvmrc`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if importFlag, _ := cmd.Flags().GetBool("import"); importFlag {
+				return nil
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := &cobraCommandContext{cmd: cmd, args: args}
 			return c.addHandler.Handle(ctx)
 		},
 	}
 
+	cmd.Flags().Bool("import", false, "Import versions from other tools' version files in the current directory")
+
 	return cmd
 }
 
@@ -496,5 +624,428 @@ Examples:
 		},
 	}
 
+	cmd.Flags().StringP("parallelism", "j", "", "Release lists to fetch at once (default from the parallelism setting, 4)")
+
+	return cmd
+}
+
+// createValidateCommand creates the validate command
+func (c *CobraCLIAdapter) createValidateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate [file...]",
+		Short: "Validate formulas, wandfiles and .wandrc files",
+		Long: `Validate YAML files against the schema wand loads them with.
+Reports unknown keys, missing required fields, unsupported platforms,
+bad URL placeholders and invalid versions with line and column.
+
+The document type is detected from the file name (wandfile, .wandrc,
+otherwise formula). With no arguments, validates ./wandfile and ./.wandrc.
+
+Examples:
+  wand validate
+  wand validate formulas/nano.yaml
+  wand validate --type wandfile team.yaml`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.validateHandler.Handle(ctx)
+		},
+	}
+
+	cmd.Flags().StringP("type", "t", "", "Document type: formula, wandfile or wandrc")
+	cmd.Flags().BoolP("verbose", "v", false, "Show which schema each file is validated against")
+
+	return cmd
+}
+
+// createFormulaCommand creates the formula command
+func (c *CobraCLIAdapter) createFormulaCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "formula",
+		Short: "Sync formulas and tools for formula authors",
+		Long: `Sync formulas from the formula repository, and tools for writing and
+checking formulas.
+
+Use new and test to verify a formula against the artifacts it
+points at before publishing it.`,
+	}
+
+	// Add subcommands
+	cmd.AddCommand(c.createFormulaSyncCommand())
+	cmd.AddCommand(c.createFormulaNewCommand())
+	cmd.AddCommand(c.createFormulaTestCommand())
+
 	return cmd
 }
+
+// createFormulaSyncCommand creates the formula sync command
+func (c *CobraCLIAdapter) createFormulaSyncCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "sync",
+		Short: "Update formulas from the formula repository",
+		Long: `Clone or pull the formula repository (the formulas.repository setting).
+Taps are left alone. When the binaries of an installed package change, the
+shims are recreated.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.formulaSyncHandler.Handle(ctx)
+		},
+	}
+}
+
+// createFormulaNewCommand creates the formula new command
+func (c *CobraCLIAdapter) createFormulaNewCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "new <owner/repo>",
+		Short: "Generate a formula from a GitHub release",
+		Long: `Generate a formula from the assets of a repository's latest GitHub release.
+
+Infers os/arch from asset names, picks up per-file checksum assets and
+replaces the release version in URLs with {version}. The result is
+validated and printed for review; use --output to write it to a file.
+
+Examples:
+  wand formula new BurntSushi/ripgrep
+  wand formula new jqlang/jq --output formulas/jq.yaml`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.formulaNewHandler.Handle(ctx)
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "", "Write the formula to a file instead of stdout")
+	cmd.Flags().BoolP("force", "f", false, "Overwrite the output file if it exists")
+
+	return cmd
+}
+
+// createFormulaTestCommand creates the formula test command
+func (c *CobraCLIAdapter) createFormulaTestCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test <name>",
+		Short: "Check a formula against its live release artifacts",
+		Long: `Check that a formula produces working artifacts for a version.
+
+Expands download_url and checksum_url for every os/arch entry and checks
+that they exist, then downloads, verifies and extracts the artifact for
+the current platform, confirms every declared binary is present and runs
+the formula's test command.
+
+If no version is specified, the latest release is used.
+
+Examples:
+  wand formula test jq
+  wand formula test jq --version 1.7.1`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.formulaTestHandler.Handle(ctx)
+		},
+	}
+
+	cmd.Flags().String("version", "", "Version to test (default: latest)")
+
+	return cmd
+}
+
+// createConfigCommand creates the config command with subcommands
+func (c *CobraCLIAdapter) createConfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "View and change wand settings",
+		Long: `View and change settings stored in ~/.wand/config.yaml.
+
+Each setting can also be given as a WAND_* environment variable, which
+overrides the file, and some as command flags, which override both.
+Set WAND_CONFIG to use another configuration file.`,
+	}
+
+	// Add subcommands
+	cmd.AddCommand(c.createConfigGetCommand())
+	cmd.AddCommand(c.createConfigSetCommand())
+	cmd.AddCommand(c.createConfigListCommand())
+	cmd.AddCommand(c.createConfigResetCommand())
+	cmd.AddCommand(c.createConfigValidateCommand())
+
+	return cmd
+}
+
+// createConfigGetCommand creates the config get command
+func (c *CobraCLIAdapter) createConfigGetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <setting>",
+		Short: "Print the effective value of a setting",
+		Long: `Print the value a setting has after applying the configuration file
+and environment variables.
+
+Examples:
+  wand config get hooks
+  wand config get network.proxy`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.configGetHandler.Handle(ctx)
+		},
+	}
+}
+
+// createConfigSetCommand creates the config set command
+func (c *CobraCLIAdapter) createConfigSetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <setting> <value>",
+		Short: "Store a setting in the configuration file",
+		Long: `Validate a value and store it in the configuration file.
+List settings take a comma-separated value.
+
+Examples:
+  wand config set hooks confirm
+  wand config set network.mirrors https://artifactory.example.com/github
+  wand config set formulas.taps ~/work/formulas`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.configSetHandler.Handle(ctx)
+		},
+	}
+}
+
+// createConfigListCommand creates the config list command
+func (c *CobraCLIAdapter) createConfigListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List every setting and where its value comes from",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.configListHandler.Handle(ctx)
+		},
+	}
+}
+
+// createConfigResetCommand creates the config reset command
+func (c *CobraCLIAdapter) createConfigResetCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reset [setting]",
+		Short: "Remove a setting, or all settings, from the configuration file",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.configResetHandler.Handle(ctx)
+		},
+	}
+}
+
+// createConfigValidateCommand creates the config validate command
+func (c *CobraCLIAdapter) createConfigValidateCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Check the configuration file and WAND_* variables",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.configValidateHandler.Handle(ctx)
+		},
+	}
+}
+
+// createInitShellCommand creates the init-shell command
+func (c *CobraCLIAdapter) createInitShellCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "init-shell <bash|zsh|fish>",
+		Aliases: []string{"activate"},
+		Short:   "Print shell code that sets up PATH and the project hook",
+		Long: `Print shell code that puts wand's shims on PATH and installs a hook that
+runs 'wand env' whenever you change directory. Inside a project with a
+.wandrc the hook exports the project's environment, and it restores the
+previous values when you leave.
+
+Add one of these lines to your shell's startup file:
+  eval "$(wand init-shell bash)"        # ~/.bashrc
+  eval "$(wand init-shell zsh)"         # ~/.zshrc
+  wand init-shell fish | source         # ~/.config/fish/config.fish`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.initShellHandler.Handle(ctx)
+		},
+	}
+
+	cmd.Flags().Bool("no-hook", false, "Only set up PATH, without the directory-change hook")
+
+	return cmd
+}
+
+// createEnvCommand creates the env command
+func (c *CobraCLIAdapter) createEnvCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "env",
+		Short: "Print shell code that applies the current project's environment",
+		Long: `Print shell code that exports the environment of the project containing
+the current directory and undoes the previous project's. The hook installed
+by 'wand init-shell' runs it on every directory change; run it yourself to
+pick up an edited .wandrc:
+  eval "$(wand env)"`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.envHandler.Handle(ctx)
+		},
+	}
+
+	cmd.Flags().String("shell", "", "Shell syntax to print: bash, zsh or fish (default: from $SHELL)")
+
+	return cmd
+}
+
+func (c *CobraCLIAdapter) createExecCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "exec [--package <name>] <binary> [args...]",
+		Short: "Run a binary at the version the current directory resolves to",
+		Long: `Run a package's binary at the version the current directory resolves to.
+A constraint in a .wandrc, such as ^20, picks the newest installed version
+it allows. Shims hand every run to wand exec, so both always pick the same
+version. With auto_install on, a version pinned in the project that is not
+installed is installed first, without changing the global version.
+
+Examples:
+  wand exec node --version
+  wand exec --package python pip install requests`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.execHandler.Handle(ctx)
+		},
+	}
+
+	cmd.Flags().String("package", "", "Package providing the binary (default: found from installed formulas)")
+	// Everything after the binary belongs to it
+	cmd.Flags().SetInterspersed(false)
+
+	return cmd
+}
+
+func (c *CobraCLIAdapter) createWhichCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "which <binary>",
+		Short: "Show the binary a shim runs here and why",
+		Long: `Print the path of the binary a shim runs in the current directory,
+followed by the chosen version and the files and rules that chose it.
+
+Examples:
+  wand which node
+  wand which --package python pip`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.whichHandler.Handle(ctx)
+		},
+	}
+
+	cmd.Flags().String("package", "", "Package providing the binary (default: found from installed formulas)")
+
+	return cmd
+}
+
+func (c *CobraCLIAdapter) createResolveCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "resolve <package>",
+		Short: "Show the version of a package that applies here and why",
+		Long: `Print the version of a package that applies in the current directory,
+followed by each file and rule consulted: .wandrc files from here upward,
+other tools' version files, extends: false, and the global version.
+
+Examples:
+  wand resolve nodejs`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.resolveHandler.Handle(ctx)
+		},
+	}
+}
+
+func (c *CobraCLIAdapter) createShellCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "shell <package>@<version> [<package>@<version>...]",
+		Short: "Start a shell that uses the given versions",
+		Long: `Start $SHELL with WAND_<PACKAGE>_VERSION set for each package given.
+These variables override .wandrc files, other tools' version files and the
+global version, so shims in the new shell run the given versions until you
+exit it. The version may be a constraint such as ^20.
+
+Examples:
+  wand shell nodejs@18.19.0
+  wand shell python@3.11 golang@1.22`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.shellHandler.Handle(ctx)
+		},
+	}
+}
+
+// createShimsCommand creates the shims command with subcommands
+func (c *CobraCLIAdapter) createShimsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "shims",
+		Short: "Inspect and repair shims",
+		Long: `Inspect and repair the shims in ~/.wand/shims.
+
+Each shim is owned by one package. When several installed packages ship a
+binary of the same name, the owner's version runs; wand install asks before
+taking a shim over (see its --shim-conflict flag), and uninstalling the owner
+hands the shim to another package that ships the binary.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List shims and the packages that own them",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.shimsListHandler.Handle(ctx)
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "which <binary>",
+		Short: "Show the package that owns a shim",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.shimsWhichHandler.Handle(ctx)
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "repair",
+		Short: "Recreate missing shims and fix their owners",
+		Long: `Bring the shims in line with the installed packages: create missing
+shims, remove shims no installed package provides, pass shims whose owner is
+no longer installed to another package that ships the binary, and rewrite
+shims that do not match their owner.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.shimsRepairHandler.Handle(ctx)
+		},
+	})
+
+	return cmd
+}
+
+// createReshimCommand creates the reshim command
+func (c *CobraCLIAdapter) createReshimCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reshim",
+		Short: "Recreate all shims",
+		Long: `Recreate every shim from the current template for the installed packages.
+Run it after moving the wand directory or upgrading wand; wand doctor
+reports shims that need it. Shim owners are kept.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := &cobraCommandContext{cmd: cmd, args: args}
+			return c.reshimHandler.Handle(ctx)
+		},
+	}
+}
@@ -3,41 +3,178 @@ package externaladapters
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/go-github/v57/github"
 	"github.com/ochairo/wand/internal/domain/interfaces"
 )
 
+// DefaultAPITimeout bounds each GitHub API request
+const DefaultAPITimeout = 30 * time.Second
+
+// DefaultRateLimitWait is how long a request waits for an exhausted rate
+// limit to reset before failing
+const DefaultRateLimitWait = time.Minute
+
+// secondaryLimitWait is assumed when a secondary rate limit gives no Retry-After
+const secondaryLimitWait = time.Minute
+
 // GitHubAdapter implements the GitHubClient interface
 type GitHubAdapter struct {
-	client *github.Client
-	ctx    context.Context
+	client           *github.Client
+	httpClient       *http.Client
+	timeout          time.Duration
+	maxRateLimitWait time.Duration
+	authenticated    bool
+
+	mu       sync.Mutex
+	releases map[string][]*interfaces.GitHubRelease // owner/repo -> releases, for this process
+}
+
+// GitHubAdapterOptions configures a GitHubAdapter
+type GitHubAdapterOptions struct {
+	Token            string
+	HTTPClient       *http.Client  // nil uses a default client
+	Timeout          time.Duration // per request; 0 means no limit beyond the caller's context
+	MaxRateLimitWait time.Duration // wait at most this long for a rate limit reset; 0 fails at once
+	CacheDir         string        // on-disk response cache for conditional requests; empty disables it
+	BaseURL          string        // API URL, for GitHub Enterprise; empty uses api.github.com
 }
 
 // NewGitHubAdapter creates a new GitHubAdapter
 func NewGitHubAdapter(token string) interfaces.GitHubClient {
-	ctx := context.Background()
-	var client *github.Client
+	return NewGitHubAdapterWithOptions(GitHubAdapterOptions{
+		Token:            token,
+		Timeout:          DefaultAPITimeout,
+		MaxRateLimitWait: DefaultRateLimitWait,
+	})
+}
 
-	if token != "" {
-		client = github.NewClient(nil).WithAuthToken(token)
-	} else {
-		client = github.NewClient(nil)
+// NewGitHubAdapterWithOptions creates a GitHubAdapter with the given options
+func NewGitHubAdapterWithOptions(opts GitHubAdapterOptions) *GitHubAdapter {
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+
+	// API requests go through the response cache; asset downloads do not
+	apiClient := httpClient
+	if opts.CacheDir != "" {
+		base := httpClient.Transport
+		if base == nil {
+			base = http.DefaultTransport
+		}
+		cached := *httpClient
+		cached.Transport = &etagTransport{base: base, dir: opts.CacheDir}
+		apiClient = &cached
+	}
+
+	client := github.NewClient(apiClient)
+	if opts.Token != "" {
+		client = client.WithAuthToken(opts.Token)
+	}
+	if opts.BaseURL != "" {
+		if baseURL, err := url.Parse(strings.TrimSuffix(opts.BaseURL, "/") + "/"); err == nil {
+			client.BaseURL = baseURL
+		}
 	}
 
 	return &GitHubAdapter{
-		client: client,
-		ctx:    ctx,
+		client:           client,
+		httpClient:       httpClient,
+		timeout:          opts.Timeout,
+		maxRateLimitWait: opts.MaxRateLimitWait,
+		authenticated:    opts.Token != "",
+		releases:         make(map[string][]*interfaces.GitHubRelease),
+	}
+}
+
+// requestContext bounds a single API request by the adapter's timeout
+func (g *GitHubAdapter) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if g.timeout > 0 {
+		return context.WithTimeout(ctx, g.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// do runs one API request, bounded by the adapter's timeout. If the rate
+// limit is exhausted it waits for the reset when that is within
+// maxRateLimitWait and tries once more, and otherwise returns a
+// *interfaces.RateLimitError.
+func (g *GitHubAdapter) do(ctx context.Context, request func(ctx context.Context) (*github.Response, error)) (*github.Response, error) {
+	for attempt := 0; ; attempt++ {
+		requestCtx, cancel := g.requestContext(ctx)
+		resp, err := request(requestCtx)
+		cancel()
+
+		reset, limited := rateLimitReset(err)
+		if !limited {
+			return resp, err
+		}
+		wait := time.Until(reset)
+		if attempt > 0 || wait > g.maxRateLimitWait {
+			return nil, &interfaces.RateLimitError{Reset: reset, Authenticated: g.authenticated}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// rateLimitReset returns when the limit reported by err resets, if err is a
+// primary or secondary rate limit error
+func rateLimitReset(err error) (time.Time, bool) {
+	var primary *github.RateLimitError
+	if errors.As(err, &primary) {
+		return primary.Rate.Reset.Time, true
 	}
+
+	var secondary *github.AbuseRateLimitError
+	if errors.As(err, &secondary) {
+		if secondary.RetryAfter != nil {
+			return time.Now().Add(*secondary.RetryAfter), true
+		}
+		return time.Now().Add(secondaryLimitWait), true
+	}
+
+	// Secondary limits are also reported as 429 Too Many Requests
+	var response *github.ErrorResponse
+	if errors.As(err, &response) && response.Response != nil && response.Response.StatusCode == http.StatusTooManyRequests {
+		if seconds, err := strconv.Atoi(response.Response.Header.Get("Retry-After")); err == nil {
+			return time.Now().Add(time.Duration(seconds) * time.Second), true
+		}
+		return time.Now().Add(secondaryLimitWait), true
+	}
+
+	return time.Time{}, false
 }
 
 // GetLatestRelease gets the latest release for a repository
 func (g *GitHubAdapter) GetLatestRelease(owner, repo string) (*interfaces.GitHubRelease, error) {
-	release, _, err := g.client.Repositories.GetLatestRelease(g.ctx, owner, repo)
+	return g.GetLatestReleaseContext(context.Background(), owner, repo)
+}
+
+// GetLatestReleaseContext gets the latest release for a repository
+func (g *GitHubAdapter) GetLatestReleaseContext(ctx context.Context, owner, repo string) (*interfaces.GitHubRelease, error) {
+	var release *github.RepositoryRelease
+	_, err := g.do(ctx, func(ctx context.Context) (resp *github.Response, err error) {
+		release, resp, err = g.client.Repositories.GetLatestRelease(ctx, owner, repo)
+		return resp, err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get latest release: %w", err)
 	}
@@ -47,7 +184,16 @@ func (g *GitHubAdapter) GetLatestRelease(owner, repo string) (*interfaces.GitHub
 
 // GetRelease gets a specific release by tag
 func (g *GitHubAdapter) GetRelease(owner, repo, tag string) (*interfaces.GitHubRelease, error) {
-	release, _, err := g.client.Repositories.GetReleaseByTag(g.ctx, owner, repo, tag)
+	return g.GetReleaseContext(context.Background(), owner, repo, tag)
+}
+
+// GetReleaseContext gets a specific release by tag
+func (g *GitHubAdapter) GetReleaseContext(ctx context.Context, owner, repo, tag string) (*interfaces.GitHubRelease, error) {
+	var release *github.RepositoryRelease
+	_, err := g.do(ctx, func(ctx context.Context) (resp *github.Response, err error) {
+		release, resp, err = g.client.Repositories.GetReleaseByTag(ctx, owner, repo, tag)
+		return resp, err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get release %s: %w", tag, err)
 	}
@@ -57,11 +203,29 @@ func (g *GitHubAdapter) GetRelease(owner, repo, tag string) (*interfaces.GitHubR
 
 // ListReleases lists all releases for a repository
 func (g *GitHubAdapter) ListReleases(owner, repo string) ([]*interfaces.GitHubRelease, error) {
+	return g.ListReleasesContext(context.Background(), owner, repo)
+}
+
+// ListReleasesContext lists all releases for a repository. The timeout
+// applies to each page. The list is fetched once per process and repository.
+func (g *GitHubAdapter) ListReleasesContext(ctx context.Context, owner, repo string) ([]*interfaces.GitHubRelease, error) {
+	key := strings.ToLower(owner + "/" + repo)
+	g.mu.Lock()
+	cached, ok := g.releases[key]
+	g.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
 	opts := &github.ListOptions{PerPage: 100}
 
 	var allReleases []*interfaces.GitHubRelease
 	for {
-		releases, resp, err := g.client.Repositories.ListReleases(g.ctx, owner, repo, opts)
+		var releases []*github.RepositoryRelease
+		resp, err := g.do(ctx, func(ctx context.Context) (resp *github.Response, err error) {
+			releases, resp, err = g.client.Repositories.ListReleases(ctx, owner, repo, opts)
+			return resp, err
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to list releases: %w", err)
 		}
@@ -76,12 +240,27 @@ func (g *GitHubAdapter) ListReleases(owner, repo string) ([]*interfaces.GitHubRe
 		opts.Page = resp.NextPage
 	}
 
+	g.mu.Lock()
+	g.releases[key] = allReleases
+	g.mu.Unlock()
+
 	return allReleases, nil
 }
 
 // DownloadAsset downloads a release asset to the specified path
 func (g *GitHubAdapter) DownloadAsset(asset *interfaces.GitHubAsset, destPath string) error {
-	resp, err := http.Get(asset.DownloadURL)
+	return g.DownloadAssetContext(context.Background(), asset, destPath)
+}
+
+// DownloadAssetContext downloads a release asset to the specified path,
+// removing the partial file if ctx is done first
+func (g *GitHubAdapter) DownloadAssetContext(ctx context.Context, asset *interfaces.GitHubAsset, destPath string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, asset.DownloadURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to download asset: %w", err)
+	}
+
+	resp, err := g.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to download asset: %w", err)
 	}
@@ -95,10 +274,13 @@ func (g *GitHubAdapter) DownloadAsset(asset *interfaces.GitHubAsset, destPath st
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
-	defer func() { _ = outFile.Close() }()
 
 	_, err = io.Copy(outFile, resp.Body)
+	if closeErr := outFile.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
+		_ = os.Remove(destPath)
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
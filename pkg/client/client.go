@@ -19,6 +19,12 @@
 //	    log.Fatal(err)
 //	}
 //
+//	// Watch installation progress
+//	unsubscribe := c.Subscribe(func(e types.InstallEvent) {
+//	    fmt.Printf("%s@%s: %s\n", e.Package, e.Version, e.Stage)
+//	})
+//	defer unsubscribe()
+//
 //	// List installed packages
 //	packages, err := c.ListPackages()
 //	for _, pkg := range packages {
@@ -27,9 +33,14 @@
 package client
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
+	"sync"
 
 	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
 	domainorchestrators "github.com/ochairo/wand/internal/domain-orchestrators"
@@ -42,8 +53,9 @@ import (
 
 // Client provides programmatic access to wand functionality.
 type Client struct {
-	wandDir string
-	homeDir string
+	wandDir    string
+	homeDir    string
+	hookPolicy services.HookPolicy
 
 	// Internal services
 	versionService   *services.VersionService
@@ -60,45 +72,158 @@ type Client struct {
 	wandrcRepo   interfaces.WandRCRepository
 	wandfileRepo interfaces.WandfileRepository
 	dotfileRepo  interfaces.DotfileRepository
+
+	// Install event subscribers
+	mu             sync.Mutex
+	subscribers    map[int]func(types.InstallEvent)
+	nextSubscriber int
+}
+
+// Option configures a Client. Options override the configuration file and
+// WAND_* environment variables, as command flags do for the CLI.
+type Option func(*options)
+
+type options struct {
+	configPath string
+	executable string
+	githubAPI  string
+	settings   [][2]string
+}
+
+// WithConfigFile reads settings from path instead of ~/.wand/config.yaml
+func WithConfigFile(path string) Option {
+	return func(o *options) { o.configPath = path }
+}
+
+// WithExecutable sets the wand binary that shims written by the client run,
+// by default the wand found on PATH, as the CLI writes its own path
+func WithExecutable(path string) Option {
+	return func(o *options) { o.executable = path }
+}
+
+// WithGitHubAPI sets the GitHub API URL releases are listed from, for GitHub
+// Enterprise; by default https://api.github.com
+func WithGitHubAPI(url string) Option {
+	return func(o *options) { o.githubAPI = url }
+}
+
+// WithSetting sets a configuration setting by the name `wand config` uses,
+// e.g. WithSetting("hooks", "deny") or WithSetting("network.proxy", url).
+func WithSetting(name, value string) Option {
+	return func(o *options) { o.settings = append(o.settings, [2]string{name, value}) }
 }
 
 // New creates a new wand client.
-// If wandDir is empty, uses the default ~/.wand directory.
-func New(wandDir string) (*Client, error) {
+// If wandDir is empty, uses the home setting, by default ~/.wand.
+//
+// Settings are read as by the CLI: ~/.wand/config.yaml (or $WAND_CONFIG),
+// overridden by WAND_* environment variables, overridden by opts.
+func New(wandDir string, opts ...Option) (*Client, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get home directory: %w", err)
 	}
 
+	o := &options{configPath: domainadapters.ConfigPath(homeDir)}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	// Initialize adapters
+	fs := domainadapters.NewFileSystemAdapter()
+	configRepo := domainadapters.NewConfigRepository(fs, o.configPath)
+	config, err := domainadapters.LoadConfig(configRepo, homeDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, setting := range o.settings {
+		if err := config.Set(setting[0], setting[1]); err != nil {
+			return nil, fmt.Errorf("invalid setting: %w", err)
+		}
+	}
+	config.ExpandPaths(homeDir)
+	hookPolicy, err := services.ParseHookPolicy(config.Hooks)
+	if err != nil {
+		return nil, err
+	}
+
+	if wandDir == "" {
+		wandDir = config.Home
+	}
 	if wandDir == "" {
 		wandDir = filepath.Join(homeDir, ".wand")
 	}
 
 	formulasDir := filepath.Join(wandDir, "formulas")
 
-	// Initialize adapters
-	fs := domainadapters.NewFileSystemAdapter()
-	downloader := domainadapters.NewDownloaderAdapter()
+	mirrors, err := domainadapters.ParseMirrors(strings.Join(config.Network.Mirrors, ","))
+	if err != nil {
+		return nil, fmt.Errorf("invalid network.mirrors: %w", err)
+	}
+	network, err := domainadapters.LoadNetworkConfig(fs, filepath.Dir(o.configPath))
+	if err != nil {
+		return nil, fmt.Errorf("invalid network configuration: %w", err)
+	}
+	config.ApplyTo(network)
+	transport, err := domainadapters.NewHTTPTransport(network, config.ConnectTimeoutDuration())
+	if err != nil {
+		return nil, fmt.Errorf("invalid network configuration: %w", err)
+	}
+	downloader := domainadapters.NewDownloaderAdapterWithOptions(domainadapters.DownloaderOptions{
+		Transport:  transport,
+		Mirrors:    mirrors,
+		PartialDir: filepath.Join(wandDir, "cache", "downloads"),
+	})
+	verifier := domainadapters.NewVerifierAdapter()
 	extractor := domainadapters.NewExtractorAdapter(fs)
 	shellExecutor := domainadapters.NewShellExecutorAdapter()
 
 	// Initialize repositories
 	registryRepo := domainadapters.NewRegistryRepository(fs, wandDir)
-	formulaRepo := domainadapters.NewFormulaRepository(fs, formulasDir)
+	lockRepo := domainadapters.NewChecksumLockRepository(fs, wandDir)
+	approvalRepo := domainadapters.NewHookApprovalRepository(fs, wandDir)
+	shimIndexRepo := domainadapters.NewShimIndexRepository(fs, wandDir)
+	formulaRepo := domainadapters.NewFormulaRepositoryWithOptions(fs, formulasDir, domainadapters.FormulaRepositoryOptions{
+		Taps:      config.Formulas.Taps,
+		RemoteURL: config.Formulas.Repository,
+		Network:   network,
+	})
 	wandrcRepo := domainadapters.NewWandRCRepository(fs)
 	wandfileRepo := domainadapters.NewWandfileRepository(fs)
 	dotfileRepo := domainadapters.NewDotfileRepository(fs, wandDir)
 
 	// Initialize external adapters
-	githubClient := externaladapters.NewGitHubAdapter("")
+	githubClient := externaladapters.NewGitHubAdapterWithOptions(externaladapters.GitHubAdapterOptions{
+		Token:            network.GitHubToken,
+		HTTPClient:       &http.Client{Transport: transport},
+		Timeout:          externaladapters.DefaultAPITimeout,
+		MaxRateLimitWait: externaladapters.DefaultRateLimitWait,
+		CacheDir:         filepath.Join(wandDir, "cache", "github"),
+		BaseURL:          o.githubAPI,
+	})
 
 	// Initialize services
 	versionService := services.NewVersionService(githubClient, formulaRepo)
-	shimService := services.NewShimService(registryRepo, wandrcRepo, formulaRepo, fs, wandDir)
+	versionFiles, err := domainadapters.NewVersionFileReaders(fs, config.VersionFileNames())
+	if err != nil {
+		return nil, fmt.Errorf("invalid version_files: %w", err)
+	}
+	executable := o.executable
+	if executable == "" {
+		executable = findExecutable()
+	}
+	shimService := services.NewShimServiceWithOptions(registryRepo, wandrcRepo, formulaRepo, fs, wandDir, services.ShimServiceOptions{
+		Executable:   executable,
+		VersionFiles: versionFiles,
+		ShimIndex:    shimIndexRepo,
+	})
 	installerService := services.NewInstallerService(
 		formulaRepo,
 		registryRepo,
+		lockRepo,
+		approvalRepo,
 		downloader,
+		verifier,
 		extractor,
 		fs,
 		shellExecutor,
@@ -128,6 +253,7 @@ func New(wandDir string) (*Client, error) {
 	return &Client{
 		wandDir:             wandDir,
 		homeDir:             homeDir,
+		hookPolicy:          hookPolicy,
 		versionService:      versionService,
 		installerService:    installerService,
 		shimService:         shimService,
@@ -138,17 +264,58 @@ func New(wandDir string) (*Client, error) {
 		wandrcRepo:          wandrcRepo,
 		wandfileRepo:        wandfileRepo,
 		dotfileRepo:         dotfileRepo,
+		subscribers:         make(map[int]func(types.InstallEvent)),
 	}, nil
 }
 
+// Subscribe registers fn to receive progress events for installs made through
+// this client and returns a function that unregisters it. Events are
+// delivered synchronously on the installing goroutine, in order.
+func (c *Client) Subscribe(fn func(types.InstallEvent)) (unsubscribe func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := c.nextSubscriber
+	c.nextSubscriber++
+	c.subscribers[id] = fn
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.subscribers, id)
+	}
+}
+
+// publish delivers an install event to every subscriber
+func (c *Client) publish(event services.InstallEvent) {
+	c.mu.Lock()
+	subscribers := make([]func(types.InstallEvent), 0, len(c.subscribers))
+	for _, fn := range c.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	c.mu.Unlock()
+
+	converted := convertInstallEvent(event)
+	for _, fn := range subscribers {
+		fn(converted)
+	}
+}
+
 // Install installs a package with the specified version.
 // If version is empty or "latest", installs the latest available version.
 func (c *Client) Install(packageName, version string) (*types.Package, error) {
+	return c.InstallContext(context.Background(), packageName, version)
+}
+
+// InstallContext is Install that stops when ctx is done. A cancelled install
+// removes its partial downloads and files.
+func (c *Client) InstallContext(ctx context.Context, packageName, version string) (*types.Package, error) {
 	if version == "" {
 		version = "latest"
 	}
 
-	err := c.installOrchestrator.InstallPackage(packageName, version)
+	opts := domainorchestrators.InstallPackageOptions{HookPolicy: c.hookPolicy, Progress: c.publish}
+	err := c.installOrchestrator.InstallPackageContext(ctx, packageName, version, opts)
 	if err != nil {
 		return nil, fmt.Errorf("installation failed: %w", err)
 	}
@@ -347,6 +514,18 @@ func (c *Client) ResolveVersion(packageName, constraint string) (*types.Version,
 }
 
 // Helper conversion functions
+func convertInstallEvent(e services.InstallEvent) types.InstallEvent {
+	return types.InstallEvent{
+		Package: e.Package,
+		Version: e.Version,
+		Stage:   types.InstallStage(e.Stage),
+		Message: e.Message,
+		Bytes:   e.Bytes,
+		Total:   e.Total,
+		Err:     e.Err,
+	}
+}
+
 func convertPackage(pkg *entities.Package) *types.Package {
 	return &types.Package{
 		Name:        pkg.Name,
@@ -354,8 +533,11 @@ func convertPackage(pkg *entities.Package) *types.Package {
 		Version:     convertVersion(pkg.Version),
 		InstalledAt: pkg.InstalledAt,
 		BinPath:     pkg.BinPath,
+		Binaries:    pkg.Binaries,
 		InstallPath: pkg.InstallPath,
 		IsGlobal:    pkg.IsGlobal,
+
+		IntegrationFiles: pkg.IntegrationFiles,
 	}
 }
 
@@ -402,21 +584,22 @@ func convertPackageEntry(e *entities.PackageEntry) *types.PackageEntry {
 
 func convertFormula(f *entities.Formula) *types.Formula {
 	return &types.Formula{
-		Name:           f.Name,
-		Type:           types.PackageType(f.Type),
-		Description:    f.Description,
-		Homepage:       f.Homepage,
-		Repository:     f.Repository,
-		License:        f.License,
-		Tags:           f.Tags,
-		VersionPattern: f.VersionPattern,
-		Binaries:       f.Binaries,
-		BinPath:        f.BinPath,
-		AppName:        f.AppName,
-		AppPath:        f.AppPath,
-		MinVersion:     f.MinVersion,
-		MaxVersion:     f.MaxVersion,
-		Dependencies:   f.Dependencies,
+		Name:            f.Name,
+		Type:            types.PackageType(f.Type),
+		Description:     f.Description,
+		Homepage:        f.Homepage,
+		Repository:      f.Repository,
+		License:         f.License,
+		Tags:            f.Tags,
+		VersionPattern:  f.VersionPattern,
+		Binaries:        f.Binaries,
+		BinPath:         f.BinPath,
+		StripComponents: f.StripComponents,
+		AppName:         f.AppName,
+		AppPath:         f.AppPath,
+		MinVersion:      f.MinVersion,
+		MaxVersion:      f.MaxVersion,
+		Dependencies:    f.Dependencies,
 	}
 }
 
@@ -432,3 +615,15 @@ func indexOf(s, substr string) int {
 	}
 	return -1
 }
+
+// findExecutable returns the absolute path of the wand on PATH, or "wand"
+func findExecutable() string {
+	path, err := exec.LookPath("wand")
+	if err != nil {
+		return "wand"
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
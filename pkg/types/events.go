@@ -0,0 +1,39 @@
+package types
+
+// InstallStage identifies a step of an installation
+type InstallStage string
+
+const (
+	// StageResolving resolves the requested version.
+	StageResolving InstallStage = "resolving"
+	// StageDownloading downloads the artifact; events carry Bytes and Total.
+	StageDownloading InstallStage = "downloading"
+	// StageVerifying checks digests, signatures and the checksum lock.
+	StageVerifying InstallStage = "verifying"
+	// StageExtracting unpacks the artifact.
+	StageExtracting InstallStage = "extracting"
+	// StageHooks runs formula build or post-install commands.
+	StageHooks InstallStage = "hooks"
+	// StageLinking creates shims for the installed binaries.
+	StageLinking InstallStage = "linking"
+	// StageDone ends a successful installation.
+	StageDone InstallStage = "done"
+	// StageFailed ends a failed installation; Err is set.
+	StageFailed InstallStage = "failed"
+)
+
+// InstallEvent reports the progress of one package installation
+type InstallEvent struct {
+	Package string       // Package being installed; build dependencies report their own events
+	Version string       // Requested version until resolved, then the exact version
+	Stage   InstallStage // Current step
+	Message string       // Detail such as the download URL or hook phase
+	Bytes   int64        // Bytes downloaded so far (StageDownloading)
+	Total   int64        // Expected download size, -1 if unknown (StageDownloading)
+	Err     error        // Cause of a StageFailed event
+}
+
+// Finished returns true for the last event of an installation
+func (e InstallEvent) Finished() bool {
+	return e.Stage == StageDone || e.Stage == StageFailed
+}
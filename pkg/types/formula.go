@@ -15,8 +15,9 @@ type Formula struct {
 	VersionPattern string
 
 	// CLI-specific
-	Binaries []string
-	BinPath  string
+	Binaries        []string
+	BinPath         string
+	StripComponents int
 
 	// GUI-specific (macOS)
 	AppName string
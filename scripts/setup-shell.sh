@@ -107,6 +107,8 @@ main() {
     echo "Next steps:"
     echo "1. Reload your shell configuration"
     echo "2. Test completion by typing: wand <TAB>"
+    echo "3. Add wand's PATH setup and project hook to your shell config:"
+    echo "     eval \"\$(wand init-shell $current_shell)\""
 }
 
 main "$@"
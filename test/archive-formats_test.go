@@ -0,0 +1,240 @@
+package test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+
+	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
+	"github.com/ochairo/wand/internal/domain/entities"
+)
+
+const helloScript = "#!/bin/sh\necho hello\n"
+
+// buildTar writes an uncompressed tarball of files, in name order
+func buildTar(t testing.TB, files map[string]string) []byte {
+	t.Helper()
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range names {
+		hdr := &tar.Header{Name: name, Mode: 0755, Size: int64(len(files[name])), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// compress compresses data with gzip, xz or zstd
+func compress(t testing.TB, format entities.ArchiveFormat, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case entities.ArchiveFormatGz:
+		w := gzip.NewWriter(&buf)
+		_, err = w.Write(data)
+		if err == nil {
+			err = w.Close()
+		}
+	case entities.ArchiveFormatXz:
+		var w *xz.Writer
+		if w, err = xz.NewWriter(&buf); err == nil {
+			if _, err = w.Write(data); err == nil {
+				err = w.Close()
+			}
+		}
+	case entities.ArchiveFormatZst:
+		var w *zstd.Encoder
+		if w, err = zstd.NewWriter(&buf); err == nil {
+			if _, err = w.Write(data); err == nil {
+				err = w.Close()
+			}
+		}
+	default:
+		t.Fatalf("cannot compress with %s", format)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// buildDeb writes a Debian package whose data.tar.xz holds files
+func buildDeb(t testing.TB, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString("!<arch>\n")
+	member := func(name string, data []byte) {
+		fmt.Fprintf(&buf, "%-16s%-12s%-6s%-6s%-8s%-10d`\n", name, "0", "0", "0", "100644", len(data))
+		buf.Write(data)
+		if len(data)%2 == 1 {
+			buf.WriteByte('\n')
+		}
+	}
+	member("debian-binary", []byte("2.0\n"))
+	member("control.tar.gz", compress(t, entities.ArchiveFormatGz, buildTar(t, map[string]string{"./control": "Package: hello\n"})))
+	member("data.tar.xz", compress(t, entities.ArchiveFormatXz, buildTar(t, files)))
+	return buf.Bytes()
+}
+
+// buildRpm writes an RPM package whose gzip-compressed cpio payload holds files
+func buildRpm(t testing.TB, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	lead := make([]byte, 96)
+	copy(lead, []byte{0xed, 0xab, 0xee, 0xdb, 3, 0})
+	buf.Write(lead)
+
+	// An empty signature header, padded to 8 bytes, then an empty main header
+	header := func(dataSize int) {
+		buf.Write([]byte{0x8e, 0xad, 0xe8, 0x01, 0, 0, 0, 0})
+		_ = binary.Write(&buf, binary.BigEndian, uint32(0))
+		_ = binary.Write(&buf, binary.BigEndian, uint32(dataSize))
+		buf.Write(make([]byte, dataSize))
+	}
+	header(5)
+	buf.Write(make([]byte, 3))
+	header(0)
+
+	var cpio bytes.Buffer
+	entry := func(name string, mode int, data string) {
+		fmt.Fprintf(&cpio, "070701%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x", 0, mode, 0, 0, 1, 0, len(data), 0, 0, 0, 0, len(name)+1, 0)
+		cpio.WriteString(name + "\x00")
+		cpio.Write(make([]byte, (4-(110+len(name)+1)%4)%4))
+		cpio.WriteString(data)
+		cpio.Write(make([]byte, (4-len(data)%4)%4))
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	entry("./usr", 0o040755, "")
+	for _, name := range names {
+		entry(name, 0o100755, files[name])
+	}
+	entry("./usr/bin/hi", 0o120777, "hello")
+	entry("TRAILER!!!", 0, "")
+
+	buf.Write(compress(t, entities.ArchiveFormatGz, cpio.Bytes()))
+	return buf.Bytes()
+}
+
+// TestArchiveFormats tests that each format is detected from its contents,
+// whatever the file is called, and extracted
+func TestArchiveFormats(t *testing.T) {
+	tree := map[string]string{"hello-1.0/bin/hello": helloScript}
+	appImage := append([]byte("\x7fELF\x02\x01\x01\x00AI\x02"), make([]byte, 64)...)
+
+	tests := []struct {
+		name   string
+		data   []byte
+		format entities.ArchiveFormat
+		want   string // file expected in the destination
+	}{
+		{"tarball", buildTar(t, tree), entities.ArchiveFormatTar, "hello-1.0/bin/hello"},
+		{"tarball.tgz", compress(t, entities.ArchiveFormatGz, buildTar(t, tree)), entities.ArchiveFormatTarGz, "hello-1.0/bin/hello"},
+		{"tarball.tar.xz", compress(t, entities.ArchiveFormatXz, buildTar(t, tree)), entities.ArchiveFormatTarXz, "hello-1.0/bin/hello"},
+		{"tarball.tar.zst", compress(t, entities.ArchiveFormatZst, buildTar(t, tree)), entities.ArchiveFormatTarZst, "hello-1.0/bin/hello"},
+		{"hello.gz", compress(t, entities.ArchiveFormatGz, []byte(helloScript)), entities.ArchiveFormatGz, "hello"},
+		{"hello.xz", compress(t, entities.ArchiveFormatXz, []byte(helloScript)), entities.ArchiveFormatXz, "hello"},
+		{"hello.zst", compress(t, entities.ArchiveFormatZst, []byte(helloScript)), entities.ArchiveFormatZst, "hello"},
+		{"hello.deb", buildDeb(t, map[string]string{"./usr/bin/hello": helloScript}), entities.ArchiveFormatDeb, "usr/bin/hello"},
+		{"hello.rpm", buildRpm(t, map[string]string{"./usr/bin/hello": helloScript}), entities.ArchiveFormatRpm, "usr/bin/hello"},
+		{"Hello.AppImage", appImage, entities.ArchiveFormatAppImage, "Hello.AppImage"},
+		{"hello", []byte(helloScript), entities.ArchiveFormatNone, ""},
+	}
+
+	extractor := domainadapters.NewExtractorAdapter(domainadapters.NewFileSystemAdapter())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			archivePath := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(archivePath, tt.data, 0644); err != nil { //nolint:gosec
+				t.Fatal(err)
+			}
+
+			format, err := extractor.Format(archivePath)
+			if err != nil || format != tt.format {
+				t.Fatalf("Format = %q, %v; want %q", format, err, tt.format)
+			}
+			if tt.want == "" {
+				if err := extractor.Extract(archivePath, filepath.Join(dir, "out")); err == nil {
+					t.Error("expected a file that is not an archive to be refused")
+				}
+				return
+			}
+
+			destDir := filepath.Join(dir, "out")
+			if err := extractor.Extract(archivePath, destDir); err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+			info, err := os.Stat(filepath.Join(destDir, tt.want))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm()&0100 == 0 {
+				t.Errorf("expected %s to be executable, mode %s", tt.want, info.Mode())
+			}
+			if tt.format == entities.ArchiveFormatRpm {
+				if link, err := os.Readlink(filepath.Join(destDir, "usr/bin/hi")); err != nil || link != "hello" {
+					t.Errorf("expected the rpm symlink to be kept, got %q, %v", link, err)
+				}
+			}
+		})
+	}
+}
+
+// TestInstallArchiveFormats tests installing compressed single binaries and
+// Linux packages, whatever their URL says
+func TestInstallArchiveFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		artifact []byte
+		want     string // relative to the install directory
+	}{
+		{"gzip binary", compress(t, entities.ArchiveFormatGz, []byte(helloScript)), "bin/hello"},
+		{"zstd binary", compress(t, entities.ArchiveFormatZst, []byte(helloScript)), "bin/hello"},
+		{"deb", buildDeb(t, map[string]string{"./usr/bin/hello": helloScript}), "usr/bin/hello"},
+		{"rpm", buildRpm(t, map[string]string{"./usr/bin/hello": helloScript}), "usr/bin/hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The fixture's URL ends in .tar.gz regardless
+			f := newTrustFixture(t, "")
+			f.artifact = tt.artifact
+			if err := f.installer.InstallPackage("hello", "1.2.0"); err != nil {
+				t.Fatalf("install failed: %v", err)
+			}
+
+			binary := filepath.Join(f.wandDir, "packages", "hello", "1.2.0", filepath.FromSlash(tt.want))
+			data, err := os.ReadFile(binary) //nolint:gosec
+			if err != nil || string(data) != helloScript {
+				t.Errorf("expected the binary at %s, got %q, %v", tt.want, data, err)
+			}
+		})
+	}
+}
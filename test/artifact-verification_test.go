@@ -0,0 +1,329 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+
+	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+func writeTestFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestVerifyChecksumFormats tests per-file checksums, manifests and algorithm detection
+func TestVerifyChecksumFormats(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("release artifact")
+	artifact := writeTestFile(t, dir, "package.tar.gz", content)
+
+	sum256 := sha256.Sum256(content)
+	sum512 := sha512.Sum512(content)
+	hex256 := hex.EncodeToString(sum256[:])
+	hex512 := hex.EncodeToString(sum512[:])
+	other := strings.Repeat("0", 64)
+
+	tests := []struct {
+		name      string
+		checksums string
+		algorithm string
+		wantErr   string
+	}{
+		{"bare sha256", hex256 + "\n", "", ""},
+		{"per-file with other name", hex256 + "  tool-1.0.0-linux.tar.gz\n", "", ""},
+		{"bare sha512 inferred", hex512, "", ""},
+		{"sha512 declared", hex512 + "  tool.tar.gz\n", "sha512", ""},
+		{"manifest match", other + "  tool-darwin.tar.gz\n" + hex256 + " *tool-linux.tar.gz\n", "", ""},
+		{"manifest with paths", other + "  ./dist/tool-darwin.tar.gz\n" + hex256 + "  ./dist/tool-linux.tar.gz\n", "", ""},
+		{"bsd style", "SHA512 (tool-darwin.tar.gz) = " + strings.Repeat("0", 128) + "\nSHA512 (tool-linux.tar.gz) = " + hex512 + "\n", "", ""},
+		{"manifest missing asset", other + "  a.tar.gz\n" + other + "  b.tar.gz\n", "", "no checksum for tool-linux.tar.gz"},
+		{"mismatch", other + "\n", "", "checksum mismatch"},
+		{"algorithm disagrees with hash", hex256, "sha512", "checksum mismatch"},
+		{"unsupported algorithm", hex256, "md5", "unsupported checksum algorithm"},
+	}
+
+	verifier := domainadapters.NewVerifierAdapter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checksumPath := writeTestFile(t, dir, "checksums", []byte(tt.checksums))
+			err := verifier.VerifyChecksum(artifact, "tool-linux.tar.gz", checksumPath, tt.algorithm)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// minisignKeyPair generates a minisign-format public key and a signing function
+func minisignKeyPair(t *testing.T) (string, func(data []byte, prehash bool, comment string) string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	publicKey := "untrusted comment: minisign public key\n" +
+		base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), pub...)) + "\n"
+
+	sign := func(data []byte, prehash bool, comment string) string {
+		alg, message := "Ed", data
+		if prehash {
+			digest := blake2b.Sum512(data)
+			alg, message = "ED", digest[:]
+		}
+		sig := ed25519.Sign(priv, message)
+		global := ed25519.Sign(priv, append(append([]byte{}, sig...), comment...))
+		return "untrusted comment: signature\n" +
+			base64.StdEncoding.EncodeToString(append(append([]byte(alg), keyID...), sig...)) + "\n" +
+			"trusted comment: " + comment + "\n" +
+			base64.StdEncoding.EncodeToString(global) + "\n"
+	}
+	return publicKey, sign
+}
+
+// TestVerifyMinisignSignature tests legacy and prehashed minisign signatures
+func TestVerifyMinisignSignature(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("release artifact")
+	artifact := writeTestFile(t, dir, "artifact", content)
+
+	publicKey, sign := minisignKeyPair(t)
+	otherKey, _ := minisignKeyPair(t)
+	config := &entities.SignatureConfig{Type: "minisign", PublicKey: publicKey}
+	verifier := domainadapters.NewVerifierAdapter()
+
+	for _, prehash := range []bool{false, true} {
+		sigPath := writeTestFile(t, dir, "artifact.minisig", []byte(sign(content, prehash, "timestamp:1 file:artifact")))
+		if err := verifier.VerifySignature(artifact, sigPath, config); err != nil {
+			t.Errorf("prehash=%v: unexpected error: %v", prehash, err)
+		}
+	}
+
+	// Tampered content
+	sigPath := writeTestFile(t, dir, "artifact.minisig", []byte(sign([]byte("other content"), true, "c")))
+	if err := verifier.VerifySignature(artifact, sigPath, config); err == nil {
+		t.Error("expected tampered artifact to fail")
+	}
+
+	// Tampered trusted comment
+	sig := strings.Replace(sign(content, true, "original"), "trusted comment: original", "trusted comment: forged", 1)
+	sigPath = writeTestFile(t, dir, "artifact.minisig", []byte(sig))
+	if err := verifier.VerifySignature(artifact, sigPath, config); err == nil {
+		t.Error("expected forged trusted comment to fail")
+	}
+
+	// Signature from a key that is not pinned (same key ID, different key)
+	sigPath = writeTestFile(t, dir, "artifact.minisig", []byte(sign(content, true, "c")))
+	if err := verifier.VerifySignature(artifact, sigPath, &entities.SignatureConfig{Type: "minisign", PublicKey: otherKey}); err == nil {
+		t.Error("expected signature from unpinned key to fail")
+	}
+}
+
+// TestVerifyCosignSignature tests key-based cosign blob signatures
+func TestVerifyCosignSignature(t *testing.T) {
+	dir := t.TempDir()
+	content := []byte("checksums manifest")
+	artifact := writeTestFile(t, dir, "checksums.txt", content)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	digest := sha256.Sum256(content)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &entities.SignatureConfig{Type: "cosign", PublicKey: publicKey}
+	verifier := domainadapters.NewVerifierAdapter()
+
+	sigPath := writeTestFile(t, dir, "checksums.txt.sig", []byte(base64.StdEncoding.EncodeToString(sig)))
+	if err := verifier.VerifySignature(artifact, sigPath, config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tampered := writeTestFile(t, dir, "tampered.txt", []byte("checksums manifest!"))
+	if err := verifier.VerifySignature(tampered, sigPath, config); err == nil {
+		t.Fatal("expected tampered file to fail")
+	}
+}
+
+// TestVerifyGPGSignature tests detached GPG signatures against a pinned key
+func TestVerifyGPGSignature(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+
+	dir := t.TempDir()
+	home, err := os.MkdirTemp("", "wand-gpg-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(home) }()
+
+	gpg := func(args ...string) []byte {
+		t.Helper()
+		cmd := exec.Command("gpg", append([]string{"--batch", "--homedir", home, "--passphrase", "", "--pinentry-mode", "loopback"}, args...)...) //nolint:gosec
+		output, err := cmd.Output()
+		if err != nil {
+			t.Skipf("gpg %v failed: %v", args, err)
+		}
+		return output
+	}
+
+	gpg("--quick-gen-key", "Wand Test <test@example.com>", "ed25519", "sign", "never")
+	publicKey := string(gpg("--armor", "--export", "test@example.com"))
+
+	artifact := writeTestFile(t, dir, "artifact", []byte("release artifact"))
+	sigPath := filepath.Join(dir, "artifact.asc")
+	gpg("--armor", "--output", sigPath, "--detach-sign", artifact)
+
+	config := &entities.SignatureConfig{Type: "gpg", PublicKey: publicKey}
+	verifier := domainadapters.NewVerifierAdapter()
+
+	if err := verifier.VerifySignature(artifact, sigPath, config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tampered := writeTestFile(t, dir, "tampered", []byte("release artifact!"))
+	if err := verifier.VerifySignature(tampered, sigPath, config); err == nil {
+		t.Fatal("expected tampered file to fail")
+	}
+}
+
+// newSignedLintFixture serves an artifact, a checksum manifest and a minisign
+// signature of the manifest, and writes a formula that pins the given key
+func newSignedLintFixture(t *testing.T, artifact, manifest, signature []byte, publicKey string) (*services.FormulaLinter, func()) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/checksums.txt"):
+			_, _ = w.Write(manifest)
+		case strings.HasSuffix(r.URL.Path, "/checksums.txt.minisig"):
+			_, _ = w.Write(signature)
+		default:
+			_, _ = w.Write(artifact)
+		}
+	}))
+
+	dir := t.TempDir()
+	formulasDir := filepath.Join(dir, "formulas")
+	if err := os.MkdirAll(formulasDir, 0755); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	var formula strings.Builder
+	formula.WriteString("name: hello\ntype: cli\ndescription: Test tool\nhomepage: https://example.com\nrepository: example/hello\n")
+	formula.WriteString("binaries:\n  - hello\n")
+	formula.WriteString("signature:\n  type: minisign\n  target: checksums\n  public_key: |\n")
+	for _, line := range strings.Split(strings.TrimSpace(publicKey), "\n") {
+		formula.WriteString("    " + line + "\n")
+	}
+	formula.WriteString("platforms:\n")
+	for _, osName := range []string{"darwin", "linux"} {
+		formula.WriteString("  " + osName + ":\n")
+		for _, arch := range []string{"amd64", "arm64"} {
+			formula.WriteString("    " + arch + ":\n")
+			formula.WriteString("      download_url: " + server.URL + "/v{version}/hello-{os}-{arch}.tar.gz\n")
+			formula.WriteString("      checksum_url: " + server.URL + "/v{version}/checksums.txt\n")
+			formula.WriteString("      checksum_algorithm: sha512\n")
+			formula.WriteString("      signature_url: " + server.URL + "/v{version}/checksums.txt.minisig\n")
+		}
+	}
+	if err := os.WriteFile(filepath.Join(formulasDir, "hello.yaml"), []byte(formula.String()), 0644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	fs := domainadapters.NewFileSystemAdapter()
+	linter := services.NewFormulaLinter(
+		domainadapters.NewFormulaRepository(fs, formulasDir),
+		domainadapters.NewHTTPCheckerAdapter(server.Client()),
+		domainadapters.NewDownloaderAdapter(),
+		domainadapters.NewVerifierAdapter(),
+		domainadapters.NewExtractorAdapter(fs),
+		fs,
+		domainadapters.NewShellExecutorAdapter(),
+		nil,
+		filepath.Join(dir, "tmp"),
+	)
+
+	return linter, server.Close
+}
+
+// TestFormulaLintVerifiesSignedManifest tests a formula whose minisign signature covers a checksum manifest
+func TestFormulaLintVerifiesSignedManifest(t *testing.T) {
+	artifact := buildTarGz(t, map[string]string{"bin/hello": "#!/bin/sh\necho hello\n"})
+	sum := sha512.Sum512(artifact)
+	manifest := []byte(fmt.Sprintf("%s  hello-darwin-amd64.tar.gz\n%s  hello-%s-%s.tar.gz\n",
+		strings.Repeat("0", 128), hex.EncodeToString(sum[:]), entities.CurrentPlatform().OS, entities.CurrentPlatform().Arch))
+
+	publicKey, sign := minisignKeyPair(t)
+
+	for _, tt := range []struct {
+		name      string
+		signature string
+		wantFail  string
+	}{
+		{"valid", sign(manifest, true, "manifest"), ""},
+		{"forged", sign([]byte("other manifest"), true, "manifest"), "signature"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			linter, cleanup := newSignedLintFixture(t, artifact, manifest, []byte(tt.signature), publicKey)
+			defer cleanup()
+
+			report, err := linter.Lint("hello", "1.2.0")
+			if err != nil {
+				t.Fatalf("Lint failed: %v", err)
+			}
+
+			var failed []string
+			for _, r := range report.Results {
+				if !r.Passed && !r.Skipped {
+					failed = append(failed, r.Check)
+				}
+			}
+			if tt.wantFail == "" && len(failed) > 0 {
+				t.Fatalf("unexpected failures: %v (%+v)", failed, report.Results)
+			}
+			if tt.wantFail != "" && (len(failed) != 1 || failed[0] != tt.wantFail) {
+				t.Fatalf("expected only %s to fail, got %v", tt.wantFail, failed)
+			}
+		})
+	}
+}
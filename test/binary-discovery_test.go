@@ -0,0 +1,76 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
+	errs "github.com/ochairo/wand/internal/domain/errors"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// TestBinaryDiscovery tests that binaries are found wherever the archive puts
+// them, honoring bin_path and strip_components, and that the path recorded
+// in the registry is the one wand exec runs
+func TestBinaryDiscovery(t *testing.T) {
+	const script = "#!/bin/sh\necho hello\n"
+	installDir := func(f *trustFixture) string {
+		return filepath.Join(f.wandDir, "packages", "hello", "1.2.0")
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		extra string
+		want  string // relative to the install directory
+	}{
+		{"wrapped in a directory", map[string]string{"hello-1.2.0-linux/hello": script, "hello-1.2.0-linux/README": "hi\n"}, "", "hello-1.2.0-linux/hello"},
+		{"at the root", map[string]string{"hello": script}, "", "hello"},
+		{"stripped", map[string]string{"hello-1.2.0-linux/bin/hello": script}, "strip_components: 1\n", "bin/hello"},
+		{"in bin_path", map[string]string{"tool/libexec/hello": script, "tool/bin/hello": script}, "bin_path: tool/libexec\n", "tool/libexec/hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTrustFixture(t, tt.extra)
+			f.artifact = buildTarGz(t, tt.files)
+			if err := f.installer.InstallPackage("hello", "1.2.0"); err != nil {
+				t.Fatalf("install failed: %v", err)
+			}
+
+			fs := domainadapters.NewFileSystemAdapter()
+			registryRepo := domainadapters.NewRegistryRepository(fs, f.wandDir)
+			registry, err := registryRepo.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			pkg, _ := registry.GetPackage("hello", "1.2.0")
+			want := filepath.Join(installDir(f), filepath.FromSlash(tt.want))
+			if pkg == nil || pkg.Binaries["hello"] != want || pkg.BinPath != filepath.Dir(want) {
+				t.Fatalf("expected hello recorded at %s, got %+v", want, pkg)
+			}
+
+			shimService := services.NewShimService(registryRepo, domainadapters.NewWandRCRepository(fs), domainadapters.NewFormulaRepository(fs, filepath.Join(f.wandDir, "formulas")), fs, f.wandDir)
+			if path, err := shimService.GetBinaryPath("hello", "1.2.0", "hello"); err != nil || path != want {
+				t.Errorf("GetBinaryPath = %q, %v; want %s", path, err, want)
+			}
+		})
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		f := newTrustFixture(t, "bin_path: bin\n")
+		f.artifact = buildTarGz(t, map[string]string{"hello-1.2.0-linux/hello": script})
+		err := f.installer.InstallPackage("hello", "1.2.0")
+		requireErrorCode(t, err, errs.ErrBinaryNotFound, "hello")
+		if _, statErr := os.Stat(installDir(f)); !os.IsNotExist(statErr) {
+			t.Error("a version without its binaries should not stay installed")
+		}
+	})
+
+	t.Run("nothing to strip", func(t *testing.T) {
+		f := newTrustFixture(t, "strip_components: 1\n")
+		f.artifact = buildTarGz(t, map[string]string{"hello": script, "README": "hi\n"})
+		requireErrorCode(t, f.installer.InstallPackage("hello", "1.2.0"), errs.ErrExtractionFailed, "single directory")
+	})
+}
@@ -0,0 +1,132 @@
+package test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	errs "github.com/ochairo/wand/internal/domain/errors"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// trustFixture serves a release artifact that tests can swap out to simulate
+// a re-published release
+type trustFixture struct {
+	mu       sync.Mutex
+	artifact []byte
+
+	server *httptest.Server
+	*testInstaller
+}
+
+func (f *trustFixture) setArtifact(data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.artifact = data
+}
+
+// newTrustFixture writes a formula for "hello" with the given extra platform
+// lines and returns an installer using an isolated wand directory
+func newTrustFixture(t *testing.T, platformExtra string) *trustFixture {
+	t.Helper()
+
+	f := &trustFixture{artifact: buildTarGz(t, map[string]string{"bin/hello": "#!/bin/sh\necho hello\n"})}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		_, _ = w.Write(f.artifact)
+	}))
+	t.Cleanup(f.server.Close)
+
+	f.testInstaller = newTestInstaller(t, map[string]string{"hello": helloFormula(f.server.URL+"/v{version}/hello.tar.gz", platformExtra)}, nil)
+	return f
+}
+
+func requireErrorCode(t *testing.T, err error, code errs.ErrorCode, substr string) {
+	t.Helper()
+	var wandErr *errs.WandError
+	if !errors.As(err, &wandErr) || wandErr.Code != code {
+		t.Fatalf("expected %s error, got %v", code, err)
+	}
+	if !strings.Contains(err.Error(), substr) {
+		t.Fatalf("expected error containing %q, got %v", substr, err)
+	}
+}
+
+// TestChecksumLockRejectsChangedArtifact tests trust-on-first-use for formulas without checksums
+func TestChecksumLockRejectsChangedArtifact(t *testing.T) {
+	f := newTrustFixture(t, "")
+
+	if err := f.installer.InstallPackage("hello", "1.2.0"); err != nil {
+		t.Fatalf("first install failed: %v", err)
+	}
+
+	lock, err := f.lockRepo.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := f.server.URL + "/v1.2/hello.tar.gz"
+	recorded, ok := lock.Get(url)
+	if !ok || recorded.Package != "hello" || recorded.Version != "1.2.0" {
+		t.Fatalf("expected lock entry for %s, got %+v", url, lock.Artifacts)
+	}
+
+	// Re-publish the release with different content
+	if err := f.installer.UninstallPackage("hello", "1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	tampered := buildTarGz(t, map[string]string{"bin/hello": "#!/bin/sh\necho pwned\n"})
+	f.setArtifact(tampered)
+
+	err = f.installer.InstallPackage("hello", "1.2.0")
+	requireErrorCode(t, err, errs.ErrChecksumMismatch, "changed since it was first installed")
+
+	// Explicit override accepts and records the new digest
+	if err := f.installer.InstallPackageWithOptions("hello", "1.2.0", services.InstallOptions{TrustNewChecksum: true}); err != nil {
+		t.Fatalf("install with override failed: %v", err)
+	}
+
+	lock, err = f.lockRepo.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(tampered)
+	if recorded, _ := lock.Get(url); recorded.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("expected lock to record the new digest, got %s", recorded.SHA256)
+	}
+}
+
+// TestPinnedChecksum tests the per-version sha256 pinned in a formula
+func TestPinnedChecksum(t *testing.T) {
+	artifact := buildTarGz(t, map[string]string{"bin/hello": "#!/bin/sh\necho hello\n"})
+	sum := sha256.Sum256(artifact)
+
+	t.Run("match", func(t *testing.T) {
+		f := newTrustFixture(t, "      sha256:\n        \"1.2.0\": "+hex.EncodeToString(sum[:])+"\n")
+		f.setArtifact(artifact)
+		if err := f.installer.InstallPackage("hello", "1.2.0"); err != nil {
+			t.Fatalf("install failed: %v", err)
+		}
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		f := newTrustFixture(t, "      sha256:\n        \"1.2.0\": "+strings.Repeat("0", 64)+"\n")
+		f.setArtifact(artifact)
+		err := f.installer.InstallPackage("hello", "1.2.0")
+		requireErrorCode(t, err, errs.ErrChecksumMismatch, "pinned")
+
+		// A failed verification records nothing
+		lock, loadErr := f.lockRepo.Load()
+		if loadErr != nil {
+			t.Fatal(loadErr)
+		}
+		if len(lock.Artifacts) != 0 {
+			t.Errorf("expected empty lock, got %+v", lock.Artifacts)
+		}
+	})
+}
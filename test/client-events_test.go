@@ -0,0 +1,89 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ochairo/wand/pkg/client"
+	"github.com/ochairo/wand/pkg/types"
+)
+
+// TestClientSubscribe tests that subscribers of a client receive the events of
+// its installs in order, and nothing once unsubscribed
+func TestClientSubscribe(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	wandDir := filepath.Join(home, ".wand")
+
+	artifact := buildTarGz(t, map[string]string{"bin/hello": "#!/bin/sh\necho hello\n"})
+	downloads := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(artifact)
+	}))
+	defer downloads.Close()
+	api := httptest.NewServer(&releaseAPI{})
+	defer api.Close()
+
+	formulasDir := filepath.Join(wandDir, "formulas")
+	if err := os.MkdirAll(formulasDir, 0755); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	formula := helloFormula(downloads.URL+"/v{version}/hello.tar.gz", "")
+	if err := os.WriteFile(filepath.Join(formulasDir, "hello.yaml"), []byte(formula), 0644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	c, err := client.New(wandDir, client.WithGitHubAPI(api.URL), client.WithExecutable("/usr/local/bin/wand"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events, dropped []types.InstallEvent
+	unsubscribe := c.Subscribe(func(e types.InstallEvent) { events = append(events, e) })
+	unsubscribeDropped := c.Subscribe(func(e types.InstallEvent) { dropped = append(dropped, e) })
+	unsubscribeDropped()
+
+	if _, err := c.Install("hello", "1.2.0"); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+
+	want := []types.InstallStage{
+		types.StageResolving,
+		types.StageDownloading,
+		types.StageVerifying,
+		types.StageExtracting,
+		types.StageLinking,
+		types.StageDone,
+	}
+	var got []types.InstallStage
+	for _, e := range events {
+		if e.Package != "hello" {
+			t.Errorf("unexpected package in %+v", e)
+		}
+		if len(got) == 0 || got[len(got)-1] != e.Stage {
+			got = append(got, e.Stage)
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("stages = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("stages = %v, want %v", got, want)
+		}
+	}
+	if len(dropped) != 0 {
+		t.Errorf("unsubscribed before the install, got %d events", len(dropped))
+	}
+
+	unsubscribe()
+	received := len(events)
+	if _, err := c.Install("hello", "1.1.0"); err != nil {
+		t.Fatalf("second install failed: %v", err)
+	}
+	if len(events) != received {
+		t.Errorf("unsubscribed, got %d more events", len(events)-received)
+	}
+}
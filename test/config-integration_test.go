@@ -0,0 +1,100 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
+	"github.com/ochairo/wand/internal/domain/entities"
+)
+
+// TestConfigFileLayering tests that the file round-trips, is parsed strictly
+// and is overridden by the environment
+func TestConfigFileLayering(t *testing.T) {
+	for _, key := range entities.ConfigKeys() {
+		t.Setenv(key.Env, "")
+	}
+
+	homeDir := t.TempDir()
+	fs := domainadapters.NewFileSystemAdapter()
+	path := filepath.Join(homeDir, ".wand", domainadapters.ConfigFile)
+	repo := domainadapters.NewConfigRepository(fs, path)
+
+	file, err := repo.Load()
+	if err != nil {
+		t.Fatalf("a missing file should load as empty: %v", err)
+	}
+	if err := file.Set("hooks", "confirm"); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Set("home", "~/tools"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Save(file); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("WAND_HOOKS", "deny")
+	config, err := domainadapters.LoadConfig(repo, homeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Hooks != "deny" {
+		t.Errorf("expected WAND_HOOKS to override the file, got %q", config.Hooks)
+	}
+	if config.Home != filepath.Join(homeDir, "tools") {
+		t.Errorf("expected home to be expanded, got %q", config.Home)
+	}
+	if config.Parallelism != 4 {
+		t.Errorf("expected the default parallelism, got %d", config.Parallelism)
+	}
+
+	if err := os.WriteFile(path, []byte("hook: deny\n"), 0644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	if _, err := repo.Load(); err == nil || !strings.Contains(err.Error(), "hook") {
+		t.Errorf("expected a misspelled setting to be an error, got %v", err)
+	}
+}
+
+// TestFormulaTaps tests that taps are searched after the main formulas
+// directory and that a missing tap is skipped
+func TestFormulaTaps(t *testing.T) {
+	writeFormula := func(dir, name, description string) {
+		t.Helper()
+		content := "name: " + name + "\ntype: cli\ndescription: " + description + "\nhomepage: https://example.com\nrepository: example/" + name + "\n"
+		if err := os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(content), 0644); err != nil { //nolint:gosec
+			t.Fatal(err)
+		}
+	}
+
+	formulasDir, tapDir := t.TempDir(), t.TempDir()
+	writeFormula(formulasDir, "hello", "Main formula")
+	writeFormula(tapDir, "hello", "Tap formula")
+	writeFormula(tapDir, "extra", "Tap only")
+
+	repo := domainadapters.NewFormulaRepositoryWithOptions(domainadapters.NewFileSystemAdapter(), formulasDir, domainadapters.FormulaRepositoryOptions{
+		Taps: []string{tapDir, filepath.Join(tapDir, "missing")},
+	})
+
+	hello, err := repo.GetFormula("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hello.Description != "Main formula" {
+		t.Errorf("expected the main directory to win, got %q", hello.Description)
+	}
+	if _, err := repo.GetFormula("extra"); err != nil {
+		t.Errorf("expected the tap formula to be found: %v", err)
+	}
+
+	formulas, err := repo.ListFormulas()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(formulas) != 2 {
+		t.Errorf("expected 2 formulas without duplicates, got %d", len(formulas))
+	}
+}
@@ -0,0 +1,283 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
+)
+
+// offsetRecorder is a progress writer that records the offsets it is told about
+type offsetRecorder struct {
+	written int64
+	total   int64
+	offsets []int64
+}
+
+func (r *offsetRecorder) Write(p []byte) (int, error) {
+	r.written += int64(len(p))
+	return len(p), nil
+}
+
+func (r *offsetRecorder) SetTotal(total int64) {
+	r.total = total
+	r.written = 0
+}
+
+func (r *offsetRecorder) SetOffset(offset int64) {
+	r.written = offset
+	r.offsets = append(r.offsets, offset)
+}
+
+// TestDownloadResumesAfterDroppedConnection tests that a retry asks only for
+// the bytes the dropped attempt did not receive
+func TestDownloadResumesAfterDroppedConnection(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	half := len(data) / 2
+
+	var mu sync.Mutex
+	var ranges, ifRanges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		ifRanges = append(ifRanges, r.Header.Get("If-Range"))
+		first := len(ranges) == 1
+		mu.Unlock()
+
+		w.Header().Set("ETag", `"v1"`)
+		if first {
+			// Drop the connection halfway through
+			w.Header().Set("Content-Length", "65536")
+			_, _ = w.Write(data[:half])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "artifact", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	downloader := domainadapters.NewDownloaderAdapterWithOptions(domainadapters.DownloaderOptions{RetryDelay: time.Millisecond})
+	dest := filepath.Join(t.TempDir(), "artifact")
+	progress := &offsetRecorder{}
+	if err := downloader.DownloadWithProgress(server.URL+"/artifact", dest, progress); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+
+	got, err := os.ReadFile(dest) //nolint:gosec
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("downloaded %d bytes, want the original %d", len(got), len(data))
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Error("expected the .part file to be renamed into place")
+	}
+
+	if len(ranges) != 2 || ranges[1] != "bytes=32768-" || ifRanges[1] != `"v1"` {
+		t.Errorf("expected the retry to resume at byte %d with If-Range, got ranges %q, If-Range %q", half, ranges, ifRanges)
+	}
+	if len(progress.offsets) != 1 || progress.offsets[0] != int64(half) {
+		t.Errorf("expected progress to resume at %d, got %v", half, progress.offsets)
+	}
+	if progress.written != int64(len(data)) || progress.total != int64(len(data)) {
+		t.Errorf("expected progress %d/%d, got %d/%d", len(data), len(data), progress.written, progress.total)
+	}
+}
+
+// TestDownloadResumesAcrossCalls tests that a cancelled download is kept by
+// URL and resumed by a later call, unless the file changed in between
+func TestDownloadResumesAcrossCalls(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	half := len(data) / 2
+
+	var mu sync.Mutex
+	var etag string
+	var stall bool
+	var ranges []string
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		stalling, current := stall, etag
+		stall = false
+		mu.Unlock()
+
+		w.Header().Set("ETag", current)
+		if stalling {
+			// Send half, then stall until the client gives up
+			w.Header().Set("Content-Length", "65536")
+			_, _ = w.Write(data[:half])
+			w.(http.Flusher).Flush()
+			started <- struct{}{}
+			<-r.Context().Done()
+			return
+		}
+		http.ServeContent(w, r, "artifact", time.Time{}, bytes.NewReader(data))
+	}))
+	defer server.Close()
+
+	partialDir := filepath.Join(t.TempDir(), "partial")
+	// Each call uses a new downloader, as a new process would
+	download := func(ctx context.Context, etagNow string, stallNow bool) ([]string, error) {
+		mu.Lock()
+		etag, stall, ranges = etagNow, stallNow, nil
+		mu.Unlock()
+
+		downloader := domainadapters.NewDownloaderAdapterWithOptions(domainadapters.DownloaderOptions{PartialDir: partialDir, RetryDelay: time.Millisecond})
+		dest := filepath.Join(t.TempDir(), "artifact")
+		err := downloader.DownloadContext(ctx, server.URL+"/artifact", dest, nil)
+		if err == nil {
+			if got, _ := os.ReadFile(dest); !bytes.Equal(got, data) { //nolint:gosec
+				t.Fatalf("downloaded %d bytes, want the original %d", len(got), len(data))
+			}
+			if entries, _ := os.ReadDir(partialDir); len(entries) != 0 {
+				t.Errorf("expected the partial download to be removed on success, found %v", entries)
+			}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return ranges, err
+	}
+	interrupt := func() {
+		t.Helper()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			// Cancel once the first half is on disk
+			<-started
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+				parts, _ := filepath.Glob(filepath.Join(partialDir, "*.part"))
+				if len(parts) != 1 {
+					continue
+				}
+				if info, err := os.Stat(parts[0]); err == nil && info.Size() == int64(half) {
+					break
+				}
+			}
+			cancel()
+		}()
+		if _, err := download(ctx, `"v1"`, true); err == nil {
+			t.Fatal("expected the cancelled download to fail")
+		}
+	}
+
+	interrupt()
+	got, err := download(context.Background(), `"v1"`, false)
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if len(got) != 1 || got[0] != "bytes=32768-" {
+		t.Errorf("expected the next call to resume at byte %d, got ranges %q", half, got)
+	}
+
+	// A file that changed since is downloaded whole
+	interrupt()
+	if _, err := download(context.Background(), `"v2"`, false); err != nil {
+		t.Fatalf("download of the changed file failed: %v", err)
+	}
+}
+
+// TestDownloadMirrors tests URL rewriting, fallback to the original URL and
+// skipping a mirror that keeps failing
+func TestDownloadMirrors(t *testing.T) {
+	var originHits atomic.Int32
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		originHits.Add(1)
+		_, _ = w.Write([]byte("from origin"))
+	}))
+	defer origin.Close()
+	originHost := strings.TrimPrefix(origin.URL, "http://")
+
+	var mirrorPaths []string
+	var mirrorMu sync.Mutex
+	var mirrorStatus atomic.Int32
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrorMu.Lock()
+		mirrorPaths = append(mirrorPaths, r.URL.Path)
+		mirrorMu.Unlock()
+		if status := int(mirrorStatus.Load()); status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte("from mirror"))
+	}))
+	defer mirror.Close()
+
+	mirrors, err := domainadapters.ParseMirrors(mirror.URL + "/remote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloader := domainadapters.NewDownloaderAdapterWithOptions(domainadapters.DownloaderOptions{Mirrors: mirrors, RetryDelay: time.Millisecond})
+	dir := t.TempDir()
+
+	download := func(want string) {
+		t.Helper()
+		dest := filepath.Join(dir, "artifact")
+		if err := downloader.Download(origin.URL+"/releases/v1/tool.tar.gz", dest); err != nil {
+			t.Fatalf("download failed: %v", err)
+		}
+		if got, _ := os.ReadFile(dest); string(got) != want { //nolint:gosec
+			t.Fatalf("expected %q, got %q", want, got)
+		}
+	}
+
+	mirrorStatus.Store(http.StatusOK)
+	download("from mirror")
+	if want := "/remote/" + originHost + "/releases/v1/tool.tar.gz"; len(mirrorPaths) != 1 || mirrorPaths[0] != want {
+		t.Fatalf("expected mirror request for %s, got %v", want, mirrorPaths)
+	}
+	if originHits.Load() != 0 {
+		t.Fatal("expected the origin not to be contacted")
+	}
+
+	// A mirror without the file falls through to the origin
+	mirrorStatus.Store(http.StatusNotFound)
+	download("from origin")
+
+	// A failing mirror is retried, then skipped once its breaker opens
+	mirrorStatus.Store(http.StatusBadGateway)
+	for i := 0; i < 3; i++ {
+		download("from origin")
+	}
+	before := len(mirrorPaths)
+	download("from origin")
+	if len(mirrorPaths) != before {
+		t.Errorf("expected the failing mirror to be skipped, got %d more requests", len(mirrorPaths)-before)
+	}
+}
+
+// TestParseMirrors tests the WAND_MIRRORS syntax
+func TestParseMirrors(t *testing.T) {
+	mirrors, err := domainadapters.ParseMirrors(" https://proxy.example.com/dl/ , https://github.com/=https://proxy.example.com/github ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mirrors) != 2 {
+		t.Fatalf("expected 2 mirrors, got %+v", mirrors)
+	}
+
+	url := "https://github.com/jqlang/jq/releases/download/jq-1.7.1/jq-linux-amd64?x=1"
+	if got, want := mirrors[0].Rewrite(url), "https://proxy.example.com/dl/github.com/jqlang/jq/releases/download/jq-1.7.1/jq-linux-amd64?x=1"; got != want {
+		t.Errorf("host mirror: got %s, want %s", got, want)
+	}
+	if got, want := mirrors[1].Rewrite(url), "https://proxy.example.com/github/jqlang/jq/releases/download/jq-1.7.1/jq-linux-amd64?x=1"; got != want {
+		t.Errorf("prefix mirror: got %s, want %s", got, want)
+	}
+	if got := mirrors[1].Rewrite("https://example.com/tool.tar.gz"); got != "" {
+		t.Errorf("expected prefix mirror to skip other hosts, got %s", got)
+	}
+
+	if _, err := domainadapters.ParseMirrors("ftp://proxy.example.com"); err == nil {
+		t.Error("expected an error for a non-http mirror")
+	}
+}
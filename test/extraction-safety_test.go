@@ -0,0 +1,276 @@
+package test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
+	"github.com/ochairo/wand/internal/domain/entities"
+)
+
+// tarEntry is one entry of a crafted tarball
+type tarEntry struct {
+	name     string
+	typeflag byte
+	body     string
+	linkname string
+	mode     int64
+	modTime  time.Time
+}
+
+// craftTar writes entries in order, exactly as given
+func craftTar(t testing.TB, entries ...tarEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, entry := range entries {
+		hdr := &tar.Header{Name: entry.name, Typeflag: entry.typeflag, Linkname: entry.linkname, Mode: entry.mode, ModTime: entry.modTime}
+		if hdr.Typeflag == 0 {
+			hdr.Typeflag = tar.TypeReg
+		}
+		if hdr.Mode == 0 {
+			hdr.Mode = 0644
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = int64(len(entry.body))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// zipEntry is one entry of a crafted zip archive; a symlink's body is its target
+type zipEntry struct {
+	name string
+	body string
+	mode os.FileMode
+}
+
+// craftZip writes entries in order, with their Unix modes
+func craftZip(t testing.TB, modTime time.Time, entries ...zipEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		hdr := &zip.FileHeader{Name: entry.name, Method: zip.Deflate, Modified: modTime}
+		hdr.SetMode(entry.mode)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entry.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// extractBytes writes an archive to a file and extracts it into a fresh
+// directory next to an outside directory that must stay empty
+func extractBytes(t testing.TB, data []byte, opts domainadapters.ExtractorOptions) (destDir, outsideDir string, err error) {
+	t.Helper()
+	root := t.TempDir()
+	archivePath := filepath.Join(root, "archive")
+	if err := os.WriteFile(archivePath, data, 0644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	destDir = filepath.Join(root, "dest")
+	outsideDir = filepath.Join(root, "outside")
+	if err := os.MkdirAll(outsideDir, 0755); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	extractor := domainadapters.NewExtractorAdapterWithOptions(domainadapters.NewFileSystemAdapter(), opts)
+	return destDir, outsideDir, extractor.Extract(archivePath, destDir)
+}
+
+// TestExtractionRefusesUnsafeEntries tests that links and paths leading out
+// of the destination are refused, naming the entry
+func TestExtractionRefusesUnsafeEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []tarEntry
+		want    string
+	}{
+		{"path traversal", []tarEntry{{name: "../evil", body: "x"}}, "../evil: path traversal"},
+		{"absolute symlink", []tarEntry{{name: "link", typeflag: tar.TypeSymlink, linkname: "/etc/passwd"}}, "link: symlink to \"/etc/passwd\" must be relative"},
+		{"climbing symlink", []tarEntry{{name: "a/link", typeflag: tar.TypeSymlink, linkname: "../../outside"}}, "a/link: symlink to \"../../outside\" points outside"},
+		{"symlink through a name", []tarEntry{{name: "link", typeflag: tar.TypeSymlink, linkname: "x/../.."}}, "link: symlink to \"x/../..\" has '..' after a directory name"},
+		{"hard link out", []tarEntry{{name: "link", typeflag: tar.TypeLink, linkname: "../outside/file"}}, "link: hard link path traversal"},
+		{"hard link to a symlink", []tarEntry{{name: "s", typeflag: tar.TypeSymlink, linkname: "f"}, {name: "f", body: "x"}, {name: "h", typeflag: tar.TypeLink, linkname: "s"}}, "h: hard link to \"s\""},
+		{"file through a symlink", []tarEntry{{name: "lib", typeflag: tar.TypeSymlink, linkname: "."}, {name: "lib/evil", body: "x"}}, "lib: a directory of the same name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, outside, err := extractBytes(t, craftTar(t, tt.entries...), domainadapters.ExtractorOptions{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected an error containing %q, got %v", tt.want, err)
+			}
+			if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+				t.Errorf("extraction wrote outside the destination: %v", entries)
+			}
+		})
+	}
+}
+
+// TestExtractionLinksAndModes tests that safe links are created and modes
+// and mtimes are kept, for tar and zip
+func TestExtractionLinksAndModes(t *testing.T) {
+	mtime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	check := func(t *testing.T, dest string) {
+		t.Helper()
+		info, err := os.Stat(filepath.Join(dest, "pkg/bin/tool"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0750 || !info.ModTime().Equal(mtime) {
+			t.Errorf("tool has mode %s and mtime %s", info.Mode(), info.ModTime())
+		}
+		if link, err := os.Readlink(filepath.Join(dest, "pkg/bin/alias")); err != nil || link != "tool" {
+			t.Errorf("alias -> %q, %v", link, err)
+		}
+		if link, err := os.Readlink(filepath.Join(dest, "pkg/lib/current")); err != nil || link != "../bin" {
+			t.Errorf("current -> %q, %v", link, err)
+		}
+	}
+
+	t.Run("tar", func(t *testing.T) {
+		data := craftTar(t,
+			tarEntry{name: "pkg/", typeflag: tar.TypeDir, mode: 0755, modTime: mtime},
+			tarEntry{name: "pkg/bin/tool", body: helloScript, mode: 0750, modTime: mtime},
+			tarEntry{name: "pkg/bin/alias", typeflag: tar.TypeSymlink, linkname: "tool"},
+			tarEntry{name: "pkg/lib/current", typeflag: tar.TypeSymlink, linkname: "../bin"},
+			tarEntry{name: "pkg/bin/hard", typeflag: tar.TypeLink, linkname: "pkg/bin/tool"},
+		)
+		dest, _, err := extractBytes(t, data, domainadapters.ExtractorOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, dest)
+		if data, err := os.ReadFile(filepath.Join(dest, "pkg/bin/hard")); err != nil || string(data) != helloScript { //nolint:gosec
+			t.Errorf("hard link has %q, %v", data, err)
+		}
+		if info, err := os.Stat(filepath.Join(dest, "pkg")); err != nil || !info.ModTime().Equal(mtime) {
+			t.Errorf("expected the directory mtime to be kept, got %v", info.ModTime())
+		}
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		data := craftZip(t, mtime,
+			zipEntry{name: "pkg/bin/tool", body: helloScript, mode: 0750},
+			zipEntry{name: "pkg/bin/alias", body: "tool", mode: os.ModeSymlink | 0777},
+			zipEntry{name: "pkg/lib/current", body: "../bin", mode: os.ModeSymlink | 0777},
+		)
+		dest, _, err := extractBytes(t, data, domainadapters.ExtractorOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, dest)
+	})
+}
+
+// TestExtractionLimits tests the total size, file size and entry limits
+func TestExtractionLimits(t *testing.T) {
+	data := craftTar(t,
+		tarEntry{name: "a", body: strings.Repeat("a", 600)},
+		tarEntry{name: "b", body: strings.Repeat("b", 600)},
+		tarEntry{name: "c", body: "c"},
+	)
+
+	tests := []struct {
+		name string
+		opts domainadapters.ExtractorOptions
+		want string
+	}{
+		{"total size", domainadapters.ExtractorOptions{MaxSize: 1000}, "b: archive unpacks to more than 1000 bytes"},
+		{"file size", domainadapters.ExtractorOptions{MaxFileSize: 500}, "a: file is too large: 600 bytes (max 500)"},
+		{"entries", domainadapters.ExtractorOptions{MaxFiles: 2}, "c: archive has more than 2 entries"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := extractBytes(t, data, tt.opts); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// A compressed binary counts what it decompresses to
+	bomb := compress(t, entities.ArchiveFormatGz, make([]byte, 4096))
+	if _, _, err := extractBytes(t, bomb, domainadapters.ExtractorOptions{MaxSize: 1024}); err == nil || !strings.Contains(err.Error(), "more than 1024 bytes") {
+		t.Errorf("expected the compressed file to hit the size limit, got %v", err)
+	}
+}
+
+// FuzzExtract feeds crafted archives of every format to the extractor, which
+// must never panic, write outside the destination or leave a link that
+// resolves outside it
+func FuzzExtract(f *testing.F) {
+	seeds := [][]byte{
+		craftTar(f,
+			tarEntry{name: "pkg/bin/tool", body: helloScript, mode: 0755},
+			tarEntry{name: "pkg/bin/alias", typeflag: tar.TypeSymlink, linkname: "tool"},
+			tarEntry{name: "pkg/bin/hard", typeflag: tar.TypeLink, linkname: "pkg/bin/tool"},
+		),
+		craftTar(f, tarEntry{name: "link", typeflag: tar.TypeSymlink, linkname: "../outside"}),
+		craftTar(f, tarEntry{name: "../outside/evil", body: "x"}),
+		craftTar(f,
+			tarEntry{name: "lib", typeflag: tar.TypeSymlink, linkname: "."},
+			tarEntry{name: "lib/evil", body: "x"},
+		),
+		craftZip(f, time.Now(),
+			zipEntry{name: "pkg/bin/tool", body: helloScript, mode: 0755},
+			zipEntry{name: "pkg/bin/alias", body: "tool", mode: os.ModeSymlink | 0777},
+			zipEntry{name: "pkg/escape", body: "../../outside", mode: os.ModeSymlink | 0777},
+		),
+		buildDeb(f, map[string]string{"./usr/bin/hello": helloScript}),
+		buildRpm(f, map[string]string{"./usr/bin/hello": helloScript}),
+		compress(f, entities.ArchiveFormatXz, []byte(helloScript)),
+	}
+	seeds = append(seeds, compress(f, entities.ArchiveFormatGz, seeds[0]))
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		dest, outside, _ := extractBytes(t, data, domainadapters.ExtractorOptions{MaxSize: 1 << 20, MaxFiles: 100})
+
+		if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+			t.Fatalf("extraction wrote outside the destination: %v", entries)
+		}
+		realDest, err := filepath.EvalSymlinks(filepath.Dir(dest))
+		if err != nil {
+			t.Fatal(err)
+		}
+		realDest = filepath.Join(realDest, "dest")
+		_ = filepath.Walk(dest, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.Mode()&os.ModeSymlink == 0 {
+				return nil
+			}
+			resolved, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				return nil // dangling links lead nowhere
+			}
+			if resolved != realDest && !strings.HasPrefix(resolved, realDest+string(filepath.Separator)) {
+				t.Fatalf("%s resolves outside the destination to %s", path, resolved)
+			}
+			return nil
+		})
+	})
+}
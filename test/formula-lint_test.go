@@ -0,0 +1,187 @@
+package test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// buildTarGz creates a tar.gz archive with the given files and modes
+func buildTarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// newLintFixture serves a release artifact for every platform from an httptest
+// server and writes a formula pointing at it. Requests for the given missing
+// arch return 404.
+func newLintFixture(t *testing.T, missingArch, testCmd string, binaries []string) (*services.FormulaLinter, func()) {
+	t.Helper()
+
+	artifact := buildTarGz(t, map[string]string{
+		"bin/hello": "#!/bin/sh\necho hello 1.2.0\n",
+	})
+	sum := sha256.Sum256(artifact)
+	checksum := hex.EncodeToString(sum[:]) + "  hello.tar.gz\n"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if missingArch != "" && strings.Contains(r.URL.Path, missingArch) {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			_, _ = w.Write([]byte(checksum))
+			return
+		}
+		_, _ = w.Write(artifact)
+	}))
+
+	dir := t.TempDir()
+	formulasDir := filepath.Join(dir, "formulas")
+	if err := os.MkdirAll(formulasDir, 0755); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	var formula strings.Builder
+	formula.WriteString("name: hello\ntype: cli\ndescription: Test tool\nhomepage: https://example.com\nrepository: example/hello\n")
+	formula.WriteString("binaries:\n")
+	for _, b := range binaries {
+		formula.WriteString("  - " + b + "\n")
+	}
+	if testCmd != "" {
+		formula.WriteString("test: " + testCmd + "\n")
+	}
+	formula.WriteString("platforms:\n")
+	for _, osName := range []string{"darwin", "linux"} {
+		formula.WriteString("  " + osName + ":\n")
+		for _, arch := range []string{"amd64", "arm64"} {
+			formula.WriteString("    " + arch + ":\n")
+			formula.WriteString("      download_url: " + server.URL + "/v{version}/hello-{os}-{arch}.tar.gz\n")
+			formula.WriteString("      checksum_url: " + server.URL + "/v{version}/hello-{os}-{arch}.tar.gz.sha256\n")
+		}
+	}
+	if err := os.WriteFile(filepath.Join(formulasDir, "hello.yaml"), []byte(formula.String()), 0644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	fs := domainadapters.NewFileSystemAdapter()
+	formulaRepo := domainadapters.NewFormulaRepository(fs, formulasDir)
+	linter := services.NewFormulaLinter(
+		formulaRepo,
+		domainadapters.NewHTTPCheckerAdapter(server.Client()),
+		domainadapters.NewDownloaderAdapter(),
+		domainadapters.NewVerifierAdapter(),
+		domainadapters.NewExtractorAdapter(fs),
+		fs,
+		domainadapters.NewShellExecutorAdapter(),
+		nil,
+		filepath.Join(dir, "tmp"),
+	)
+
+	return linter, server.Close
+}
+
+// TestFormulaLintPasses tests a formula whose artifacts all exist
+func TestFormulaLintPasses(t *testing.T) {
+	linter, cleanup := newLintFixture(t, "", "hello --version", []string{"hello"})
+	defer cleanup()
+
+	report, err := linter.Lint("hello", "1.2.0")
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+
+	if report.Failed() {
+		for _, r := range report.Results {
+			t.Logf("%+v", r)
+		}
+		t.Fatal("expected all checks to pass")
+	}
+
+	counts := make(map[string]int)
+	for _, r := range report.Results {
+		if r.Passed {
+			counts[r.Check]++
+		}
+	}
+	// 4 platforms x (download_url + checksum_url)
+	if counts["url"] != 8 {
+		t.Errorf("expected 8 URL checks, got %d", counts["url"])
+	}
+	for _, check := range []string{"download", "checksum", "binary", "test"} {
+		if counts[check] != 1 {
+			t.Errorf("expected %s check to pass once, got %d", check, counts[check])
+		}
+	}
+}
+
+// TestFormulaLintReportsMissingArtifacts tests that a missing artifact for another platform is reported
+func TestFormulaLintReportsMissingArtifacts(t *testing.T) {
+	linter, cleanup := newLintFixture(t, "darwin-arm64", "", []string{"hello"})
+	defer cleanup()
+
+	report, err := linter.Lint("hello", "1.2.0")
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+
+	if !report.Failed() {
+		t.Fatal("expected lint to fail")
+	}
+
+	for _, r := range report.Results {
+		if !r.Passed && !r.Skipped && (r.Check != "url" || !strings.HasPrefix(r.Target, "darwin/arm64")) {
+			t.Errorf("unexpected failure: %+v", r)
+		}
+	}
+}
+
+// TestFormulaLintReportsMissingBinary tests that a declared binary absent from the archive is reported
+func TestFormulaLintReportsMissingBinary(t *testing.T) {
+	linter, cleanup := newLintFixture(t, "", "", []string{"hello", "goodbye"})
+	defer cleanup()
+
+	report, err := linter.Lint("hello", "1.2.0")
+	if err != nil {
+		t.Fatalf("Lint failed: %v", err)
+	}
+
+	var missing []string
+	for _, r := range report.Results {
+		if r.Check == "binary" && !r.Passed {
+			missing = append(missing, r.Target)
+		}
+	}
+	if len(missing) != 1 || missing[0] != "goodbye" {
+		t.Errorf("expected only goodbye to be missing, got %v", missing)
+	}
+}
@@ -0,0 +1,209 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+	"github.com/ochairo/wand/internal/domain/services"
+	"github.com/ochairo/wand/internal/domain/validation"
+	"gopkg.in/yaml.v3"
+)
+
+// fakeGitHubClient serves a fixed latest release; listings also include the
+// other releases
+type fakeGitHubClient struct {
+	release *interfaces.GitHubRelease
+	others  []*interfaces.GitHubRelease
+}
+
+func (f *fakeGitHubClient) GetLatestRelease(owner, repo string) (*interfaces.GitHubRelease, error) {
+	return f.release, nil
+}
+
+func (f *fakeGitHubClient) GetRelease(owner, repo, tag string) (*interfaces.GitHubRelease, error) {
+	return f.release, nil
+}
+
+func (f *fakeGitHubClient) ListReleases(owner, repo string) ([]*interfaces.GitHubRelease, error) {
+	return append([]*interfaces.GitHubRelease{f.release}, f.others...), nil
+}
+
+func (f *fakeGitHubClient) DownloadAsset(asset *interfaces.GitHubAsset, destPath string) error {
+	return nil
+}
+
+func (f *fakeGitHubClient) GetLatestReleaseContext(ctx context.Context, owner, repo string) (*interfaces.GitHubRelease, error) {
+	return f.release, ctx.Err()
+}
+
+func (f *fakeGitHubClient) GetReleaseContext(ctx context.Context, owner, repo, tag string) (*interfaces.GitHubRelease, error) {
+	return f.release, ctx.Err()
+}
+
+func (f *fakeGitHubClient) ListReleasesContext(ctx context.Context, owner, repo string) ([]*interfaces.GitHubRelease, error) {
+	return append([]*interfaces.GitHubRelease{f.release}, f.others...), ctx.Err()
+}
+
+func (f *fakeGitHubClient) DownloadAssetContext(ctx context.Context, asset *interfaces.GitHubAsset, destPath string) error {
+	return ctx.Err()
+}
+
+func newRelease(owner, repo, tag string, names ...string) *interfaces.GitHubRelease {
+	release := &interfaces.GitHubRelease{TagName: tag}
+	for _, name := range names {
+		release.Assets = append(release.Assets, interfaces.GitHubAsset{
+			Name:        name,
+			DownloadURL: "https://github.com/" + owner + "/" + repo + "/releases/download/" + tag + "/" + name,
+		})
+	}
+	return release
+}
+
+// TestFormulaScaffoldRustStyleAssets tests target-triple asset names with per-file checksums
+func TestFormulaScaffoldRustStyleAssets(t *testing.T) {
+	release := newRelease("BurntSushi", "ripgrep", "14.1.1",
+		"ripgrep-14.1.1-aarch64-apple-darwin.tar.gz",
+		"ripgrep-14.1.1-aarch64-apple-darwin.tar.gz.sha256",
+		"ripgrep-14.1.1-x86_64-apple-darwin.tar.gz",
+		"ripgrep-14.1.1-x86_64-apple-darwin.tar.gz.sha256",
+		"ripgrep-14.1.1-x86_64-unknown-linux-gnu.tar.gz",
+		"ripgrep-14.1.1-x86_64-unknown-linux-gnu.tar.gz.sha256",
+		"ripgrep-14.1.1-x86_64-unknown-linux-musl.tar.gz",
+		"ripgrep-14.1.1-x86_64-unknown-linux-musl.tar.gz.sha256",
+		"ripgrep-14.1.1-aarch64-unknown-linux-gnu.tar.gz",
+		"ripgrep-14.1.1-aarch64-unknown-linux-gnu.tar.gz.sha256",
+		"ripgrep-14.1.1-x86_64-pc-windows-msvc.zip",
+		"ripgrep_14.1.1-1_amd64.deb",
+	)
+
+	scaffolder := services.NewFormulaScaffolder(&fakeGitHubClient{release: release})
+	result, err := scaffolder.Scaffold("BurntSushi/ripgrep")
+	if err != nil {
+		t.Fatalf("Scaffold failed: %v", err)
+	}
+
+	if validation.HasErrors(result.Issues) {
+		t.Errorf("generated formula has errors: %v", result.Issues)
+	}
+
+	// The YAML must round-trip into a formula
+	var formula entities.Formula
+	if err := yaml.Unmarshal(result.YAML, &formula); err != nil {
+		t.Fatalf("generated YAML does not parse: %v", err)
+	}
+
+	tests := []struct {
+		os, arch, url string
+	}{
+		{"darwin", "arm64", "ripgrep-{version}-aarch64-apple-darwin.tar.gz"},
+		{"darwin", "amd64", "ripgrep-{version}-x86_64-apple-darwin.tar.gz"},
+		{"linux", "amd64", "ripgrep-{version}-x86_64-unknown-linux-musl.tar.gz"},
+		{"linux", "arm64", "ripgrep-{version}-aarch64-unknown-linux-gnu.tar.gz"},
+	}
+	for _, tt := range tests {
+		config := formula.GetPlatformConfig(tt.os, tt.arch)
+		if config == nil {
+			t.Errorf("missing %s/%s", tt.os, tt.arch)
+			continue
+		}
+		want := "https://github.com/BurntSushi/ripgrep/releases/download/{version}/" + tt.url
+		if config.DownloadURL != want {
+			t.Errorf("%s/%s download_url = %s, want %s", tt.os, tt.arch, config.DownloadURL, want)
+		}
+		if config.ChecksumURL != want+".sha256" {
+			t.Errorf("%s/%s checksum_url = %s", tt.os, tt.arch, config.ChecksumURL)
+		}
+	}
+
+	if formula.Name != "ripgrep" || formula.Repository != "BurntSushi/ripgrep" {
+		t.Errorf("unexpected metadata: %s %s", formula.Name, formula.Repository)
+	}
+}
+
+// TestFormulaScaffoldGoStyleAssets tests os_arch asset names with a checksum manifest
+func TestFormulaScaffoldGoStyleAssets(t *testing.T) {
+	release := newRelease("example", "Tool", "v2.3.4",
+		"tool_2.3.4_Darwin_x86_64.tar.gz",
+		"tool_2.3.4_Darwin_arm64.tar.gz",
+		"tool_2.3.4_Linux_x86_64.tar.gz",
+		"tool_2.3.4_Linux_arm64.tar.gz",
+		"tool_2.3.4_checksums.txt",
+	)
+
+	scaffolder := services.NewFormulaScaffolder(&fakeGitHubClient{release: release})
+	result, err := scaffolder.Scaffold("example/Tool")
+	if err != nil {
+		t.Fatalf("Scaffold failed: %v", err)
+	}
+
+	if result.Version != "2.3.4" {
+		t.Errorf("version = %s, want 2.3.4", result.Version)
+	}
+	if result.Formula.Name != "tool" {
+		t.Errorf("name = %s, want tool", result.Formula.Name)
+	}
+
+	config := result.Formula.GetPlatformConfig("linux", "amd64")
+	if config == nil || !strings.HasSuffix(config.DownloadURL, "/v{version}/tool_{version}_Linux_x86_64.tar.gz") {
+		t.Errorf("unexpected linux/amd64 config: %+v", config)
+	}
+
+	if config != nil && !strings.HasSuffix(config.ChecksumURL, "/v{version}/tool_{version}_checksums.txt") {
+		t.Errorf("expected checksum_url to reference the manifest, got %s", config.ChecksumURL)
+	}
+	for _, note := range result.Notes {
+		if strings.Contains(note, "no checksum asset") {
+			t.Errorf("unexpected note: %s", note)
+		}
+	}
+}
+
+// TestFormulaScaffoldNoMatchingAssets tests that a release without platform assets is rejected
+func TestFormulaScaffoldNoMatchingAssets(t *testing.T) {
+	release := newRelease("example", "lib", "1.0.0", "lib-1.0.0-src.tar.gz")
+
+	scaffolder := services.NewFormulaScaffolder(&fakeGitHubClient{release: release})
+	if _, err := scaffolder.Scaffold("example/lib"); err == nil {
+		t.Fatal("expected an error when no assets match a platform")
+	}
+}
+
+// TestFormulaScaffoldVersionInNames tests that only the tag and the asset's own
+// version are templated, not the repository name or longer versions
+func TestFormulaScaffoldVersionInNames(t *testing.T) {
+	release := newRelease("example", "hello-1.2", "v1.2",
+		"hello-1.2-linux-amd64-glibc2.1.2.tar.gz",
+		"hello-1.2-darwin-arm64-sdk1.2.10.tar.gz",
+	)
+
+	scaffolder := services.NewFormulaScaffolder(&fakeGitHubClient{release: release})
+	result, err := scaffolder.Scaffold("example/hello-1.2")
+	if err != nil {
+		t.Fatalf("Scaffold failed: %v", err)
+	}
+	var formula entities.Formula
+	if err := yaml.Unmarshal(result.YAML, &formula); err != nil {
+		t.Fatalf("generated YAML does not parse: %v", err)
+	}
+
+	tests := []struct {
+		os, arch, url string
+	}{
+		{"linux", "amd64", "hello-{version}-linux-amd64-glibc2.1.2.tar.gz"},
+		{"darwin", "arm64", "hello-{version}-darwin-arm64-sdk1.2.10.tar.gz"},
+	}
+	for _, tt := range tests {
+		config := formula.GetPlatformConfig(tt.os, tt.arch)
+		if config == nil {
+			t.Errorf("missing %s/%s", tt.os, tt.arch)
+			continue
+		}
+		want := "https://github.com/example/hello-1.2/releases/download/v{version}/" + tt.url
+		if config.DownloadURL != want {
+			t.Errorf("%s/%s download_url = %s, want %s", tt.os, tt.arch, config.DownloadURL, want)
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
 	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/validation"
 )
 
 // TestAllFormulasLoad tests that all formulas in the formulas directory load correctly
@@ -135,3 +138,29 @@ func TestFormulaURLTemplates(t *testing.T) {
 		})
 	}
 }
+
+// TestAllFormulasPassSchemaValidation tests that every shipped formula validates without errors
+func TestAllFormulasPassSchemaValidation(t *testing.T) {
+	files, err := filepath.Glob("../formulas/*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to list formulas: %v", err)
+	}
+	if len(files) == 0 {
+		t.Fatal("No formulas found")
+	}
+
+	validator := validation.NewSchemaValidator()
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file) //nolint:gosec // G304: test fixture path
+			if err != nil {
+				t.Fatalf("Failed to read: %v", err)
+			}
+			for _, issue := range validator.ValidateFormula(data) {
+				if issue.Severity == validation.SeverityError {
+					t.Errorf("%s", issue)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
+	errs "github.com/ochairo/wand/internal/domain/errors"
+	"github.com/ochairo/wand/internal/domain/services"
+	externaladapters "github.com/ochairo/wand/internal/external-adapters"
+)
+
+// releaseAPI is a fake GitHub API serving the releases of example/hello
+type releaseAPI struct {
+	mu          sync.Mutex
+	requests    int
+	conditional int       // requests carrying a matching If-None-Match
+	limitedTill time.Time // respond with a rate limit until then
+}
+
+func (a *releaseAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.requests++
+
+	if r.URL.Path != "/repos/example/hello/releases" {
+		http.NotFound(w, r)
+		return
+	}
+	if time.Now().Before(a.limitedTill) {
+		w.Header().Set("X-RateLimit-Limit", "60")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(a.limitedTill.Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message": "API rate limit exceeded"}`))
+		return
+	}
+
+	w.Header().Set("ETag", `"releases-v1"`)
+	if r.Header.Get("If-None-Match") == `"releases-v1"` {
+		a.conditional++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`[{"tag_name": "v1.2.0"}, {"tag_name": "v1.1.0"}]`))
+}
+
+// counts returns the number of requests and of conditional requests
+func (a *releaseAPI) counts() (int, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.requests, a.conditional
+}
+
+// newReleaseVersionService returns a version service for "hello" whose GitHub
+// adapter talks to the API at apiURL and caches responses in cacheDir
+func newReleaseVersionService(t *testing.T, apiURL, cacheDir string, maxWait time.Duration) *services.VersionService {
+	t.Helper()
+
+	formulasDir := t.TempDir()
+	formula := "name: hello\ntype: cli\ndescription: Test tool\nhomepage: https://example.com\nrepository: example/hello\n"
+	if err := os.WriteFile(filepath.Join(formulasDir, "hello.yaml"), []byte(formula), 0644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	github := externaladapters.NewGitHubAdapterWithOptions(externaladapters.GitHubAdapterOptions{
+		BaseURL:          apiURL,
+		CacheDir:         cacheDir,
+		MaxRateLimitWait: maxWait,
+	})
+	formulaRepo := domainadapters.NewFormulaRepository(domainadapters.NewFileSystemAdapter(), formulasDir)
+	return services.NewVersionService(github, formulaRepo)
+}
+
+// TestReleaseListCaching tests that releases are fetched once per process and
+// revalidated with a conditional request in the next one
+func TestReleaseListCaching(t *testing.T) {
+	api := &releaseAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	cacheDir := t.TempDir()
+
+	versions := newReleaseVersionService(t, server.URL, cacheDir, 0)
+	if _, err := versions.ResolveVersion("hello", "1.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := versions.ResolveVersion("hello", "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.String() != "1.2.0" {
+		t.Errorf("expected latest 1.2.0, got %s", latest)
+	}
+	if requests, _ := api.counts(); requests != 1 {
+		t.Errorf("expected one API request for both lookups, got %d", requests)
+	}
+
+	// A new process revalidates the stored list instead of downloading it
+	versions = newReleaseVersionService(t, server.URL, cacheDir, 0)
+	available, err := versions.ListAvailableVersions("hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(available) != 2 {
+		t.Errorf("expected the cached releases, got %v", available)
+	}
+	if requests, conditional := api.counts(); requests != 2 || conditional != 1 {
+		t.Errorf("expected one conditional request, got %d requests, %d conditional", requests, conditional)
+	}
+}
+
+// TestReleaseListRateLimit tests failing fast on a distant reset and waiting
+// for a near one
+func TestReleaseListRateLimit(t *testing.T) {
+	api := &releaseAPI{limitedTill: time.Now().Add(time.Hour)}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	versions := newReleaseVersionService(t, server.URL, "", 0)
+	_, err := versions.ResolveVersion("hello", "latest")
+	requireErrorCode(t, err, errs.ErrRateLimited, "WAND_GITHUB_TOKEN")
+
+	// The reset header has one-second resolution
+	api.mu.Lock()
+	api.limitedTill = time.Now().Add(time.Second).Truncate(time.Second)
+	api.requests = 0
+	api.mu.Unlock()
+	versions = newReleaseVersionService(t, server.URL, "", 5*time.Second)
+	latest, err := versions.ResolveVersion("hello", "latest")
+	if err != nil {
+		t.Fatalf("expected the request to succeed after the reset, got %v", err)
+	}
+	if requests, _ := api.counts(); latest.String() != "1.2.0" || requests != 2 {
+		t.Errorf("expected 1.2.0 after one retry, got %s after %d requests", latest, requests)
+	}
+}
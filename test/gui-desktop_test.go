@@ -0,0 +1,257 @@
+package test
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
+	"github.com/ochairo/wand/internal/domain/entities"
+	errs "github.com/ochairo/wand/internal/domain/errors"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// newGUIFixture serves "hello" as a GUI application, released as 1.2.0 and
+// 1.3.0. Without binaries the formula declares none, leaving the executable
+// to its desktop entry.
+func newGUIFixture(t *testing.T, binaries bool, platformExtra string) *trustFixture {
+	t.Helper()
+	if !entities.CurrentPlatform().IsLinux() {
+		t.Skip("desktop integration is Linux only")
+	}
+
+	f := newTrustFixture(t, platformExtra)
+	f.github.others = append(f.github.others, newRelease("example", "hello", "v1.3.0"))
+	path := filepath.Join(f.wandDir, "formulas", "hello.yaml")
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		t.Fatal(err)
+	}
+	formula := strings.Replace(string(data), "type: cli\n", "type: gui\n", 1)
+	if !binaries {
+		formula = strings.Replace(formula, "binaries:\n  - hello\n", "", 1)
+	}
+	if err := os.WriteFile(path, []byte(formula), 0644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	return f
+}
+
+// readDesktopEntry parses an installed desktop entry into its groups
+func readDesktopEntry(t *testing.T, path string) map[string]map[string]string {
+	t.Helper()
+	data, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		t.Fatalf("desktop entry not installed: %v", err)
+	}
+
+	groups := make(map[string]map[string]string)
+	var group string
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "["):
+			group = strings.Trim(line, "[]")
+			groups[group] = make(map[string]string)
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok || group == "" {
+				t.Fatalf("invalid desktop entry line %q in:\n%s", line, data)
+			}
+			if _, exists := groups[group][key]; exists {
+				t.Fatalf("duplicate key %s in:\n%s", key, data)
+			}
+			groups[group][key] = value
+		}
+	}
+	return groups
+}
+
+// pngIcon encodes a blank square icon
+func pngIcon(t *testing.T, size int) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, size, size))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// guiArtifact is an application whose desktop entry and icon wand installs
+func guiArtifact(t *testing.T) []byte {
+	t.Helper()
+	return buildTarGz(t, map[string]string{
+		"opt/hello/hello": helloScript,
+		"share/hello.png": pngIcon(t, 48),
+		"share/hello.desktop": "[Desktop Entry]\nType=Application\nName=Hello\nTryExec=hello\n" +
+			"Exec=/opt/hello/hello --new-window %U\nIcon=/opt/hello/icon.png\nActions=private;\n\n" +
+			"# Launched from the menu\n[Desktop Action private]\nName=Private Window\nExec=/opt/hello/hello --private %U\n",
+	})
+}
+
+// guiPlatformExtra points the formula at guiArtifact's desktop entry and icon
+const guiPlatformExtra = "      desktop_file: share/hello.desktop\n      icon_file: share/hello.png\n"
+
+// TestGUIDesktopIntegration tests that a Linux application's desktop entry is
+// rewritten to run its launcher shim, its icon is installed into the hicolor
+// theme, and uninstalling removes both
+func TestGUIDesktopIntegration(t *testing.T) {
+	f := newGUIFixture(t, false, guiPlatformExtra)
+	f.artifact = guiArtifact(t)
+	if err := f.installer.InstallPackage("hello", "1.2.0"); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+
+	home := f.home
+	executable := filepath.Join(f.wandDir, "apps", "hello", "1.2.0", "opt", "hello", "hello")
+	launcher := filepath.Join(f.wandDir, "shims", "hello")
+	desktopPath := filepath.Join(home, ".local", "share", "applications", "hello.desktop")
+	iconPath := filepath.Join(home, ".local", "share", "icons", "hicolor", "48x48", "apps", "hello.png")
+
+	entry := readDesktopEntry(t, desktopPath)
+	want := map[string]map[string]string{
+		"Desktop Entry": {
+			"Type":    "Application",
+			"Name":    "Hello",
+			"TryExec": launcher,
+			"Exec":    launcher + " --new-window %U",
+			"Icon":    "hello",
+			"Actions": "private;",
+		},
+		"Desktop Action private": {
+			"Name": "Private Window",
+			"Exec": launcher + " --private %U",
+		},
+	}
+	for group, keys := range want {
+		for key, value := range keys {
+			if entry[group][key] != value {
+				t.Errorf("[%s] %s = %q, want %q", group, key, entry[group][key], value)
+			}
+		}
+	}
+	if _, err := os.Stat(iconPath); err != nil {
+		t.Errorf("icon not installed in the hicolor theme: %v", err)
+	}
+
+	// The executable gets a launcher shim, which runs the recorded path
+	fs := domainadapters.NewFileSystemAdapter()
+	shims := services.NewShimService(domainadapters.NewRegistryRepository(fs, f.wandDir), domainadapters.NewWandRCRepository(fs),
+		domainadapters.NewFormulaRepository(fs, filepath.Join(f.wandDir, "formulas")), fs, f.wandDir)
+	if path, err := shims.GetBinaryPath("hello", "1.2.0", "hello"); err != nil || path != executable {
+		t.Errorf("launcher runs %q, %v; want %s", path, err, executable)
+	}
+	if providers, err := shims.BinaryProviders(); err != nil || len(providers["hello"]) != 1 {
+		t.Errorf("expected the application to provide the hello launcher, got %v, %v", providers, err)
+	}
+
+	if err := f.installer.UninstallPackage("hello", ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{desktopPath, iconPath, filepath.Join(f.wandDir, "apps", "hello")} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed on uninstall", path)
+		}
+	}
+}
+
+// TestGUIUninstallOneVersion tests that the desktop entry and icon, which all
+// versions share, stay until the last version is uninstalled
+func TestGUIUninstallOneVersion(t *testing.T) {
+	f := newGUIFixture(t, false, guiPlatformExtra)
+	f.artifact = guiArtifact(t)
+	for _, version := range []string{"1.2.0", "1.3.0"} {
+		if err := f.installer.InstallPackage("hello", version); err != nil {
+			t.Fatalf("install of %s failed: %v", version, err)
+		}
+	}
+
+	home := f.home
+	desktopPath := filepath.Join(home, ".local", "share", "applications", "hello.desktop")
+	iconPath := filepath.Join(home, ".local", "share", "icons", "hicolor", "48x48", "apps", "hello.png")
+
+	if err := f.installer.UninstallPackage("hello", "1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{desktopPath, iconPath, filepath.Join(f.wandDir, "apps", "hello", "1.3.0", "opt", "hello", "hello")} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s to stay while 1.3.0 is installed: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(f.wandDir, "apps", "hello", "1.2.0")); !os.IsNotExist(err) {
+		t.Errorf("expected 1.2.0 to be removed, got %v", err)
+	}
+
+	if err := f.installer.UninstallPackage("hello", "1.3.0"); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{desktopPath, iconPath, filepath.Join(f.wandDir, "apps", "hello")} {
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed with the last version", path)
+		}
+	}
+}
+
+// TestGUIAppImage tests that an AppImage is installed as the application
+// itself, with a generated desktop entry
+func TestGUIAppImage(t *testing.T) {
+	f := newGUIFixture(t, true, "")
+	f.artifact = append([]byte("\x7fELF\x02\x01\x01\x00AI\x02"), make([]byte, 64)...)
+	if err := f.installer.InstallPackage("hello", "1.2.0"); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+
+	appImage := filepath.Join(f.wandDir, "apps", "hello", "1.2.0", "hello.AppImage")
+	launcher := filepath.Join(f.wandDir, "shims", "hello")
+	info, err := os.Stat(appImage)
+	if err != nil || info.Mode().Perm()&0100 == 0 {
+		t.Fatalf("expected an executable AppImage at %s, got %v", appImage, err)
+	}
+
+	entry := readDesktopEntry(t, filepath.Join(f.home, ".local", "share", "applications", "hello.desktop"))
+	want := map[string]string{
+		"Type":     "Application",
+		"Name":     "hello",
+		"Comment":  "Test tool",
+		"Exec":     launcher + " %U",
+		"TryExec":  launcher,
+		"Terminal": "false",
+	}
+	for key, value := range want {
+		if entry["Desktop Entry"][key] != value {
+			t.Errorf("%s = %q, want %q", key, entry["Desktop Entry"][key], value)
+		}
+	}
+	if _, ok := entry["Desktop Entry"]["Icon"]; ok {
+		t.Error("expected no Icon key without an icon_file")
+	}
+}
+
+// TestGUIInvalidDesktopEntry tests that a desktop entry desktop environments
+// would ignore fails the install, naming the problem, and leaves nothing behind
+func TestGUIInvalidDesktopEntry(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+		want  string
+	}{
+		{"no name", "[Desktop Entry]\nType=Application\nExec=hello\n", "has no Name key"},
+		{"wrong first group", "[Other]\nA=b\n[Desktop Entry]\nType=Application\nName=Hello\nExec=hello\n", "first group must be [Desktop Entry]"},
+		{"duplicate key", "[Desktop Entry]\nType=Application\nName=Hello\nName=Again\nExec=hello\n", "duplicate key Name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newGUIFixture(t, true, "      desktop_file: hello.desktop\n")
+			f.artifact = buildTarGz(t, map[string]string{"bin/hello": helloScript, "hello.desktop": tt.entry})
+			requireErrorCode(t, f.installer.InstallPackage("hello", "1.2.0"), errs.ErrInstallationFailed, tt.want)
+			if _, err := os.Stat(filepath.Join(f.wandDir, "apps", "hello")); !os.IsNotExist(err) {
+				t.Errorf("expected the failed install to be removed, got %v", err)
+			}
+		})
+	}
+}
@@ -164,6 +164,8 @@ func TestGUIServiceIntegration(t *testing.T) {
 
 	fs := domainadapters.NewFileSystemAdapter()
 	registryRepo := domainadapters.NewRegistryRepository(fs, wandDir)
+	lockRepo := domainadapters.NewChecksumLockRepository(fs, wandDir)
+	approvalRepo := domainadapters.NewHookApprovalRepository(fs, wandDir)
 
 	t.Run("CheckGUIAppsInWandfile", func(t *testing.T) {
 		// Create wandfile with GUI apps
@@ -184,6 +186,7 @@ func TestGUIServiceIntegration(t *testing.T) {
 		wandfileRepo := domainadapters.NewWandfileRepository(fs)
 		dotfileRepo := domainadapters.NewDotfileRepository(fs, wandDir)
 		downloader := domainadapters.NewDownloaderAdapter()
+		verifier := domainadapters.NewVerifierAdapter()
 		extractor := domainadapters.NewExtractorAdapter(fs)
 		shellExecutor := domainadapters.NewShellExecutorAdapter()
 
@@ -192,7 +195,10 @@ func TestGUIServiceIntegration(t *testing.T) {
 		installerSvc := services.NewInstallerService(
 			formulaRepo,
 			registryRepo,
+			lockRepo,
+			approvalRepo,
 			downloader,
+			verifier,
 			extractor,
 			fs,
 			shellExecutor,
@@ -0,0 +1,180 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
+	"github.com/ochairo/wand/internal/domain/entities"
+	errs "github.com/ochairo/wand/internal/domain/errors"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// cancelFixture installs "hello" from a server whose behaviour each test chooses
+type cancelFixture struct {
+	installer *services.InstallerService
+	wandDir   string
+}
+
+// newCancelFixture writes a formula for "hello" downloading from handler, with
+// the given extra formula lines, and returns an installer using downloader
+func newCancelFixture(t *testing.T, handler http.HandlerFunc, downloader interfaces.Downloader, extra string) *cancelFixture {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	ti := newTestInstaller(t, map[string]string{"hello": helloFormula(server.URL+"/hello.tar.gz", extra)}, downloader)
+	return &cancelFixture{installer: ti.installer, wandDir: ti.wandDir}
+}
+
+// requireCleanedUp fails if a download or the install directory was left behind
+func (f *cancelFixture) requireCleanedUp(t *testing.T) {
+	t.Helper()
+	if entries, _ := os.ReadDir(filepath.Join(f.wandDir, "tmp")); len(entries) != 0 {
+		t.Errorf("expected no staged files, found %v", entries)
+	}
+	if _, err := os.Stat(filepath.Join(f.wandDir, "packages", "hello", "1.2.0")); !os.IsNotExist(err) {
+		t.Errorf("expected the partial install to be removed, got %v", err)
+	}
+}
+
+// stallingHandler sends the start of a large body and then stops sending
+// until the client gives up, signalling started once data was sent
+func stallingHandler(started chan<- struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1048576")
+		_, _ = w.Write(make([]byte, 1024))
+		w.(http.Flusher).Flush()
+		if started != nil {
+			select {
+			case started <- struct{}{}:
+			default:
+			}
+		}
+		<-r.Context().Done()
+	}
+}
+
+// TestInstallCancelledDuringDownload tests that cancelling the context stops a
+// download and removes the staged files
+func TestInstallCancelledDuringDownload(t *testing.T) {
+	started := make(chan struct{}, 1)
+	f := newCancelFixture(t, stallingHandler(started), domainadapters.NewDownloaderAdapter(), "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	start := time.Now()
+	err := f.installer.InstallPackageContext(ctx, "hello", "1.2.0", services.InstallOptions{})
+	requireErrorCode(t, err, errs.ErrCancelled, "was cancelled")
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected cancellation to stop the download promptly, took %s", elapsed)
+	}
+	f.requireCleanedUp(t)
+}
+
+// TestWandfileInstallCancelled tests that cancelling a wandfile install stops
+// the package being installed instead of failing it as a broken package
+func TestWandfileInstallCancelled(t *testing.T) {
+	started := make(chan struct{}, 1)
+	f := newCancelFixture(t, stallingHandler(started), domainadapters.NewDownloaderAdapter(), "")
+	wandfileSvc := services.NewWandfileService(nil, nil, f.installer, nil, nil, nil, nil, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	wandfile := &entities.Wandfile{CLI: []entities.WandfileCLI{{Name: "hello", Version: "1.2.0"}}}
+	requireErrorCode(t, wandfileSvc.InstallContext(ctx, wandfile), errs.ErrCancelled, "was cancelled")
+	f.requireCleanedUp(t)
+}
+
+// TestInstallDownloadTimeout tests the per-download limit
+func TestInstallDownloadTimeout(t *testing.T) {
+	f := newCancelFixture(t, stallingHandler(nil), domainadapters.NewDownloaderAdapter(), "")
+
+	err := f.installer.InstallPackageWithOptions("hello", "1.2.0", services.InstallOptions{DownloadTimeout: 200 * time.Millisecond})
+	requireErrorCode(t, err, errs.ErrTimeout, "timed out")
+	f.requireCleanedUp(t)
+}
+
+// TestStalledDownloadIsRetried tests that a download receiving no data is
+// aborted and retried instead of hanging
+func TestStalledDownloadIsRetried(t *testing.T) {
+	artifact := buildTarGz(t, map[string]string{"bin/hello": "#!/bin/sh\necho hello\n"})
+	var requests atomic.Int32
+	stall := stallingHandler(nil)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			stall(w, r)
+			return
+		}
+		_, _ = w.Write(artifact)
+	}
+	downloader := domainadapters.NewDownloaderAdapterWithOptions(domainadapters.DownloaderOptions{StallTimeout: 200 * time.Millisecond, RetryDelay: time.Millisecond})
+	f := newCancelFixture(t, handler, downloader, "")
+
+	if err := f.installer.InstallPackage("hello", "1.2.0"); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("expected the stalled request to be retried once, got %d requests", n)
+	}
+	if _, err := os.Stat(filepath.Join(f.wandDir, "packages", "hello", "1.2.0", "bin", "hello")); err != nil {
+		t.Errorf("expected installed binary: %v", err)
+	}
+}
+
+// TestInstallCancelledDuringHook tests that cancelling the context kills a
+// running post-install command and removes the half-installed version
+func TestInstallCancelledDuringHook(t *testing.T) {
+	artifact := buildTarGz(t, map[string]string{"bin/hello": "#!/bin/sh\necho hello\n"})
+	f := newCancelFixture(t,
+		func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write(artifact) },
+		domainadapters.NewDownloaderAdapter(),
+		"post_install:\n  commands:\n    - sleep 30\n",
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := services.InstallOptions{
+		Progress: func(event services.InstallEvent) {
+			if event.Stage == services.StageHooks {
+				time.AfterFunc(100*time.Millisecond, cancel)
+			}
+		},
+	}
+
+	start := time.Now()
+	err := f.installer.InstallPackageContext(ctx, "hello", "1.2.0", opts)
+	requireErrorCode(t, err, errs.ErrCancelled, "hello")
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the command to be killed promptly, took %s", elapsed)
+	}
+	f.requireCleanedUp(t)
+
+	logs, _ := filepath.Glob(filepath.Join(f.wandDir, "logs", "hello-1.2.0-*.log"))
+	if len(logs) != 1 {
+		t.Fatalf("expected one hook log, got %v", logs)
+	}
+	data, _ := os.ReadFile(logs[0]) //nolint:gosec
+	if !strings.Contains(string(data), "cancelled") {
+		t.Errorf("expected the log to record the cancellation:\n%s", data)
+	}
+}
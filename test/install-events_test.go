@@ -0,0 +1,90 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// recordEvents returns an observer that appends to events
+func recordEvents(events *[]services.InstallEvent) services.InstallObserver {
+	return func(event services.InstallEvent) {
+		*events = append(*events, event)
+	}
+}
+
+// stages returns the distinct stages in order, collapsing repeats
+func stages(events []services.InstallEvent) []services.InstallStage {
+	var result []services.InstallStage
+	for _, event := range events {
+		if len(result) == 0 || result[len(result)-1] != event.Stage {
+			result = append(result, event.Stage)
+		}
+	}
+	return result
+}
+
+// TestInstallEvents tests the event stream of a successful install
+func TestInstallEvents(t *testing.T) {
+	f := newTrustFixture(t, "post_install:\n  commands:\n    - true\n")
+	artifact := buildTarGz(t, map[string]string{"bin/hello": "#!/bin/sh\necho hello\n"})
+	f.setArtifact(artifact)
+
+	var events []services.InstallEvent
+	if err := f.installer.InstallPackageWithOptions("hello", "1.2.0", services.InstallOptions{Progress: recordEvents(&events)}); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+
+	want := []services.InstallStage{
+		services.StageResolving,
+		services.StageDownloading,
+		services.StageVerifying,
+		services.StageExtracting,
+		services.StageHooks,
+		services.StageDone,
+	}
+	got := stages(events)
+	if len(got) != len(want) {
+		t.Fatalf("stages = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("stages = %v, want %v", got, want)
+		}
+	}
+
+	var last services.InstallEvent
+	for _, event := range events {
+		if event.Package != "hello" {
+			t.Errorf("unexpected package in %+v", event)
+		}
+		if event.Stage == services.StageDownloading {
+			last = event
+		}
+	}
+	if last.Total != int64(len(artifact)) || last.Bytes != last.Total {
+		t.Errorf("final download event = %d/%d bytes, want %d", last.Bytes, last.Total, len(artifact))
+	}
+	if done := events[len(events)-1]; done.Version != "1.2.0" {
+		t.Errorf("done event version = %q, want resolved version", done.Version)
+	}
+}
+
+// TestInstallEventsReportFailure tests that a failed install ends with a failed event
+func TestInstallEventsReportFailure(t *testing.T) {
+	f := newTrustFixture(t, "")
+	if err := f.installer.InstallPackage("hello", "1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []services.InstallEvent
+	err := f.installer.InstallPackageWithOptions("hello", "1.2.0", services.InstallOptions{Progress: recordEvents(&events)})
+	if err == nil {
+		t.Fatal("expected reinstall to fail")
+	}
+
+	last := events[len(events)-1]
+	if last.Stage != services.StageFailed || last.Err == nil {
+		t.Errorf("last event = %+v, want failed with error", last)
+	}
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	errs "github.com/ochairo/wand/internal/domain/errors"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// hookFormula builds from the fixture archive, which unpacks to a single bin
+// directory, so the build commands run inside it
+const hookFormula = `      requires_build: true
+      build_commands:
+        - mkdir -p {prefix}/bin && cp hello {prefix}/bin/
+        - echo "$WAND_PACKAGE $WAND_VERSION" > "$WAND_PREFIX/built.txt"
+post_install:
+  env:
+    GREETING: hi
+  commands:
+    - hello > "$WAND_PREFIX/ran.txt" && echo "$GREETING" >> "$WAND_PREFIX/ran.txt"
+    - test -z "$WAND_TEST_SECRET"
+`
+
+// TestInstallHooksRunWithControlledEnvironment tests that hooks run through sh -c with the documented environment
+func TestInstallHooksRunWithControlledEnvironment(t *testing.T) {
+	t.Setenv("WAND_TEST_SECRET", "leaked")
+	f := newTrustFixture(t, hookFormula)
+
+	if err := f.installer.InstallPackage("hello", "1.2.0"); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+
+	installDir := filepath.Join(f.wandDir, "packages", "hello", "1.2.0")
+	built, err := os.ReadFile(filepath.Join(installDir, "built.txt")) //nolint:gosec
+	if err != nil || strings.TrimSpace(string(built)) != "hello 1.2.0" {
+		t.Errorf("build command output = %q, %v", built, err)
+	}
+	ran, err := os.ReadFile(filepath.Join(installDir, "ran.txt")) //nolint:gosec
+	if err != nil || string(ran) != "hello\nhi\n" {
+		t.Errorf("post-install output = %q, %v", ran, err)
+	}
+
+	logs, _ := filepath.Glob(filepath.Join(f.wandDir, "logs", "hello-1.2.0-*.log"))
+	if len(logs) != 1 {
+		t.Fatalf("expected one install log, got %v", logs)
+	}
+	log, _ := os.ReadFile(logs[0]) //nolint:gosec
+	for _, want := range []string{"## build: echo", "## post_install: hello", "# WAND_PREFIX=" + installDir} {
+		if !strings.Contains(string(log), want) {
+			t.Errorf("log missing %q:\n%s", want, log)
+		}
+	}
+}
+
+// TestInstallHooksPolicy tests the deny and confirm hook policies
+func TestInstallHooksPolicy(t *testing.T) {
+	t.Run("deny", func(t *testing.T) {
+		f := newTrustFixture(t, hookFormula)
+		err := f.installer.InstallPackageWithOptions("hello", "1.2.0", services.InstallOptions{HookPolicy: services.HookPolicyDeny})
+		requireErrorCode(t, err, errs.ErrHookDenied, "build: echo")
+
+		if _, statErr := os.Stat(filepath.Join(f.wandDir, "packages", "hello")); !os.IsNotExist(statErr) {
+			t.Error("nothing should be installed when hooks are denied")
+		}
+	})
+
+	t.Run("confirm", func(t *testing.T) {
+		f := newTrustFixture(t, hookFormula)
+
+		declined := services.InstallOptions{
+			HookPolicy:   services.HookPolicyConfirm,
+			ConfirmHooks: func(plan *services.HookPlan) bool { return false },
+		}
+		err := f.installer.InstallPackageWithOptions("hello", "1.2.0", declined)
+		requireErrorCode(t, err, errs.ErrHookDenied, "declined")
+
+		var shown []string
+		approved := services.InstallOptions{
+			HookPolicy: services.HookPolicyConfirm,
+			ConfirmHooks: func(plan *services.HookPlan) bool {
+				shown = plan.Commands()
+				return true
+			},
+		}
+		if err := f.installer.InstallPackageWithOptions("hello", "1.2.0", approved); err != nil {
+			t.Fatalf("approved install failed: %v", err)
+		}
+		if len(shown) != 4 || !strings.HasPrefix(shown[0], "build: ") || !strings.HasPrefix(shown[3], "post_install: test -z") {
+			t.Errorf("unexpected commands shown: %v", shown)
+		}
+
+		// The same commands are remembered and not asked about again
+		if err := f.installer.UninstallPackage("hello", "1.2.0"); err != nil {
+			t.Fatal(err)
+		}
+		remembered := services.InstallOptions{HookPolicy: services.HookPolicyConfirm}
+		if err := f.installer.InstallPackageWithOptions("hello", "1.2.0", remembered); err != nil {
+			t.Fatalf("reinstall with approved commands failed: %v", err)
+		}
+	})
+}
+
+// TestInstallHooksTimeout tests that a hanging hook is killed and logged
+func TestInstallHooksTimeout(t *testing.T) {
+	f := newTrustFixture(t, "post_install:\n  commands:\n    - sleep 30\n")
+
+	start := time.Now()
+	err := f.installer.InstallPackageWithOptions("hello", "1.2.0", services.InstallOptions{HookTimeout: 200 * time.Millisecond})
+	requireErrorCode(t, err, errs.ErrInstallationFailed, "timed out")
+
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("hook was not killed promptly (%s)", elapsed)
+	}
+	if !strings.Contains(err.Error(), filepath.Join(f.wandDir, "logs")) {
+		t.Errorf("expected error to point at the install log, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// testInstaller is an installer over an isolated home and wand directory,
+// whose GitHub releases tests may replace
+type testInstaller struct {
+	installer *services.InstallerService
+	lockRepo  interfaces.ChecksumLockRepository
+	github    *fakeGitHubClient
+	home      string
+	wandDir   string
+}
+
+// newTestInstaller writes formulas, keyed by package name, into a fresh wand
+// directory and returns an installer for them. A nil downloader downloads
+// over HTTP. Every package has the release v1.2.0.
+func newTestInstaller(t *testing.T, formulas map[string]string, downloader interfaces.Downloader) *testInstaller {
+	t.Helper()
+
+	home := t.TempDir()
+	ti := &testInstaller{
+		github:  &fakeGitHubClient{release: newRelease("example", "hello", "v1.2.0")},
+		home:    home,
+		wandDir: filepath.Join(home, ".wand"),
+	}
+	formulasDir := filepath.Join(ti.wandDir, "formulas")
+	if err := os.MkdirAll(formulasDir, 0755); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	for name, body := range formulas {
+		if err := os.WriteFile(filepath.Join(formulasDir, name+".yaml"), []byte(body), 0644); err != nil { //nolint:gosec
+			t.Fatal(err)
+		}
+	}
+
+	if downloader == nil {
+		downloader = domainadapters.NewDownloaderAdapter()
+	}
+	fs := domainadapters.NewFileSystemAdapter()
+	formulaRepo := domainadapters.NewFormulaRepository(fs, formulasDir)
+	ti.lockRepo = domainadapters.NewChecksumLockRepository(fs, ti.wandDir)
+	ti.installer = services.NewInstallerService(
+		formulaRepo,
+		domainadapters.NewRegistryRepository(fs, ti.wandDir),
+		ti.lockRepo,
+		domainadapters.NewHookApprovalRepository(fs, ti.wandDir),
+		downloader,
+		domainadapters.NewVerifierAdapter(),
+		domainadapters.NewExtractorAdapter(fs),
+		fs,
+		domainadapters.NewShellExecutorAdapter(),
+		services.NewVersionService(ti.github, formulaRepo),
+		ti.wandDir,
+		home,
+	)
+	return ti
+}
+
+// helloFormula returns a formula for the "hello" CLI downloading from
+// downloadURL on the current platform, with extra platform lines
+func helloFormula(downloadURL, platformExtra string) string {
+	platform := entities.CurrentPlatform()
+	return "name: hello\ntype: cli\ndescription: Test tool\nhomepage: https://example.com\nrepository: example/hello\n" +
+		"binaries:\n  - hello\nplatforms:\n  " + platform.OS + ":\n    " + platform.Arch + ":\n" +
+		"      download_url: " + downloadURL + "\n" + platformExtra
+}
@@ -0,0 +1,151 @@
+package test
+
+import (
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
+	"github.com/ochairo/wand/internal/domain/entities"
+)
+
+// newNetworkDownloader returns a downloader using a transport built from network
+func newNetworkDownloader(t *testing.T, network *entities.NetworkConfig) func(url string) error {
+	t.Helper()
+	transport, err := domainadapters.NewHTTPTransport(network, domainadapters.DefaultConnectTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloader := domainadapters.NewDownloaderAdapterWithOptions(domainadapters.DownloaderOptions{Transport: transport})
+	dest := filepath.Join(t.TempDir(), "artifact")
+	return func(url string) error { return downloader.Download(url, dest) }
+}
+
+// TestDownloadWithCAFileAndHostCredential tests that a private CA is trusted
+// and a host credential is sent to that host only
+func TestDownloadWithCAFileAndHostCredential(t *testing.T) {
+	var mu sync.Mutex
+	auth := map[string]string{}
+	record := func(name string, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		auth[name] = r.Header.Get("Authorization")
+	}
+
+	storage := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record("storage", r)
+		_, _ = w.Write([]byte("artifact"))
+	}))
+	defer storage.Close()
+	private := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		record("private", r)
+		http.Redirect(w, r, storage.URL+"/blob", http.StatusFound)
+	}))
+	private.Config.ErrorLog = log.New(io.Discard, "", 0) // the untrusted attempt logs a handshake error
+	private.StartTLS()
+	defer private.Close()
+
+	// Both servers use the same httptest certificate
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: private.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	if err := newNetworkDownloader(t, nil)(private.URL + "/tool"); err == nil || !strings.Contains(err.Error(), "certificate") {
+		t.Fatalf("expected an untrusted certificate error without the CA file, got %v", err)
+	}
+
+	network := &entities.NetworkConfig{
+		CAFile: caFile,
+		Hosts:  map[string]*entities.HostCredential{strings.TrimPrefix(private.URL, "https://"): {Token: "secret"}},
+	}
+	if err := newNetworkDownloader(t, network)(private.URL + "/tool"); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+
+	if auth["private"] != "Bearer secret" {
+		t.Errorf("expected the credential to be sent to its host, got %q", auth["private"])
+	}
+	if auth["storage"] != "" {
+		t.Errorf("expected the credential not to follow the redirect, got %q", auth["storage"])
+	}
+}
+
+// TestDownloadThroughProxy tests that downloads use the configured proxy and
+// that credentials are never sent over plain HTTP
+func TestDownloadThroughProxy(t *testing.T) {
+	var requested, authorization string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+		authorization = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte("via proxy"))
+	}))
+	defer proxy.Close()
+
+	network := &entities.NetworkConfig{
+		Proxy: proxy.URL,
+		Hosts: map[string]*entities.HostCredential{"files.example.com": {Username: "me", Password: "pw"}},
+	}
+	if err := newNetworkDownloader(t, network)("http://files.example.com/tool.tar.gz"); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+
+	if requested != "http://files.example.com/tool.tar.gz" {
+		t.Errorf("expected the proxy to receive the request, got %q", requested)
+	}
+	if authorization != "" {
+		t.Errorf("expected no credential over plain HTTP, got %q", authorization)
+	}
+}
+
+// TestLoadNetworkConfig tests the credentials file and environment overrides
+func TestLoadNetworkConfig(t *testing.T) {
+	for _, name := range []string{"WAND_PROXY", "WAND_CA_FILE", "WAND_GITHUB_TOKEN", "GITHUB_TOKEN", "GH_TOKEN"} {
+		t.Setenv(name, "")
+	}
+
+	wandDir := t.TempDir()
+	path := filepath.Join(wandDir, "credentials.yaml")
+	content := "github_token: from-file\nhosts:\n  Files.Example.com:\n    username: me\n    password: pw\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	fs := domainadapters.NewFileSystemAdapter()
+
+	if _, err := domainadapters.LoadNetworkConfig(fs, wandDir); err == nil || !strings.Contains(err.Error(), "chmod 600") {
+		t.Fatalf("expected a readable credentials file to be refused, got %v", err)
+	}
+
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	network, err := domainadapters.LoadNetworkConfig(fs, wandDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if network.GitHubToken != "from-file" {
+		t.Errorf("expected the file token, got %q", network.GitHubToken)
+	}
+	if credential, ok := network.CredentialFor("files.example.com"); !ok || credential.Username != "me" {
+		t.Errorf("expected the host credential, got %+v", credential)
+	}
+
+	t.Setenv("GH_TOKEN", "from-gh")
+	t.Setenv("WAND_GITHUB_TOKEN", "from-wand")
+	t.Setenv("WAND_PROXY", "http://proxy.example.com:3128")
+	network, err = domainadapters.LoadNetworkConfig(fs, wandDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if network.GitHubToken != "from-wand" || network.Proxy != "http://proxy.example.com:3128" {
+		t.Errorf("expected environment overrides, got token %q, proxy %q", network.GitHubToken, network.Proxy)
+	}
+}
@@ -0,0 +1,73 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// TestFormulaRuntimeEnv tests that the env service applies the runtime
+// environment a formula declares for the active version
+func TestFormulaRuntimeEnv(t *testing.T) {
+	wandDir, formulasDir := t.TempDir(), t.TempDir()
+	formula := `name: tool
+type: cli
+description: Test tool
+homepage: https://example.com
+repository: example/tool
+binaries: [tool]
+env:
+  TOOL_HOME: "{install_path}"
+  TOOL_GREETING: "it's here"
+path: [libexec/bin]
+`
+	if err := os.WriteFile(filepath.Join(formulasDir, "tool.yaml"), []byte(formula), 0644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	installPath := filepath.Join(wandDir, "packages", "tool", "1.0.0")
+
+	fs := domainadapters.NewFileSystemAdapter()
+	registryRepo := domainadapters.NewRegistryRepository(fs, wandDir)
+	registry := entities.NewRegistry()
+	version, _ := entities.NewVersion("1.0.0")
+	pkg := entities.NewPackage("tool", entities.PackageTypeCLI, version)
+	pkg.InstallPath = installPath
+	pkg.BinPath = filepath.Join(installPath, "bin")
+	registry.AddPackage(pkg)
+	registry.SetGlobalVersion("tool", "1.0.0")
+	if err := registryRepo.Save(registry); err != nil {
+		t.Fatal(err)
+	}
+
+	formulaRepo := domainadapters.NewFormulaRepository(fs, formulasDir)
+	wandrcRepo := domainadapters.NewWandRCRepository(fs)
+	shimService := services.NewShimService(registryRepo, wandrcRepo, formulaRepo, fs, wandDir)
+
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, ".wandrc"), []byte("versions:\n  tool: \"^1\"\n"), 0644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	env, err := services.NewEnvService(registryRepo, wandrcRepo, formulaRepo, shimService).Environment(projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Vars["TOOL_HOME"] != installPath || env.Vars["TOOL_GREETING"] != "it's here" || env.Vars["WAND_VERSIONS"] != "tool@1.0.0" {
+		t.Errorf("expected the project's environment, got %+v", env)
+	}
+
+	env, err = services.NewEnvService(registryRepo, wandrcRepo, formulaRepo, shimService).Environment(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.Vars["TOOL_HOME"] != installPath || env.Project != "" {
+		t.Errorf("expected the global version's environment, got %+v", env)
+	}
+	if len(env.Path) != 1 || env.Path[0] != filepath.Join(installPath, "libexec", "bin") {
+		t.Errorf("expected the formula's PATH directory, got %v", env.Path)
+	}
+}
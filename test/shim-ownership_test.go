@@ -0,0 +1,188 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// TestShimOwnership tests that a binary two packages ship keeps one owner,
+// passes to the other package when its owner is uninstalled, and that
+// repair brings the shims back in line with the installed packages
+func TestShimOwnership(t *testing.T) {
+	wandDir, formulasDir := t.TempDir(), t.TempDir()
+	for name, binaries := range map[string]string{"findutils": "[find, xargs]", "coreutils": "[find, ls]"} {
+		formula := "name: " + name + "\ntype: cli\ndescription: Test\nhomepage: https://example.com\nrepository: example/" + name + "\nbinaries: " + binaries + "\n"
+		if err := os.WriteFile(filepath.Join(formulasDir, name+".yaml"), []byte(formula), 0644); err != nil { //nolint:gosec
+			t.Fatal(err)
+		}
+	}
+
+	fs := domainadapters.NewFileSystemAdapter()
+	registryRepo := domainadapters.NewRegistryRepository(fs, wandDir)
+	registry := entities.NewRegistry()
+	for _, name := range []string{"findutils", "coreutils"} {
+		version, _ := entities.NewVersion("1.0.0")
+		registry.AddPackage(entities.NewPackage(name, entities.PackageTypeCLI, version))
+	}
+	if err := registryRepo.Save(registry); err != nil {
+		t.Fatal(err)
+	}
+
+	shimService := services.NewShimServiceWithOptions(registryRepo, domainadapters.NewWandRCRepository(fs), domainadapters.NewFormulaRepository(fs, formulasDir), fs, wandDir, services.ShimServiceOptions{
+		ShimIndex: domainadapters.NewShimIndexRepository(fs, wandDir),
+	})
+	shimPath := func(binary string) string { return filepath.Join(wandDir, "shims", binary) }
+	runs := func(binary string) string {
+		data, err := os.ReadFile(shimPath(binary)) //nolint:gosec
+		if err != nil {
+			return ""
+		}
+		content := string(data)
+		start := strings.Index(content, "--package '") + len("--package '")
+		return content[start : start+strings.Index(content[start:], "'")]
+	}
+
+	if err := shimService.CreateShims("findutils", []string{"find", "xargs"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Installing coreutils meets findutils' find shim
+	conflicts, err := shimService.ShimConflicts("coreutils", []string{"find", "ls"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || conflicts[0].Binary != "find" || conflicts[0].Owner != "findutils" {
+		t.Fatalf("expected find to conflict with findutils, got %+v", conflicts)
+	}
+	if err := shimService.CreateShims("coreutils", []string{"ls"}); err != nil {
+		t.Fatal(err)
+	}
+	if owner, _ := shimService.PackageForBinary("find"); owner != "findutils" || runs("find") != "findutils" {
+		t.Errorf("expected findutils to keep find, got %s running %s", owner, runs("find"))
+	}
+
+	// Uninstalling findutils hands find to coreutils and removes xargs
+	registry.RemovePackage("findutils", "1.0.0")
+	if err := registryRepo.Save(registry); err != nil {
+		t.Fatal(err)
+	}
+	if err := shimService.RemoveShims("findutils", []string{"find", "xargs"}); err != nil {
+		t.Fatal(err)
+	}
+	if runs("find") != "coreutils" {
+		t.Errorf("expected find to pass to coreutils, it runs %q", runs("find"))
+	}
+	if _, err := os.Stat(shimPath("xargs")); !os.IsNotExist(err) {
+		t.Error("expected the xargs shim to be removed")
+	}
+	if err := shimService.RemoveShims("findutils", []string{"ls"}); err != nil || runs("ls") != "coreutils" {
+		t.Errorf("expected another package's shim to be left alone, ls runs %q (%v)", runs("ls"), err)
+	}
+
+	// Repair recreates what is missing and removes what nothing provides
+	if err := os.Remove(shimPath("ls")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(shimPath("stale"), []byte("#!/bin/sh\n"), 0755); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	changes, err := shimService.RepairShims()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	for _, change := range changes {
+		got[change.Binary] = change.Note
+	}
+	if len(got) != 2 || got["ls"] != "created" || !strings.HasPrefix(got["stale"], "removed") {
+		t.Errorf("expected ls to be created and stale removed, got %+v", changes)
+	}
+	if runs("ls") != "coreutils" {
+		t.Errorf("expected the repaired ls shim to run coreutils, it runs %q", runs("ls"))
+	}
+	if changes, _ := shimService.RepairShims(); len(changes) != 0 {
+		t.Errorf("expected nothing left to repair, got %+v", changes)
+	}
+}
+
+// TestStaleShims tests that shims written for another wand directory, or
+// from an older template, are reported outdated until they are recreated
+func TestStaleShims(t *testing.T) {
+	oldDir, formulasDir := filepath.Join(t.TempDir(), "wand"), t.TempDir()
+	formula := "name: tool\ntype: cli\ndescription: Test\nhomepage: https://example.com\nrepository: example/tool\nbinaries: [tool]\n"
+	if err := os.WriteFile(filepath.Join(formulasDir, "tool.yaml"), []byte(formula), 0644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	fs := domainadapters.NewFileSystemAdapter()
+	newService := func(wandDir string) *services.ShimService {
+		return services.NewShimServiceWithOptions(domainadapters.NewRegistryRepository(fs, wandDir), domainadapters.NewWandRCRepository(fs), domainadapters.NewFormulaRepository(fs, formulasDir), fs, wandDir, services.ShimServiceOptions{
+			ShimIndex: domainadapters.NewShimIndexRepository(fs, wandDir),
+		})
+	}
+	outdated := func(shimService *services.ShimService) []string {
+		shims, err := shimService.ListShims()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, shim := range shims {
+			if shim.Outdated {
+				names = append(names, shim.Binary)
+			}
+		}
+		return names
+	}
+
+	registry := entities.NewRegistry()
+	version, _ := entities.NewVersion("1.0.0")
+	registry.AddPackage(entities.NewPackage("tool", entities.PackageTypeCLI, version))
+	if err := os.MkdirAll(oldDir, 0755); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	if err := domainadapters.NewRegistryRepository(fs, oldDir).Save(registry); err != nil {
+		t.Fatal(err)
+	}
+	if err := newService(oldDir).CreateShims("tool", []string{"tool"}); err != nil {
+		t.Fatal(err)
+	}
+	if names := outdated(newService(oldDir)); len(names) != 0 {
+		t.Fatalf("expected fresh shims to be current, got %v outdated", names)
+	}
+
+	// Moving the wand directory leaves shims pointing at the old one
+	newDir := filepath.Join(t.TempDir(), "wand")
+	if err := os.Rename(oldDir, newDir); err != nil {
+		t.Fatal(err)
+	}
+	moved := newService(newDir)
+	if names := outdated(moved); len(names) != 1 || names[0] != "tool" {
+		t.Errorf("expected the tool shim to be outdated after the move, got %v", names)
+	}
+	if err := moved.RefreshAllShims(); err != nil {
+		t.Fatal(err)
+	}
+	if names := outdated(moved); len(names) != 0 {
+		t.Errorf("expected reshim to bring the shims up to date, got %v outdated", names)
+	}
+
+	// So does an older template
+	shimPath := filepath.Join(newDir, "shims", "tool")
+	data, err := os.ReadFile(shimPath) //nolint:gosec
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := strings.Replace(string(data), "# wand-shim-version: ", "# wand-shim-version: 0", 1)
+	if err := os.WriteFile(shimPath, []byte(old), 0755); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	if names := outdated(moved); len(names) != 1 {
+		t.Errorf("expected an older template to be outdated, got %v", names)
+	}
+}
@@ -0,0 +1,186 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ochairo/wand/internal/domain/entities"
+	errs "github.com/ochairo/wand/internal/domain/errors"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// sourceFixture serves archives by path and installs formulas written into an
+// isolated wand directory
+type sourceFixture struct {
+	server    *httptest.Server
+	installer *services.InstallerService
+	home      string
+	wandDir   string
+}
+
+// newSourceFixture serves the given archives and writes the given formulas;
+// "{server}" in a formula is replaced with the server URL
+func newSourceFixture(t *testing.T, archives map[string][]byte, formulas map[string]string) *sourceFixture {
+	t.Helper()
+
+	f := &sourceFixture{}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := archives[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(f.server.Close)
+
+	served := make(map[string]string, len(formulas))
+	for name, body := range formulas {
+		served[name] = strings.ReplaceAll(body, "{server}", f.server.URL)
+	}
+	ti := newTestInstaller(t, served, nil)
+	f.installer, f.home, f.wandDir = ti.installer, ti.home, ti.wandDir
+
+	return f
+}
+
+// builds returns how many times the build commands ran
+func (f *sourceFixture) builds(t *testing.T) int {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(f.home, "builds")) //nolint:gosec
+	if os.IsNotExist(err) {
+		return 0
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "\n")
+}
+
+const sourceFormulaHeader = "name: hello\ntype: cli\ndescription: Test tool\nhomepage: https://example.com\nrepository: example/hello\nbinaries:\n  - hello\n"
+
+// sourceSection builds hello from hello-{version}/hello.sh, checking that the
+// commands run inside the unpacked source directory
+const sourceSection = `source:
+  url: "{server}/src/hello-{version}.tar.gz"
+  commands:
+    - test "$(basename "$PWD")" = hello-{version}
+    - mkdir -p {prefix}/bin && cp hello.sh {prefix}/bin/hello
+    - echo built >> "$HOME/builds"
+`
+
+// TestSourceBuildIsCached tests that a source-only formula is built into the
+// install directory once and restored from the build cache afterwards
+func TestSourceBuildIsCached(t *testing.T) {
+	f := newSourceFixture(t,
+		map[string][]byte{"/src/hello-1.2.tar.gz": buildTarGz(t, map[string]string{"hello-1.2/hello.sh": "#!/bin/sh\necho hello\n"})},
+		map[string]string{"hello": sourceFormulaHeader + sourceSection},
+	)
+
+	if err := f.installer.InstallPackage("hello", "1.2.0"); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+
+	installDir := filepath.Join(f.wandDir, "packages", "hello", "1.2.0")
+	if _, err := os.Stat(filepath.Join(installDir, "bin", "hello")); err != nil {
+		t.Fatalf("expected built binary: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(installDir, "hello.sh")); !os.IsNotExist(err) {
+		t.Error("expected the source tree to stay out of the install directory")
+	}
+	if n := f.builds(t); n != 1 {
+		t.Fatalf("expected 1 build, got %d", n)
+	}
+
+	cached, _ := filepath.Glob(filepath.Join(f.wandDir, "cache", "builds", "hello", "1.2.0-*"))
+	if len(cached) != 1 {
+		t.Fatalf("expected one cached build, got %v", cached)
+	}
+
+	// Reinstalling restores the cached build without running the commands
+	if err := f.installer.UninstallPackage("hello", "1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.installer.InstallPackage("hello", "1.2.0"); err != nil {
+		t.Fatalf("reinstall failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(installDir, "bin", "hello")); err != nil {
+		t.Fatalf("expected restored binary: %v", err)
+	}
+	if n := f.builds(t); n != 1 {
+		t.Errorf("expected the cached build to be reused, got %d builds", n)
+	}
+}
+
+// TestBuildFromSourceOverridesPrebuilt tests that BuildFromSource builds even
+// when the platform has a prebuilt artifact, and fails without a source section
+func TestBuildFromSourceOverridesPrebuilt(t *testing.T) {
+	platform := entities.CurrentPlatform()
+	prebuilt := "platforms:\n  " + platform.OS + ":\n    " + platform.Arch + ":\n      download_url: \"{server}/bin/hello-{version}.tar.gz\"\n"
+	archives := map[string][]byte{
+		"/bin/hello-1.2.tar.gz": buildTarGz(t, map[string]string{"bin/hello": "#!/bin/sh\necho prebuilt\n"}),
+		"/src/hello-1.2.tar.gz": buildTarGz(t, map[string]string{"hello-1.2/hello.sh": "#!/bin/sh\necho built\n"}),
+	}
+
+	t.Run("with source", func(t *testing.T) {
+		f := newSourceFixture(t, archives, map[string]string{"hello": sourceFormulaHeader + prebuilt + sourceSection})
+
+		if err := f.installer.InstallPackageWithOptions("hello", "1.2.0", services.InstallOptions{BuildFromSource: true}); err != nil {
+			t.Fatalf("install failed: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(f.wandDir, "packages", "hello", "1.2.0", "bin", "hello")) //nolint:gosec
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), "echo built") {
+			t.Errorf("expected the source build, got %q", data)
+		}
+	})
+
+	t.Run("without source", func(t *testing.T) {
+		f := newSourceFixture(t, archives, map[string]string{"hello": sourceFormulaHeader + prebuilt})
+
+		err := f.installer.InstallPackageWithOptions("hello", "1.2.0", services.InstallOptions{BuildFromSource: true})
+		requireErrorCode(t, err, errs.ErrConfigInvalid, "cannot be built from source")
+	})
+}
+
+// TestBuildDependencies tests that build dependencies are installed first and
+// their binaries are on PATH while building
+func TestBuildDependencies(t *testing.T) {
+	platform := entities.CurrentPlatform()
+	tool := "name: tool\ntype: cli\ndescription: Build tool\nhomepage: https://example.com\nrepository: example/tool\nbinaries:\n  - tool\n" +
+		"platforms:\n  " + platform.OS + ":\n    " + platform.Arch + ":\n      download_url: \"{server}/bin/tool-{version}.tar.gz\"\n"
+	hello := sourceFormulaHeader + "build_dependencies:\n  - tool\n" + `source:
+  url: "{server}/src/hello-{version}.tar.gz"
+  commands:
+    - mkdir -p {prefix}/bin && tool > {prefix}/bin/hello
+`
+
+	f := newSourceFixture(t,
+		map[string][]byte{
+			"/bin/tool-1.2.tar.gz":  buildTarGz(t, map[string]string{"bin/tool": "#!/bin/sh\necho 'echo made by tool'\n"}),
+			"/src/hello-1.2.tar.gz": buildTarGz(t, map[string]string{"hello-1.2/README": "hello\n"}),
+		},
+		map[string]string{"hello": hello, "tool": tool},
+	)
+
+	if err := f.installer.InstallPackage("hello", "1.2.0"); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(f.wandDir, "packages", "hello", "1.2.0", "bin", "hello")) //nolint:gosec
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(string(data)) != "echo made by tool" {
+		t.Errorf("unexpected build output %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(f.wandDir, "packages", "tool", "1.2.0", "bin", "tool")); err != nil {
+		t.Errorf("expected the build dependency to be installed: %v", err)
+	}
+}
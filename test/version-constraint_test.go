@@ -0,0 +1,118 @@
+package test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/interfaces"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// TestWandRCVersionConstraints tests that a constraint in a .wandrc resolves
+// to the newest installed match, and that shims hand it to wand exec
+func TestWandRCVersionConstraints(t *testing.T) {
+	wandDir, formulasDir := t.TempDir(), t.TempDir()
+	formula := `name: tool
+type: cli
+description: Test tool
+homepage: https://example.com
+repository: example/tool
+binaries: [tool]
+`
+	if err := os.WriteFile(filepath.Join(formulasDir, "tool.yaml"), []byte(formula), 0644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	fs := domainadapters.NewFileSystemAdapter()
+	registryRepo := domainadapters.NewRegistryRepository(fs, wandDir)
+	registry := entities.NewRegistry()
+	for _, v := range []string{"1.1.0", "1.2.0", "2.0.0"} {
+		version, _ := entities.NewVersion(v)
+		pkg := entities.NewPackage("tool", entities.PackageTypeCLI, version)
+		pkg.InstallPath = filepath.Join(wandDir, "packages", "tool", v)
+		pkg.BinPath = filepath.Join(pkg.InstallPath, "bin")
+		registry.AddPackage(pkg)
+	}
+	registry.SetGlobalVersion("tool", "2.0.0")
+	if err := registryRepo.Save(registry); err != nil {
+		t.Fatal(err)
+	}
+
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, ".wandrc"), []byte("versions:\n  tool: \"^1\"\n"), 0644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	formulaRepo := domainadapters.NewFormulaRepository(fs, formulasDir)
+	wandrcRepo := domainadapters.NewWandRCRepository(fs)
+
+	// A stand-in for the wand binary that reports how the shim called it
+	wandBin := filepath.Join(t.TempDir(), "wand")
+	if err := os.WriteFile(wandBin, []byte("#!/bin/sh\necho \"$@\"\n"), 0755); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	shimService := services.NewShimServiceWithOptions(registryRepo, wandrcRepo, formulaRepo, fs, wandDir, services.ShimServiceOptions{Executable: wandBin})
+
+	version, err := shimService.ResolveVersion("tool", projectDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "1.2.0" {
+		t.Errorf("expected ^1 to resolve to 1.2.0, got %s", version)
+	}
+
+	if err := os.WriteFile(filepath.Join(projectDir, ".wandrc"), []byte("versions:\n  tool: \"~1.3\"\n"), 0644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	if _, err := shimService.ResolveVersion("tool", projectDir); err == nil || !strings.Contains(err.Error(), "wand install tool@~1.3") {
+		t.Errorf("expected an install hint for an unmatched constraint, got %v", err)
+	}
+
+	if err := shimService.CreateShims("tool", []string{"tool"}); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(filepath.Join(wandDir, "shims", "tool"), "--flag", "arg") //nolint:gosec
+	cmd.Dir = projectDir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("shim failed: %v\n%s", err, output)
+	}
+	if want := "exec --package tool tool --flag arg"; strings.TrimSpace(string(output)) != want {
+		t.Errorf("expected the shim to run %q, got %q", want, strings.TrimSpace(string(output)))
+	}
+}
+
+// TestResolveTwoPartVersion tests that a two-part version names the release
+// tagged with it, and is a prefix only when no such release exists
+func TestResolveTwoPartVersion(t *testing.T) {
+	formulasDir := t.TempDir()
+	formula := "name: nano\ntype: cli\ndescription: Text editor\nhomepage: https://example.com\nrepository: example/nano\n"
+	if err := os.WriteFile(filepath.Join(formulasDir, "nano.yaml"), []byte(formula), 0644); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	github := &fakeGitHubClient{
+		release: newRelease("example", "nano", "v8.7.1"),
+		others: []*interfaces.GitHubRelease{
+			newRelease("example", "nano", "v8.7"),
+			newRelease("example", "nano", "v8.6.2"),
+			newRelease("example", "nano", "v8.6.1"),
+		},
+	}
+	versions := services.NewVersionService(github, domainadapters.NewFormulaRepository(domainadapters.NewFileSystemAdapter(), formulasDir))
+
+	for spec, want := range map[string]string{"8.7": "8.7.0", "v8.7": "8.7.0", "8.7.x": "8.7.1", "8.6": "8.6.2", "8": "8.7.1"} {
+		version, err := versions.ResolveVersion("nano", spec)
+		if err != nil {
+			t.Errorf("%s: %v", spec, err)
+			continue
+		}
+		if version.String() != want {
+			t.Errorf("expected %s to resolve to %s, got %s", spec, want, version)
+		}
+	}
+}
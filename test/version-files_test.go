@@ -0,0 +1,103 @@
+package test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// TestVersionFiles tests that other tools' version files pin versions after
+// .wandrc, with the nearest directory winning
+func TestVersionFiles(t *testing.T) {
+	wandDir := t.TempDir()
+	fs := domainadapters.NewFileSystemAdapter()
+	registryRepo := domainadapters.NewRegistryRepository(fs, wandDir)
+	registry := entities.NewRegistry()
+	for name, v := range map[string]string{"nodejs": "22.1.0", "python": "3.12.1", "golang": "1.23.0"} {
+		version, _ := entities.NewVersion(v)
+		registry.AddPackage(entities.NewPackage(name, entities.PackageTypeCLI, version))
+		registry.SetGlobalVersion(name, v)
+	}
+	if err := registryRepo.Save(registry); err != nil {
+		t.Fatal(err)
+	}
+
+	// repo/            .tool-versions, go.mod
+	// repo/web/        .nvmrc, .wandrc pinning python
+	root := t.TempDir()
+	web := filepath.Join(root, "web")
+	if err := os.MkdirAll(web, 0755); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(root, ".tool-versions"): "# asdf\nnodejs 18.19.0\npython system 3.11.7\nruby ref:abc\n",
+		filepath.Join(root, "go.mod"):         "module example.com/x\n\ngo 1.22\n\ntoolchain go1.22.1\n",
+		filepath.Join(web, ".nvmrc"):          "v20.10.0\n",
+		filepath.Join(web, ".wandrc"):         "versions:\n  python: \"3.12\"\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil { //nolint:gosec
+			t.Fatal(err)
+		}
+	}
+
+	readers, err := domainadapters.NewVersionFileReaders(fs, entities.VersionFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shimService := services.NewShimServiceWithOptions(registryRepo, domainadapters.NewWandRCRepository(fs), domainadapters.NewFormulaRepository(fs, t.TempDir()), fs, wandDir, services.ShimServiceOptions{VersionFiles: readers})
+
+	tests := []struct {
+		pkg, dir     string
+		spec, source string
+	}{
+		{"nodejs", web, "20.10.0", filepath.Join(web, ".nvmrc")},
+		{"nodejs", root, "18.19.0", filepath.Join(root, ".tool-versions")},
+		{"python", web, "3.12", filepath.Join(web, ".wandrc")},
+		{"python", root, "3.11.7", filepath.Join(root, ".tool-versions")},
+		{"golang", web, "1.22.1", filepath.Join(root, "go.mod")},
+		{"nodejs", t.TempDir(), "22.1.0", "global"},
+	}
+	for _, tt := range tests {
+		spec, source, err := shimService.ResolveSpec(tt.pkg, tt.dir)
+		if err != nil {
+			t.Fatalf("%s in %s: %v", tt.pkg, tt.dir, err)
+		}
+		if spec != tt.spec || source != tt.source {
+			t.Errorf("%s in %s: got %s from %s, want %s from %s", tt.pkg, tt.dir, spec, source, tt.spec, tt.source)
+		}
+	}
+
+	// Only the configured files are read
+	readers, _ = domainadapters.NewVersionFileReaders(fs, []string{"go.mod"})
+	shimService = services.NewShimServiceWithOptions(registryRepo, domainadapters.NewWandRCRepository(fs), domainadapters.NewFormulaRepository(fs, t.TempDir()), fs, wandDir, services.ShimServiceOptions{VersionFiles: readers})
+	if spec, source, _ := shimService.ResolveSpec("nodejs", web); source != "global" {
+		t.Errorf("expected .nvmrc to be ignored, got %s from %s", spec, source)
+	}
+
+	// Shims leave directories with a version file for the package to wand exec
+	wandBin := filepath.Join(t.TempDir(), "wand")
+	if err := os.WriteFile(wandBin, []byte("#!/bin/sh\necho \"$@\"\n"), 0755); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	readers, _ = domainadapters.NewVersionFileReaders(fs, entities.VersionFiles)
+	shimService = services.NewShimServiceWithOptions(registryRepo, domainadapters.NewWandRCRepository(fs), domainadapters.NewFormulaRepository(fs, t.TempDir()), fs, wandDir, services.ShimServiceOptions{Executable: wandBin, VersionFiles: readers})
+	if err := shimService.CreateShims("nodejs", []string{"node"}); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(filepath.Join(wandDir, "shims", "node"), "-v") //nolint:gosec
+	cmd.Dir = web
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("shim failed: %v\n%s", err, output)
+	}
+	if want := "exec --package nodejs node -v"; strings.TrimSpace(string(output)) != want {
+		t.Errorf("expected the shim to run %q, got %q", want, strings.TrimSpace(string(output)))
+	}
+}
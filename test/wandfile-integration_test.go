@@ -54,11 +54,14 @@ func TestWandfileWorkflow(t *testing.T) {
 	// Initialize all dependencies
 	fs := domain_adapters.NewFileSystemAdapter()
 	downloader := domain_adapters.NewDownloaderAdapter()
+	verifier := domain_adapters.NewVerifierAdapter()
 	extractor := domain_adapters.NewExtractorAdapter(fs)
 	shellExecutor := domain_adapters.NewShellExecutorAdapter()
 
 	// Initialize repositories
 	registryRepo := domain_adapters.NewRegistryRepository(fs, wandDir)
+	lockRepo := domain_adapters.NewChecksumLockRepository(fs, wandDir)
+	approvalRepo := domain_adapters.NewHookApprovalRepository(fs, wandDir)
 	formulaRepo := domain_adapters.NewFormulaRepository(fs, formulasDir)
 	wandrcRepo := domain_adapters.NewWandRCRepository(fs)
 	wandfileRepo := domain_adapters.NewWandfileRepository(fs)
@@ -73,7 +76,10 @@ func TestWandfileWorkflow(t *testing.T) {
 	installerService := services.NewInstallerService(
 		formulaRepo,
 		registryRepo,
+		lockRepo,
+		approvalRepo,
 		downloader,
+		verifier,
 		extractor,
 		fs,
 		shellExecutor,
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	domainadapters "github.com/ochairo/wand/internal/domain-adapters"
+	"github.com/ochairo/wand/internal/domain/entities"
+	"github.com/ochairo/wand/internal/domain/services"
+)
+
+// TestWandRCInheritance tests that every directory level is consulted, child
+// .wandrc files override parents, extends: false stops the search, and
+// WAND_<PACKAGE>_VERSION overrides them all
+func TestWandRCInheritance(t *testing.T) {
+	wandDir := t.TempDir()
+	fs := domainadapters.NewFileSystemAdapter()
+	registryRepo := domainadapters.NewRegistryRepository(fs, wandDir)
+	registry := entities.NewRegistry()
+	for _, v := range []string{"1.0.0", "2.0.0", "3.0.0"} {
+		version, _ := entities.NewVersion(v)
+		registry.AddPackage(entities.NewPackage("tool", entities.PackageTypeCLI, version))
+	}
+	registry.SetGlobalVersion("tool", "3.0.0")
+	if err := registryRepo.Save(registry); err != nil {
+		t.Fatal(err)
+	}
+
+	// root/.wandrc        tool 1.0.0
+	// root/a/.wandrc      other packages only
+	// root/a/b/.wandrc    other packages only
+	// root/a/b/c/         working directory
+	root := t.TempDir()
+	deep := filepath.Join(root, "a", "b", "c")
+	if err := os.MkdirAll(deep, 0755); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+	write := func(dir, content string) {
+		if err := os.WriteFile(filepath.Join(dir, ".wandrc"), []byte(content), 0644); err != nil { //nolint:gosec
+			t.Fatal(err)
+		}
+	}
+	write(root, "versions:\n  tool: 1.0.0\n")
+	write(filepath.Join(root, "a"), "versions:\n  jq: 1.7.1\n")
+	write(filepath.Join(root, "a", "b"), "versions:\n  nano: \"8.2\"\n")
+
+	wandrcRepo := domainadapters.NewWandRCRepository(fs)
+	shimService := services.NewShimService(registryRepo, wandrcRepo, domainadapters.NewFormulaRepository(fs, t.TempDir()), fs, wandDir)
+
+	res, err := shimService.Resolve("tool", deep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Version != "1.0.0" || res.Source != filepath.Join(root, ".wandrc") || len(res.Steps) != 4 {
+		t.Errorf("expected 1.0.0 from the root .wandrc after two misses, got %+v", res)
+	}
+
+	packages, err := shimService.ProjectPackages(deep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 3 {
+		t.Errorf("expected the pins of all three files, got %v", packages)
+	}
+
+	// The child overrides the parent
+	write(filepath.Join(root, "a"), "versions:\n  tool: \"2\"\n")
+	if version, err := shimService.ResolveVersion("tool", deep); err != nil || version != "2.0.0" {
+		t.Errorf("expected the nearer pin 2.0.0, got %q (%v)", version, err)
+	}
+
+	// extends: false stops at the directory that sets it
+	write(filepath.Join(root, "a", "b"), "extends: false\nversions:\n  nano: \"8.2\"\n")
+	if version, err := shimService.ResolveVersion("tool", deep); err != nil || version != "3.0.0" {
+		t.Errorf("expected the global version 3.0.0, got %q (%v)", version, err)
+	}
+	if packages, _ := shimService.ProjectPackages(deep); len(packages) != 1 || packages[0] != "nano" {
+		t.Errorf("expected only the nearest file's pins, got %v", packages)
+	}
+
+	// WAND_TOOL_VERSION overrides every file and the global version
+	t.Setenv("WAND_TOOL_VERSION", "^2")
+	res, err = shimService.Resolve("tool", deep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Version != "2.0.0" || res.Source != "WAND_TOOL_VERSION" || len(res.Steps) != 2 {
+		t.Errorf("expected 2.0.0 from WAND_TOOL_VERSION, got %+v", res)
+	}
+}
@@ -55,11 +55,14 @@ func TestWandRCWorkflow(t *testing.T) {
 	// Initialize all dependencies exactly like main.go but with test paths
 	fs := domain_adapters.NewFileSystemAdapter()
 	downloader := domain_adapters.NewDownloaderAdapter()
+	verifier := domain_adapters.NewVerifierAdapter()
 	extractor := domain_adapters.NewExtractorAdapter(fs)
 	shellExecutor := domain_adapters.NewShellExecutorAdapter()
 
 	// Initialize repositories with test paths
 	registryRepo := domain_adapters.NewRegistryRepository(fs, wandDir)
+	lockRepo := domain_adapters.NewChecksumLockRepository(fs, wandDir)
+	approvalRepo := domain_adapters.NewHookApprovalRepository(fs, wandDir)
 	formulaRepo := domain_adapters.NewFormulaRepository(fs, formulasDir)
 	wandrcRepo := domain_adapters.NewWandRCRepository(fs)
 
@@ -72,7 +75,10 @@ func TestWandRCWorkflow(t *testing.T) {
 	installerService := services.NewInstallerService(
 		formulaRepo,
 		registryRepo,
+		lockRepo,
+		approvalRepo,
 		downloader,
+		verifier,
 		extractor,
 		fs,
 		shellExecutor,